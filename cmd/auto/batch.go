@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/batch"
+)
+
+// batch runs the batch subcommands
+func (c *cli) batch(args []string) int {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprintln(c.stderr, c.usage)
+		return exitUsage
+	}
+	return c.batchRun(args[1:])
+}
+
+// batchRun spawns every task of a manifest and waits for them to finish.
+// Progress goes to stderr; the summary, or the result as JSON, to stdout.
+func (c *cli) batchRun(args []string) int {
+	fs := c.flags("batch run")
+	out := fs.String("out", "", "Where to write the JSON result (default: <manifest>.result.json)")
+	asJSON := fs.Bool("json", false, "Print the result as JSON instead of a summary")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	manifest, err := batch.Load(positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if *out == "" {
+		*out = batch.ResultPath(positional[0])
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	b := batch.Submit(c.ctx, m, manifest)
+	fmt.Fprintf(c.stderr, "Submitted %d tasks from %s\n", len(manifest.Tasks), manifest.Name)
+
+	// Not every status change produces an event, so also poll
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	reported := make([]batch.Outcome, len(manifest.Tasks))
+	interrupted := false
+	for !b.Done() && !interrupted {
+		select {
+		case <-c.ctx.Done():
+			interrupted = true
+		case <-c.events:
+		case <-ticker.C:
+		}
+
+		b.Update()
+		for i, t := range b.Result().Tasks {
+			if t.Outcome == reported[i] || t.Outcome == batch.OutcomeSpawning {
+				continue
+			}
+			reported[i] = t.Outcome
+			line := fmt.Sprintf("%s  %-10s %s", t.Name, t.Outcome, t.AgentID)
+			if t.Error != "" {
+				line += "  " + t.Error
+			}
+			fmt.Fprintln(c.stderr, line)
+		}
+	}
+
+	result := b.Result()
+	if err := result.WriteFile(*out); err != nil {
+		return c.fail(fmt.Errorf("failed to write result: %w", err))
+	}
+
+	if *asJSON {
+		if code := c.writeJSON(result); code != exitOK {
+			return code
+		}
+	} else {
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tOUTCOME\tAGENT\tDIRECTORY\tERROR")
+		for _, t := range result.Tasks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Name, t.Outcome, orDash(t.AgentID), t.Directory, t.Error)
+		}
+		w.Flush()
+		fmt.Fprintf(c.stdout, "\n%d completed, %d errored, %d cancelled; result written to %s\n",
+			result.Summary[batch.OutcomeCompleted], result.Summary[batch.OutcomeErrored], result.Summary[batch.OutcomeCancelled], *out)
+	}
+
+	if interrupted {
+		fmt.Fprintln(c.stderr, "Interrupted; unfinished tasks keep running")
+		return exitError
+	}
+	if !result.Succeeded() {
+		return exitError
+	}
+	return exitOK
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/daemon"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+	"github.com/CastAIPhil/AUTO/internal/session"
+)
+
+// Exit codes of the CLI subcommands
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitUnsupported = 4
+)
+
+// command is a scriptable subcommand
+type command struct {
+	usage   string
+	summary string
+	run     func(c *cli, args []string) int
+}
+
+var commands = map[string]command{
+	"list":     {"list [--status S] [--type T] [--json]", "List agents", (*cli).list},
+	"show":     {"show [--json] <id>", "Show an agent's details", (*cli).show},
+	"tail":     {"tail [-f] [-n N] <id>", "Print an agent's output", (*cli).tail},
+	"send":     {"send <id> <input...>", "Send input to an agent (\"-\" reads it from stdin)", (*cli).send},
+	"spawn":    {"spawn [--dir D] [--prompt P] [--type T] [--name N] [--priority P] [--worktree] [--json]", "Start a new agent, or queue it when at the concurrency limit", (*cli).spawn},
+	"kill":     {"kill <id>", "Terminate an agent", (*cli).kill},
+	"batch":    {"batch run [--out FILE] [--json] <manifest>", "Spawn every task of a manifest and wait for them to finish", (*cli).batch},
+	"workflow": {"workflow run [--json] <file>", "Run a workflow of dependent agent steps", (*cli).workflow},
+	"worktree": {"worktree diff [--stat] <id> | merge [--message M] <id> | discard <id>", "Review, merge or discard the worktree of an agent spawned with --worktree", (*cli).worktree},
+}
+
+// commandNames returns the subcommand names in order
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cli runs subcommands against the agents of a running daemon, or of an
+// in-process session manager when no daemon is available
+type cli struct {
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+
+	usage      string // of the running command, for its help output
+	configPath string
+	standalone bool
+
+	manager *session.Manager
+	events  chan agent.Event
+	closers []func()
+}
+
+// runCommand runs a subcommand and returns its exit code
+func runCommand(name string, args []string) int {
+	cmd := commands[name]
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	c := &cli{
+		ctx:    ctx,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		usage:  fmt.Sprintf("Usage: auto %s\n\n%s", cmd.usage, cmd.summary),
+		events: make(chan agent.Event, 100),
+	}
+	defer c.close()
+
+	return cmd.run(c, args)
+}
+
+// flags returns a flag set for a subcommand with the options shared by all of them
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configPath, "config", "", "Path to config file")
+	fs.StringVar(&c.configPath, "c", "", "Path to config file (shorthand)")
+	fs.BoolVar(&c.standalone, "standalone", false, "Discover agents in-process instead of using a running daemon")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "%s\n\nFlags:\n", c.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses flags appearing anywhere among args and checks the number of
+// positional arguments. It returns false with an exit code on failure.
+func (c *cli) parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, int, bool) {
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, exitOK, false
+		}
+		return nil, exitUsage, false
+	}
+	if len(positional) < minArgs || (maxArgs >= 0 && len(positional) > maxArgs) {
+		fs.Usage()
+		return nil, exitUsage, false
+	}
+	return positional, exitOK, true
+}
+
+// parseArgs parses flags interspersed with positional arguments, which it
+// returns. Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// connect returns the session manager, attaching to a running daemon unless
+// --standalone was given
+func (c *cli) connect() (*session.Manager, error) {
+	if c.manager != nil {
+		return c.manager, nil
+	}
+
+	configPath := c.configPath
+	if configPath == "" {
+		configPath = config.ConfigPath()
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var client *daemon.Client
+	if !c.standalone {
+		client, _ = daemon.Dial(cfg.Daemon.Socket)
+	}
+
+	var registry *agent.Registry
+	if client != nil {
+		registry = agent.NewRegistry()
+		registry.Register(client)
+		c.closers = append(c.closers, func() { client.Close() })
+
+		// The daemon queues spawns against its own limits
+		attachedCfg := *cfg
+		attachedCfg.Queue = config.QueueConfig{}
+		cfg = &attachedCfg
+	} else {
+		var closeRegistry func()
+		registry, closeRegistry = setupRegistry(cfg)
+		c.closers = append(c.closers, closeRegistry)
+	}
+
+	// The daemon or TUI owns persistence, alerting, restarts and conflict detection
+	ownCfg := *cfg
+	ownCfg.Restart = config.RestartConfig{}
+	ownCfg.Conflicts = config.ConflictsConfig{}
+	manager := session.NewManager(&ownCfg, nil, registry, nil)
+	manager.Subscribe(eventbus.Options{Name: "cli"}, func(event agent.Event) {
+		select {
+		case c.events <- event:
+		default:
+		}
+	})
+	if err := manager.Start(c.ctx); err != nil {
+		return nil, fmt.Errorf("failed to start session manager: %w", err)
+	}
+	c.closers = append(c.closers, manager.Stop)
+
+	c.manager = manager
+	return manager, nil
+}
+
+func (c *cli) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i]()
+	}
+}
+
+// lookup finds an agent by ID or unique ID prefix
+func (c *cli) lookup(m *session.Manager, id string) (agent.Agent, int) {
+	if a, ok := m.Get(id); ok {
+		return a, exitOK
+	}
+
+	var matches []agent.Agent
+	for _, a := range m.List() {
+		if strings.HasPrefix(a.ID(), id) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], exitOK
+	case 0:
+		fmt.Fprintf(c.stderr, "agent not found: %s\n", id)
+		return nil, exitNotFound
+	default:
+		fmt.Fprintf(c.stderr, "agent ID %s is ambiguous: %d agents match\n", id, len(matches))
+		return nil, exitUsage
+	}
+}
+
+// fail reports an error and returns the matching exit code
+func (c *cli) fail(err error) int {
+	fmt.Fprintf(c.stderr, "Error: %v\n", err)
+	if errors.Is(err, agent.ErrUnsupported) {
+		return exitUnsupported
+	}
+	return exitError
+}
+
+func (c *cli) list(args []string) int {
+	fs := c.flags("list")
+	status := fs.String("status", "", "Only list agents with this status")
+	agentType := fs.String("type", "", "Only list agents of this type")
+	asJSON := fs.Bool("json", false, "Print JSON")
+	if _, code, ok := c.parse(fs, args, 0, 0); !ok {
+		return code
+	}
+
+	var want agent.Status
+	if *status != "" {
+		st, ok := agent.ParseStatus(*status)
+		if !ok {
+			fmt.Fprintf(c.stderr, "unknown status: %s\n", *status)
+			return exitUsage
+		}
+		want = st
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	var agents []agent.Agent
+	for _, a := range m.List() {
+		if *status != "" && a.Status() != want {
+			continue
+		}
+		if *agentType != "" && a.Type() != *agentType {
+			continue
+		}
+		agents = append(agents, a)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		if !agents[i].LastActivity().Equal(agents[j].LastActivity()) {
+			return agents[i].LastActivity().After(agents[j].LastActivity())
+		}
+		return agents[i].ID() < agents[j].ID()
+	})
+
+	if *asJSON {
+		snaps := make([]plugin.AgentSnapshot, 0, len(agents))
+		for _, a := range agents {
+			snap := plugin.Snapshot(a)
+			snap.Output = ""
+			snap.Restarts = m.Restarts(a.ID())
+			snaps = append(snaps, snap)
+		}
+		return c.writeJSON(snaps)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tLAST ACTIVITY\tNAME")
+	for _, a := range agents {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", a.ID(), a.Type(), a.Status(), formatAge(a.LastActivity()), a.Name())
+	}
+	w.Flush()
+	return exitOK
+}
+
+func (c *cli) show(args []string) int {
+	fs := c.flags("show")
+	asJSON := fs.Bool("json", false, "Print JSON, including the tail of the output")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+	a, code := c.lookup(m, positional[0])
+	if a == nil {
+		return code
+	}
+
+	if *asJSON {
+		snap := plugin.Snapshot(a)
+		snap.Restarts = m.Restarts(a.ID())
+		return c.writeJSON(snap)
+	}
+
+	metrics := a.Metrics()
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", a.ID())
+	fmt.Fprintf(w, "Name:\t%s\n", a.Name())
+	fmt.Fprintf(w, "Type:\t%s\n", a.Type())
+	fmt.Fprintf(w, "Status:\t%s\n", a.Status())
+	fmt.Fprintf(w, "Directory:\t%s\n", a.Directory())
+	if a.ParentID() != "" {
+		fmt.Fprintf(w, "Parent:\t%s\n", a.ParentID())
+	}
+	if task := a.CurrentTask(); task != "" {
+		fmt.Fprintf(w, "Task:\t%s\n", task)
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", formatTime(a.StartTime()))
+	fmt.Fprintf(w, "Last activity:\t%s\n", formatTime(a.LastActivity()))
+	if metrics.Model != "" {
+		fmt.Fprintf(w, "Model:\t%s\n", metrics.Model)
+	}
+	fmt.Fprintf(w, "Tokens:\t%d in, %d out\n", metrics.TokensIn, metrics.TokensOut)
+	fmt.Fprintf(w, "Cost:\t$%.4f\n", metrics.EstimatedCost)
+	if metrics.ContextUtilization > 0 {
+		fmt.Fprintf(w, "Context:\t%.0f%%\n", metrics.ContextUtilization*100)
+	}
+	if restarts := m.Restarts(a.ID()); restarts > 0 {
+		fmt.Fprintf(w, "Restarts:\t%d\n", restarts)
+	}
+	if err := a.LastError(); err != nil {
+		fmt.Fprintf(w, "Error:\t%v\n", err)
+	}
+	w.Flush()
+	return exitOK
+}
+
+func (c *cli) tail(args []string) int {
+	fs := c.flags("tail")
+	follow := fs.Bool("f", false, "Keep printing output until the agent goes away or AUTO is interrupted")
+	lines := fs.Int("n", 10, "Number of trailing lines to print (0 = all)")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+	a, code := c.lookup(m, positional[0])
+	if a == nil {
+		return code
+	}
+	id := a.ID()
+
+	printed := readOutput(a)
+	fmt.Fprint(c.stdout, lastLines(printed, *lines))
+	if !*follow {
+		return exitOK
+	}
+
+	// Not every output change produces an event, so also poll
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return exitOK
+		case event := <-c.events:
+			if event.AgentID != id {
+				continue
+			}
+			if event.Type == agent.EventAgentTerminated {
+				return exitOK
+			}
+			if event.Agent != nil {
+				a = event.Agent
+			}
+		case <-ticker.C:
+			// Daemon agents only report output changes when asked
+			if r, ok := a.(interface{ Refresh() error }); ok {
+				r.Refresh()
+			}
+		}
+
+		current := readOutput(a)
+		fmt.Fprint(c.stdout, newOutput(printed, current))
+		printed = current
+	}
+}
+
+func (c *cli) send(args []string) int {
+	fs := c.flags("send")
+	positional, code, ok := c.parse(fs, args, 2, -1)
+	if !ok {
+		return code
+	}
+
+	input := strings.Join(positional[1:], " ")
+	if input == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return c.fail(fmt.Errorf("failed to read input: %w", err))
+		}
+		input = strings.TrimRight(string(data), "\n")
+	}
+	if strings.TrimSpace(input) == "" {
+		fmt.Fprintln(c.stderr, "empty input")
+		return exitUsage
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+	a, code := c.lookup(m, positional[0])
+	if a == nil {
+		return code
+	}
+
+	if err := m.SendInput(a.ID(), input); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+func (c *cli) spawn(args []string) int {
+	fs := c.flags("spawn")
+	dir := fs.String("dir", ".", "Working directory of the new agent")
+	prompt := fs.String("prompt", "", "Initial prompt")
+	agentType := fs.String("type", "opencode", "Agent type")
+	name := fs.String("name", "", "Agent name")
+	priority := fs.String("priority", "normal", "Queue priority: low, normal or high")
+	isolate := fs.Bool("worktree", false, "Run the agent in a new git worktree and branch of the repository containing --dir")
+	asJSON := fs.Bool("json", false, "Print the new agent as JSON instead of its ID")
+	if _, code, ok := c.parse(fs, args, 0, 0); !ok {
+		return code
+	}
+
+	prio, ok := agent.ParsePriority(*priority)
+	if !ok {
+		fmt.Fprintf(c.stderr, "unknown priority: %s\n", *priority)
+		return exitUsage
+	}
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	a, err := m.Spawn(c.ctx, agent.SpawnConfig{
+		Type:      *agentType,
+		Name:      *name,
+		Directory: absDir,
+		Prompt:    *prompt,
+		Priority:  prio,
+		Worktree:  *isolate,
+	})
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if *asJSON {
+		snap := plugin.Snapshot(a)
+		snap.Restarts = m.Restarts(a.ID())
+		return c.writeJSON(snap)
+	}
+	fmt.Fprintln(c.stdout, a.ID())
+	return exitOK
+}
+
+func (c *cli) kill(args []string) int {
+	fs := c.flags("kill")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+	a, code := c.lookup(m, positional[0])
+	if a == nil {
+		return code
+	}
+
+	if err := m.Terminate(a.ID()); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+func (c *cli) writeJSON(v interface{}) int {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// readOutput returns an agent's output as a string
+func readOutput(a agent.Agent) string {
+	r := a.Output()
+	if r == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
+
+// lastLines returns the last n lines of s, or all of it if n is 0
+func lastLines(s string, n int) string {
+	if n <= 0 || s == "" {
+		return s
+	}
+	trimmed := strings.TrimSuffix(s, "\n")
+	idx := len(trimmed)
+	for i := 0; i < n; i++ {
+		idx = strings.LastIndexByte(trimmed[:idx], '\n')
+		if idx < 0 {
+			return s
+		}
+	}
+	return s[idx+1:]
+}
+
+// newOutput returns the part of current not yet printed. Output may be a
+// window that dropped its head, so the longest tail of printed that current
+// starts with is taken as already shown.
+func newOutput(printed, current string) string {
+	if current == "" {
+		return ""
+	}
+	for i := 0; i < len(printed); i++ {
+		next := strings.IndexByte(printed[i:], current[0])
+		if next < 0 {
+			break
+		}
+		i += next
+		if strings.HasPrefix(current, printed[i:]) {
+			return current[len(printed)-i:]
+		}
+	}
+	return current
+}
+
+// formatAge formats a time relative to now for tables
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
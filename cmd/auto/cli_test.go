@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/batch"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+	"github.com/CastAIPhil/AUTO/internal/session"
+	"github.com/CastAIPhil/AUTO/internal/workflow"
+)
+
+// unsupportedAgent rejects termination
+type unsupportedAgent struct {
+	*agent.MockAgent
+}
+
+func (a *unsupportedAgent) Terminate() error {
+	return fmt.Errorf("terminate %w for test sessions", agent.ErrUnsupported)
+}
+
+// idleProvider spawns agents that have already finished their prompt
+type idleProvider struct {
+	*agent.MockProvider
+}
+
+func (p *idleProvider) Type() string { return "idle" }
+
+func (p *idleProvider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	a := agent.NewMockAgent(config.Name+"-id", config.Name)
+	a.MockStatus = agent.StatusIdle
+	a.MockDirectory = config.Directory
+	return a, nil
+}
+
+// newTestCLI returns a CLI backed by a manager with a running and an idle agent
+func newTestCLI(t *testing.T) (*cli, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	registry := agent.NewRegistry()
+	registry.Register(agent.NewMockProvider())
+	registry.Register(&idleProvider{MockProvider: agent.NewMockProvider()})
+	manager := session.NewManager(&config.Config{}, nil, registry, nil)
+
+	running := agent.NewMockAgent("ses_running", "Builder")
+	running.MockStatus = agent.StatusRunning
+	running.MockLastActivity = time.Now()
+	manager.AddAgentForTesting(running)
+
+	idle := &unsupportedAgent{MockAgent: agent.NewMockAgent("ses_idle", "Reviewer")}
+	idle.MockStatus = agent.StatusIdle
+	idle.MockLastActivity = time.Now().Add(-time.Hour)
+	manager.AddAgentForTesting(idle)
+
+	var stdout, stderr bytes.Buffer
+	c := &cli{
+		ctx:     context.Background(),
+		stdout:  &stdout,
+		stderr:  &stderr,
+		manager: manager,
+		events:  make(chan agent.Event, 1),
+	}
+	return c, &stdout, &stderr
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		json       bool
+	}{
+		{[]string{"--json", "ses_1"}, []string{"ses_1"}, true},
+		{[]string{"ses_1", "--json"}, []string{"ses_1"}, true},
+		{[]string{"ses_1", "fix", "the", "bug"}, []string{"ses_1", "fix", "the", "bug"}, false},
+		{[]string{"ses_1", "--", "--json"}, []string{"ses_1", "--json"}, false},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		asJSON := fs.Bool("json", false, "")
+		positional, err := parseArgs(fs, tt.args)
+		if err != nil {
+			t.Fatalf("parseArgs(%v) error = %v", tt.args, err)
+		}
+		if !reflect.DeepEqual(positional, tt.positional) || *asJSON != tt.json {
+			t.Errorf("parseArgs(%v) = %v, json=%v; want %v, json=%v", tt.args, positional, *asJSON, tt.positional, tt.json)
+		}
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	c, stdout, _ := newTestCLI(t)
+
+	if code := c.list(nil); code != exitOK {
+		t.Fatalf("list exit code = %d, want %d", code, exitOK)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "ses_running") || !strings.HasPrefix(lines[2], "ses_idle") {
+		t.Errorf("list output = %q, want header then agents by last activity", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := c.list([]string{"--status", "idle", "--json"}); code != exitOK {
+		t.Fatalf("list --json exit code = %d, want %d", code, exitOK)
+	}
+	var snaps []plugin.AgentSnapshot
+	if err := json.Unmarshal(stdout.Bytes(), &snaps); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].ID != "ses_idle" || snaps[0].Status != "idle" {
+		t.Errorf("list --status idle = %+v", snaps)
+	}
+
+	if code := c.list([]string{"--status", "sleeping"}); code != exitUsage {
+		t.Errorf("unknown status exit code = %d, want %d", code, exitUsage)
+	}
+	if code := c.list([]string{"extra"}); code != exitUsage {
+		t.Errorf("unexpected argument exit code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestShowCommand(t *testing.T) {
+	c, stdout, _ := newTestCLI(t)
+
+	if code := c.show([]string{"ses_run", "--json"}); code != exitOK {
+		t.Fatalf("show exit code = %d, want %d", code, exitOK)
+	}
+	var snap plugin.AgentSnapshot
+	if err := json.Unmarshal(stdout.Bytes(), &snap); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if snap.ID != "ses_running" || snap.Name != "Builder" {
+		t.Errorf("show by prefix = %+v", snap)
+	}
+
+	if code := c.show([]string{"ses_missing"}); code != exitNotFound {
+		t.Errorf("missing agent exit code = %d, want %d", code, exitNotFound)
+	}
+	if code := c.show([]string{"ses_"}); code != exitUsage {
+		t.Errorf("ambiguous prefix exit code = %d, want %d", code, exitUsage)
+	}
+	if code := c.show(nil); code != exitUsage {
+		t.Errorf("missing ID exit code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestControlCommands(t *testing.T) {
+	c, stdout, _ := newTestCLI(t)
+
+	if code := c.send([]string{"ses_running", "fix", "tests"}); code != exitOK {
+		t.Fatalf("send exit code = %d, want %d", code, exitOK)
+	}
+	a, _ := c.manager.Get("ses_running")
+	if got := a.(*agent.MockAgent).LastInput; got != "fix tests" {
+		t.Errorf("sent input = %q, want %q", got, "fix tests")
+	}
+
+	if code := c.kill([]string{"ses_running"}); code != exitOK {
+		t.Fatalf("kill exit code = %d, want %d", code, exitOK)
+	}
+	if !a.(*agent.MockAgent).TerminateCalled {
+		t.Error("kill should terminate the agent")
+	}
+	if code := c.kill([]string{"ses_idle"}); code != exitUnsupported {
+		t.Errorf("unsupported kill exit code = %d, want %d", code, exitUnsupported)
+	}
+
+	if code := c.spawn([]string{"--type", "mock", "--name", "worker", "--dir", "/tmp/w"}); code != exitOK {
+		t.Fatalf("spawn exit code = %d, want %d", code, exitOK)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "worker-id" {
+		t.Errorf("spawn output = %q, want the new agent's ID", got)
+	}
+}
+
+func TestBatchCommand(t *testing.T) {
+	c, stdout, _ := newTestCLI(t)
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "fanout.yaml")
+	content := "defaults:\n  type: idle\ntasks:\n  - directory: api\n  - directory: web\n    type: other\n"
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if code := c.batch([]string{"run", manifest}); code != exitError {
+		t.Fatalf("batch run exit code = %d, want %d when a task fails", code, exitError)
+	}
+	if out := stdout.String(); !strings.Contains(out, "1 completed, 1 errored, 0 cancelled") {
+		t.Errorf("batch run output = %q", out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "fanout.result.json"))
+	if err != nil {
+		t.Fatalf("result file not written: %v", err)
+	}
+	var result batch.Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if len(result.Tasks) != 2 || result.Tasks[0].Outcome != batch.OutcomeCompleted || result.Tasks[0].AgentID != "api-id" ||
+		result.Tasks[1].Outcome != batch.OutcomeErrored || !strings.Contains(result.Tasks[1].Error, "unknown agent type") {
+		t.Errorf("result = %s", data)
+	}
+
+	stdout.Reset()
+	out := filepath.Join(dir, "out.json")
+	content = "tasks:\n  - directory: api\n    type: idle\n"
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if code := c.batch([]string{"run", "--json", "--out", out, manifest}); code != exitOK {
+		t.Fatalf("batch run exit code = %d, want %d", code, exitOK)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil || !result.Succeeded() {
+		t.Errorf("batch run --json = %s", stdout.String())
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("--out file not written: %v", err)
+	}
+
+	if code := c.batch([]string{"start", manifest}); code != exitUsage {
+		t.Errorf("unknown batch command exit code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestWorkflowCommand(t *testing.T) {
+	c, stdout, _ := newTestCLI(t)
+
+	path := filepath.Join(t.TempDir(), "feature.yaml")
+	content := "directory: /srv/app\ntype: idle\nsteps:\n  - id: plan\n  - id: build\n    depends_on: [plan]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if code := c.workflow([]string{"run", "--json", path}); code != exitOK {
+		t.Fatalf("workflow run exit code = %d, want %d", code, exitOK)
+	}
+	var result struct {
+		Name  string               `json:"name"`
+		Steps []workflow.StepState `json:"steps"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.Name != "feature" || len(result.Steps) != 2 || result.Steps[1].Status != workflow.StepCompleted || result.Steps[1].AgentID != "build-id" {
+		t.Errorf("workflow run --json = %s", stdout.String())
+	}
+
+	stdout.Reset()
+	content = "directory: /srv/app\nsteps:\n  - id: plan\n    type: other\n  - id: build\n    type: idle\n    depends_on: [plan]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if code := c.workflow([]string{"run", path}); code != exitError {
+		t.Fatalf("workflow run exit code = %d, want %d when a step fails", code, exitError)
+	}
+	if out := stdout.String(); !strings.Contains(out, "errored") || !strings.Contains(out, "skipped") {
+		t.Errorf("workflow run output = %q", out)
+	}
+
+	if code := c.workflow([]string{"start", path}); code != exitUsage {
+		t.Errorf("unknown workflow command exit code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestWorktreeCommand(t *testing.T) {
+	// Worktrees are created under the default directory in $HOME
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "--quiet"}, {"commit", "--quiet", "--allow-empty", "--message", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	c, stdout, stderr := newTestCLI(t)
+	for _, name := range []string{"keep", "drop"} {
+		if code := c.spawn([]string{"--type", "idle", "--name", name, "--dir", repo, "--worktree"}); code != exitOK {
+			t.Fatalf("spawn --worktree exit code = %d: %s", code, stderr.String())
+		}
+		a, _ := c.manager.Get(name + "-id")
+		if err := os.WriteFile(filepath.Join(a.Directory(), name+".txt"), []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	stdout.Reset()
+	if code := c.worktree([]string{"diff", "keep-id"}); code != exitOK {
+		t.Fatalf("worktree diff exit code = %d: %s", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "+keep") || strings.Contains(out, "drop") {
+		t.Errorf("worktree diff = %q", out)
+	}
+
+	if code := c.worktree([]string{"merge", "keep-id"}); code != exitOK {
+		t.Fatalf("worktree merge exit code = %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(repo, "keep.txt")); err != nil {
+		t.Errorf("merged file missing from the repository: %v", err)
+	}
+
+	drop, _ := c.manager.Get("drop-id")
+	drop.(*agent.MockAgent).MockStatus = agent.StatusRunning
+	if code := c.worktree([]string{"discard", "drop-id"}); code != exitError || !strings.Contains(stderr.String(), "still running") {
+		t.Errorf("worktree discard of a running agent exit code = %d, stderr = %q", code, stderr.String())
+	}
+	drop.(*agent.MockAgent).Pause()
+	if code := c.worktree([]string{"discard", "drop-id"}); code != exitError || !strings.Contains(stderr.String(), "is paused") {
+		t.Errorf("worktree discard of a paused agent exit code = %d, stderr = %q", code, stderr.String())
+	}
+	drop.(*agent.MockAgent).Resume()
+	drop.(*agent.MockAgent).MockStatus = agent.StatusCompleted
+	if code := c.worktree([]string{"discard", "drop-id"}); code != exitOK {
+		t.Fatalf("worktree discard exit code = %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(repo, "drop.txt")); !os.IsNotExist(err) {
+		t.Error("discarded file should not reach the repository")
+	}
+
+	stderr.Reset()
+	if code := c.worktree([]string{"diff", "ses_running"}); code != exitError || !strings.Contains(stderr.String(), "does not run in its own worktree") {
+		t.Errorf("worktree diff of a plain agent exit code = %d, stderr = %q", code, stderr.String())
+	}
+	if code := c.worktree([]string{"rebase", "keep-id"}); code != exitUsage {
+		t.Errorf("unknown worktree command exit code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"a\nb\n", 0, "a\nb\n"},
+		{"", 3, ""},
+	}
+	for _, tt := range tests {
+		if got := lastLines(tt.in, tt.n); got != tt.want {
+			t.Errorf("lastLines(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestNewOutput(t *testing.T) {
+	tests := []struct {
+		name             string
+		printed, current string
+		want             string
+	}{
+		{"appended", "one\n", "one\ntwo\n", "two\n"},
+		{"unchanged", "one\n", "one\n", ""},
+		{"window moved", "one\ntwo\n", "two\nthree\n", "three\n"},
+		{"replaced", "one\n", "other\n", "other\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newOutput(tt.printed, tt.current); got != tt.want {
+				t.Errorf("newOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/daemon"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+	"github.com/CastAIPhil/AUTO/internal/session"
+	"github.com/CastAIPhil/AUTO/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runAttached runs the TUI against a running daemon. The daemon owns the
+// agents, store and alert channels; this process only mirrors them.
+func runAttached(cfg *config.Config, client *daemon.Client) {
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := agent.NewRegistry()
+	registry.Register(client)
+
+	// Alerts are delivered by the daemon, so the local manager has no channels
+	alertMgr := alert.NewManager(&config.AlertsConfig{}, nil)
+	if history, err := client.Alerts(ctx, 100); err != nil {
+		log.Printf("Failed to load daemon alerts: %v", err)
+	} else {
+		for i := len(history) - 1; i >= 0; i-- {
+			alertMgr.Send(ctx, history[i])
+		}
+	}
+	client.OnAlert(func(a *alert.Alert) {
+		alertMgr.Send(ctx, a)
+	})
+
+	// No store, alert manager, queue limits, restarts or conflict detection:
+	// the daemon already persists, alerts, queues spawns, restarts agents and
+	// tracks the files they change
+	attachedCfg := *cfg
+	attachedCfg.Queue = config.QueueConfig{}
+	attachedCfg.Restart = config.RestartConfig{}
+	attachedCfg.Conflicts = config.ConflictsConfig{}
+	sessionMgr := session.NewManager(&attachedCfg, nil, registry, nil)
+	if err := sessionMgr.Start(ctx); err != nil {
+		log.Fatalf("Failed to start session manager: %v", err)
+	}
+
+	app := tui.NewApp(cfg, sessionMgr, alertMgr)
+	app.SetContext(ctx)
+	app.SetFileSource(client.Files)
+	app.SetSnoozer(client)
+	app.SetDeliverySource(client.Deliveries)
+
+	sessionMgr.Subscribe(eventbus.Options{Name: "tui"}, func(event agent.Event) {
+		select {
+		case app.EventChannel() <- event:
+		default:
+		}
+	})
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	p := tea.NewProgram(app,
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+
+	lost := make(chan struct{})
+	go func() {
+		select {
+		case <-client.Done():
+			close(lost)
+			p.Quit()
+		case <-ctx.Done():
+		}
+	}()
+
+	if _, err := p.Run(); err != nil {
+		log.Fatalf("Error running program: %v", err)
+	}
+
+	select {
+	case <-lost:
+		fmt.Fprintln(os.Stderr, "lost connection to daemon")
+		os.Exit(1)
+	default:
+	}
+}
@@ -4,21 +4,32 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 	"time"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/agent/providers/claude"
 	"github.com/CastAIPhil/AUTO/internal/agent/providers/opencode"
+	"github.com/CastAIPhil/AUTO/internal/agent/providers/process"
+	"github.com/CastAIPhil/AUTO/internal/agent/providers/replay"
 	"github.com/CastAIPhil/AUTO/internal/alert"
 	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/daemon"
 	"github.com/CastAIPhil/AUTO/internal/debug"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
 	"github.com/CastAIPhil/AUTO/internal/session"
 	"github.com/CastAIPhil/AUTO/internal/store"
 	"github.com/CastAIPhil/AUTO/internal/tui"
+	"github.com/CastAIPhil/AUTO/pkg/api"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -31,8 +42,25 @@ var (
 func main() {
 	startTime := time.Now()
 
+	// "auto daemon" runs headless, other subcommands run once and exit;
+	// everything else starts the TUI
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			log.SetOutput(io.Discard)
+			os.Exit(runCommand(args[0], args[1:]))
+		}
+	}
+	daemonMode := len(args) > 0 && args[0] == "daemon"
+	logName, logMode := "auto.log", os.O_TRUNC
+	if daemonMode {
+		// Append, so starting a second daemon doesn't wipe the running one's log
+		args = args[1:]
+		logName, logMode = "daemon.log", os.O_APPEND
+	}
+
 	os.MkdirAll("./logs", 0755)
-	logFile, err := os.OpenFile("./logs/auto.log", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	logFile, err := os.OpenFile(filepath.Join("./logs", logName), os.O_CREATE|os.O_WRONLY|logMode, 0644)
 	if err == nil {
 		log.SetOutput(logFile)
 		defer logFile.Close()
@@ -46,6 +74,10 @@ func main() {
 		profile     bool
 		profileAddr string
 		traceFile   string
+		standalone  bool
+		recordPath  string
+		replayPath  string
+		replaySpeed float64
 	)
 
 	flag.StringVar(&configPath, "config", "", "Path to config file")
@@ -55,7 +87,12 @@ func main() {
 	flag.BoolVar(&profile, "profile", false, "Enable pprof profiling server")
 	flag.StringVar(&profileAddr, "profile-addr", "localhost:6060", "Address for pprof server")
 	flag.StringVar(&traceFile, "trace", "", "Write execution trace to file")
-	flag.Parse()
+	flag.BoolVar(&standalone, "standalone", false, "Monitor agents in-process instead of attaching to a running daemon")
+	flag.StringVar(&recordPath, "record", "", "Append agent events to an NDJSON recording (overrides recording.path)")
+	flag.StringVar(&replayPath, "replay", "", "Play a recording back in the TUI instead of monitoring agents")
+	flag.Float64Var(&replaySpeed, "speed", 1, "Replay speed, e.g. 10 plays a recording ten times as fast")
+	flag.Usage = usage
+	flag.CommandLine.Parse(args)
 
 	if showVersion {
 		fmt.Printf("AUTO version %s (commit: %s, built: %s)\n", version, commit, date)
@@ -98,8 +135,31 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := alert.ValidateConfig(&cfg.Alerts); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
 	log.Printf("[TIMING] Config loaded in %v", time.Since(t))
 
+	if replayPath != "" {
+		if daemonMode {
+			log.Fatalf("--replay cannot be used with the daemon")
+		}
+		runReplay(cfg, replayPath, replaySpeed)
+		return
+	}
+	if recordPath != "" {
+		cfg.Recording.Path = recordPath
+	}
+
+	// An attached TUI records nothing, so --record monitors in-process
+	if !daemonMode && !standalone && recordPath == "" {
+		if client, err := daemon.Dial(cfg.Daemon.Socket); err == nil {
+			log.Printf("Attached to daemon on %s (pid %d)", cfg.Daemon.Socket, client.Info().PID)
+			runAttached(cfg, client)
+			return
+		}
+	}
+
 	t = time.Now()
 	st, err := store.New(cfg.Storage.DatabasePath)
 	if err != nil {
@@ -109,46 +169,102 @@ func main() {
 	log.Printf("[TIMING] Store initialized in %v", time.Since(t))
 
 	t = time.Now()
-	registry := agent.NewRegistry()
-
-	if cfg.Providers.OpenCode.Enabled {
-		log.Printf("[TIMING] OpenCode provider enabled, storage: %s, maxAge: %v", cfg.Providers.OpenCode.StoragePath, cfg.Providers.OpenCode.MaxAge)
-		provider := opencode.NewProvider(
-			cfg.Providers.OpenCode.StoragePath,
-			cfg.Providers.OpenCode.WatchInterval,
-			cfg.Providers.OpenCode.MaxAge,
-		)
-		registry.Register(provider)
-	}
+	registry, closeRegistry := setupRegistry(cfg)
+	defer closeRegistry()
 	log.Printf("[TIMING] Registry setup in %v", time.Since(t))
 
 	alertMgr := alert.NewManager(&cfg.Alerts, st)
+	defer alertMgr.Close()
 
 	sessionMgr := session.NewManager(cfg, st, registry, alertMgr)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	var daemonServer *daemon.Server
+	if daemonMode {
+		daemonServer = daemon.NewServer(sessionMgr, alertMgr, cfg.Daemon.Socket)
+		daemonServer.SetContext(ctx)
+		if err := daemonServer.Listen(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start daemon: %v\n", err)
+			log.Fatalf("Failed to start daemon: %v", err)
+		}
+		defer daemonServer.Stop()
+	}
+
+	var recorder *replay.Recorder
+	if cfg.Recording.Path != "" {
+		recorder, err = replay.NewRecorder(cfg.Recording.Path, cfg.Recording.Output)
+		if err != nil {
+			log.Fatalf("Failed to start recording: %v", err)
+		}
+		defer recorder.Close()
+		recorder.SetRestarts(sessionMgr.Restarts)
+		// A recording must not miss events, so it may hold up the manager
+		opts := eventbus.Options{Name: "recorder", Policy: eventbus.Block, QueueSize: 1024}
+		sessionMgr.Subscribe(opts, recorder.RecordEvent)
+		sessionMgr.SubscribeStreams(opts, recorder.RecordStream)
+		log.Printf("Recording events to %s", cfg.Recording.Path)
+	}
+
 	t = time.Now()
 	log.Printf("[TIMING] Starting session manager...")
 	if err := sessionMgr.Start(ctx); err != nil {
 		log.Fatalf("Failed to start session manager: %v", err)
 	}
+	defer sessionMgr.Stop()
+	if recorder != nil {
+		recorder.RecordAgents(sessionMgr.List())
+	}
 	log.Printf("[TIMING] Session manager started in %v", time.Since(t))
 	log.Printf("[TIMING] Total startup time: %v", time.Since(startTime))
 
-	app := tui.NewApp(cfg, sessionMgr, alertMgr)
-	app.SetContext(ctx)
-
-	sessionMgr.OnEvent(func(event agent.Event) {
-		select {
-		case app.EventChannel() <- event:
-		default:
+	var apiServer *api.Server
+	if cfg.API.Enabled {
+		apiServer = api.NewServer(sessionMgr, cfg.API.Address)
+		apiServer.SetContext(ctx)
+		apiServer.SetAlerts(alertMgr)
+		apiServer.SetToken(cfg.API.Token)
+		if cfg.API.Token == "" && !isLoopback(cfg.API.Address) {
+			log.Printf("Warning: the API listens on %s without a token; anyone who can reach it can spawn and control agents", cfg.API.Address)
 		}
-	})
+		go func() {
+			log.Printf("API server listening on %s", apiServer.Addr())
+			if err := apiServer.Start(); err != nil && err != http.ErrServerClosed {
+				log.Printf("API server error: %v", err)
+			}
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			apiServer.Stop(ctx)
+		}()
+	}
 
-	alertMgr.OnAlert(func(a *alert.Alert) {
-	})
+	var app *tui.App
+	if !daemonMode {
+		app = tui.NewApp(cfg, sessionMgr, alertMgr)
+		app.SetContext(ctx)
+	}
+
+	// The TUI and streaming clients keep up with the latest state, so they
+	// drop events rather than hold up the manager
+	if app != nil {
+		sessionMgr.Subscribe(eventbus.Options{Name: "tui"}, func(event agent.Event) {
+			select {
+			case app.EventChannel() <- event:
+			default:
+			}
+		})
+	}
+	if apiServer != nil {
+		sessionMgr.Subscribe(eventbus.Options{Name: "api"}, apiServer.PublishEvent)
+		alertMgr.Subscribe(eventbus.Options{Name: "api"}, apiServer.PublishAlert)
+	}
+	if daemonServer != nil {
+		sessionMgr.Subscribe(eventbus.Options{Name: "daemon"}, daemonServer.PublishEvent)
+		alertMgr.Subscribe(eventbus.Options{Name: "daemon"}, daemonServer.PublishAlert)
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -158,6 +274,20 @@ func main() {
 		cancel()
 	}()
 
+	if daemonMode {
+		go func() {
+			if err := daemonServer.Serve(); err != nil {
+				log.Printf("Daemon server error: %v", err)
+				cancel()
+			}
+		}()
+		fmt.Printf("AUTO daemon listening on %s\n", daemonServer.Path())
+		log.Printf("Daemon listening on %s", daemonServer.Path())
+		<-ctx.Done()
+		log.Printf("Daemon shutting down")
+		return
+	}
+
 	p := tea.NewProgram(app,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
@@ -167,3 +297,107 @@ func main() {
 		log.Fatalf("Error running program: %v", err)
 	}
 }
+
+// usage prints the top-level help
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n  auto [flags]             Start the TUI\n  auto daemon [flags]      Monitor agents headless for TUIs and commands to attach to\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(out, "  auto %-20s%s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(out, "\nRun \"auto <command> -h\" for a command's flags.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// setupRegistry registers the configured providers and plugins. The returned
+// function shuts down plugin processes.
+func setupRegistry(cfg *config.Config) (*agent.Registry, func()) {
+	registry := agent.NewRegistry()
+	closeFn := func() {}
+
+	catalog := agent.NewModelCatalog()
+	catalog.SetFallback(agent.ModelInfo{
+		InputPer1K:  cfg.Metrics.TokenCostInput,
+		OutputPer1K: cfg.Metrics.TokenCostOutput,
+	})
+	for key, mc := range cfg.Metrics.Models {
+		catalog.Set(key, agent.ModelInfo{
+			InputPer1K:      mc.Input,
+			OutputPer1K:     mc.Output,
+			CacheReadPer1K:  mc.CacheRead,
+			CacheWritePer1K: mc.CacheWrite,
+			ContextWindow:   mc.ContextWindow,
+		})
+	}
+
+	if cfg.Providers.OpenCode.Enabled {
+		log.Printf("[TIMING] OpenCode provider enabled, storage: %s, maxAge: %v", cfg.Providers.OpenCode.StoragePath, cfg.Providers.OpenCode.MaxAge)
+		provider := opencode.NewProvider(
+			cfg.Providers.OpenCode.StoragePath,
+			cfg.Providers.OpenCode.WatchInterval,
+			cfg.Providers.OpenCode.MaxAge,
+		)
+		provider.SetCatalog(catalog)
+		registry.Register(provider)
+	}
+
+	if cfg.Providers.Claude.Enabled {
+		log.Printf("[TIMING] Claude provider enabled, projects: %s, maxAge: %v", cfg.Providers.Claude.ProjectsPath, cfg.Providers.Claude.MaxAge)
+		provider := claude.NewProvider(
+			cfg.Providers.Claude.ProjectsPath,
+			cfg.Providers.Claude.WatchInterval,
+			cfg.Providers.Claude.MaxAge,
+		)
+		provider.SetCatalog(catalog)
+		registry.Register(provider)
+	}
+
+	for _, pc := range cfg.Providers.Process {
+		provider, err := process.NewProvider(process.Config{
+			Type:    pc.Type,
+			Name:    pc.Name,
+			Command: pc.Command,
+			Args:    pc.Args,
+			Env:     pc.Env,
+			Patterns: process.Patterns{
+				Running:      pc.Patterns.Running,
+				Idle:         pc.Patterns.Idle,
+				Completed:    pc.Patterns.Completed,
+				Errored:      pc.Patterns.Errored,
+				ContextLimit: pc.Patterns.ContextLimit,
+			},
+			IdleAfter:        pc.IdleAfter,
+			TerminateTimeout: pc.TerminateTimeout,
+		})
+		if err != nil {
+			log.Printf("Skipping process provider %q: %v", pc.Type, err)
+			continue
+		}
+		log.Printf("[TIMING] Process provider %s enabled, command: %s", pc.Type, pc.Command)
+		registry.Register(provider)
+	}
+
+	if cfg.Plugins.Dir != "" {
+		pluginMgr := plugin.NewManager(registry)
+		pluginMgr.SetCatalog(catalog)
+		if err := pluginMgr.LoadDir(cfg.Plugins.Dir, cfg.Plugins.Enabled); err != nil {
+			log.Printf("Plugin loading: %v", err)
+		}
+		closeFn = func() { pluginMgr.Close() }
+	}
+
+	return registry, closeFn
+}
+
+// isLoopback reports whether a listen address only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/agent/providers/replay"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+	"github.com/CastAIPhil/AUTO/internal/session"
+	"github.com/CastAIPhil/AUTO/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runReplay plays a recording back in the TUI. The replayed agents go
+// through a session manager and alert manager like live ones, but nothing
+// is persisted and alerts are only shown in the TUI.
+func runReplay(cfg *config.Config, path string, speed float64) {
+	provider, err := replay.NewProvider(path, speed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to replay: %v\n", err)
+		os.Exit(1)
+	}
+	defer provider.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := agent.NewRegistry()
+	registry.Register(provider)
+
+	replayCfg := *cfg
+	replayCfg.Alerts.DesktopNotifications = false
+	replayCfg.Alerts.SlackEnabled = false
+	replayCfg.Alerts.DiscordEnabled = false
+	replayCfg.Alerts.Webhooks = nil
+	replayCfg.Alerts.Emails = nil
+	// Replayed agents cannot be controlled, and their files were tracked live
+	replayCfg.Queue = config.QueueConfig{}
+	replayCfg.Restart = config.RestartConfig{}
+	replayCfg.Conflicts = config.ConflictsConfig{}
+
+	alertMgr := alert.NewManager(&replayCfg.Alerts, nil)
+	defer alertMgr.Close()
+	sessionMgr := session.NewManager(&replayCfg, nil, registry, alertMgr)
+	sessionMgr.SetReplay()
+
+	app := tui.NewApp(&replayCfg, sessionMgr, alertMgr)
+	app.SetContext(ctx)
+	sessionMgr.Subscribe(eventbus.Options{Name: "tui"}, func(event agent.Event) {
+		select {
+		case app.EventChannel() <- event:
+		default:
+		}
+	})
+
+	if err := sessionMgr.Start(ctx); err != nil {
+		log.Fatalf("Failed to start session manager: %v", err)
+	}
+	defer sessionMgr.Stop()
+	log.Printf("Replaying %s at %vx", path, speed)
+
+	go func() {
+		select {
+		case <-provider.Done():
+			log.Printf("Replay of %s finished", path)
+		case <-ctx.Done():
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	p := tea.NewProgram(app,
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+
+	if _, err := p.Run(); err != nil {
+		log.Fatalf("Error running program: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/workflow"
+)
+
+// workflow runs the workflow subcommands
+func (c *cli) workflow(args []string) int {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprintln(c.stderr, c.usage)
+		return exitUsage
+	}
+	return c.workflowRun(args[1:])
+}
+
+// workflowRun runs a workflow until every step has finished or it halts.
+// Progress goes to stderr; the step summary, or the steps as JSON, to stdout.
+func (c *cli) workflowRun(args []string) int {
+	fs := c.flags("workflow run")
+	asJSON := fs.Bool("json", false, "Print the steps, including their output, as JSON instead of a summary")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	def, err := workflow.Load(positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+
+	m, err := c.connect()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	run := workflow.Start(c.ctx, m, def)
+	fmt.Fprintf(c.stderr, "Started workflow %s with %d steps\n", def.Name, len(def.Steps))
+
+	// Not every status change produces an event, so also poll
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	reported := make(map[string]string)
+	interrupted := false
+	for {
+		run.Update()
+		for _, st := range run.Steps() {
+			state := fmt.Sprintf("%s/%d", st.Status, st.Attempts)
+			if st.Status == workflow.StepWaiting || reported[st.ID] == state {
+				continue
+			}
+			reported[st.ID] = state
+			line := fmt.Sprintf("%s  %-10s %s", st.ID, st.Status, st.AgentID)
+			if st.Attempts > 1 {
+				line += fmt.Sprintf("  (attempt %d)", st.Attempts)
+			}
+			if st.Error != "" && st.Status != workflow.StepStarting {
+				line += "  " + st.Error
+			}
+			fmt.Fprintln(c.stderr, line)
+		}
+		if run.Done() || interrupted {
+			break
+		}
+
+		select {
+		case <-c.ctx.Done():
+			interrupted = true
+		case <-c.events:
+		case <-ticker.C:
+		}
+	}
+
+	steps := run.Steps()
+	if *asJSON {
+		if code := c.writeJSON(struct {
+			Name  string               `json:"name"`
+			Steps []workflow.StepState `json:"steps"`
+		}{def.Name, steps}); code != exitOK {
+			return code
+		}
+	} else {
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STEP\tSTATUS\tATTEMPTS\tAGENT\tDEPENDS ON\tERROR")
+		for _, st := range steps {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", st.ID, st.Status, st.Attempts, orDash(st.AgentID), orDash(strings.Join(st.DependsOn, ",")), st.Error)
+		}
+		w.Flush()
+	}
+
+	if interrupted {
+		fmt.Fprintln(c.stderr, "Interrupted; running steps keep running")
+		return exitError
+	}
+	if !run.Succeeded() {
+		return exitError
+	}
+	return exitOK
+}
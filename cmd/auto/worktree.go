@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/worktree"
+)
+
+// worktree runs the worktree subcommands
+func (c *cli) worktree(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(c.stderr, c.usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "diff":
+		return c.worktreeDiff(args[1:])
+	case "merge":
+		return c.worktreeMerge(args[1:])
+	case "discard":
+		return c.worktreeDiscard(args[1:])
+	}
+	fmt.Fprintln(c.stderr, c.usage)
+	return exitUsage
+}
+
+// worktreeDiff prints the changes an agent made in its worktree
+func (c *cli) worktreeDiff(args []string) int {
+	fs := c.flags("worktree diff")
+	stat := fs.Bool("stat", false, "Print a summary of changed files instead of the diff")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	_, w, code := c.openWorktree(positional[0], false)
+	if w == nil {
+		return code
+	}
+
+	var diff string
+	var err error
+	if *stat {
+		diff, err = w.DiffStat()
+		diff += "\n"
+	} else {
+		diff, err = w.Diff()
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprint(c.stdout, diff)
+	return exitOK
+}
+
+// worktreeMerge commits an agent's changes and merges its branch
+func (c *cli) worktreeMerge(args []string) int {
+	fs := c.flags("worktree merge")
+	message := fs.String("message", "", "Message for the commit of uncommitted changes")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	a, w, code := c.openWorktree(positional[0], true)
+	if w == nil {
+		return code
+	}
+	if *message == "" {
+		*message = worktreeCommitMessage(a)
+	}
+
+	if err := w.Merge(*message); err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprintf(c.stdout, "Merged %s into %s\n", w.Branch, w.Repo)
+	return exitOK
+}
+
+// worktreeDiscard removes an agent's worktree and branch
+func (c *cli) worktreeDiscard(args []string) int {
+	fs := c.flags("worktree discard")
+	positional, code, ok := c.parse(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	_, w, code := c.openWorktree(positional[0], true)
+	if w == nil {
+		return code
+	}
+
+	if err := w.Remove(); err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprintf(c.stdout, "Discarded %s\n", w.Branch)
+	return exitOK
+}
+
+// openWorktree finds an agent and the worktree it runs in. Unless the
+// worktree is only read, the agent must have stopped working in it.
+func (c *cli) openWorktree(id string, modify bool) (agent.Agent, *worktree.Worktree, int) {
+	m, err := c.connect()
+	if err != nil {
+		return nil, nil, c.fail(err)
+	}
+	a, code := c.lookup(m, id)
+	if a == nil {
+		return nil, nil, code
+	}
+
+	w, err := worktree.Open(a.Directory())
+	if errors.Is(err, worktree.ErrNotWorktree) {
+		err = fmt.Errorf("agent %s does not run in its own worktree", a.ID())
+	}
+	if err != nil {
+		return nil, nil, c.fail(err)
+	}
+	if status := a.Status(); modify && (status == agent.StatusPending || status == agent.StatusRunning) {
+		return nil, nil, c.fail(fmt.Errorf("agent %s is still running; stop it first", a.ID()))
+	}
+	if modify && agent.IsPaused(a) {
+		return nil, nil, c.fail(fmt.Errorf("agent %s is paused; stop it first", a.ID()))
+	}
+	return a, w, exitOK
+}
+
+// worktreeCommitMessage is the default message for an agent's changes
+func worktreeCommitMessage(a agent.Agent) string {
+	return fmt.Sprintf("Changes by agent %s (%s)", a.Name(), a.ID())
+}
@@ -3,10 +3,39 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"time"
 )
 
+// ErrUnsupported is wrapped by control methods an agent type cannot perform
+var ErrUnsupported = errors.New("not supported")
+
+// contextOverflowPhrases are fragments of model API errors caused by a full context window
+var contextOverflowPhrases = []string{
+	"prompt is too long",
+	"input is too long",
+	"context length",
+	"context_length_exceeded",
+	"context window",
+	"maximum context",
+	"too many tokens",
+}
+
+// IsContextOverflow reports whether an error message says the context window is full
+func IsContextOverflow(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, phrase := range contextOverflowPhrases {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
 // Status represents the current state of an agent
 type Status int
 
@@ -41,6 +70,16 @@ func (s Status) String() string {
 	}
 }
 
+// ParseStatus converts a status name back into a Status
+func ParseStatus(s string) (Status, bool) {
+	for st := StatusPending; st <= StatusCancelled; st++ {
+		if st.String() == s {
+			return st, true
+		}
+	}
+	return StatusPending, false
+}
+
 // StatusIcon returns the icon for the status
 func (s Status) Icon() string {
 	switch s {
@@ -76,6 +115,7 @@ type Metrics struct {
 	TasksCompleted     int           `json:"tasks_completed"`
 	TasksFailed        int           `json:"tasks_failed"`
 	ContextUtilization float64       `json:"context_utilization"` // 0.0 - 1.0
+	Model              string        `json:"model,omitempty"`     // Most recently used model
 }
 
 // Agent represents a single AI agent instance
@@ -111,6 +151,47 @@ type Agent interface {
 	Resume() error
 }
 
+// Progress describes work an agent has started but not yet finished
+type Progress struct {
+	Tool          string    // Longest-running tool call, empty if none is running
+	ToolStarted   time.Time // When that tool call started
+	AwaitingReply time.Time // When the latest user message was sent, zero once answered
+}
+
+// ProgressReporter is implemented by agents that can report in-flight work
+type ProgressReporter interface {
+	Progress() Progress
+}
+
+// PauseReporter is implemented by agents that can be paused. A paused agent
+// reports StatusIdle without having finished its work.
+type PauseReporter interface {
+	IsPaused() bool
+}
+
+// IsPaused reports whether an agent is paused
+func IsPaused(a Agent) bool {
+	p, ok := a.(PauseReporter)
+	return ok && p.IsPaused()
+}
+
+// RestartCounter is implemented by agents that report how often they were
+// restarted elsewhere, such as agents served by a daemon
+type RestartCounter interface {
+	Restarts() int
+}
+
+// FileChange is a file an agent wrote, with an absolute path
+type FileChange struct {
+	Path string
+	Time time.Time
+}
+
+// FileReporter is implemented by agents that know which files they changed
+type FileReporter interface {
+	FileChanges() []FileChange
+}
+
 // EventType represents the type of agent event
 type EventType int
 
@@ -157,6 +238,16 @@ func (e EventType) String() string {
 	}
 }
 
+// ParseEventType converts an event type name back into an EventType
+func ParseEventType(s string) (EventType, bool) {
+	for et := EventAgentDiscovered; et <= EventAgentOutput; et++ {
+		if et.String() == s {
+			return et, true
+		}
+	}
+	return EventAgentUpdated, false
+}
+
 // Event represents an agent lifecycle event
 type Event struct {
 	Type      EventType
@@ -167,16 +258,22 @@ type Event struct {
 	Error     error
 }
 
+// Replacement is the Data of a terminated event for an agent that was
+// replaced by another, such as a queued spawn that has started
+type Replacement struct {
+	AgentID string
+}
+
 type StreamEvent struct {
-	Type      string
-	AgentID   string
-	SessionID string
-	MessageID string
-	Text      string
-	ToolName  string
-	State     string
-	Error     string
-	Timestamp time.Time
+	Type      string    `json:"type"`
+	AgentID   string    `json:"agent_id"`
+	SessionID string    `json:"session_id,omitempty"`
+	MessageID string    `json:"message_id,omitempty"`
+	Text      string    `json:"text,omitempty"`
+	ToolName  string    `json:"tool_name,omitempty"`
+	State     string    `json:"state,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type StreamingAgent interface {
@@ -186,6 +283,38 @@ type StreamingAgent interface {
 	CancelExecution()
 }
 
+// Priority orders spawns waiting in the queue; higher priorities start first
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("%d", int(p))
+	}
+}
+
+// ParsePriority converts a priority name back into a Priority
+func ParsePriority(s string) (Priority, bool) {
+	for p := PriorityLow; p <= PriorityHigh; p++ {
+		if p.String() == s {
+			return p, true
+		}
+	}
+	return PriorityNormal, false
+}
+
 // SpawnConfig holds configuration for spawning a new agent
 type SpawnConfig struct {
 	Type      string            `json:"type"`
@@ -193,6 +322,8 @@ type SpawnConfig struct {
 	Directory string            `json:"directory"`
 	Prompt    string            `json:"prompt"`
 	Env       map[string]string `json:"env"`
+	Priority  Priority          `json:"priority,omitempty"`
+	Worktree  bool              `json:"worktree,omitempty"` // run in a new git worktree and branch
 }
 
 // Provider discovers and manages agents of a specific type
@@ -213,6 +344,13 @@ type Provider interface {
 	SendInput(id string, input string) error
 }
 
+// Forwarder is a provider that spawns agents of other types on their
+// behalf, such as a client of a remote AUTO daemon
+type Forwarder interface {
+	Provider
+	Forwards(providerType string) bool
+}
+
 // Registry manages multiple agent providers
 type Registry struct {
 	providers map[string]Provider
@@ -230,10 +368,27 @@ func (r *Registry) Register(provider Provider) {
 	r.providers[provider.Type()] = provider
 }
 
-// Get returns a provider by type
+// Get returns a provider by type, or a forwarder that serves the type
 func (r *Registry) Get(providerType string) (Provider, bool) {
-	p, ok := r.providers[providerType]
-	return p, ok
+	if p, ok := r.providers[providerType]; ok {
+		return p, true
+	}
+	for _, p := range r.providers {
+		if f, ok := p.(Forwarder); ok && f.Forwards(providerType) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Types returns the types of all registered providers, sorted
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.providers))
+	for t := range r.providers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
 }
 
 // List returns all registered providers
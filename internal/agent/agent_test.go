@@ -73,6 +73,32 @@ func TestEventTypeString(t *testing.T) {
 	}
 }
 
+func TestParseStatusRoundTrip(t *testing.T) {
+	for st := StatusPending; st <= StatusCancelled; st++ {
+		got, ok := ParseStatus(st.String())
+		if !ok || got != st {
+			t.Errorf("ParseStatus(%q) = %v, %v", st.String(), got, ok)
+		}
+	}
+
+	if _, ok := ParseStatus("bogus"); ok {
+		t.Error("ParseStatus should reject unknown names")
+	}
+}
+
+func TestParseEventTypeRoundTrip(t *testing.T) {
+	for et := EventAgentDiscovered; et <= EventAgentOutput; et++ {
+		got, ok := ParseEventType(et.String())
+		if !ok || got != et {
+			t.Errorf("ParseEventType(%q) = %v, %v", et.String(), got, ok)
+		}
+	}
+
+	if _, ok := ParseEventType("bogus"); ok {
+		t.Error("ParseEventType should reject unknown names")
+	}
+}
+
 func TestRegistryBasicOperations(t *testing.T) {
 	registry := NewRegistry()
 
@@ -85,3 +111,82 @@ func TestRegistryBasicOperations(t *testing.T) {
 		t.Error("Get should return false for nonexistent provider")
 	}
 }
+
+func TestModelCatalogLookup(t *testing.T) {
+	c := NewModelCatalog()
+	c.Set("Custom/My-Model", ModelInfo{InputPer1K: 1, ContextWindow: 1000})
+
+	tests := []struct {
+		provider string
+		model    string
+		want     float64 // InputPer1K
+		found    bool
+	}{
+		{"anthropic", "claude-sonnet-4", 0.003, true},
+		{"anthropic", "claude-opus-4-1-20250805", 0.015, true},
+		{"anthropic", "claude-opus-4-5-20251101", 0.005, true},
+		{"", "anthropic/claude-haiku-4-5", 0.001, true},
+		{"openrouter", "openai/gpt-4o-mini", 0.00015, true},
+		{"openai", "gpt-4o-2024-08-06", 0.0025, true},
+		{"custom", "my-model", 1, true},
+		{"openai", "gpt-4", 0, false},
+		{"", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		info, ok := c.Lookup(tt.provider, tt.model)
+		if ok != tt.found || info.InputPer1K != tt.want {
+			t.Errorf("Lookup(%q, %q) = %v, %v, want %v, %v", tt.provider, tt.model, info.InputPer1K, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func TestModelCatalogCost(t *testing.T) {
+	c := NewModelCatalog()
+	c.SetFallback(ModelInfo{InputPer1K: 0.01, OutputPer1K: 0.02})
+
+	usage := TokenUsage{Input: 1000, Output: 1000, CacheRead: 10000, CacheWrite: 1000}
+	if got, want := c.Cost("anthropic", "claude-sonnet-4", usage), 0.003+0.015+0.003+0.00375; !floatEqual(got, want) {
+		t.Errorf("Cost(known) = %f, want %f", got, want)
+	}
+	if got, want := c.Cost("", "unknown-model", usage), 0.01+0.02+0.1+0.01; !floatEqual(got, want) {
+		t.Errorf("Cost(fallback) = %f, want %f", got, want)
+	}
+
+	// A nil catalog uses the built-in models and prices unknown models at zero
+	var nilCatalog *ModelCatalog
+	if got := nilCatalog.Cost("", "unknown-model", usage); got != 0 {
+		t.Errorf("nil Cost(unknown) = %f, want 0", got)
+	}
+	if got := nilCatalog.ContextUtilization("anthropic", "claude-sonnet-4", 300000); got != 1 {
+		t.Errorf("ContextUtilization() = %f, want clamped to 1", got)
+	}
+	if got := c.ContextUtilization("", "unknown-model", 1000); got != 0 {
+		t.Errorf("ContextUtilization(unknown) = %f, want 0", got)
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
+
+func TestIsContextOverflow(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"API Error: 400 prompt is too long: 210000 tokens > 200000 maximum", true},
+		{"This model's maximum context length is 128000 tokens", true},
+		{"error code: context_length_exceeded", true},
+		{"Input exceeds the context window of this model", true},
+		{"invalid api key", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsContextOverflow(tt.msg); got != tt.want {
+			t.Errorf("IsContextOverflow(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
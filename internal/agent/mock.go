@@ -2,7 +2,9 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"time"
 )
@@ -22,6 +24,7 @@ type MockAgent struct {
 	MockMetrics      Metrics
 	MockLastError    error
 	MockOutput       []byte
+	MockPaused       bool
 
 	TerminateCalled bool
 	SendInputCalled bool
@@ -65,7 +68,12 @@ func (m *MockAgent) StartTime() time.Time    { return m.MockStartTime }
 func (m *MockAgent) LastActivity() time.Time { return m.MockLastActivity }
 func (m *MockAgent) Metrics() Metrics        { return m.MockMetrics }
 func (m *MockAgent) LastError() error        { return m.MockLastError }
-func (m *MockAgent) Output() io.Reader       { return nil }
+func (m *MockAgent) Output() io.Reader {
+	if m.MockOutput == nil {
+		return nil
+	}
+	return bytes.NewReader(m.MockOutput)
+}
 
 func (m *MockAgent) SendInput(input string) error {
 	m.SendInputCalled = true
@@ -81,14 +89,20 @@ func (m *MockAgent) Terminate() error {
 
 func (m *MockAgent) Pause() error {
 	m.MockStatus = StatusIdle
+	m.MockPaused = true
 	return nil
 }
 
 func (m *MockAgent) Resume() error {
 	m.MockStatus = StatusRunning
+	m.MockPaused = false
 	return nil
 }
 
+func (m *MockAgent) IsPaused() bool {
+	return m.MockPaused
+}
+
 func (m *MockAgent) Refresh() error {
 	m.MockLastActivity = time.Now()
 	return nil
@@ -109,6 +123,10 @@ func NewMockProvider() *MockProvider {
 	}
 }
 
+func (p *MockProvider) Name() string {
+	return "Mock"
+}
+
 func (p *MockProvider) Type() string {
 	return "mock"
 }
@@ -129,6 +147,35 @@ func (p *MockProvider) Spawn(ctx context.Context, config SpawnConfig) (Agent, er
 	return agent, nil
 }
 
+func (p *MockProvider) Get(id string) (Agent, error) {
+	for _, a := range p.MockAgents {
+		if a.ID() == id {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("agent not found: %s", id)
+}
+
+func (p *MockProvider) List() []Agent {
+	return p.MockAgents
+}
+
+func (p *MockProvider) Terminate(id string) error {
+	a, err := p.Get(id)
+	if err != nil {
+		return err
+	}
+	return a.Terminate()
+}
+
+func (p *MockProvider) SendInput(id string, input string) error {
+	a, err := p.Get(id)
+	if err != nil {
+		return err
+	}
+	return a.SendInput(input)
+}
+
 // AddAgent adds an agent to the mock provider
 func (p *MockProvider) AddAgent(agent Agent) {
 	p.MockAgents = append(p.MockAgents, agent)
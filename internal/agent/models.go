@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelInfo holds per-1k token prices and the context window of a model
+type ModelInfo struct {
+	InputPer1K      float64 `json:"input_per_1k"`
+	OutputPer1K     float64 `json:"output_per_1k"`
+	CacheReadPer1K  float64 `json:"cache_read_per_1k"`
+	CacheWritePer1K float64 `json:"cache_write_per_1k"`
+	ContextWindow   int64   `json:"context_window"`
+}
+
+// TokenUsage is a token count broken down the way models are priced
+type TokenUsage struct {
+	Input      int64
+	Output     int64
+	CacheRead  int64
+	CacheWrite int64
+}
+
+// Total returns the number of tokens across all categories
+func (u TokenUsage) Total() int64 {
+	return u.Input + u.Output + u.CacheRead + u.CacheWrite
+}
+
+// DefaultModels returns the built-in catalog entries, keyed by provider/model
+func DefaultModels() map[string]ModelInfo {
+	return map[string]ModelInfo{
+		"anthropic/claude-opus-4":     {InputPer1K: 0.015, OutputPer1K: 0.075, CacheReadPer1K: 0.0015, CacheWritePer1K: 0.01875, ContextWindow: 200000},
+		"anthropic/claude-opus-4-1":   {InputPer1K: 0.015, OutputPer1K: 0.075, CacheReadPer1K: 0.0015, CacheWritePer1K: 0.01875, ContextWindow: 200000},
+		"anthropic/claude-opus-4-5":   {InputPer1K: 0.005, OutputPer1K: 0.025, CacheReadPer1K: 0.0005, CacheWritePer1K: 0.00625, ContextWindow: 200000},
+		"anthropic/claude-sonnet-4":   {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375, ContextWindow: 200000},
+		"anthropic/claude-sonnet-4-5": {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375, ContextWindow: 200000},
+		"anthropic/claude-3-7-sonnet": {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375, ContextWindow: 200000},
+		"anthropic/claude-3-5-sonnet": {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375, ContextWindow: 200000},
+		"anthropic/claude-haiku-4-5":  {InputPer1K: 0.001, OutputPer1K: 0.005, CacheReadPer1K: 0.0001, CacheWritePer1K: 0.00125, ContextWindow: 200000},
+		"anthropic/claude-3-5-haiku":  {InputPer1K: 0.0008, OutputPer1K: 0.004, CacheReadPer1K: 0.00008, CacheWritePer1K: 0.001, ContextWindow: 200000},
+		"openai/gpt-4o":               {InputPer1K: 0.0025, OutputPer1K: 0.01, CacheReadPer1K: 0.00125, ContextWindow: 128000},
+		"openai/gpt-4o-mini":          {InputPer1K: 0.00015, OutputPer1K: 0.0006, CacheReadPer1K: 0.000075, ContextWindow: 128000},
+		"openai/gpt-4.1":              {InputPer1K: 0.002, OutputPer1K: 0.008, CacheReadPer1K: 0.0005, ContextWindow: 1047576},
+		"openai/o3":                   {InputPer1K: 0.002, OutputPer1K: 0.008, CacheReadPer1K: 0.0005, ContextWindow: 200000},
+		"openai/gpt-5":                {InputPer1K: 0.00125, OutputPer1K: 0.01, CacheReadPer1K: 0.000125, ContextWindow: 400000},
+		"google/gemini-2.5-pro":       {InputPer1K: 0.00125, OutputPer1K: 0.01, CacheReadPer1K: 0.00031, ContextWindow: 1048576},
+		"google/gemini-2.5-flash":     {InputPer1K: 0.0003, OutputPer1K: 0.0025, CacheReadPer1K: 0.000075, ContextWindow: 1048576},
+	}
+}
+
+// defaultCatalog backs lookups on a nil *ModelCatalog
+var defaultCatalog = NewModelCatalog()
+
+// ModelCatalog resolves model IDs to prices and context windows
+type ModelCatalog struct {
+	mu       sync.RWMutex
+	models   map[string]ModelInfo
+	fallback ModelInfo
+}
+
+// NewModelCatalog creates a catalog seeded with the built-in models
+func NewModelCatalog() *ModelCatalog {
+	return &ModelCatalog{models: DefaultModels()}
+}
+
+// Set adds or replaces the entry for a provider/model key
+func (c *ModelCatalog) Set(key string, info ModelInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models[strings.ToLower(key)] = info
+}
+
+// SetFallback sets the prices used for models not in the catalog
+func (c *ModelCatalog) SetFallback(info ModelInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallback = info
+}
+
+// Lookup finds a model by provider and model ID. Model IDs may carry a
+// provider prefix ("anthropic/claude-sonnet-4") or a version suffix
+// ("claude-sonnet-4-20250514"); the longest matching catalog entry wins,
+// preferring entries from the same provider.
+func (c *ModelCatalog) Lookup(provider, model string) (ModelInfo, bool) {
+	if c == nil {
+		c = defaultCatalog
+	}
+
+	provider = strings.ToLower(provider)
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		if provider == "" {
+			provider = model[:i]
+		}
+		model = model[i+1:]
+	}
+	if model == "" {
+		return ModelInfo{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if info, ok := c.models[provider+"/"+model]; ok {
+		return info, true
+	}
+
+	var best ModelInfo
+	bestLen, bestSameProvider, found := 0, false, false
+	for key, info := range c.models {
+		keyProvider, keyModel := "", key
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			keyProvider, keyModel = key[:i], key[i+1:]
+		}
+		if !strings.HasPrefix(model, keyModel) {
+			continue
+		}
+		// Only match whole ID segments so "gpt-4" doesn't claim "gpt-4o"
+		if len(model) > len(keyModel) && model[len(keyModel)] != '-' && model[len(keyModel)] != '@' {
+			continue
+		}
+
+		sameProvider := keyProvider == provider
+		if len(keyModel) > bestLen || (len(keyModel) == bestLen && sameProvider && !bestSameProvider) {
+			best, bestLen, bestSameProvider, found = info, len(keyModel), sameProvider, true
+		}
+	}
+	return best, found
+}
+
+// Cost estimates the cost of a token usage, using the fallback prices for
+// unknown models. Cache tokens of a fallback-priced model cost as much as input.
+func (c *ModelCatalog) Cost(provider, model string, usage TokenUsage) float64 {
+	if c == nil {
+		c = defaultCatalog
+	}
+
+	info, ok := c.Lookup(provider, model)
+	if !ok {
+		c.mu.RLock()
+		info = c.fallback
+		c.mu.RUnlock()
+		info.CacheReadPer1K = info.InputPer1K
+		info.CacheWritePer1K = info.InputPer1K
+	}
+
+	return float64(usage.Input)/1000*info.InputPer1K +
+		float64(usage.Output)/1000*info.OutputPer1K +
+		float64(usage.CacheRead)/1000*info.CacheReadPer1K +
+		float64(usage.CacheWrite)/1000*info.CacheWritePer1K
+}
+
+// ContextUtilization returns how much of the model's context window the
+// given number of tokens fills (0.0 - 1.0), or 0 if the window is unknown
+func (c *ModelCatalog) ContextUtilization(provider, model string, tokens int64) float64 {
+	info, ok := c.Lookup(provider, model)
+	if !ok || info.ContextWindow <= 0 {
+		return 0
+	}
+
+	u := float64(tokens) / float64(info.ContextWindow)
+	if u > 1 {
+		u = 1
+	}
+	return u
+}
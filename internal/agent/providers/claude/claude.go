@@ -0,0 +1,977 @@
+// Package claude provides an agent provider for Claude Code sessions
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/fsnotify/fsnotify"
+)
+
+// TranscriptEntry represents a single line of a Claude Code JSONL transcript
+type TranscriptEntry struct {
+	Type              string          `json:"type"` // "user", "assistant", "system", "summary"
+	UUID              string          `json:"uuid,omitempty"`
+	ParentUUID        string          `json:"parentUuid,omitempty"`
+	SessionID         string          `json:"sessionId,omitempty"`
+	Cwd               string          `json:"cwd,omitempty"`
+	GitBranch         string          `json:"gitBranch,omitempty"`
+	Timestamp         time.Time       `json:"timestamp"`
+	IsSidechain       bool            `json:"isSidechain,omitempty"`
+	IsAPIErrorMessage bool            `json:"isApiErrorMessage,omitempty"`
+	IsCompactSummary  bool            `json:"isCompactSummary,omitempty"`
+	Summary           string          `json:"summary,omitempty"`
+	Subtype           string          `json:"subtype,omitempty"` // "compact_boundary" marks a compaction
+	Message           *MessageContent `json:"message,omitempty"`
+}
+
+// MessageContent represents the message payload of a transcript entry
+type MessageContent struct {
+	ID         string          `json:"id,omitempty"`
+	Role       string          `json:"role"`
+	Model      string          `json:"model,omitempty"`
+	Content    json.RawMessage `json:"content"` // string or []ContentBlock
+	StopReason string          `json:"stop_reason,omitempty"`
+	Usage      *Usage          `json:"usage,omitempty"`
+}
+
+// ContentBlock represents a single block inside a message's content array
+type ContentBlock struct {
+	Type      string          `json:"type"` // "text", "tool_use", "tool_result", "thinking"
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// Usage represents token usage reported on assistant messages
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// Blocks returns the content as a list of blocks, wrapping plain string content
+func (m *MessageContent) Blocks() []ContentBlock {
+	if m == nil || len(m.Content) == 0 {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []ContentBlock{{Type: "text", Text: text}}
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(m.Content, &blocks); err != nil {
+		return nil
+	}
+	return blocks
+}
+
+// ClaudeAgent implements the Agent interface for Claude Code sessions
+type ClaudeAgent struct {
+	id             string
+	summary        string
+	directory      string
+	projectID      string
+	transcriptPath string
+	status         agent.Status
+	startTime      time.Time
+	lastActivity   time.Time
+	currentTask    string
+	metrics        agent.Metrics
+	lastError      error
+	output         *bytes.Buffer
+	mu             sync.RWMutex
+
+	// Incremental parsing state
+	offset       int64
+	sawTimestamp bool
+	lastEntry    *TranscriptEntry
+	pendingTools map[string]pendingTool // tool_use id -> call awaiting its result
+	countedUsage map[string]bool        // message ids whose usage was already counted
+	firstPrompt  string
+	contextFull  bool      // set by a compaction or context overflow, cleared by the next reply
+	promptAt     time.Time // when the unanswered user prompt was sent
+
+	catalog *agent.ModelCatalog
+}
+
+// pendingTool is a tool call that has not received its result yet
+type pendingTool struct {
+	name    string
+	started time.Time
+}
+
+// NewClaudeAgent creates a new ClaudeAgent from a transcript file
+func NewClaudeAgent(transcriptPath string) (*ClaudeAgent, error) {
+	return newClaudeAgent(transcriptPath, nil)
+}
+
+// newClaudeAgent creates a ClaudeAgent that prices usage with the given catalog
+func newClaudeAgent(transcriptPath string, catalog *agent.ModelCatalog) (*ClaudeAgent, error) {
+	info, err := os.Stat(transcriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat transcript: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("transcript path is a directory: %s", transcriptPath)
+	}
+
+	a := &ClaudeAgent{
+		id:             strings.TrimSuffix(filepath.Base(transcriptPath), ".jsonl"),
+		projectID:      filepath.Base(filepath.Dir(transcriptPath)),
+		transcriptPath: transcriptPath,
+		status:         agent.StatusPending,
+		startTime:      info.ModTime(),
+		lastActivity:   info.ModTime(),
+		output:         bytes.NewBuffer(nil),
+		pendingTools:   make(map[string]pendingTool),
+		countedUsage:   make(map[string]bool),
+		catalog:        catalog,
+	}
+
+	if err := a.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Refresh reads any transcript lines appended since the last refresh
+func (a *ClaudeAgent) Refresh() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.Open(a.transcriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Transcript was truncated or rewritten, start over
+	if info.Size() < a.offset {
+		a.reset()
+	}
+
+	if _, err := f.Seek(a.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// Leave partial trailing lines for the next refresh
+			break
+		}
+		a.offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry TranscriptEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		a.applyEntry(&entry)
+	}
+
+	a.determineStatus()
+	return nil
+}
+
+// reset clears all state derived from the transcript
+func (a *ClaudeAgent) reset() {
+	a.offset = 0
+	a.sawTimestamp = false
+	a.lastEntry = nil
+	a.pendingTools = make(map[string]pendingTool)
+	a.promptAt = time.Time{}
+	a.countedUsage = make(map[string]bool)
+	a.firstPrompt = ""
+	a.contextFull = false
+	a.summary = ""
+	a.currentTask = ""
+	a.metrics = agent.Metrics{}
+	a.lastError = nil
+	a.output.Reset()
+}
+
+// applyEntry folds a single transcript entry into the agent state
+func (a *ClaudeAgent) applyEntry(entry *TranscriptEntry) {
+	if entry.Type == "summary" {
+		if entry.Summary != "" {
+			a.summary = entry.Summary
+		}
+		return
+	}
+
+	if entry.Type == "system" && entry.Subtype == "compact_boundary" {
+		a.contextFull = true
+	}
+
+	if entry.Cwd != "" {
+		a.directory = entry.Cwd
+	}
+	if !entry.Timestamp.IsZero() {
+		if !a.sawTimestamp || entry.Timestamp.Before(a.startTime) {
+			a.startTime = entry.Timestamp
+			a.sawTimestamp = true
+		}
+		a.lastActivity = entry.Timestamp
+	}
+
+	if entry.Message == nil {
+		return
+	}
+
+	switch entry.Type {
+	case "user":
+		a.applyUser(entry)
+	case "assistant":
+		a.applyAssistant(entry)
+	default:
+		return
+	}
+
+	a.lastEntry = entry
+}
+
+func (a *ClaudeAgent) applyUser(entry *TranscriptEntry) {
+	for _, block := range entry.Message.Blocks() {
+		switch block.Type {
+		case "text":
+			// Compaction summaries are written as user messages but aren't prompts
+			if entry.IsSidechain || entry.IsCompactSummary || block.Text == "" {
+				continue
+			}
+			a.setTask(block.Text)
+			if a.firstPrompt == "" {
+				a.firstPrompt = a.currentTask
+			}
+			a.output.WriteString(">>> ")
+			a.output.WriteString(block.Text)
+			a.output.WriteString("\n")
+			a.promptAt = entry.Timestamp
+		case "tool_result":
+			delete(a.pendingTools, block.ToolUseID)
+			if block.IsError {
+				a.metrics.ErrorCount++
+			}
+		}
+	}
+}
+
+func (a *ClaudeAgent) applyAssistant(entry *TranscriptEntry) {
+	msg := entry.Message
+
+	// Claude Code writes one entry per content block, repeating the usage of
+	// the enclosing API message, so usage is only counted once per message ID
+	if msg.Usage != nil && (msg.ID == "" || !a.countedUsage[msg.ID]) {
+		if msg.ID != "" {
+			a.countedUsage[msg.ID] = true
+		}
+		usage := agent.TokenUsage{
+			Input:      msg.Usage.InputTokens,
+			Output:     msg.Usage.OutputTokens,
+			CacheRead:  msg.Usage.CacheReadInputTokens,
+			CacheWrite: msg.Usage.CacheCreationInputTokens,
+		}
+		a.metrics.TokensIn += usage.Input + usage.CacheRead + usage.CacheWrite
+		a.metrics.TokensOut += usage.Output
+		a.metrics.EstimatedCost += a.catalog.Cost("anthropic", msg.Model, usage)
+
+		// Every request carries the whole conversation, so the latest usage
+		// is what currently occupies the context window
+		a.metrics.ContextUtilization = a.catalog.ContextUtilization("anthropic", msg.Model, usage.Total())
+	}
+	if msg.Model != "" && msg.Model != "<synthetic>" {
+		a.metrics.Model = msg.Model
+	}
+
+	if entry.IsAPIErrorMessage {
+		a.metrics.ErrorCount++
+		for _, block := range msg.Blocks() {
+			if block.Type == "text" && block.Text != "" {
+				a.lastError = fmt.Errorf("%s", block.Text)
+				break
+			}
+		}
+		if a.lastError == nil {
+			a.lastError = fmt.Errorf("API error")
+		}
+		a.contextFull = agent.IsContextOverflow(a.lastError.Error())
+	} else if !entry.IsSidechain {
+		a.contextFull = false
+	}
+	if !entry.IsSidechain {
+		a.promptAt = time.Time{}
+	}
+
+	for _, block := range msg.Blocks() {
+		switch block.Type {
+		case "text":
+			if block.Text != "" && !entry.IsSidechain {
+				a.output.WriteString(block.Text)
+				a.output.WriteString("\n")
+			}
+		case "tool_use":
+			a.metrics.ToolCalls++
+			a.pendingTools[block.ID] = pendingTool{name: block.Name, started: entry.Timestamp}
+		}
+	}
+}
+
+func (a *ClaudeAgent) setTask(text string) {
+	task := strings.TrimSpace(text)
+	if idx := strings.IndexByte(task, '\n'); idx >= 0 {
+		task = task[:idx]
+	}
+	if len(task) > 100 {
+		task = task[:100] + "..."
+	}
+	a.currentTask = task
+}
+
+// determineStatus derives the agent status from the most recent entry
+func (a *ClaudeAgent) determineStatus() {
+	if a.status == agent.StatusCancelled {
+		return
+	}
+
+	if a.lastEntry == nil {
+		a.status = agent.StatusPending
+		return
+	}
+
+	sinceLast := time.Since(a.lastActivity)
+
+	if a.contextFull && sinceLast < 30*time.Minute {
+		a.status = agent.StatusContextLimit
+		return
+	}
+
+	if a.lastEntry.IsAPIErrorMessage && sinceLast < 5*time.Minute {
+		a.status = agent.StatusErrored
+		return
+	}
+
+	// The model finished its turn and is waiting for the user
+	finished := a.lastEntry.Type == "assistant" && a.lastEntry.Message.StopReason == "end_turn" && len(a.pendingTools) == 0
+
+	switch {
+	case !finished && len(a.pendingTools) > 0 && sinceLast < 30*time.Minute:
+		a.status = agent.StatusRunning
+	case !finished && sinceLast < 60*time.Second:
+		a.status = agent.StatusRunning
+	case sinceLast < 30*time.Minute:
+		a.status = agent.StatusIdle
+	default:
+		a.status = agent.StatusCompleted
+	}
+}
+
+// ID returns the agent's unique identifier
+func (a *ClaudeAgent) ID() string {
+	return a.id
+}
+
+// Name returns the agent's display name
+func (a *ClaudeAgent) Name() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.summary != "" {
+		return a.summary
+	}
+	if a.firstPrompt != "" {
+		if len(a.firstPrompt) > 50 {
+			return a.firstPrompt[:50] + "..."
+		}
+		return a.firstPrompt
+	}
+	if len(a.id) > 8 {
+		return a.id[:8]
+	}
+	return a.id
+}
+
+// Type returns the agent type
+func (a *ClaudeAgent) Type() string {
+	return "claude"
+}
+
+// Directory returns the working directory
+func (a *ClaudeAgent) Directory() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.directory
+}
+
+// ProjectID returns the project identifier (the transcript's project directory)
+func (a *ClaudeAgent) ProjectID() string {
+	return a.projectID
+}
+
+// ParentID returns the parent session ID (Claude Code sessions are top-level)
+func (a *ClaudeAgent) ParentID() string {
+	return ""
+}
+
+// IsBackground returns whether the agent is a background session
+func (a *ClaudeAgent) IsBackground() bool {
+	return false
+}
+
+// Status returns the current status
+func (a *ClaudeAgent) Status() agent.Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.status
+}
+
+// StartTime returns when the session started
+func (a *ClaudeAgent) StartTime() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.startTime
+}
+
+// LastActivity returns the last activity time
+func (a *ClaudeAgent) LastActivity() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastActivity
+}
+
+// Output returns the conversation text seen so far
+func (a *ClaudeAgent) Output() io.Reader {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return bytes.NewReader(a.output.Bytes())
+}
+
+// CurrentTask returns the most recent user prompt
+func (a *ClaudeAgent) CurrentTask() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.currentTask
+}
+
+// Metrics returns the agent's metrics
+func (a *ClaudeAgent) Metrics() agent.Metrics {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	m := a.metrics
+	m.Duration = a.lastActivity.Sub(a.startTime)
+	return m
+}
+
+// Progress reports the oldest pending tool call and any unanswered prompt
+func (a *ClaudeAgent) Progress() agent.Progress {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	p := agent.Progress{AwaitingReply: a.promptAt}
+	for _, tool := range a.pendingTools {
+		if p.Tool == "" || tool.started.Before(p.ToolStarted) {
+			p.Tool = tool.name
+			p.ToolStarted = tool.started
+		}
+	}
+	return p
+}
+
+// LastError returns the last error
+func (a *ClaudeAgent) LastError() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastError
+}
+
+// SendInput resumes the session non-interactively with the given prompt
+func (a *ClaudeAgent) SendInput(input string) error {
+	if input == "" {
+		return fmt.Errorf("empty input")
+	}
+
+	a.mu.RLock()
+	sessionID := a.id
+	workDir := a.directory
+	a.mu.RUnlock()
+
+	cmd := exec.Command("claude", "-p", "--resume", sessionID, input)
+	cmd.Dir = workDir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("claude failed: %s", stderr.String())
+		}
+		return fmt.Errorf("claude failed: %w", err)
+	}
+
+	return nil
+}
+
+// Terminate terminates the agent (not supported: the session's process,
+// if any, is not AUTO's)
+func (a *ClaudeAgent) Terminate() error {
+	return fmt.Errorf("terminate %w for claude sessions", agent.ErrUnsupported)
+}
+
+// Pause pauses the agent (not supported)
+func (a *ClaudeAgent) Pause() error {
+	return fmt.Errorf("pause %w for claude sessions", agent.ErrUnsupported)
+}
+
+// Resume resumes the agent (not supported)
+func (a *ClaudeAgent) Resume() error {
+	return fmt.Errorf("resume %w for claude sessions", agent.ErrUnsupported)
+}
+
+// Provider implements the agent.Provider interface for Claude Code
+type Provider struct {
+	projectsPath  string
+	watchInterval time.Duration
+	maxAge        time.Duration
+	agents        map[string]*ClaudeAgent
+	mu            sync.RWMutex
+	watcher       *fsnotify.Watcher
+	catalog       *agent.ModelCatalog
+}
+
+// NewProvider creates a new Claude Code provider reading transcripts under projectsPath
+func NewProvider(projectsPath string, watchInterval time.Duration, maxAge time.Duration) *Provider {
+	if watchInterval <= 0 {
+		watchInterval = 5 * time.Second
+	}
+	return &Provider{
+		projectsPath:  projectsPath,
+		watchInterval: watchInterval,
+		maxAge:        maxAge,
+		agents:        make(map[string]*ClaudeAgent),
+	}
+}
+
+// SetCatalog sets the model catalog used to price usage. Known sessions
+// are re-read on their next refresh so their costs use the new prices.
+func (p *Provider) SetCatalog(catalog *agent.ModelCatalog) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.catalog = catalog
+	for _, a := range p.agents {
+		a.mu.Lock()
+		a.catalog = catalog
+		a.reset()
+		a.mu.Unlock()
+	}
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "Claude Code"
+}
+
+// Type returns the provider type
+func (p *Provider) Type() string {
+	return "claude"
+}
+
+// Discover discovers all Claude Code sessions
+func (p *Provider) Discover(ctx context.Context) ([]agent.Agent, error) {
+	t := time.Now()
+
+	// Transcripts are stored in <projects>/<encoded-project-dir>/<session-id>.jsonl
+	projectDirs, err := os.ReadDir(p.projectsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read projects directory: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.agents = make(map[string]*ClaudeAgent)
+
+	var agents []agent.Agent
+	for _, projectDir := range projectDirs {
+		if !projectDir.IsDir() {
+			continue
+		}
+
+		projectPath := filepath.Join(p.projectsPath, projectDir.Name())
+		files, err := os.ReadDir(projectPath)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".jsonl") {
+				continue
+			}
+
+			if p.maxAge > 0 {
+				if info, err := file.Info(); err == nil && time.Since(info.ModTime()) > p.maxAge {
+					continue
+				}
+			}
+
+			a, err := newClaudeAgent(filepath.Join(projectPath, file.Name()), p.catalog)
+			if err != nil {
+				continue
+			}
+
+			p.agents[a.ID()] = a
+			agents = append(agents, a)
+		}
+	}
+
+	log.Printf("[TIMING] Claude: Discover completed in %v - loaded: %d", time.Since(t), len(agents))
+
+	return agents, nil
+}
+
+// Watch watches for changes in Claude Code transcripts
+func (p *Provider) Watch(ctx context.Context) (<-chan agent.Event, error) {
+	events := make(chan agent.Event, 100)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	p.watcher = watcher
+
+	// Directory might not exist yet; the periodic refresh still runs
+	_ = watcher.Add(p.projectsPath)
+
+	projectDirs, _ := os.ReadDir(p.projectsPath)
+	for _, projectDir := range projectDirs {
+		if projectDir.IsDir() {
+			watcher.Add(filepath.Join(p.projectsPath, projectDir.Name()))
+		}
+	}
+
+	go func() {
+		defer close(events)
+		defer watcher.Close()
+
+		ticker := time.NewTicker(p.watchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+					p.handleFileChange(event.Name, events)
+				}
+
+			case <-ticker.C:
+				p.refreshAll(events)
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// handleFileChange handles a file change event
+func (p *Provider) handleFileChange(path string, events chan<- agent.Event) {
+	// A new project directory appeared
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if filepath.Dir(path) == filepath.Clean(p.projectsPath) && p.watcher != nil {
+			p.watcher.Add(path)
+		}
+		return
+	}
+
+	if !strings.HasSuffix(path, ".jsonl") {
+		return
+	}
+
+	id := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+
+	p.mu.RLock()
+	existing, exists := p.agents[id]
+	catalog := p.catalog
+	p.mu.RUnlock()
+
+	if !exists {
+		a, err := newClaudeAgent(path, catalog)
+		if err != nil {
+			return
+		}
+
+		p.mu.Lock()
+		p.agents[a.ID()] = a
+		p.mu.Unlock()
+
+		events <- agent.Event{
+			Type:      agent.EventAgentDiscovered,
+			AgentID:   a.ID(),
+			Agent:     a,
+			Timestamp: time.Now(),
+		}
+		return
+	}
+
+	// Transcripts are written to often; only report writes that changed
+	// something events carry
+	before := existing.snapshot()
+	existing.Refresh()
+	after := existing.snapshot()
+	if after == before {
+		return
+	}
+
+	events <- agent.Event{
+		Type:      statusEventType(before.status, after.status),
+		AgentID:   existing.ID(),
+		Agent:     existing,
+		Timestamp: time.Now(),
+	}
+}
+
+// snapshot is the state of an agent that update events report
+type snapshot struct {
+	status   agent.Status
+	task     string
+	metrics  agent.Metrics // without Duration, which grows with every entry
+	progress agent.Progress
+}
+
+// snapshot returns the agent's current snapshot
+func (a *ClaudeAgent) snapshot() snapshot {
+	m := a.Metrics()
+	m.Duration = 0
+	return snapshot{a.Status(), a.CurrentTask(), m, a.Progress()}
+}
+
+// refreshAll refreshes all agents, emitting events for status changes
+func (p *Provider) refreshAll(events chan<- agent.Event) {
+	p.mu.RLock()
+	agents := make([]*ClaudeAgent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	p.mu.RUnlock()
+
+	for _, a := range agents {
+		oldStatus := a.Status()
+		a.Refresh()
+		newStatus := a.Status()
+
+		if oldStatus != newStatus {
+			events <- agent.Event{
+				Type:      statusEventType(oldStatus, newStatus),
+				AgentID:   a.ID(),
+				Agent:     a,
+				Timestamp: time.Now(),
+			}
+		}
+	}
+}
+
+// statusEventType maps a status transition to an event type
+func statusEventType(oldStatus, newStatus agent.Status) agent.EventType {
+	if oldStatus == newStatus {
+		return agent.EventAgentUpdated
+	}
+	switch newStatus {
+	case agent.StatusRunning:
+		return agent.EventAgentStarted
+	case agent.StatusCompleted:
+		return agent.EventAgentCompleted
+	case agent.StatusErrored:
+		return agent.EventAgentErrored
+	case agent.StatusContextLimit:
+		return agent.EventAgentContextLimit
+	}
+	return agent.EventAgentUpdated
+}
+
+// Spawn starts a new headless Claude Code session
+func (p *Provider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	prompt := config.Prompt
+	if prompt == "" {
+		prompt = "Hello, start a new session"
+	}
+
+	cmd := exec.Command("claude", "-p", "--output-format", "stream-json", "--verbose", prompt)
+	cmd.Dir = config.Directory
+	cmd.Env = os.Environ()
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start claude: %w", err)
+	}
+
+	// The first stream-json message is the init event carrying the session ID
+	sessionIDs := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		sent := false
+		for scanner.Scan() {
+			if sent {
+				continue
+			}
+			var msg struct {
+				SessionID string `json:"session_id"`
+			}
+			if json.Unmarshal(scanner.Bytes(), &msg) == nil && msg.SessionID != "" {
+				sessionIDs <- msg.SessionID
+				sent = true
+			}
+		}
+		close(sessionIDs)
+		cmd.Wait()
+	}()
+
+	var sessionID string
+	select {
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		return nil, ctx.Err()
+	case <-time.After(30 * time.Second):
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("timeout waiting for session creation")
+	case id, ok := <-sessionIDs:
+		if !ok {
+			return nil, fmt.Errorf("claude exited without creating a session")
+		}
+		sessionID = id
+	}
+
+	// Wait for the transcript to be written
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		if path := p.findTranscript(sessionID); path != "" {
+			p.mu.RLock()
+			catalog := p.catalog
+			p.mu.RUnlock()
+
+			a, err := newClaudeAgent(path, catalog)
+			if err != nil {
+				return nil, err
+			}
+			p.mu.Lock()
+			p.agents[a.ID()] = a
+			p.mu.Unlock()
+			return a, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find transcript for session %s", sessionID)
+}
+
+// findTranscript locates the transcript file for a session ID
+func (p *Provider) findTranscript(sessionID string) string {
+	matches, _ := filepath.Glob(filepath.Join(p.projectsPath, "*", sessionID+".jsonl"))
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// Get returns an agent by ID
+func (p *Provider) Get(id string) (agent.Agent, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if a, ok := p.agents[id]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("agent not found: %s", id)
+}
+
+// List returns all agents
+func (p *Provider) List() []agent.Agent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	agents := make([]agent.Agent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// Terminate terminates an agent
+func (p *Provider) Terminate(id string) error {
+	p.mu.RLock()
+	a, ok := p.agents[id]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+
+	return a.Terminate()
+}
+
+// SendInput sends input to an agent
+func (p *Provider) SendInput(id string, input string) error {
+	p.mu.RLock()
+	a, ok := p.agents[id]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+
+	return a.SendInput(input)
+}
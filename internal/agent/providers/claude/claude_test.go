@@ -0,0 +1,615 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// Helper to build a transcript line
+func entryLine(t *testing.T, entry map[string]interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to marshal entry: %v", err)
+	}
+	return string(data) + "\n"
+}
+
+func userPrompt(t *testing.T, text string, ts time.Time) string {
+	return entryLine(t, map[string]interface{}{
+		"type":      "user",
+		"sessionId": "sess-1",
+		"cwd":       "/home/user/project",
+		"timestamp": ts.Format(time.RFC3339Nano),
+		"message":   map[string]interface{}{"role": "user", "content": text},
+	})
+}
+
+func assistantText(t *testing.T, msgID, text, stopReason string, ts time.Time) string {
+	return entryLine(t, map[string]interface{}{
+		"type":      "assistant",
+		"sessionId": "sess-1",
+		"cwd":       "/home/user/project",
+		"timestamp": ts.Format(time.RFC3339Nano),
+		"message": map[string]interface{}{
+			"id":          msgID,
+			"role":        "assistant",
+			"model":       "claude-sonnet-4-5",
+			"stop_reason": stopReason,
+			"content":     []map[string]interface{}{{"type": "text", "text": text}},
+			"usage": map[string]interface{}{
+				"input_tokens":                100,
+				"output_tokens":               50,
+				"cache_creation_input_tokens": 10,
+				"cache_read_input_tokens":     20,
+			},
+		},
+	})
+}
+
+func assistantToolUse(t *testing.T, msgID, toolID, tool string, ts time.Time) string {
+	return entryLine(t, map[string]interface{}{
+		"type":      "assistant",
+		"sessionId": "sess-1",
+		"timestamp": ts.Format(time.RFC3339Nano),
+		"message": map[string]interface{}{
+			"id":          msgID,
+			"role":        "assistant",
+			"stop_reason": "tool_use",
+			"content":     []map[string]interface{}{{"type": "tool_use", "id": toolID, "name": tool}},
+			"usage":       map[string]interface{}{"input_tokens": 100, "output_tokens": 50},
+		},
+	})
+}
+
+func toolResult(t *testing.T, toolID string, isError bool, ts time.Time) string {
+	return entryLine(t, map[string]interface{}{
+		"type":      "user",
+		"sessionId": "sess-1",
+		"timestamp": ts.Format(time.RFC3339Nano),
+		"message": map[string]interface{}{
+			"role":    "user",
+			"content": []map[string]interface{}{{"type": "tool_result", "tool_use_id": toolID, "is_error": isError}},
+		},
+	})
+}
+
+// Helper to create a projects tree with a single transcript
+func createTestTranscript(t *testing.T, project, sessionID string, lines ...string) (string, string) {
+	t.Helper()
+
+	projectsPath := t.TempDir()
+	projectDir := filepath.Join(projectsPath, project)
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+
+	path := filepath.Join(projectDir, sessionID+".jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	return projectsPath, path
+}
+
+func appendLines(t *testing.T, path string, lines ...string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open transcript: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range lines {
+		if _, err := f.WriteString(line); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+}
+
+func TestNewClaudeAgent(t *testing.T) {
+	now := time.Now()
+	_, path := createTestTranscript(t, "-home-user-project", "sess-1",
+		userPrompt(t, "Fix the login bug", now.Add(-10*time.Second)),
+	)
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+
+	if a.ID() != "sess-1" {
+		t.Errorf("ID() = %v, want sess-1", a.ID())
+	}
+	if a.Type() != "claude" {
+		t.Errorf("Type() = %v, want claude", a.Type())
+	}
+	if a.Directory() != "/home/user/project" {
+		t.Errorf("Directory() = %v, want /home/user/project", a.Directory())
+	}
+	if a.ProjectID() != "-home-user-project" {
+		t.Errorf("ProjectID() = %v, want -home-user-project", a.ProjectID())
+	}
+	if a.CurrentTask() != "Fix the login bug" {
+		t.Errorf("CurrentTask() = %q, want %q", a.CurrentTask(), "Fix the login bug")
+	}
+	if a.Name() != "Fix the login bug" {
+		t.Errorf("Name() = %q, want first prompt", a.Name())
+	}
+	// Waiting on the model after a fresh prompt
+	if a.Status() != agent.StatusRunning {
+		t.Errorf("Status() = %v, want %v", a.Status(), agent.StatusRunning)
+	}
+}
+
+func TestNewClaudeAgent_InvalidPath(t *testing.T) {
+	if _, err := NewClaudeAgent("/nonexistent/sess.jsonl"); err == nil {
+		t.Error("NewClaudeAgent() should error for nonexistent path")
+	}
+}
+
+func TestClaudeAgent_EmptyTranscript(t *testing.T) {
+	_, path := createTestTranscript(t, "proj", "sess-1")
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+	if a.Status() != agent.StatusPending {
+		t.Errorf("Status() = %v, want %v", a.Status(), agent.StatusPending)
+	}
+	if a.Name() != "sess-1" {
+		t.Errorf("Name() = %q, want id fallback", a.Name())
+	}
+}
+
+func TestClaudeAgent_Status(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		lines func(t *testing.T) []string
+		want  agent.Status
+	}{
+		{
+			name: "idle after end_turn",
+			lines: func(t *testing.T) []string {
+				return []string{
+					userPrompt(t, "hi", now.Add(-2*time.Minute)),
+					assistantText(t, "msg-1", "hello", "end_turn", now.Add(-90*time.Second)),
+				}
+			},
+			want: agent.StatusIdle,
+		},
+		{
+			name: "running with pending tool call",
+			lines: func(t *testing.T) []string {
+				return []string{
+					userPrompt(t, "run tests", now.Add(-10*time.Minute)),
+					assistantToolUse(t, "msg-1", "tool-1", "Bash", now.Add(-5*time.Minute)),
+				}
+			},
+			want: agent.StatusRunning,
+		},
+		{
+			name: "completed when old",
+			lines: func(t *testing.T) []string {
+				return []string{
+					userPrompt(t, "hi", now.Add(-2*time.Hour)),
+					assistantText(t, "msg-1", "hello", "end_turn", now.Add(-2*time.Hour)),
+				}
+			},
+			want: agent.StatusCompleted,
+		},
+		{
+			name: "errored on recent API error",
+			lines: func(t *testing.T) []string {
+				return []string{
+					userPrompt(t, "hi", now.Add(-2*time.Minute)),
+					entryLine(t, map[string]interface{}{
+						"type":              "assistant",
+						"timestamp":         now.Add(-time.Minute).Format(time.RFC3339Nano),
+						"isApiErrorMessage": true,
+						"message": map[string]interface{}{
+							"role":    "assistant",
+							"content": []map[string]interface{}{{"type": "text", "text": "API Error: overloaded"}},
+						},
+					}),
+				}
+			},
+			want: agent.StatusErrored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, path := createTestTranscript(t, "proj", "sess-1", tt.lines(t)...)
+			a, err := NewClaudeAgent(path)
+			if err != nil {
+				t.Fatalf("NewClaudeAgent() error = %v", err)
+			}
+			if a.Status() != tt.want {
+				t.Errorf("Status() = %v, want %v", a.Status(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClaudeAgent_Metrics(t *testing.T) {
+	now := time.Now()
+	_, path := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "do it", now.Add(-time.Minute)),
+		assistantToolUse(t, "msg-1", "tool-1", "Read", now.Add(-50*time.Second)),
+		toolResult(t, "tool-1", false, now.Add(-45*time.Second)),
+		assistantToolUse(t, "msg-2", "tool-2", "Bash", now.Add(-40*time.Second)),
+		toolResult(t, "tool-2", true, now.Add(-35*time.Second)),
+		// Two entries sharing a message ID must only be counted once
+		assistantText(t, "msg-3", "part one", "", now.Add(-30*time.Second)),
+		assistantText(t, "msg-3", "part two", "end_turn", now.Add(-30*time.Second)),
+	)
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+
+	m := a.Metrics()
+	if m.TokensIn != 100+100+130 {
+		t.Errorf("TokensIn = %d, want %d", m.TokensIn, 330)
+	}
+	if m.TokensOut != 150 {
+		t.Errorf("TokensOut = %d, want 150", m.TokensOut)
+	}
+	if m.ToolCalls != 2 {
+		t.Errorf("ToolCalls = %d, want 2", m.ToolCalls)
+	}
+	if m.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1", m.ErrorCount)
+	}
+}
+
+func TestClaudeAgent_CatalogPricing(t *testing.T) {
+	now := time.Now()
+	projectsPath, _ := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "do it", now.Add(-time.Minute)),
+		assistantToolUse(t, "msg-1", "tool-1", "Read", now.Add(-50*time.Second)),
+		toolResult(t, "tool-1", false, now.Add(-45*time.Second)),
+		assistantText(t, "msg-2", "done", "end_turn", now.Add(-30*time.Second)),
+	)
+
+	catalog := agent.NewModelCatalog()
+	catalog.SetFallback(agent.ModelInfo{InputPer1K: 0.01, OutputPer1K: 0.02})
+
+	p := NewProvider(projectsPath, time.Second, 0)
+	p.SetCatalog(catalog)
+	agents, err := p.Discover(context.Background())
+	if err != nil || len(agents) != 1 {
+		t.Fatalf("Discover() = %v, %v", agents, err)
+	}
+
+	m := agents[0].Metrics()
+	if m.Model != "claude-sonnet-4-5" {
+		t.Errorf("Model = %q, want claude-sonnet-4-5", m.Model)
+	}
+	// msg-1 has no model and uses the fallback, msg-2 the built-in sonnet prices
+	wantCost := 0.002 + (100*0.003+50*0.015+20*0.0003+10*0.00375)/1000
+	if diff := m.EstimatedCost - wantCost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("EstimatedCost = %f, want %f", m.EstimatedCost, wantCost)
+	}
+	if want := 180.0 / 200000; m.ContextUtilization != want {
+		t.Errorf("ContextUtilization = %f, want %f", m.ContextUtilization, want)
+	}
+}
+
+func TestClaudeAgent_ContextLimit(t *testing.T) {
+	now := time.Now()
+	overflow := entryLine(t, map[string]interface{}{
+		"type":              "assistant",
+		"sessionId":         "sess-1",
+		"timestamp":         now.Add(-20 * time.Second).Format(time.RFC3339Nano),
+		"isApiErrorMessage": true,
+		"message": map[string]interface{}{
+			"role":    "assistant",
+			"content": []map[string]interface{}{{"type": "text", "text": "API Error: 400 prompt is too long: 210000 tokens > 200000 maximum"}},
+		},
+	})
+	_, path := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "do it", now.Add(-time.Minute)),
+		overflow,
+	)
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+	if a.Status() != agent.StatusContextLimit {
+		t.Errorf("Status() after overflow = %v, want %v", a.Status(), agent.StatusContextLimit)
+	}
+
+	// A reply after compaction clears the limit
+	appendLines(t, path,
+		entryLine(t, map[string]interface{}{
+			"type":      "system",
+			"subtype":   "compact_boundary",
+			"sessionId": "sess-1",
+			"timestamp": now.Add(-10 * time.Second).Format(time.RFC3339Nano),
+		}),
+		entryLine(t, map[string]interface{}{
+			"type":             "user",
+			"sessionId":        "sess-1",
+			"isCompactSummary": true,
+			"timestamp":        now.Add(-10 * time.Second).Format(time.RFC3339Nano),
+			"message":          map[string]interface{}{"role": "user", "content": "This session is being continued..."},
+		}),
+	)
+	a.Refresh()
+	if a.Status() != agent.StatusContextLimit {
+		t.Errorf("Status() after compaction = %v, want %v", a.Status(), agent.StatusContextLimit)
+	}
+	if a.CurrentTask() != "do it" {
+		t.Errorf("CurrentTask() = %q, compaction summary should not replace the task", a.CurrentTask())
+	}
+
+	appendLines(t, path, assistantText(t, "msg-2", "continuing", "", now))
+	a.Refresh()
+	if a.Status() != agent.StatusRunning {
+		t.Errorf("Status() after reply = %v, want %v", a.Status(), agent.StatusRunning)
+	}
+}
+
+func TestClaudeAgent_Progress(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	_, path := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "do it", now.Add(-time.Minute)),
+	)
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+	if p := a.Progress(); !p.AwaitingReply.Equal(now.Add(-time.Minute)) || p.Tool != "" {
+		t.Errorf("Progress() after prompt = %+v, want awaiting reply", p)
+	}
+
+	appendLines(t, path,
+		assistantToolUse(t, "msg-1", "tool-1", "Bash", now.Add(-50*time.Second)),
+		assistantToolUse(t, "msg-2", "tool-2", "Read", now.Add(-40*time.Second)),
+		toolResult(t, "tool-2", false, now.Add(-30*time.Second)),
+	)
+	a.Refresh()
+	p := a.Progress()
+	if !p.AwaitingReply.IsZero() {
+		t.Errorf("AwaitingReply = %v, want zero after a reply", p.AwaitingReply)
+	}
+	if p.Tool != "Bash" || !p.ToolStarted.Equal(now.Add(-50*time.Second)) {
+		t.Errorf("Progress() = %+v, want Bash still running", p)
+	}
+}
+
+func TestClaudeAgent_RefreshIncremental(t *testing.T) {
+	now := time.Now()
+	_, path := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "first task", now.Add(-3*time.Minute)),
+		assistantText(t, "msg-1", "done", "end_turn", now.Add(-2*time.Minute)),
+	)
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+	if a.Status() != agent.StatusIdle {
+		t.Fatalf("Status() = %v, want %v", a.Status(), agent.StatusIdle)
+	}
+
+	appendLines(t, path,
+		userPrompt(t, "second task", now),
+		// Partial line without newline must be left for later
+		`{"type":"assistant","timestamp":`,
+	)
+
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if a.CurrentTask() != "second task" {
+		t.Errorf("CurrentTask() = %q, want %q", a.CurrentTask(), "second task")
+	}
+	if a.Status() != agent.StatusRunning {
+		t.Errorf("Status() = %v, want %v", a.Status(), agent.StatusRunning)
+	}
+	if a.Metrics().TokensIn != 130 {
+		t.Errorf("TokensIn = %d, want 130 (no double counting)", a.Metrics().TokensIn)
+	}
+
+	data, _ := io.ReadAll(a.Output())
+	if !strings.Contains(string(data), "second task") {
+		t.Errorf("Output() = %q, should contain second prompt", string(data))
+	}
+}
+
+func TestClaudeAgent_SummaryName(t *testing.T) {
+	now := time.Now()
+	_, path := createTestTranscript(t, "proj", "sess-1",
+		entryLine(t, map[string]interface{}{"type": "summary", "summary": "Login bug fix"}),
+		userPrompt(t, "Fix the login bug", now),
+	)
+
+	a, err := NewClaudeAgent(path)
+	if err != nil {
+		t.Fatalf("NewClaudeAgent() error = %v", err)
+	}
+	if a.Name() != "Login bug fix" {
+		t.Errorf("Name() = %q, want summary", a.Name())
+	}
+}
+
+func TestClaudeAgent_UnsupportedOperations(t *testing.T) {
+	_, path := createTestTranscript(t, "proj", "sess-1")
+	a, _ := NewClaudeAgent(path)
+
+	if err := a.Pause(); err == nil {
+		t.Error("Pause() should return an error")
+	}
+	if err := a.Resume(); err == nil {
+		t.Error("Resume() should return an error")
+	}
+	if err := a.SendInput(""); err == nil {
+		t.Error("SendInput() should error for empty input")
+	}
+	if err := a.Terminate(); !errors.Is(err, agent.ErrUnsupported) {
+		t.Errorf("Terminate() error = %v, want unsupported", err)
+	}
+	if a.Status() == agent.StatusCancelled {
+		t.Error("Terminate() reported a termination that did not happen")
+	}
+}
+
+func TestProvider_Discover(t *testing.T) {
+	now := time.Now()
+	projectsPath, _ := createTestTranscript(t, "proj-a", "sess-1", userPrompt(t, "a", now))
+
+	otherDir := filepath.Join(projectsPath, "proj-b")
+	os.MkdirAll(otherDir, 0755)
+	os.WriteFile(filepath.Join(otherDir, "sess-2.jsonl"), []byte(userPrompt(t, "b", now)), 0644)
+	os.WriteFile(filepath.Join(otherDir, "notes.txt"), []byte("ignored"), 0644)
+
+	p := NewProvider(projectsPath, time.Second, 0)
+	if p.Type() != "claude" {
+		t.Errorf("Type() = %v, want claude", p.Type())
+	}
+
+	agents, err := p.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("Discover() returned %d agents, want 2", len(agents))
+	}
+
+	if _, err := p.Get("sess-2"); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	if _, err := p.Get("missing"); err == nil {
+		t.Error("Get() should error for unknown agent")
+	}
+	if len(p.List()) != 2 {
+		t.Errorf("List() = %d, want 2", len(p.List()))
+	}
+}
+
+func TestProvider_Discover_NonExistentPath(t *testing.T) {
+	p := NewProvider("/nonexistent/projects", time.Second, 0)
+	agents, err := p.Discover(context.Background())
+	if err != nil {
+		t.Errorf("Discover() error = %v", err)
+	}
+	if len(agents) != 0 {
+		t.Errorf("Discover() returned %d agents, want 0", len(agents))
+	}
+}
+
+func TestProvider_Discover_MaxAge(t *testing.T) {
+	now := time.Now()
+	projectsPath, path := createTestTranscript(t, "proj", "old", userPrompt(t, "a", now))
+	old := now.Add(-48 * time.Hour)
+	os.Chtimes(path, old, old)
+
+	p := NewProvider(projectsPath, time.Second, 24*time.Hour)
+	agents, _ := p.Discover(context.Background())
+	if len(agents) != 0 {
+		t.Errorf("Discover() returned %d agents, want 0", len(agents))
+	}
+}
+
+func TestProvider_Watch(t *testing.T) {
+	now := time.Now()
+	projectsPath, path := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "hi", now.Add(-2*time.Minute)),
+		assistantText(t, "msg-1", "hello", "end_turn", now.Add(-90*time.Second)),
+	)
+
+	p := NewProvider(projectsPath, 100*time.Millisecond, 0)
+	p.Discover(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// New session in the same project
+	os.WriteFile(filepath.Join(projectsPath, "proj", "sess-2.jsonl"), []byte(userPrompt(t, "new", time.Now())), 0644)
+	// Existing session starts working again
+	appendLines(t, path, userPrompt(t, "again", time.Now()))
+
+	var discovered, started bool
+	for !(discovered && started) {
+		select {
+		case event := <-events:
+			if event.Type == agent.EventAgentDiscovered && event.AgentID == "sess-2" {
+				discovered = true
+			}
+			if event.Type == agent.EventAgentStarted && event.AgentID == "sess-1" {
+				started = true
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out: discovered=%v started=%v", discovered, started)
+		}
+	}
+}
+
+func TestProvider_HandleFileChangeSkipsNoops(t *testing.T) {
+	now := time.Now()
+	projectsPath, path := createTestTranscript(t, "proj", "sess-1",
+		userPrompt(t, "hi", now.Add(-2*time.Minute)),
+		assistantText(t, "msg-1", "hello", "end_turn", now.Add(-90*time.Second)),
+	)
+	p := NewProvider(projectsPath, time.Second, 0)
+	p.Discover(context.Background())
+	events := make(chan agent.Event, 10)
+
+	// A write that changes nothing events report
+	appendLines(t, path, entryLine(t, map[string]interface{}{"type": "summary", "summary": "Greeting"}))
+	p.handleFileChange(path, events)
+	if len(events) != 0 {
+		t.Errorf("got %v, want no event", (<-events).Type)
+	}
+
+	// Usage from a new message changes the metrics
+	appendLines(t, path, assistantText(t, "msg-2", "more", "end_turn", now.Add(-time.Minute)))
+	p.handleFileChange(path, events)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want an update", len(events))
+	}
+	if event := <-events; event.Type != agent.EventAgentUpdated || event.AgentID != "sess-1" {
+		t.Errorf("event = %v for %s", event.Type, event.AgentID)
+	}
+}
+
+func TestProvider_TerminateAndSendInput(t *testing.T) {
+	projectsPath, _ := createTestTranscript(t, "proj", "sess-1")
+	p := NewProvider(projectsPath, time.Second, 0)
+	p.Discover(context.Background())
+
+	if err := p.Terminate("sess-1"); !errors.Is(err, agent.ErrUnsupported) {
+		t.Errorf("Terminate() error = %v, want unsupported", err)
+	}
+	if err := p.Terminate("missing"); err == nil {
+		t.Error("Terminate() should error for unknown agent")
+	}
+	if err := p.SendInput("missing", "hi"); err == nil {
+		t.Error("SendInput() should error for unknown agent")
+	}
+}
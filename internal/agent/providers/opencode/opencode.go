@@ -14,6 +14,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
@@ -36,6 +37,9 @@ type SessionData struct {
 		Additions int `json:"additions"`
 		Deletions int `json:"deletions"`
 		Files     int `json:"files"`
+		Diffs     []struct {
+			File string `json:"file"` // relative to the session directory
+		} `json:"diffs,omitempty"`
 	} `json:"summary"`
 }
 
@@ -47,14 +51,67 @@ type MessageData struct {
 	Time      struct {
 		Created int64 `json:"created"` // Unix timestamp in milliseconds
 	} `json:"time"`
-	Summary struct {
-		Title string `json:"title"`
-	} `json:"summary"`
-	Agent string `json:"agent,omitempty"`
-	Model struct {
+	Summary MessageSummary `json:"summary"`
+	Agent   string         `json:"agent,omitempty"`
+	Model   struct {
 		ProviderID string `json:"providerID"`
 		ModelID    string `json:"modelID"`
 	} `json:"model,omitempty"`
+
+	// Assistant messages record the model that answered and what it cost
+	ProviderID string        `json:"providerID,omitempty"`
+	ModelID    string        `json:"modelID,omitempty"`
+	Cost       float64       `json:"cost,omitempty"`
+	Tokens     *TokenUsage   `json:"tokens,omitempty"`
+	Error      *MessageError `json:"error,omitempty"`
+}
+
+// MessageSummary is the summary of a user message. Assistant messages that
+// opencode writes when compacting a session carry `"summary": true` instead.
+type MessageSummary struct {
+	Title      string `json:"title"`
+	Compaction bool   `json:"-"`
+}
+
+// UnmarshalJSON accepts both the object and the boolean form
+func (s *MessageSummary) UnmarshalJSON(data []byte) error {
+	var compaction bool
+	if err := json.Unmarshal(data, &compaction); err == nil {
+		s.Compaction = compaction
+		return nil
+	}
+
+	type plain MessageSummary
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// MarshalJSON writes compaction summaries in the boolean form
+func (s MessageSummary) MarshalJSON() ([]byte, error) {
+	if s.Compaction {
+		return []byte("true"), nil
+	}
+
+	type plain MessageSummary
+	return json.Marshal(plain(s))
+}
+
+// TokenUsage holds the token counts opencode records on assistant messages
+type TokenUsage struct {
+	Input     int64 `json:"input"`
+	Output    int64 `json:"output"`
+	Reasoning int64 `json:"reasoning"`
+	Cache     struct {
+		Read  int64 `json:"read"`
+		Write int64 `json:"write"`
+	} `json:"cache"`
+}
+
+// MessageError is the error opencode attaches to a failed assistant message
+type MessageError struct {
+	Name string `json:"name"`
+	Data struct {
+		Message string `json:"message"`
+	} `json:"data"`
 }
 
 // PartData represents a message part (content, tool calls, etc.)
@@ -66,10 +123,102 @@ type PartData struct {
 	Time      struct {
 		Created int64 `json:"created"`
 	} `json:"time"`
-	Text       string `json:"text,omitempty"`
-	ToolName   string `json:"toolName,omitempty"`
-	ToolCallID string `json:"toolCallId,omitempty"`
-	State      string `json:"state,omitempty"` // "running", "success", "error"
+	Text       string    `json:"text,omitempty"`
+	ToolName   string    `json:"toolName,omitempty"`
+	ToolCallID string    `json:"toolCallId,omitempty"`
+	Tool       string    `json:"tool,omitempty"`  // Tool name on "tool" parts
+	State      PartState `json:"state,omitempty"` // "running", "success", "completed", "error"
+
+	// Input and end time of tool calls, from the object form of the state
+	Input   ToolInput `json:"-"`
+	EndTime int64     `json:"-"`
+}
+
+// ToolInput holds the arguments of file tools that AUTO tracks
+type ToolInput struct {
+	FilePath  string `json:"filePath"`  // edit, write and multiedit
+	PatchText string `json:"patchText"` // patch
+}
+
+// UnmarshalJSON also reads the tool input from the object form of the state
+func (p *PartData) UnmarshalJSON(data []byte) error {
+	type plain PartData
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	if !p.isTool() {
+		return nil
+	}
+
+	var tool struct {
+		State struct {
+			Input ToolInput `json:"input"`
+			Time  struct {
+				End int64 `json:"end"`
+			} `json:"time"`
+		} `json:"state"`
+	}
+	if json.Unmarshal(data, &tool) == nil {
+		p.Input = tool.State.Input
+		p.EndTime = tool.State.Time.End
+	}
+	return nil
+}
+
+// writtenFiles returns the files a completed tool call wrote, as given
+func (p *PartData) writtenFiles() []string {
+	if !p.isTool() || (p.State != "completed" && p.State != "success") {
+		return nil
+	}
+	tool := p.Tool
+	if tool == "" {
+		tool = p.ToolName
+	}
+
+	switch tool {
+	case "edit", "write", "multiedit":
+		if p.Input.FilePath != "" {
+			return []string{p.Input.FilePath}
+		}
+	case "patch":
+		var files []string
+		for _, line := range strings.Split(p.Input.PatchText, "\n") {
+			for _, prefix := range []string{"*** Add File: ", "*** Update File: ", "*** Move to: "} {
+				if strings.HasPrefix(line, prefix) {
+					files = append(files, strings.TrimSpace(strings.TrimPrefix(line, prefix)))
+				}
+			}
+		}
+		return files
+	}
+	return nil
+}
+
+// PartState is a tool part's state. Older opencode versions store it as a
+// plain string, newer ones as an object with a "status" field.
+type PartState string
+
+// UnmarshalJSON accepts both the string and the object form
+func (s *PartState) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = PartState(str)
+		return nil
+	}
+
+	var obj struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*s = PartState(obj.Status)
+	return nil
+}
+
+// isTool reports whether the part records a tool call
+func (p *PartData) isTool() bool {
+	return p.Type == "tool" || p.Type == "tool-invocation"
 }
 
 // OpenCodeAgent implements the Agent interface for opencode sessions
@@ -92,6 +241,14 @@ type OpenCodeAgent struct {
 	sessionData  *SessionData
 	messages     []MessageData
 	loaded       bool
+	catalog      *agent.ModelCatalog
+	contextFull  bool // set by a compaction or context overflow, cleared by the next reply
+	runningTool  string
+	toolStarted  time.Time
+	fileChanges  map[string]time.Time // path -> latest write by a tool call
+	paused       bool                 // stopped with SIGSTOP
+	terminatedAt time.Time            // when AUTO killed the session's processes
+	emit         func(a *OpenCodeAgent, eventType agent.EventType)
 
 	activeRunner *Runner
 	runnerCancel context.CancelFunc
@@ -178,12 +335,71 @@ func (a *OpenCodeAgent) loadMessages() error {
 		return a.messages[i].Time.Created < a.messages[j].Time.Created
 	})
 
-	// Load parts to get actual content and token metrics
+	a.computeMessageMetrics()
+
+	// Load parts to get actual content and tool metrics
 	a.loadParts()
 
 	return nil
 }
 
+// computeMessageMetrics rebuilds token, cost and error metrics from the loaded messages
+func (a *OpenCodeAgent) computeMessageMetrics() {
+	metrics := agent.Metrics{}
+	a.contextFull = false
+
+	for _, msg := range a.messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		a.contextFull = msg.Summary.Compaction
+		if msg.ModelID != "" {
+			metrics.Model = msg.ModelID
+		}
+
+		if msg.Tokens != nil {
+			usage := agent.TokenUsage{
+				Input:      msg.Tokens.Input,
+				Output:     msg.Tokens.Output + msg.Tokens.Reasoning,
+				CacheRead:  msg.Tokens.Cache.Read,
+				CacheWrite: msg.Tokens.Cache.Write,
+			}
+			metrics.TokensIn += usage.Input + usage.CacheRead + usage.CacheWrite
+			metrics.TokensOut += usage.Output
+
+			cost := msg.Cost
+			if cost == 0 {
+				cost = a.catalog.Cost(msg.ProviderID, msg.ModelID, usage)
+			}
+			metrics.EstimatedCost += cost
+
+			// Each request resends the whole conversation, so the latest
+			// message's tokens are what currently occupies the context
+			metrics.ContextUtilization = a.catalog.ContextUtilization(msg.ProviderID, msg.ModelID, usage.Total())
+		} else {
+			metrics.EstimatedCost += msg.Cost
+		}
+
+		if msg.Error != nil {
+			metrics.ErrorCount++
+			text := msg.Error.Data.Message
+			if text == "" {
+				text = msg.Error.Name
+			}
+			a.lastError = fmt.Errorf("%s", text)
+			if agent.IsContextOverflow(text) {
+				a.contextFull = true
+			}
+		}
+	}
+
+	if !a.startTime.IsZero() && a.lastActivity.After(a.startTime) {
+		metrics.Duration = a.lastActivity.Sub(a.startTime)
+	}
+
+	a.metrics = metrics
+}
+
 func (a *OpenCodeAgent) loadParts() {
 	type partWithTime struct {
 		part PartData
@@ -191,6 +407,10 @@ func (a *OpenCodeAgent) loadParts() {
 	}
 	var allParts []partWithTime
 
+	a.runningTool = ""
+	a.toolStarted = time.Time{}
+	a.fileChanges = make(map[string]time.Time)
+
 	for _, msg := range a.messages {
 		partsPath := filepath.Join(a.storagePath, "part", msg.ID)
 		entries, err := os.ReadDir(partsPath)
@@ -213,7 +433,11 @@ func (a *OpenCodeAgent) loadParts() {
 				continue
 			}
 
-			allParts = append(allParts, partWithTime{part: part, time: part.Time.Created})
+			created := part.Time.Created
+			if created == 0 {
+				created = msg.Time.Created
+			}
+			allParts = append(allParts, partWithTime{part: part, time: created})
 		}
 	}
 
@@ -222,6 +446,27 @@ func (a *OpenCodeAgent) loadParts() {
 	})
 
 	for _, p := range allParts {
+		if p.part.isTool() {
+			a.metrics.ToolCalls++
+			// Parts are sorted, so the first running one is the oldest
+			if p.part.State == "running" && a.toolStarted.IsZero() {
+				a.runningTool = p.part.Tool
+				if a.runningTool == "" {
+					a.runningTool = p.part.ToolName
+				}
+				a.toolStarted = time.UnixMilli(p.time)
+			}
+		}
+		for _, path := range p.part.writtenFiles() {
+			at := p.time
+			if p.part.EndTime > at {
+				at = p.part.EndTime
+			}
+			a.fileChanges[a.absPath(path)] = time.UnixMilli(at)
+		}
+		if p.part.State == "error" {
+			a.metrics.ErrorCount++
+		}
 		if p.part.Type == "text" && p.part.Text != "" {
 			a.output.WriteString(p.part.Text)
 			a.output.WriteString("\n")
@@ -265,6 +510,12 @@ func (a *OpenCodeAgent) determineStatus() {
 	lastMsgTime := time.UnixMilli(lastMsg.Time.Created)
 	timeSinceLastActivity := time.Since(lastMsgTime)
 
+	if a.contextFull && timeSinceLastActivity < 30*time.Minute {
+		a.status = agent.StatusContextLimit
+		a.lastActivity = lastMsgTime
+		return
+	}
+
 	for _, msg := range a.messages {
 		partsPath := filepath.Join(a.storagePath, "part", msg.ID)
 		entries, _ := os.ReadDir(partsPath)
@@ -348,11 +599,30 @@ func (a *OpenCodeAgent) Refresh() error {
 	}
 
 	a.determineStatus()
+	a.applyControlState()
 	a.extractCurrentTask()
 
 	return nil
 }
 
+// applyControlState keeps paused and terminated sessions from looking active,
+// since their files still show the work that was interrupted
+func (a *OpenCodeAgent) applyControlState() {
+	if a.paused {
+		a.status = agent.StatusIdle
+		return
+	}
+	if a.terminatedAt.IsZero() {
+		return
+	}
+	// A message after the kill means the session was continued elsewhere
+	if n := len(a.messages); n > 0 && time.UnixMilli(a.messages[n-1].Time.Created).After(a.terminatedAt) {
+		a.terminatedAt = time.Time{}
+		return
+	}
+	a.status = agent.StatusCancelled
+}
+
 // ID returns the agent's unique identifier
 func (a *OpenCodeAgent) ID() string {
 	return a.id
@@ -432,6 +702,59 @@ func (a *OpenCodeAgent) CurrentTask() string {
 	return a.currentTask
 }
 
+// Progress reports the oldest running tool call and any unanswered prompt
+func (a *OpenCodeAgent) Progress() agent.Progress {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// Work interrupted by a pause or kill is not in flight
+	if a.paused || !a.terminatedAt.IsZero() {
+		return agent.Progress{}
+	}
+
+	p := agent.Progress{Tool: a.runningTool, ToolStarted: a.toolStarted}
+	if n := len(a.messages); n > 0 && a.messages[n-1].Role == "user" {
+		p.AwaitingReply = time.UnixMilli(a.messages[n-1].Time.Created)
+	}
+	return p
+}
+
+// FileChanges returns the files the session wrote with its file tools, and
+// the other files in its diff summary as of the session's last update
+func (a *OpenCodeAgent) FileChanges() []agent.FileChange {
+	a.mu.RLock()
+	if !a.loaded {
+		a.mu.RUnlock()
+		a.LoadFullHistory()
+		a.mu.RLock()
+	}
+	defer a.mu.RUnlock()
+
+	changes := make([]agent.FileChange, 0, len(a.fileChanges))
+	for path, at := range a.fileChanges {
+		changes = append(changes, agent.FileChange{Path: path, Time: at})
+	}
+	if a.sessionData != nil {
+		updated := time.UnixMilli(a.sessionData.Time.Updated)
+		for _, diff := range a.sessionData.Summary.Diffs {
+			path := a.absPath(diff.File)
+			if _, ok := a.fileChanges[path]; !ok && diff.File != "" {
+				changes = append(changes, agent.FileChange{Path: path, Time: updated})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// absPath resolves a path relative to the session directory
+func (a *OpenCodeAgent) absPath(path string) string {
+	if filepath.IsAbs(path) || a.directory == "" {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(a.directory, path)
+}
+
 // Metrics returns the agent's metrics
 func (a *OpenCodeAgent) Metrics() agent.Metrics {
 	a.mu.RLock()
@@ -473,24 +796,93 @@ func (a *OpenCodeAgent) SendInput(input string) error {
 	return nil
 }
 
-// Terminate terminates the agent
+// processes returns the running opencode processes serving this session
+func (a *OpenCodeAgent) processes() ([]sessionProcess, error) {
+	procs := findSessionProcesses(a.id)
+	if len(procs) == 0 {
+		return nil, fmt.Errorf("no running opencode process for session %s", a.id)
+	}
+	return procs, nil
+}
+
+// notify emits an event for Watch consumers
+func (a *OpenCodeAgent) notify(eventType agent.EventType) {
+	if a.emit != nil {
+		a.emit(a, eventType)
+	}
+}
+
+// Terminate stops the session's opencode processes, escalating SIGTERM -> SIGKILL
 func (a *OpenCodeAgent) Terminate() error {
-	// OpenCode sessions can be terminated by killing the process
-	// For now, we just mark it as cancelled
+	procs, err := a.processes()
+	if err != nil {
+		return err
+	}
+
+	err = terminateProcesses(procs, terminateTimeout)
+
 	a.mu.Lock()
+	a.paused = false
+	a.terminatedAt = time.Now()
 	a.status = agent.StatusCancelled
 	a.mu.Unlock()
-	return nil
+
+	a.notify(agent.EventAgentTerminated)
+	return err
 }
 
-// Pause pauses the agent (not supported)
+// Pause stops the session's opencode processes with SIGSTOP
 func (a *OpenCodeAgent) Pause() error {
-	return fmt.Errorf("pause not supported for opencode sessions")
+	a.mu.RLock()
+	paused := a.paused
+	a.mu.RUnlock()
+	if paused {
+		return nil
+	}
+
+	procs, err := a.processes()
+	if err != nil {
+		return err
+	}
+	if err := signalProcesses(procs, syscall.SIGSTOP); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.paused = true
+	a.status = agent.StatusIdle
+	a.mu.Unlock()
+
+	a.notify(agent.EventAgentPaused)
+	return nil
 }
 
-// Resume resumes the agent (not supported)
+// Resume continues the session's opencode processes with SIGCONT. Processes
+// are continued even if AUTO did not pause them, e.g. before a restart.
 func (a *OpenCodeAgent) Resume() error {
-	return fmt.Errorf("resume not supported for opencode sessions")
+	procs, err := a.processes()
+	if err != nil {
+		return err
+	}
+	if err := signalProcesses(procs, syscall.SIGCONT); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.paused = false
+	a.status = agent.StatusRunning
+	a.lastActivity = time.Now()
+	a.mu.Unlock()
+
+	a.notify(agent.EventAgentResumed)
+	return nil
+}
+
+// IsPaused returns whether the session's processes are stopped
+func (a *OpenCodeAgent) IsPaused() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.paused
 }
 
 func (a *OpenCodeAgent) SendInputAsync(ctx context.Context, input string) (<-chan agent.StreamEvent, error) {
@@ -519,6 +911,7 @@ func (a *OpenCodeAgent) SendInputAsync(ctx context.Context, input string) (<-cha
 	}
 
 	a.activeRunner = runner
+	a.terminatedAt = time.Time{}
 	a.status = agent.StatusRunning
 	a.currentTask = input
 	if len(a.currentTask) > 100 {
@@ -541,7 +934,9 @@ func (a *OpenCodeAgent) SendInputAsync(ctx context.Context, input string) (<-cha
 		defer func() {
 			a.mu.Lock()
 			a.activeRunner = nil
-			a.status = agent.StatusIdle
+			if a.terminatedAt.IsZero() {
+				a.status = agent.StatusIdle
+			}
 			a.lastActivity = time.Now()
 			a.mu.Unlock()
 		}()
@@ -627,6 +1022,8 @@ type Provider struct {
 	agents        map[string]*OpenCodeAgent
 	mu            sync.RWMutex
 	watcher       *fsnotify.Watcher
+	catalog       *agent.ModelCatalog
+	updates       chan agent.Event // control events raised by agents
 }
 
 // NewProvider creates a new opencode provider
@@ -636,6 +1033,48 @@ func NewProvider(storagePath string, watchInterval time.Duration, maxAge time.Du
 		watchInterval: watchInterval,
 		maxAge:        maxAge,
 		agents:        make(map[string]*OpenCodeAgent),
+		updates:       make(chan agent.Event, 100),
+	}
+}
+
+// SetCatalog sets the model catalog used to price messages without a recorded cost
+func (p *Provider) SetCatalog(catalog *agent.ModelCatalog) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.catalog = catalog
+	for _, a := range p.agents {
+		a.mu.Lock()
+		a.catalog = catalog
+		a.mu.Unlock()
+	}
+}
+
+// newAgent loads a session file and applies the provider's model catalog
+func (p *Provider) newAgent(sessionFilePath string) (*OpenCodeAgent, error) {
+	a, err := NewOpenCodeAgent(p.storagePath, sessionFilePath)
+	if err != nil {
+		return nil, err
+	}
+	a.catalog = p.catalog
+	a.emit = p.emit
+	return a, nil
+}
+
+// emit queues an agent event for Watch consumers
+func (p *Provider) emit(a *OpenCodeAgent, eventType agent.EventType) {
+	event := agent.Event{
+		Type:      eventType,
+		AgentID:   a.ID(),
+		Agent:     a,
+		Timestamp: time.Now(),
+		Error:     a.LastError(),
+	}
+
+	// Control calls must not block when nobody is watching
+	select {
+	case p.updates <- event:
+	default:
 	}
 }
 
@@ -695,7 +1134,7 @@ func (p *Provider) Discover(ctx context.Context) ([]agent.Agent, error) {
 			totalFiles++
 
 			sessionFilePath := filepath.Join(projectPath, sessionFile.Name())
-			a, err := NewOpenCodeAgent(p.storagePath, sessionFilePath)
+			a, err := p.newAgent(sessionFilePath)
 			if err != nil {
 				skippedErr++
 				continue // Skip invalid sessions
@@ -777,6 +1216,9 @@ func (p *Provider) Watch(ctx context.Context) (<-chan agent.Event, error) {
 				// Periodic refresh of all agents
 				p.refreshAll(events)
 
+			case event := <-p.updates:
+				events <- event
+
 			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
@@ -794,7 +1236,9 @@ func (p *Provider) Watch(ctx context.Context) (<-chan agent.Event, error) {
 func (p *Provider) handleFileChange(path string, events chan<- agent.Event) {
 	// Check if this is a new session file
 	if strings.Contains(path, "/session/") && strings.HasSuffix(path, ".json") {
-		a, err := NewOpenCodeAgent(p.storagePath, path)
+		p.mu.RLock()
+		a, err := p.newAgent(path)
+		p.mu.RUnlock()
 		if err != nil {
 			return
 		}
@@ -864,6 +1308,8 @@ func (p *Provider) handleFileChange(path string, events chan<- agent.Event) {
 						eventType = agent.EventAgentCompleted
 					case agent.StatusErrored:
 						eventType = agent.EventAgentErrored
+					case agent.StatusContextLimit:
+						eventType = agent.EventAgentContextLimit
 					}
 				}
 
@@ -901,6 +1347,8 @@ func (p *Provider) refreshAll(events chan<- agent.Event) {
 				eventType = agent.EventAgentCompleted
 			case agent.StatusErrored:
 				eventType = agent.EventAgentErrored
+			case agent.StatusContextLimit:
+				eventType = agent.EventAgentContextLimit
 			}
 
 			events <- agent.Event{
@@ -924,6 +1372,7 @@ func (p *Provider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.A
 		Directory: config.Directory,
 		Message:   prompt,
 		Title:     config.Name,
+		Env:       config.Env,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create runner: %w", err)
@@ -994,6 +1443,7 @@ func (p *Provider) SpawnAsync(ctx context.Context, config agent.SpawnConfig) (<-
 		Directory: config.Directory,
 		Message:   prompt,
 		Title:     config.Name,
+		Env:       config.Env,
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create runner: %w", err)
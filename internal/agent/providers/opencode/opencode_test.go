@@ -3,6 +3,7 @@ package opencode
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -376,16 +377,33 @@ func TestOpenCodeAgent_Terminate(t *testing.T) {
 		t.Fatalf("NewOpenCodeAgent() error = %v", err)
 	}
 
+	installFakeOpenCode(t, "ses_test", false)
+	done := startExternalOpenCode(t, "ses_test")
+
 	if err := a.Terminate(); err != nil {
 		t.Errorf("Terminate() error = %v", err)
 	}
 
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("opencode process still running after Terminate()")
+	}
+
 	if a.Status() != agent.StatusCancelled {
 		t.Errorf("After Terminate(), Status() = %v, want %v", a.Status(), agent.StatusCancelled)
 	}
+
+	// The session files still look recent, but the agent stays cancelled
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if a.Status() != agent.StatusCancelled {
+		t.Errorf("After Refresh(), Status() = %v, want %v", a.Status(), agent.StatusCancelled)
+	}
 }
 
-func TestOpenCodeAgent_UnsupportedOperations(t *testing.T) {
+func TestOpenCodeAgent_NoProcess(t *testing.T) {
 	now := time.Now()
 	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
 	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
@@ -395,16 +413,22 @@ func TestOpenCodeAgent_UnsupportedOperations(t *testing.T) {
 		t.Fatalf("NewOpenCodeAgent() error = %v", err)
 	}
 
+	t.Setenv("PATH", t.TempDir())
+
 	if err := a.SendInput("test"); err == nil {
-		t.Error("SendInput() should return error for unsupported operation")
+		t.Error("SendInput() should return error without opencode installed")
+	}
+
+	if err := a.Terminate(); err == nil {
+		t.Error("Terminate() should return error without a running process")
 	}
 
 	if err := a.Pause(); err == nil {
-		t.Error("Pause() should return error for unsupported operation")
+		t.Error("Pause() should return error without a running process")
 	}
 
 	if err := a.Resume(); err == nil {
-		t.Error("Resume() should return error for unsupported operation")
+		t.Error("Resume() should return error without a running process")
 	}
 }
 
@@ -628,11 +652,20 @@ func TestProvider_Terminate(t *testing.T) {
 	p := NewProvider(tempDir, 5*time.Second, 0)
 	p.Discover(context.Background())
 
+	installFakeOpenCode(t, "ses_1", false)
+	runner := startRunner(t, "ses_1")
+
 	// Terminate existing agent
 	if err := p.Terminate("ses_1"); err != nil {
 		t.Errorf("Terminate() error = %v", err)
 	}
 
+	select {
+	case <-runner.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("runner still running after Terminate()")
+	}
+
 	// Check status changed
 	a, _ := p.Get("ses_1")
 	if a.Status() != agent.StatusCancelled {
@@ -802,3 +835,285 @@ func TestOpenCodeAgent_Metrics(t *testing.T) {
 		t.Error("Metrics should have non-negative values")
 	}
 }
+
+func TestOpenCodeAgent_TokenAndCostMetrics(t *testing.T) {
+	now := time.Now()
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
+
+	user := MessageData{ID: "msg-1", SessionID: "ses_test", Role: "user"}
+	user.Time.Created = now.Add(-3 * time.Second).UnixMilli()
+	addTestMessage(t, storagePath, "ses_test", user)
+
+	// Recorded cost is used as-is
+	first := MessageData{ID: "msg-2", SessionID: "ses_test", Role: "assistant", ModelID: "claude-sonnet-4", ProviderID: "anthropic", Cost: 0.05}
+	first.Time.Created = now.Add(-2 * time.Second).UnixMilli()
+	first.Tokens = &TokenUsage{Input: 100, Output: 200, Reasoning: 50}
+	first.Tokens.Cache.Read = 1000
+	first.Tokens.Cache.Write = 400
+	addTestMessage(t, storagePath, "ses_test", first)
+
+	// Missing cost on an unknown model falls back to per-1k pricing
+	second := MessageData{ID: "msg-3", SessionID: "ses_test", Role: "assistant", ModelID: "local-llm"}
+	second.Time.Created = now.Add(-1 * time.Second).UnixMilli()
+	second.Tokens = &TokenUsage{Input: 2000, Output: 1000}
+	addTestMessage(t, storagePath, "ses_test", second)
+
+	catalog := agent.NewModelCatalog()
+	catalog.SetFallback(agent.ModelInfo{InputPer1K: 0.003, OutputPer1K: 0.015})
+	p := NewProvider(storagePath, time.Second, 0)
+	p.SetCatalog(catalog)
+	a, err := p.newAgent(sessionFile)
+	if err != nil {
+		t.Fatalf("newAgent() error = %v", err)
+	}
+	a.LoadFullHistory()
+
+	check := func(label string) {
+		t.Helper()
+		m := a.Metrics()
+		if m.TokensIn != 3500 {
+			t.Errorf("%s: TokensIn = %d, want 3500", label, m.TokensIn)
+		}
+		if m.TokensOut != 1250 {
+			t.Errorf("%s: TokensOut = %d, want 1250", label, m.TokensOut)
+		}
+		wantCost := 0.05 + 2*0.003 + 1*0.015
+		if diff := m.EstimatedCost - wantCost; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: EstimatedCost = %f, want %f", label, m.EstimatedCost, wantCost)
+		}
+	}
+	check("initial load")
+
+	// Refreshing must not double count
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	check("after refresh")
+}
+
+func TestOpenCodeAgent_ToolAndErrorMetrics(t *testing.T) {
+	now := time.Now()
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
+
+	msg := MessageData{ID: "msg-1", SessionID: "ses_test", Role: "assistant"}
+	msg.Time.Created = now.Add(-10 * time.Minute).UnixMilli()
+	msg.Error = &MessageError{Name: "ProviderAuthError"}
+	msg.Error.Data.Message = "invalid api key"
+	addTestMessage(t, storagePath, "ses_test", msg)
+
+	addTestPart(t, storagePath, "msg-1", PartData{ID: "part-1", MessageID: "msg-1", Type: "tool-invocation", ToolName: "bash", State: "success"})
+	addTestPart(t, storagePath, "msg-1", PartData{ID: "part-2", MessageID: "msg-1", Type: "text", Text: "done"})
+
+	// Newer opencode versions store the state as an object
+	partsDir := filepath.Join(storagePath, "part", "msg-1")
+	objectState := `{"id":"part-3","messageID":"msg-1","type":"tool","tool":"edit","state":{"status":"error","error":"file not found"}}`
+	if err := os.WriteFile(filepath.Join(partsDir, "part-3.json"), []byte(objectState), 0644); err != nil {
+		t.Fatalf("Failed to write part: %v", err)
+	}
+
+	a, err := NewOpenCodeAgent(storagePath, sessionFile)
+	if err != nil {
+		t.Fatalf("NewOpenCodeAgent() error = %v", err)
+	}
+	a.LoadFullHistory()
+
+	m := a.Metrics()
+	if m.ToolCalls != 2 {
+		t.Errorf("ToolCalls = %d, want 2", m.ToolCalls)
+	}
+	if m.ErrorCount != 2 {
+		t.Errorf("ErrorCount = %d, want 2", m.ErrorCount)
+	}
+	if a.LastError() == nil || a.LastError().Error() != "invalid api key" {
+		t.Errorf("LastError() = %v, want invalid api key", a.LastError())
+	}
+
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got := a.Metrics(); got.ToolCalls != 2 || got.ErrorCount != 2 {
+		t.Errorf("after Refresh() ToolCalls/ErrorCount = %d/%d, want 2/2", got.ToolCalls, got.ErrorCount)
+	}
+}
+
+func TestOpenCodeAgent_FileChanges(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
+
+	// The diff summary lists files relative to the session directory
+	session := SessionData{ID: "ses_test", ProjectID: "global", Directory: "/project", Title: "Test"}
+	session.Time.Created = now.UnixMilli()
+	session.Time.Updated = now.UnixMilli()
+	session.Summary.Diffs = append(session.Summary.Diffs, struct {
+		File string `json:"file"`
+	}{"README.md"}, struct {
+		File string `json:"file"`
+	}{"main.go"})
+	data, _ := json.Marshal(session)
+	if err := os.WriteFile(sessionFile, data, 0644); err != nil {
+		t.Fatalf("Failed to write session: %v", err)
+	}
+
+	msg := MessageData{ID: "msg-1", SessionID: "ses_test", Role: "assistant"}
+	msg.Time.Created = now.Add(-10 * time.Minute).UnixMilli()
+	addTestMessage(t, storagePath, "ses_test", msg)
+
+	edited := now.Add(-5 * time.Minute).UnixMilli()
+	partsDir := filepath.Join(storagePath, "part", "msg-1")
+	if err := os.MkdirAll(partsDir, 0755); err != nil {
+		t.Fatalf("Failed to create part dir: %v", err)
+	}
+	parts := map[string]string{
+		"part-1": fmt.Sprintf(`{"id":"part-1","messageID":"msg-1","type":"tool","tool":"edit","state":{"status":"completed","input":{"filePath":"/project/main.go"},"time":{"start":%d,"end":%d}}}`, edited-100, edited),
+		"part-2": `{"id":"part-2","messageID":"msg-1","type":"tool","tool":"write","state":{"status":"error","input":{"filePath":"/project/failed.go"}}}`,
+		"part-3": `{"id":"part-3","messageID":"msg-1","type":"tool","tool":"read","state":{"status":"completed","input":{"filePath":"/project/read.go"}}}`,
+		"part-4": `{"id":"part-4","messageID":"msg-1","type":"tool","tool":"patch","state":{"status":"completed","input":{"patchText":"*** Begin Patch\n*** Add File: pkg/new.go\n+package pkg\n*** End Patch"}}}`,
+	}
+	for name, content := range parts {
+		if err := os.WriteFile(filepath.Join(partsDir, name+".json"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write part: %v", err)
+		}
+	}
+
+	a, err := NewOpenCodeAgent(storagePath, sessionFile)
+	if err != nil {
+		t.Fatalf("NewOpenCodeAgent() error = %v", err)
+	}
+
+	changes := a.FileChanges()
+	want := []agent.FileChange{
+		{Path: "/project/README.md", Time: now},
+		{Path: "/project/main.go", Time: time.UnixMilli(edited)},
+		{Path: "/project/pkg/new.go", Time: time.UnixMilli(msg.Time.Created)},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("FileChanges() = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i].Path != want[i].Path || !changes[i].Time.Equal(want[i].Time) {
+			t.Errorf("FileChanges()[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestOpenCodeAgent_CatalogPricing(t *testing.T) {
+	now := time.Now()
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
+
+	msg := MessageData{ID: "msg-1", SessionID: "ses_test", Role: "assistant", ProviderID: "anthropic", ModelID: "claude-sonnet-4-20250514"}
+	msg.Time.Created = now.UnixMilli()
+	msg.Tokens = &TokenUsage{Input: 1000, Output: 1000}
+	msg.Tokens.Cache.Read = 48000
+	addTestMessage(t, storagePath, "ses_test", msg)
+
+	a, err := NewOpenCodeAgent(storagePath, sessionFile)
+	if err != nil {
+		t.Fatalf("NewOpenCodeAgent() error = %v", err)
+	}
+	a.LoadFullHistory()
+
+	m := a.Metrics()
+	if m.Model != "claude-sonnet-4-20250514" {
+		t.Errorf("Model = %q, want claude-sonnet-4-20250514", m.Model)
+	}
+	wantCost := 0.003 + 0.015 + 48*0.0003
+	if diff := m.EstimatedCost - wantCost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("EstimatedCost = %f, want %f", m.EstimatedCost, wantCost)
+	}
+	if m.ContextUtilization != 0.25 {
+		t.Errorf("ContextUtilization = %f, want 0.25", m.ContextUtilization)
+	}
+}
+
+func TestOpenCodeAgent_ContextLimit(t *testing.T) {
+	now := time.Now()
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
+
+	overflow := MessageData{ID: "msg-1", SessionID: "ses_test", Role: "assistant"}
+	overflow.Time.Created = now.Add(-2 * time.Second).UnixMilli()
+	overflow.Error = &MessageError{Name: "APIError"}
+	overflow.Error.Data.Message = "prompt is too long: 201234 tokens > 200000 maximum"
+	addTestMessage(t, storagePath, "ses_test", overflow)
+
+	a, err := NewOpenCodeAgent(storagePath, sessionFile)
+	if err != nil {
+		t.Fatalf("NewOpenCodeAgent() error = %v", err)
+	}
+	a.LoadFullHistory()
+	if a.Status() != agent.StatusContextLimit {
+		t.Errorf("Status() after overflow = %v, want %v", a.Status(), agent.StatusContextLimit)
+	}
+
+	// The compaction summary is written as "summary": true
+	compaction := MessageData{ID: "msg-2", SessionID: "ses_test", Role: "assistant"}
+	compaction.Time.Created = now.Add(-1 * time.Second).UnixMilli()
+	compaction.Summary.Compaction = true
+	addTestMessage(t, storagePath, "ses_test", compaction)
+
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(a.messages) != 2 {
+		t.Fatalf("loaded %d messages, want 2", len(a.messages))
+	}
+	if a.Status() != agent.StatusContextLimit {
+		t.Errorf("Status() after compaction = %v, want %v", a.Status(), agent.StatusContextLimit)
+	}
+
+	reply := MessageData{ID: "msg-3", SessionID: "ses_test", Role: "assistant"}
+	reply.Time.Created = now.UnixMilli()
+	addTestMessage(t, storagePath, "ses_test", reply)
+
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if a.Status() != agent.StatusRunning {
+		t.Errorf("Status() after reply = %v, want %v", a.Status(), agent.StatusRunning)
+	}
+}
+
+func TestOpenCodeAgent_Progress(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	sessionFile := getSessionFilePath(storagePath, "global", "ses_test")
+
+	prompt := MessageData{ID: "msg-1", SessionID: "ses_test", Role: "user"}
+	prompt.Time.Created = now.Add(-2 * time.Minute).UnixMilli()
+	addTestMessage(t, storagePath, "ses_test", prompt)
+
+	a, err := NewOpenCodeAgent(storagePath, sessionFile)
+	if err != nil {
+		t.Fatalf("NewOpenCodeAgent() error = %v", err)
+	}
+	a.LoadFullHistory()
+	if p := a.Progress(); !p.AwaitingReply.Equal(now.Add(-2 * time.Minute)) {
+		t.Errorf("Progress() after prompt = %+v, want awaiting reply", p)
+	}
+
+	reply := MessageData{ID: "msg-2", SessionID: "ses_test", Role: "assistant"}
+	reply.Time.Created = now.Add(-time.Minute).UnixMilli()
+	addTestMessage(t, storagePath, "ses_test", reply)
+
+	tool := PartData{ID: "part-1", MessageID: "msg-2", Type: "tool", Tool: "bash", State: "running"}
+	tool.Time.Created = now.Add(-50 * time.Second).UnixMilli()
+	addTestPart(t, storagePath, "msg-2", tool)
+
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	p := a.Progress()
+	if !p.AwaitingReply.IsZero() {
+		t.Errorf("AwaitingReply = %v, want zero after a reply", p.AwaitingReply)
+	}
+	if p.Tool != "bash" || !p.ToolStarted.Equal(now.Add(-50*time.Second)) {
+		t.Errorf("Progress() = %+v, want bash running", p)
+	}
+}
@@ -0,0 +1,167 @@
+package opencode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// terminateTimeout is how long Terminate waits after SIGTERM before sending SIGKILL
+var terminateTimeout = 5 * time.Second
+
+// runners tracks the opencode processes AUTO launched, so they can be
+// signalled before their session shows up on disk
+var runners = struct {
+	sync.Mutex
+	set map[*Runner]struct{}
+}{set: make(map[*Runner]struct{})}
+
+func trackRunner(r *Runner) {
+	runners.Lock()
+	defer runners.Unlock()
+	runners.set[r] = struct{}{}
+}
+
+func untrackRunner(r *Runner) {
+	runners.Lock()
+	defer runners.Unlock()
+	delete(runners.set, r)
+}
+
+// sessionProcess is a running opencode process serving a session
+type sessionProcess struct {
+	pid   int
+	group bool            // signal the process group AUTO created for it
+	done  <-chan struct{} // closed on exit for processes AUTO launched
+}
+
+// findSessionProcesses returns the opencode processes serving a session:
+// runners AUTO launched, then any process whose command line names the session
+func findSessionProcesses(sessionID string) []sessionProcess {
+	var procs []sessionProcess
+	seen := map[int]bool{os.Getpid(): true}
+
+	runners.Lock()
+	for r := range runners.set {
+		pid := r.Pid()
+		if pid == 0 || !r.IsRunning() || r.SessionID() != sessionID {
+			continue
+		}
+		seen[pid] = true
+		procs = append(procs, sessionProcess{pid: pid, group: true, done: r.Done()})
+	}
+	runners.Unlock()
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return procs
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || seen[pid] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+		if commandServesSession(args, sessionID) {
+			procs = append(procs, sessionProcess{pid: pid})
+		}
+	}
+
+	return procs
+}
+
+// commandServesSession reports whether a command line runs opencode on a session
+func commandServesSession(args []string, sessionID string) bool {
+	isOpenCode := false
+	for _, arg := range args {
+		if filepath.Base(arg) == "opencode" {
+			isOpenCode = true
+			break
+		}
+	}
+	if !isOpenCode {
+		return false
+	}
+
+	for i, arg := range args {
+		switch {
+		case (arg == "-s" || arg == "--session") && i+1 < len(args) && args[i+1] == sessionID:
+			return true
+		case arg == "-s="+sessionID || arg == "--session="+sessionID:
+			return true
+		}
+	}
+	return false
+}
+
+// signal sends a signal to the process, or its whole group if AUTO started it
+func (p sessionProcess) signal(sig syscall.Signal) error {
+	if p.group {
+		if err := syscall.Kill(-p.pid, sig); err == nil {
+			return nil
+		}
+	}
+	if err := syscall.Kill(p.pid, sig); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("failed to signal process %d: %w", p.pid, err)
+	}
+	return nil
+}
+
+// exited reports whether the process is gone
+func (p sessionProcess) exited() bool {
+	if p.done != nil {
+		select {
+		case <-p.done:
+			return true
+		default:
+			return false
+		}
+	}
+	return syscall.Kill(p.pid, 0) == syscall.ESRCH
+}
+
+// signalProcesses sends a signal to every process, returning the first error
+func signalProcesses(procs []sessionProcess, sig syscall.Signal) error {
+	var firstErr error
+	for _, p := range procs {
+		if err := p.signal(sig); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// terminateProcesses sends SIGTERM, then SIGKILL to processes still running after timeout
+func terminateProcesses(procs []sessionProcess, timeout time.Duration) error {
+	if err := signalProcesses(procs, syscall.SIGTERM); err != nil {
+		return err
+	}
+	// A stopped process only acts on SIGTERM once continued
+	signalProcesses(procs, syscall.SIGCONT)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var running []sessionProcess
+		for _, p := range procs {
+			if !p.exited() {
+				running = append(running, p)
+			}
+		}
+		if len(running) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return signalProcesses(running, syscall.SIGKILL)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
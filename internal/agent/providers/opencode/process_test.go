@@ -0,0 +1,259 @@
+package opencode
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// installFakeOpenCode puts an opencode script on PATH that reports a session
+// and then idles until killed, optionally ignoring SIGTERM
+func installFakeOpenCode(t *testing.T, sessionID string, ignoreTerm bool) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("process lookup requires /proc")
+	}
+
+	script := "#!/bin/sh\n"
+	if ignoreTerm {
+		script += "trap '' TERM\n"
+	}
+	script += `echo '{"type":"step_start","sessionID":"` + sessionID + `"}'` + "\n"
+	script += "while :; do sleep 0.05; done\n"
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "opencode"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake opencode: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// startExternalOpenCode runs opencode on a session outside of a Runner, the
+// way a user would from a terminal. The returned channel closes on exit.
+func startExternalOpenCode(t *testing.T, sessionID string) <-chan struct{} {
+	t.Helper()
+
+	cmd := exec.Command("opencode", "run", "-s", sessionID, "hello")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start fake opencode: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		<-done
+	})
+	return done
+}
+
+// startRunner runs opencode through a Runner and waits for it to report its session
+func startRunner(t *testing.T, sessionID string) *Runner {
+	t.Helper()
+
+	runner, err := NewRunner(context.Background(), RunConfig{Message: "hello"})
+	if err != nil {
+		t.Fatalf("NewRunner() error = %v", err)
+	}
+	if err := runner.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() {
+		runner.Stop()
+		<-runner.Done()
+	})
+
+	select {
+	case <-runner.Events():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for runner output")
+	}
+	if got := runner.SessionID(); got != sessionID {
+		t.Fatalf("SessionID() = %q, want %q", got, sessionID)
+	}
+	return runner
+}
+
+func TestRunnerEnv(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "{\"type\":\"text\",\"sessionID\":\"ses_env\",\"text\":\"$TASK_LABEL $HOME\"}"
+`
+	if err := os.WriteFile(filepath.Join(dir, "opencode"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake opencode: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", "/home/auto")
+
+	runner, err := NewRunner(context.Background(), RunConfig{Message: "hello", Env: map[string]string{"TASK_LABEL": "deps"}})
+	if err != nil {
+		t.Fatalf("NewRunner() error = %v", err)
+	}
+	if err := runner.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer runner.Stop()
+
+	select {
+	case event := <-runner.Events():
+		if event.Text != "deps /home/auto" {
+			t.Errorf("opencode saw %q, want the task's env on top of AUTO's", event.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for runner output")
+	}
+}
+
+// waitStopped waits for a process to enter or leave the stopped state,
+// reporting whether it got there
+func waitStopped(t *testing.T, pid int, stopped bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+		if err != nil {
+			t.Fatalf("Failed to read process state: %v", err)
+		}
+		stat := string(data)
+		fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+		if (fields[0] == "T") == stopped {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCommandServesSession(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"short flag", []string{"opencode", "run", "-s", "ses_1", "hi"}, true},
+		{"long flag", []string{"/usr/local/bin/opencode", "--session", "ses_1"}, true},
+		{"long flag with value", []string{"opencode", "--session=ses_1"}, true},
+		{"node wrapper", []string{"node", "/opt/opencode/bin/opencode", "-s", "ses_1"}, true},
+		{"other session", []string{"opencode", "-s", "ses_2"}, false},
+		{"session as prompt", []string{"opencode", "run", "ses_1"}, false},
+		{"not opencode", []string{"vim", "-s", "ses_1"}, false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandServesSession(tt.args, "ses_1"); got != tt.want {
+				t.Errorf("commandServesSession(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenCodeAgent_TerminateEscalates(t *testing.T) {
+	now := time.Now()
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+	a, err := NewOpenCodeAgent(storagePath, getSessionFilePath(storagePath, "global", "ses_test"))
+	if err != nil {
+		t.Fatalf("NewOpenCodeAgent() error = %v", err)
+	}
+
+	old := terminateTimeout
+	terminateTimeout = 200 * time.Millisecond
+	defer func() { terminateTimeout = old }()
+
+	installFakeOpenCode(t, "ses_test", true)
+	runner := startRunner(t, "ses_test")
+
+	if err := a.Terminate(); err != nil {
+		t.Errorf("Terminate() error = %v", err)
+	}
+
+	select {
+	case <-runner.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("process ignoring SIGTERM was not killed")
+	}
+}
+
+func TestProvider_PauseResume(t *testing.T) {
+	now := time.Now()
+	storagePath := createTestStorage(t, "ses_test", "global", "Test", "/project", now, now)
+
+	p := NewProvider(storagePath, time.Hour, 0)
+	if _, err := p.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	installFakeOpenCode(t, "ses_test", false)
+	runner := startRunner(t, "ses_test")
+	pid := runner.Pid()
+
+	a := p.agents["ses_test"]
+
+	nextEvent := func() agent.EventType {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e.Type
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for event")
+			return 0
+		}
+	}
+
+	if err := a.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if got := nextEvent(); got != agent.EventAgentPaused {
+		t.Errorf("event after Pause() = %v, want %v", got, agent.EventAgentPaused)
+	}
+	if !waitStopped(t, pid, true) {
+		t.Error("process not stopped after Pause()")
+	}
+
+	// Refreshing from disk doesn't make a paused session look active
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if a.Status() != agent.StatusIdle {
+		t.Errorf("Status() while paused = %v, want %v", a.Status(), agent.StatusIdle)
+	}
+
+	if err := a.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if got := nextEvent(); got != agent.EventAgentResumed {
+		t.Errorf("event after Resume() = %v, want %v", got, agent.EventAgentResumed)
+	}
+	if !waitStopped(t, pid, false) {
+		t.Error("process still stopped after Resume()")
+	}
+
+	if err := p.Terminate("ses_test"); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+	if got := nextEvent(); got != agent.EventAgentTerminated {
+		t.Errorf("event after Terminate() = %v, want %v", got, agent.EventAgentTerminated)
+	}
+}
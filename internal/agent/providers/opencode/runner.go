@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"sync"
+	"syscall"
 )
 
 type StreamEvent struct {
@@ -29,6 +31,7 @@ type RunConfig struct {
 	Model     string
 	Agent     string
 	Title     string
+	Env       map[string]string // added to AUTO's environment
 }
 
 type Runner struct {
@@ -71,9 +74,17 @@ func NewRunner(ctx context.Context, cfg RunConfig) (*Runner, error) {
 	}
 
 	cmd := exec.CommandContext(ctx, "opencode", args...)
+	// Own process group, so signals also reach the tools opencode runs
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	if cfg.Directory != "" {
 		cmd.Dir = cfg.Directory
 	}
+	if len(cfg.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range cfg.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -110,8 +121,12 @@ func (r *Runner) Start() error {
 	r.mu.Unlock()
 
 	if err := r.cmd.Start(); err != nil {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
 		return fmt.Errorf("failed to start opencode: %w", err)
 	}
+	trackRunner(r)
 
 	go r.streamOutput()
 	go r.streamErrors()
@@ -186,6 +201,7 @@ func (r *Runner) waitForCompletion() {
 	r.mu.Lock()
 	r.running = false
 	r.mu.Unlock()
+	untrackRunner(r)
 
 	close(r.done)
 }
@@ -212,6 +228,14 @@ func (r *Runner) IsRunning() bool {
 	return r.running
 }
 
+// Pid returns the process ID of the running opencode, or 0 before it starts
+func (r *Runner) Pid() int {
+	if r.cmd.Process == nil {
+		return 0
+	}
+	return r.cmd.Process.Pid
+}
+
 func (r *Runner) SessionID() string {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -0,0 +1,787 @@
+// Package process provides a generic agent provider that runs any CLI agent
+// inside a pseudo-terminal
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/creack/pty"
+)
+
+// maxOutputSize bounds the captured output kept in memory per agent
+const maxOutputSize = 1024 * 1024
+
+// Patterns holds the raw regular expressions used to infer status from output
+type Patterns struct {
+	Running      []string
+	Idle         []string
+	Completed    []string
+	Errored      []string
+	ContextLimit []string
+}
+
+// Config configures a process provider for one agent type
+type Config struct {
+	Type             string
+	Name             string
+	Command          string
+	Args             []string // "{prompt}" is replaced with the spawn prompt
+	Env              map[string]string
+	Patterns         Patterns
+	IdleAfter        time.Duration // no output for this long means idle
+	TerminateTimeout time.Duration // wait between escalating signals
+}
+
+// statusPattern pairs a compiled expression with the status it implies
+type statusPattern struct {
+	re     *regexp.Regexp
+	status agent.Status
+}
+
+// ansiPattern matches terminal escape sequences
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes terminal escape sequences and carriage returns
+func StripANSI(s string) string {
+	s = ansiPattern.ReplaceAllString(s, "")
+	return strings.ReplaceAll(s, "\r", "")
+}
+
+// compilePatterns compiles the configured patterns in match priority order
+func compilePatterns(p Patterns) ([]statusPattern, error) {
+	groups := []struct {
+		exprs  []string
+		status agent.Status
+	}{
+		{p.Errored, agent.StatusErrored},
+		{p.ContextLimit, agent.StatusContextLimit},
+		{p.Completed, agent.StatusCompleted},
+		{p.Idle, agent.StatusIdle},
+		{p.Running, agent.StatusRunning},
+	}
+
+	var compiled []statusPattern
+	for _, g := range groups {
+		for _, expr := range g.exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern %q: %w", g.status, expr, err)
+			}
+			compiled = append(compiled, statusPattern{re: re, status: g.status})
+		}
+	}
+	return compiled, nil
+}
+
+// ProcessAgent is an agent backed by a process running in a pseudo-terminal
+type ProcessAgent struct {
+	id           string
+	name         string
+	agentType    string
+	directory    string
+	cmd          *exec.Cmd
+	ptmx         *os.File
+	patterns     []statusPattern
+	idleAfter    time.Duration
+	termTimeout  time.Duration
+	status       agent.Status
+	startTime    time.Time
+	lastActivity time.Time
+	lastOutput   time.Time
+	currentTask  string
+	metrics      agent.Metrics
+	lastError    error
+	output       *bytes.Buffer
+	partial      string
+	paused       bool
+	terminated   bool
+	exited       bool
+	done         chan struct{}
+	readerDone   chan struct{}
+	mu           sync.RWMutex
+
+	emit func(a *ProcessAgent, eventType agent.EventType)
+}
+
+// start launches the command in a pseudo-terminal and begins capturing output
+func (a *ProcessAgent) start() error {
+	ptmx, err := pty.StartWithSize(a.cmd, &pty.Winsize{Rows: 40, Cols: 120})
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", a.cmd.Path, err)
+	}
+	a.ptmx = ptmx
+
+	go a.readOutput()
+	go a.wait()
+
+	return nil
+}
+
+// readOutput copies pty output into the buffer until the process closes it
+func (a *ProcessAgent) readOutput() {
+	defer close(a.readerDone)
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := a.ptmx.Read(buf)
+		if n > 0 {
+			a.handleOutput(string(buf[:n]))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleOutput appends a chunk of output and re-evaluates status
+func (a *ProcessAgent) handleOutput(chunk string) {
+	clean := StripANSI(chunk)
+
+	a.mu.Lock()
+	a.output.WriteString(clean)
+	if a.output.Len() > maxOutputSize {
+		trimmed := a.output.Bytes()[a.output.Len()-maxOutputSize/2:]
+		a.output = bytes.NewBuffer(append([]byte(nil), trimmed...))
+	}
+	now := time.Now()
+	a.lastOutput = now
+	a.lastActivity = now
+
+	// Match complete lines plus the trailing partial line (prompts rarely end in a newline)
+	text := a.partial + clean
+	lines := strings.Split(text, "\n")
+	a.partial = lines[len(lines)-1]
+	if len(a.partial) > 4096 {
+		a.partial = a.partial[len(a.partial)-4096:]
+	}
+
+	// Output drained after exit or while stopped does not change status,
+	// nor does output after a completion banner, such as a shell prompt
+	if a.exited || a.paused || a.status == agent.StatusCompleted {
+		a.mu.Unlock()
+		return
+	}
+
+	old := a.status
+	matched := false
+	for _, line := range lines {
+		if status, ok := a.matchLine(line); ok {
+			a.status = status
+			matched = true
+			if status == agent.StatusErrored {
+				a.metrics.ErrorCount++
+				a.lastError = errors.New(strings.TrimSpace(line))
+			}
+		}
+	}
+	// Output wakes an idle agent; an errored one waits for a pattern
+	if !matched && a.status == agent.StatusIdle {
+		a.status = agent.StatusRunning
+	}
+	newStatus := a.status
+	a.mu.Unlock()
+
+	a.notify(old, newStatus)
+}
+
+// matchLine returns the status implied by the first matching pattern
+func (a *ProcessAgent) matchLine(line string) (agent.Status, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return 0, false
+	}
+	for _, p := range a.patterns {
+		if p.re.MatchString(line) {
+			return p.status, true
+		}
+	}
+	return 0, false
+}
+
+// wait records the process exit status
+func (a *ProcessAgent) wait() {
+	err := a.cmd.Wait()
+
+	a.mu.Lock()
+	old := a.status
+	a.exited = true
+	a.paused = false
+	a.lastActivity = time.Now()
+	switch {
+	case a.terminated:
+		a.status = agent.StatusCancelled
+	case err != nil:
+		a.status = agent.StatusErrored
+		a.lastError = fmt.Errorf("process exited: %w", err)
+		a.metrics.ErrorCount++
+	default:
+		a.status = agent.StatusCompleted
+		a.metrics.TasksCompleted++
+	}
+	newStatus := a.status
+	a.mu.Unlock()
+
+	// Drain output still buffered in the pty before closing it
+	select {
+	case <-a.readerDone:
+	case <-time.After(time.Second):
+	}
+	a.ptmx.Close()
+	close(a.done)
+	a.notify(old, newStatus)
+}
+
+// checkIdle marks a running agent idle once output has been quiet long enough
+func (a *ProcessAgent) checkIdle() {
+	a.mu.Lock()
+	old := a.status
+	if !a.exited && !a.paused && a.status == agent.StatusRunning && a.idleAfter > 0 && time.Since(a.lastOutput) > a.idleAfter {
+		a.status = agent.StatusIdle
+	}
+	newStatus := a.status
+	a.mu.Unlock()
+
+	a.notify(old, newStatus)
+}
+
+// notify emits an event when the status changed
+func (a *ProcessAgent) notify(old, newStatus agent.Status) {
+	if old == newStatus || a.emit == nil {
+		return
+	}
+
+	eventType := agent.EventAgentUpdated
+	switch newStatus {
+	case agent.StatusRunning:
+		eventType = agent.EventAgentStarted
+	case agent.StatusCompleted:
+		eventType = agent.EventAgentCompleted
+	case agent.StatusErrored:
+		eventType = agent.EventAgentErrored
+	case agent.StatusContextLimit:
+		eventType = agent.EventAgentContextLimit
+	case agent.StatusCancelled:
+		eventType = agent.EventAgentTerminated
+	}
+	a.emit(a, eventType)
+}
+
+// ID returns the agent's unique identifier
+func (a *ProcessAgent) ID() string {
+	return a.id
+}
+
+// Name returns the agent's display name
+func (a *ProcessAgent) Name() string {
+	return a.name
+}
+
+// Type returns the configured agent type
+func (a *ProcessAgent) Type() string {
+	return a.agentType
+}
+
+// Directory returns the working directory
+func (a *ProcessAgent) Directory() string {
+	return a.directory
+}
+
+// ProjectID returns the project identifier (the working directory)
+func (a *ProcessAgent) ProjectID() string {
+	return a.directory
+}
+
+// ParentID returns the parent agent ID (process agents are top-level)
+func (a *ProcessAgent) ParentID() string {
+	return ""
+}
+
+// IsBackground returns whether the agent is a background agent
+func (a *ProcessAgent) IsBackground() bool {
+	return false
+}
+
+// Status returns the current status
+func (a *ProcessAgent) Status() agent.Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.status
+}
+
+// StartTime returns when the process was started
+func (a *ProcessAgent) StartTime() time.Time {
+	return a.startTime
+}
+
+// LastActivity returns the last activity time
+func (a *ProcessAgent) LastActivity() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastActivity
+}
+
+// Output returns the captured terminal output with escape sequences removed
+func (a *ProcessAgent) Output() io.Reader {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return bytes.NewReader(a.output.Bytes())
+}
+
+// CurrentTask returns the last input sent to the process
+func (a *ProcessAgent) CurrentTask() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.currentTask
+}
+
+// Metrics returns the agent's metrics
+func (a *ProcessAgent) Metrics() agent.Metrics {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	m := a.metrics
+	m.Duration = a.lastActivity.Sub(a.startTime)
+	return m
+}
+
+// LastError returns the last error
+func (a *ProcessAgent) LastError() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastError
+}
+
+// SendInput types the input into the terminal followed by Enter
+func (a *ProcessAgent) SendInput(input string) error {
+	if input == "" {
+		return fmt.Errorf("empty input")
+	}
+
+	a.mu.Lock()
+	if a.exited {
+		a.mu.Unlock()
+		return fmt.Errorf("process has exited")
+	}
+	a.currentTask = input
+	if len(a.currentTask) > 100 {
+		a.currentTask = a.currentTask[:100] + "..."
+	}
+	a.lastActivity = time.Now()
+	// Input starts a new task for an agent that completed the last one
+	old := a.status
+	if a.status == agent.StatusCompleted {
+		a.status = agent.StatusRunning
+	}
+	newStatus := a.status
+	a.mu.Unlock()
+	a.notify(old, newStatus)
+
+	_, err := a.ptmx.Write([]byte(input + "\r"))
+	return err
+}
+
+// signal sends a signal to the agent's whole process group
+func (a *ProcessAgent) signal(sig syscall.Signal) error {
+	if a.cmd.Process == nil {
+		return fmt.Errorf("process not started")
+	}
+	// pty.Start runs the command in its own session, so its pid is the group id
+	if err := syscall.Kill(-a.cmd.Process.Pid, sig); err != nil {
+		return a.cmd.Process.Signal(sig)
+	}
+	return nil
+}
+
+// Terminate stops the process, escalating SIGINT -> SIGTERM -> SIGKILL
+func (a *ProcessAgent) Terminate() error {
+	a.mu.Lock()
+	if a.exited {
+		a.mu.Unlock()
+		return nil
+	}
+	a.terminated = true
+	paused := a.paused
+	timeout := a.termTimeout
+	a.mu.Unlock()
+
+	// A stopped process cannot handle signals until continued
+	if paused {
+		a.signal(syscall.SIGCONT)
+	}
+
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL} {
+		if err := a.signal(sig); err != nil {
+			return err
+		}
+		select {
+		case <-a.done:
+			return nil
+		case <-time.After(timeout):
+		}
+	}
+
+	return fmt.Errorf("process %d did not exit after SIGKILL", a.cmd.Process.Pid)
+}
+
+// Pause stops the process with SIGSTOP
+func (a *ProcessAgent) Pause() error {
+	a.mu.Lock()
+	if a.exited {
+		a.mu.Unlock()
+		return fmt.Errorf("process has exited")
+	}
+	if a.paused {
+		a.mu.Unlock()
+		return nil
+	}
+	a.mu.Unlock()
+
+	if err := a.signal(syscall.SIGSTOP); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.paused = true
+	a.status = agent.StatusIdle
+	a.mu.Unlock()
+
+	if a.emit != nil {
+		a.emit(a, agent.EventAgentPaused)
+	}
+	return nil
+}
+
+// Resume continues a paused process with SIGCONT
+func (a *ProcessAgent) Resume() error {
+	a.mu.Lock()
+	if a.exited {
+		a.mu.Unlock()
+		return fmt.Errorf("process has exited")
+	}
+	if !a.paused {
+		a.mu.Unlock()
+		return nil
+	}
+	a.mu.Unlock()
+
+	if err := a.signal(syscall.SIGCONT); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.paused = false
+	a.status = agent.StatusRunning
+	a.lastOutput = time.Now()
+	a.mu.Unlock()
+
+	if a.emit != nil {
+		a.emit(a, agent.EventAgentResumed)
+	}
+	return nil
+}
+
+// IsPaused returns whether the process is currently stopped
+func (a *ProcessAgent) IsPaused() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.paused
+}
+
+// Done returns a channel that is closed when the process exits
+func (a *ProcessAgent) Done() <-chan struct{} {
+	return a.done
+}
+
+// Provider implements agent.Provider for one configured CLI agent type
+type Provider struct {
+	cfg      Config
+	patterns []statusPattern
+	agents   map[string]*ProcessAgent
+	updates  chan agent.Event // updates that can be dropped when Watch lags
+	counter  int
+	mu       sync.RWMutex
+
+	// Status transitions are never dropped: each agent's latest one waits
+	// here until Watch sends it
+	transitions map[string]agent.Event
+	pending     []string // agent IDs with a transition, oldest first
+	signal      chan struct{}
+}
+
+// NewProvider creates a new process provider, validating its patterns
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Type == "" {
+		return nil, fmt.Errorf("process provider type cannot be empty")
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("process provider %s: command cannot be empty", cfg.Type)
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+	if cfg.IdleAfter <= 0 {
+		cfg.IdleAfter = 30 * time.Second
+	}
+	if cfg.TerminateTimeout <= 0 {
+		cfg.TerminateTimeout = 5 * time.Second
+	}
+
+	patterns, err := compilePatterns(cfg.Patterns)
+	if err != nil {
+		return nil, fmt.Errorf("process provider %s: %w", cfg.Type, err)
+	}
+
+	return &Provider{
+		cfg:         cfg,
+		patterns:    patterns,
+		agents:      make(map[string]*ProcessAgent),
+		updates:     make(chan agent.Event, 100),
+		transitions: make(map[string]agent.Event),
+		signal:      make(chan struct{}, 1),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// Type returns the provider type
+func (p *Provider) Type() string {
+	return p.cfg.Type
+}
+
+// Discover returns the processes spawned by this provider
+func (p *Provider) Discover(ctx context.Context) ([]agent.Agent, error) {
+	return p.List(), nil
+}
+
+// Watch streams status changes of spawned processes
+func (p *Provider) Watch(ctx context.Context) (<-chan agent.Event, error) {
+	events := make(chan agent.Event, 100)
+
+	go func() {
+		defer close(events)
+
+		interval := p.cfg.IdleAfter / 2
+		if interval > time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-p.updates:
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-p.signal:
+				for _, event := range p.takeTransitions() {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-ticker.C:
+				for _, a := range p.processAgents() {
+					a.checkIdle()
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// emit queues an agent event for Watch consumers
+func (p *Provider) emit(a *ProcessAgent, eventType agent.EventType) {
+	event := agent.Event{
+		Type:      eventType,
+		AgentID:   a.ID(),
+		Agent:     a,
+		Timestamp: time.Now(),
+		Error:     a.LastError(),
+	}
+
+	// Never block the pty reader on a slow consumer. Plain updates are
+	// dropped when it lags; a status transition replaces the agent's
+	// earlier unsent one.
+	if eventType == agent.EventAgentUpdated {
+		select {
+		case p.updates <- event:
+		default:
+		}
+		return
+	}
+
+	p.mu.Lock()
+	if _, ok := p.transitions[a.id]; !ok {
+		p.pending = append(p.pending, a.id)
+	}
+	p.transitions[a.id] = event
+	p.mu.Unlock()
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+// takeTransitions returns the unsent status transitions, oldest first
+func (p *Provider) takeTransitions() []agent.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := make([]agent.Event, 0, len(p.pending))
+	for _, id := range p.pending {
+		events = append(events, p.transitions[id])
+		delete(p.transitions, id)
+	}
+	p.pending = p.pending[:0]
+	return events
+}
+
+// Spawn starts a new process for this agent type
+func (p *Provider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	args := make([]string, len(p.cfg.Args))
+	promptInArgs := false
+	for i, arg := range p.cfg.Args {
+		if strings.Contains(arg, "{prompt}") {
+			promptInArgs = true
+			arg = strings.ReplaceAll(arg, "{prompt}", config.Prompt)
+		}
+		args[i] = arg
+	}
+
+	cmd := exec.Command(p.cfg.Command, args...)
+	cmd.Dir = config.Directory
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	for k, v := range p.cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	p.mu.Lock()
+	p.counter++
+	id := fmt.Sprintf("%s-%d-%d", p.cfg.Type, time.Now().Unix(), p.counter)
+	p.mu.Unlock()
+
+	name := config.Name
+	if name == "" {
+		name = id
+	}
+
+	now := time.Now()
+	a := &ProcessAgent{
+		id:           id,
+		name:         name,
+		agentType:    p.cfg.Type,
+		directory:    config.Directory,
+		cmd:          cmd,
+		patterns:     p.patterns,
+		idleAfter:    p.cfg.IdleAfter,
+		termTimeout:  p.cfg.TerminateTimeout,
+		status:       agent.StatusRunning,
+		startTime:    now,
+		lastActivity: now,
+		lastOutput:   now,
+		currentTask:  config.Prompt,
+		output:       bytes.NewBuffer(nil),
+		done:         make(chan struct{}),
+		readerDone:   make(chan struct{}),
+		emit:         p.emit,
+	}
+
+	if err := a.start(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.agents[id] = a
+	p.mu.Unlock()
+
+	if config.Prompt != "" && !promptInArgs {
+		if err := a.SendInput(config.Prompt); err != nil {
+			return a, fmt.Errorf("failed to send prompt: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+func (p *Provider) processAgents() []*ProcessAgent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	agents := make([]*ProcessAgent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// Get returns an agent by ID
+func (p *Provider) Get(id string) (agent.Agent, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if a, ok := p.agents[id]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("agent not found: %s", id)
+}
+
+// List returns all agents
+func (p *Provider) List() []agent.Agent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	agents := make([]agent.Agent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// Terminate terminates an agent
+func (p *Provider) Terminate(id string) error {
+	p.mu.RLock()
+	a, ok := p.agents[id]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+
+	return a.Terminate()
+}
+
+// SendInput sends input to an agent
+func (p *Provider) SendInput(id string, input string) error {
+	p.mu.RLock()
+	a, ok := p.agents[id]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+
+	return a.SendInput(input)
+}
@@ -0,0 +1,341 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// waitFor polls cond until it returns true or the timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+func outputOf(a agent.Agent) string {
+	data, _ := io.ReadAll(a.Output())
+	return string(data)
+}
+
+func newShellProvider(t *testing.T, script string, patterns Patterns) *Provider {
+	t.Helper()
+
+	p, err := NewProvider(Config{
+		Type:             "shell",
+		Command:          "/bin/sh",
+		Args:             []string{"-c", script},
+		Patterns:         patterns,
+		IdleAfter:        200 * time.Millisecond,
+		TerminateTimeout: 500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return p
+}
+
+func TestNewProvider_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"valid", Config{Type: "aider", Command: "aider"}, false},
+		{"missing type", Config{Command: "aider"}, true},
+		{"missing command", Config{Type: "aider"}, true},
+		{"bad pattern", Config{Type: "aider", Command: "aider", Patterns: Patterns{Idle: []string{"("}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProvider(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	in := "\x1b[32mgreen\x1b[0m text\r\n\x1b]0;title\x07done"
+	want := "green text\ndone"
+	if got := StripANSI(in); got != want {
+		t.Errorf("StripANSI() = %q, want %q", got, want)
+	}
+}
+
+func TestProcessAgent_CompletesAndCapturesOutput(t *testing.T) {
+	p := newShellProvider(t, "echo hello from pty", Patterns{})
+
+	a, err := p.Spawn(context.Background(), agent.SpawnConfig{Name: "echo", Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+
+	if !waitFor(t, 3*time.Second, func() bool { return a.Status() == agent.StatusCompleted }) {
+		t.Fatalf("Status() = %v, want %v", a.Status(), agent.StatusCompleted)
+	}
+	if !strings.Contains(outputOf(a), "hello from pty") {
+		t.Errorf("Output() = %q, want echoed text", outputOf(a))
+	}
+	if a.Type() != "shell" || a.Name() != "echo" {
+		t.Errorf("Type()/Name() = %s/%s, want shell/echo", a.Type(), a.Name())
+	}
+}
+
+func TestProcessAgent_ExitErrorIsErrored(t *testing.T) {
+	p := newShellProvider(t, "exit 3", Patterns{})
+
+	a, err := p.Spawn(context.Background(), agent.SpawnConfig{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+
+	if !waitFor(t, 3*time.Second, func() bool { return a.Status() == agent.StatusErrored }) {
+		t.Fatalf("Status() = %v, want %v", a.Status(), agent.StatusErrored)
+	}
+	if a.LastError() == nil {
+		t.Error("LastError() should be set")
+	}
+}
+
+func TestProcessAgent_SendInputAndPatterns(t *testing.T) {
+	script := `while true; do printf 'ready> '; read line || exit 0; case "$line" in fail) echo "ERROR: boom";; quit) exit 0;; *) echo "got $line";; esac; done`
+	p := newShellProvider(t, script, Patterns{
+		Idle:    []string{`^ready>`},
+		Errored: []string{`^ERROR:`},
+	})
+
+	a, err := p.Spawn(context.Background(), agent.SpawnConfig{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	defer a.Terminate()
+
+	if !waitFor(t, 3*time.Second, func() bool { return a.Status() == agent.StatusIdle }) {
+		t.Fatalf("Status() = %v, want idle prompt", a.Status())
+	}
+
+	if err := a.SendInput("hello"); err != nil {
+		t.Fatalf("SendInput() error = %v", err)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return strings.Contains(outputOf(a), "got hello") }) {
+		t.Fatalf("Output() = %q, want response to input", outputOf(a))
+	}
+	if a.CurrentTask() != "hello" {
+		t.Errorf("CurrentTask() = %q, want hello", a.CurrentTask())
+	}
+
+	a.SendInput("fail")
+	if !waitFor(t, 3*time.Second, func() bool { return a.Metrics().ErrorCount > 0 }) {
+		t.Fatal("error pattern was not matched")
+	}
+	if a.LastError() == nil || !strings.Contains(a.LastError().Error(), "boom") {
+		t.Errorf("LastError() = %v, want matched line", a.LastError())
+	}
+
+	a.SendInput("quit")
+	if !waitFor(t, 3*time.Second, func() bool { return a.Status() == agent.StatusCompleted }) {
+		t.Errorf("Status() = %v, want %v", a.Status(), agent.StatusCompleted)
+	}
+	if err := a.SendInput("late"); err == nil {
+		t.Error("SendInput() should fail after exit")
+	}
+}
+
+func TestProcessAgent_PromptPlaceholder(t *testing.T) {
+	p, err := NewProvider(Config{
+		Type:    "shell",
+		Command: "/bin/sh",
+		Args:    []string{"-c", "echo task={prompt}"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	a, err := p.Spawn(context.Background(), agent.SpawnConfig{Prompt: "refactor", Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return strings.Contains(outputOf(a), "task=refactor") }) {
+		t.Errorf("Output() = %q, want substituted prompt", outputOf(a))
+	}
+}
+
+func TestProcessAgent_PauseResume(t *testing.T) {
+	script := `i=0; while true; do i=$((i+1)); echo "tick $i"; sleep 0.05; done`
+	p := newShellProvider(t, script, Patterns{})
+
+	spawned, err := p.Spawn(context.Background(), agent.SpawnConfig{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	a := spawned.(*ProcessAgent)
+	defer a.Terminate()
+
+	if !waitFor(t, 3*time.Second, func() bool { return strings.Contains(outputOf(a), "tick 2") }) {
+		t.Fatal("process produced no output")
+	}
+
+	if err := a.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if !a.IsPaused() || a.Status() != agent.StatusIdle {
+		t.Errorf("after Pause(): paused=%v status=%v", a.IsPaused(), a.Status())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	before := len(outputOf(a))
+	time.Sleep(300 * time.Millisecond)
+	if after := len(outputOf(a)); after != before {
+		t.Errorf("output grew while paused: %d -> %d", before, after)
+	}
+
+	if err := a.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return len(outputOf(a)) > before }) {
+		t.Error("output did not resume after Resume()")
+	}
+}
+
+func TestProcessAgent_TerminateEscalates(t *testing.T) {
+	// Ignore SIGINT and SIGTERM so only SIGKILL stops the process
+	p := newShellProvider(t, `trap '' INT TERM; echo started; while true; do sleep 0.05; done`, Patterns{})
+
+	spawned, err := p.Spawn(context.Background(), agent.SpawnConfig{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	a := spawned.(*ProcessAgent)
+
+	if !waitFor(t, 3*time.Second, func() bool { return strings.Contains(outputOf(a), "started") }) {
+		t.Fatal("process did not start")
+	}
+
+	if err := p.Terminate(a.ID()); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+
+	select {
+	case <-a.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("process did not exit")
+	}
+	if a.Status() != agent.StatusCancelled {
+		t.Errorf("Status() = %v, want %v", a.Status(), agent.StatusCancelled)
+	}
+}
+
+func TestProvider_WatchEmitsEvents(t *testing.T) {
+	p := newShellProvider(t, `echo working; sleep 0.1; exit 0`, Patterns{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	a, err := p.Spawn(ctx, agent.SpawnConfig{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type == agent.EventAgentCompleted && event.AgentID == a.ID() {
+				agents, _ := p.Discover(ctx)
+				if len(agents) != 1 {
+					t.Errorf("Discover() = %d agents, want 1", len(agents))
+				}
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for completed event")
+		}
+	}
+}
+
+func TestProvider_TransitionsSurviveLag(t *testing.T) {
+	p := newShellProvider(t, "true", Patterns{})
+	a := &ProcessAgent{id: "a", output: bytes.NewBuffer(nil), emit: p.emit}
+	b := &ProcessAgent{id: "b", output: bytes.NewBuffer(nil), emit: p.emit}
+
+	// A lagging consumer: updates overflow, then transitions queue up
+	for i := 0; i < 150; i++ {
+		p.emit(a, agent.EventAgentUpdated)
+	}
+	p.emit(a, agent.EventAgentErrored)
+	p.emit(b, agent.EventAgentCompleted)
+	p.emit(a, agent.EventAgentTerminated)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	events, _ := p.Watch(ctx)
+	got := map[string]agent.EventType{}
+	for len(got) < 2 {
+		select {
+		case event := <-events:
+			if event.Type != agent.EventAgentUpdated {
+				got[event.AgentID] = event.Type
+			}
+		case <-ctx.Done():
+			t.Fatalf("got transitions %v, want both agents'", got)
+		}
+	}
+	if got["a"] != agent.EventAgentTerminated || got["b"] != agent.EventAgentCompleted {
+		t.Errorf("transitions = %v, want each agent's latest", got)
+	}
+}
+
+func TestProcessAgent_OutputAfterStatus(t *testing.T) {
+	patterns, _ := compilePatterns(Patterns{Completed: []string{`^Done`}, Errored: []string{`^ERROR`}, Running: []string{`^Working`}})
+	a := &ProcessAgent{patterns: patterns, output: bytes.NewBuffer(nil), status: agent.StatusIdle}
+
+	a.handleOutput("thinking\n")
+	if a.Status() != agent.StatusRunning {
+		t.Errorf("Status() = %v, want output to wake an idle agent", a.Status())
+	}
+	a.handleOutput("ERROR: boom\nretrying\n")
+	if a.Status() != agent.StatusErrored {
+		t.Errorf("Status() = %v, want errored until a pattern matches", a.Status())
+	}
+	a.handleOutput("Working on it\nDone.\n$ ")
+	if a.Status() != agent.StatusCompleted {
+		t.Fatalf("Status() = %v, want completed", a.Status())
+	}
+	a.handleOutput("$ Working\n")
+	if a.Status() != agent.StatusCompleted {
+		t.Errorf("Status() = %v, want completion to survive trailing output", a.Status())
+	}
+}
+
+func TestProvider_UnknownAgent(t *testing.T) {
+	p := newShellProvider(t, "true", Patterns{})
+
+	if _, err := p.Get("missing"); err == nil {
+		t.Error("Get() should error for unknown agent")
+	}
+	if err := p.Terminate("missing"); err == nil {
+		t.Error("Terminate() should error for unknown agent")
+	}
+	if err := p.SendInput("missing", "x"); err == nil {
+		t.Error("SendInput() should error for unknown agent")
+	}
+}
@@ -0,0 +1,177 @@
+// Package replay records agent events to an NDJSON file and plays
+// recordings back as a provider
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+)
+
+// Record kinds
+const (
+	KindStart  = "start"  // a recorder started; replay skips the gap before it
+	KindEvent  = "event"  // an agent event with a snapshot of its agent
+	KindStream = "stream" // a stream event of a run started with input
+)
+
+// Record is one line of a recording
+type Record struct {
+	Kind   string             `json:"kind"`
+	Time   time.Time          `json:"time"`
+	Event  *EventRecord       `json:"event,omitempty"`
+	Stream *agent.StreamEvent `json:"stream,omitempty"`
+}
+
+// EventRecord is a recorded agent event
+type EventRecord struct {
+	Type       string                `json:"type"`
+	AgentID    string                `json:"agent_id"`
+	Timestamp  time.Time             `json:"timestamp"`
+	Error      string                `json:"error,omitempty"`
+	ReplacedBy string                `json:"replaced_by,omitempty"`
+	Agent      *plugin.AgentSnapshot `json:"agent,omitempty"`
+	SameOutput bool                  `json:"same_output,omitempty"` // output left out as it did not change
+	Progress   *Progress             `json:"progress,omitempty"`
+}
+
+// Progress is the recorded in-flight work of an agent
+type Progress struct {
+	Tool          string    `json:"tool,omitempty"`
+	ToolStarted   time.Time `json:"tool_started"`
+	AwaitingReply time.Time `json:"awaiting_reply"`
+}
+
+// Recorder appends agent and stream events to a recording
+type Recorder struct {
+	mu       sync.Mutex
+	file     *os.File
+	enc      *json.Encoder
+	output   bool
+	outputs  map[string]uint64 // agent ID -> hash of the last recorded output
+	restarts func(id string) int
+	failed   bool
+}
+
+// NewRecorder opens a recording for appending. With output set, agent
+// output is recorded whenever it changes.
+func NewRecorder(path string, output bool) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+
+	r := &Recorder{
+		file:    f,
+		enc:     json.NewEncoder(f),
+		output:  output,
+		outputs: make(map[string]uint64),
+	}
+	r.write(Record{Kind: KindStart, Time: time.Now()})
+	return r, nil
+}
+
+// SetRestarts sets how the restarts of an agent are looked up
+func (r *Recorder) SetRestarts(fn func(id string) int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.restarts = fn
+}
+
+// RecordAgents records agents already known as discovered, so a replay
+// starts with them
+func (r *Recorder) RecordAgents(agents []agent.Agent) {
+	now := time.Now()
+	for _, a := range agents {
+		r.RecordEvent(agent.Event{Type: agent.EventAgentDiscovered, AgentID: a.ID(), Agent: a, Timestamp: now})
+	}
+}
+
+// RecordEvent records an agent event with a snapshot of its agent
+func (r *Recorder) RecordEvent(e agent.Event) {
+	rec := &EventRecord{
+		Type:      e.Type.String(),
+		AgentID:   e.AgentID,
+		Timestamp: e.Timestamp,
+	}
+	if e.Error != nil {
+		rec.Error = e.Error.Error()
+	}
+	if repl, ok := e.Data.(agent.Replacement); ok {
+		rec.ReplacedBy = repl.AgentID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a := e.Agent; a != nil {
+		var snap plugin.AgentSnapshot
+		if r.output {
+			snap = plugin.Snapshot(a)
+			h := fnv.New64a()
+			io.WriteString(h, snap.Output)
+			if prev, ok := r.outputs[a.ID()]; ok && prev == h.Sum64() {
+				snap.Output = ""
+				rec.SameOutput = true
+			}
+			r.outputs[a.ID()] = h.Sum64()
+		} else {
+			snap = plugin.Snapshot(withoutOutput{a})
+		}
+		if r.restarts != nil {
+			snap.Restarts = r.restarts(a.ID())
+		}
+		rec.Agent = &snap
+
+		if pr, ok := a.(agent.ProgressReporter); ok {
+			p := pr.Progress()
+			rec.Progress = &Progress{Tool: p.Tool, ToolStarted: p.ToolStarted, AwaitingReply: p.AwaitingReply}
+		}
+	}
+	if e.Type == agent.EventAgentTerminated {
+		delete(r.outputs, e.AgentID)
+	}
+
+	r.write(Record{Kind: KindEvent, Time: time.Now(), Event: rec})
+}
+
+// RecordStream records a stream event
+func (r *Recorder) RecordStream(e agent.StreamEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Record{Kind: KindStream, Time: time.Now(), Stream: &e})
+}
+
+// Close closes the recording
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// write appends a record; the first failure is logged
+func (r *Recorder) write(rec Record) {
+	if err := r.enc.Encode(rec); err != nil && !r.failed {
+		r.failed = true
+		log.Printf("Failed to write recording: %v", err)
+	}
+}
+
+// withoutOutput hides an agent's output from its snapshot
+type withoutOutput struct {
+	agent.Agent
+}
+
+func (withoutOutput) Output() io.Reader { return nil }
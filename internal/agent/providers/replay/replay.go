@@ -0,0 +1,410 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+)
+
+// errReplayed is returned by every control method of a replayed agent
+var errReplayed = fmt.Errorf("%w for replayed agents", agent.ErrUnsupported)
+
+// Provider plays a recording back as agent events. Recorded times are
+// moved to the replay, so agents look as old as they did when recorded.
+type Provider struct {
+	file  *os.File
+	speed float64
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	agents map[string]*replayAgent
+}
+
+// NewProvider opens a recording to replay at the given speed, where 2
+// plays it twice as fast
+func NewProvider(path string, speed float64) (*Provider, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("replay speed must be positive, got %v", speed)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	return &Provider{
+		file:   f,
+		speed:  speed,
+		done:   make(chan struct{}),
+		agents: make(map[string]*replayAgent),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "Replay"
+}
+
+// Type returns the provider type
+func (p *Provider) Type() string {
+	return "replay"
+}
+
+// Discover returns no agents; they appear as the recording plays
+func (p *Provider) Discover(ctx context.Context) ([]agent.Agent, error) {
+	return nil, nil
+}
+
+// Watch plays the recording. The channel closes when it ends.
+func (p *Provider) Watch(ctx context.Context) (<-chan agent.Event, error) {
+	events := make(chan agent.Event, 100)
+	go func() {
+		defer close(events)
+		defer close(p.done)
+		p.play(ctx, events)
+	}()
+	return events, nil
+}
+
+// Done is closed once the recording has played
+func (p *Provider) Done() <-chan struct{} {
+	return p.done
+}
+
+// Close closes the recording
+func (p *Provider) Close() error {
+	return p.file.Close()
+}
+
+// play sends the recorded events at their recorded pace
+func (p *Provider) play(ctx context.Context, events chan<- agent.Event) {
+	dec := json.NewDecoder(bufio.NewReader(p.file))
+	c := &clock{start: time.Now(), speed: p.speed}
+
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Replay stopped: %v", err)
+			}
+			return
+		}
+		c.advance(rec)
+
+		if wait := time.Until(c.at(rec.Time)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		event, ok := p.apply(rec, c)
+		if !ok {
+			continue
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// apply updates the replayed agents with a record and returns its event
+func (p *Provider) apply(rec Record, c *clock) (agent.Event, bool) {
+	switch {
+	case rec.Kind == KindEvent && rec.Event != nil:
+		e := rec.Event
+		eventType, ok := agent.ParseEventType(e.Type)
+		if !ok {
+			return agent.Event{}, false
+		}
+		event := agent.Event{Type: eventType, AgentID: e.AgentID, Timestamp: c.at(e.Timestamp)}
+		if e.Error != "" {
+			event.Error = errors.New(e.Error)
+		}
+		if e.ReplacedBy != "" {
+			event.Data = agent.Replacement{AgentID: e.ReplacedBy}
+		}
+		if e.Agent != nil {
+			event.Agent = p.upsert(e, c)
+		} else if a, ok := p.get(e.AgentID); ok {
+			event.Agent = a
+		}
+		return event, true
+
+	case rec.Kind == KindStream && rec.Stream != nil:
+		stream := *rec.Stream
+		stream.Timestamp = c.at(stream.Timestamp)
+		event := agent.Event{Type: agent.EventAgentOutput, AgentID: stream.AgentID, Timestamp: stream.Timestamp, Data: stream}
+		if a, ok := p.get(stream.AgentID); ok {
+			event.Agent = a
+		}
+		return event, true
+	}
+	return agent.Event{}, false
+}
+
+// upsert updates or creates the replayed agent of an event record
+func (p *Provider) upsert(e *EventRecord, c *clock) *replayAgent {
+	snap := *e.Agent
+	snap.StartTime = c.at(snap.StartTime)
+	snap.LastActivity = c.at(snap.LastActivity)
+	var progress agent.Progress
+	if e.Progress != nil {
+		progress = agent.Progress{
+			Tool:          e.Progress.Tool,
+			ToolStarted:   c.at(e.Progress.ToolStarted),
+			AwaitingReply: c.at(e.Progress.AwaitingReply),
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	a, ok := p.agents[snap.ID]
+	if !ok {
+		a = &replayAgent{}
+		p.agents[snap.ID] = a
+	}
+	a.update(snap, progress, e.SameOutput)
+	return a
+}
+
+func (p *Provider) get(id string) (*replayAgent, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	a, ok := p.agents[id]
+	return a, ok
+}
+
+// Spawn is not supported during a replay
+func (p *Provider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	return nil, errReplayed
+}
+
+// Get returns a replayed agent by ID
+func (p *Provider) Get(id string) (agent.Agent, error) {
+	a, ok := p.get(id)
+	if !ok {
+		return nil, fmt.Errorf("agent not found: %s", id)
+	}
+	return a, nil
+}
+
+// List returns the agents replayed so far
+func (p *Provider) List() []agent.Agent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	agents := make([]agent.Agent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// Terminate is not supported during a replay
+func (p *Provider) Terminate(id string) error {
+	return errReplayed
+}
+
+// SendInput is not supported during a replay
+func (p *Provider) SendInput(id string, input string) error {
+	return errReplayed
+}
+
+// clock maps recorded times onto the replay. The gap before each later
+// start record, while nothing was recorded, is skipped.
+type clock struct {
+	start time.Time     // when the replay started
+	first time.Time     // time of the first record
+	last  time.Time     // time of the previous record
+	skip  time.Duration // recorded time skipped so far
+	speed float64
+}
+
+// advance moves the clock to a record
+func (c *clock) advance(rec Record) {
+	if c.first.IsZero() {
+		c.first = rec.Time
+	}
+	if rec.Kind == KindStart && !c.last.IsZero() && rec.Time.After(c.last) {
+		c.skip += rec.Time.Sub(c.last)
+	}
+	c.last = rec.Time
+}
+
+// at maps a recorded time onto the replay; zero stays zero
+func (c *clock) at(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	elapsed := t.Sub(c.first) - c.skip
+	return c.start.Add(time.Duration(float64(elapsed) / c.speed))
+}
+
+// replayAgent is an agent as it was recorded
+type replayAgent struct {
+	mu       sync.RWMutex
+	snap     plugin.AgentSnapshot
+	progress agent.Progress
+	lastErr  error
+}
+
+func (a *replayAgent) update(snap plugin.AgentSnapshot, progress agent.Progress, sameOutput bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if sameOutput {
+		snap.Output = a.snap.Output
+	}
+	a.snap = snap
+	a.progress = progress
+	a.lastErr = nil
+	if snap.Error != "" {
+		a.lastErr = errors.New(snap.Error)
+	}
+}
+
+// ID returns the agent ID
+func (a *replayAgent) ID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ID
+}
+
+// Name returns the agent name
+func (a *replayAgent) Name() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Name
+}
+
+// Type returns the recorded agent type
+func (a *replayAgent) Type() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Type
+}
+
+// Directory returns the working directory
+func (a *replayAgent) Directory() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Directory
+}
+
+// ProjectID returns the project ID
+func (a *replayAgent) ProjectID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ProjectID
+}
+
+// ParentID returns the parent agent ID
+func (a *replayAgent) ParentID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ParentID
+}
+
+// IsBackground returns whether this is a background agent
+func (a *replayAgent) IsBackground() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Background
+}
+
+// Status returns the recorded status
+func (a *replayAgent) Status() agent.Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	status, _ := agent.ParseStatus(a.snap.Status)
+	return status
+}
+
+// StartTime returns when the agent started
+func (a *replayAgent) StartTime() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.StartTime
+}
+
+// LastActivity returns the last activity time
+func (a *replayAgent) LastActivity() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.LastActivity
+}
+
+// Output returns the recorded output
+func (a *replayAgent) Output() io.Reader {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return bytes.NewReader([]byte(a.snap.Output))
+}
+
+// CurrentTask returns the current task description
+func (a *replayAgent) CurrentTask() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.CurrentTask
+}
+
+// Metrics returns the recorded metrics
+func (a *replayAgent) Metrics() agent.Metrics {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Metrics
+}
+
+// Progress returns the recorded in-flight work
+func (a *replayAgent) Progress() agent.Progress {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.progress
+}
+
+// Restarts returns how many times AUTO had restarted the agent
+func (a *replayAgent) Restarts() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Restarts
+}
+
+// LastError returns the recorded error
+func (a *replayAgent) LastError() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastErr
+}
+
+// SendInput is not supported during a replay
+func (a *replayAgent) SendInput(input string) error {
+	return errReplayed
+}
+
+// Terminate is not supported during a replay
+func (a *replayAgent) Terminate() error {
+	return errReplayed
+}
+
+// Pause is not supported during a replay
+func (a *replayAgent) Pause() error {
+	return errReplayed
+}
+
+// Resume is not supported during a replay
+func (a *replayAgent) Resume() error {
+	return errReplayed
+}
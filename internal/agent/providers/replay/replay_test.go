@@ -0,0 +1,305 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+	"github.com/CastAIPhil/AUTO/internal/session"
+)
+
+// replayManager plays a recording through a session manager and alert
+// manager, the way the TUI does, and returns them once it has played
+func replayManager(t testing.TB, path string, speed float64) (*session.Manager, *alert.Manager, []agent.Event) {
+	t.Helper()
+
+	p, err := NewProvider(path, speed)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	registry := agent.NewRegistry()
+	registry.Register(p)
+	alertMgr := alert.NewManager(&config.AlertsConfig{}, nil)
+	m := session.NewManager(&config.Config{}, nil, registry, alertMgr)
+
+	var mu sync.Mutex
+	var events []agent.Event
+	m.Subscribe(eventbus.Options{Name: "test", Policy: eventbus.Block}, func(e agent.Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay did not finish")
+	}
+	// The last events may still be on their way to the manager
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		settled := len(events) == n
+		mu.Unlock()
+		if settled || time.Now().After(deadline) {
+			break
+		}
+	}
+	m.Stop()
+	alertMgr.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return m, alertMgr, events
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec", "session.ndjson")
+	r, err := NewRecorder(path, true)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	r.SetRestarts(func(id string) int { return 2 })
+
+	a := agent.NewMockAgent("ses_a", "alpha")
+	a.MockType = "opencode"
+	a.MockProjectID = "proj"
+	a.MockStatus = agent.StatusRunning
+	a.MockOutput = []byte("hello\n")
+	b := agent.NewMockAgent("ses_b", "beta")
+	b.MockStatus = agent.StatusIdle
+
+	r.RecordAgents([]agent.Agent{a, b})
+	a.MockMetrics.TokensIn = 1200
+	r.RecordEvent(agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a, Timestamp: time.Now()})
+	r.RecordStream(agent.StreamEvent{Type: "text", AgentID: a.ID(), Text: "working", Timestamp: time.Now()})
+	a.MockStatus = agent.StatusErrored
+	a.MockLastError = errors.New("model overloaded")
+	a.MockOutput = []byte("hello\nfailed\n")
+	r.RecordEvent(agent.Event{Type: agent.EventAgentErrored, AgentID: a.ID(), Agent: a, Timestamp: time.Now(), Error: a.MockLastError})
+	r.RecordEvent(agent.Event{Type: agent.EventAgentTerminated, AgentID: b.ID(), Data: agent.Replacement{AgentID: "ses_c"}, Timestamp: time.Now()})
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	m, alertMgr, events := replayManager(t, path, 1000)
+
+	var types []agent.EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []agent.EventType{
+		agent.EventAgentDiscovered, agent.EventAgentDiscovered, agent.EventAgentUpdated,
+		agent.EventAgentOutput, agent.EventAgentErrored, agent.EventAgentTerminated,
+	}
+	if len(types) != len(want) {
+		t.Fatalf("replayed events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("replayed events = %v, want %v", types, want)
+		}
+	}
+
+	if stream, ok := events[3].Data.(agent.StreamEvent); !ok || stream.Text != "working" {
+		t.Errorf("output event data = %#v", events[3].Data)
+	}
+	if repl, ok := events[5].Data.(agent.Replacement); !ok || repl.AgentID != "ses_c" {
+		t.Errorf("terminated event data = %#v", events[5].Data)
+	}
+
+	got, ok := m.Get("ses_a")
+	if !ok {
+		t.Fatal("replayed agent missing")
+	}
+	if got.Type() != "opencode" || got.ProjectID() != "proj" || got.Status() != agent.StatusErrored || got.Metrics().TokensIn != 1200 {
+		t.Errorf("replayed agent = %s %s %s %+v", got.Type(), got.ProjectID(), got.Status(), got.Metrics())
+	}
+	if got.LastError() == nil || got.LastError().Error() != "model overloaded" {
+		t.Errorf("LastError() = %v", got.LastError())
+	}
+	if out, _ := io.ReadAll(got.Output()); string(out) != "hello\nfailed\n" {
+		t.Errorf("Output() = %q", out)
+	}
+	if m.Restarts("ses_a") != 2 {
+		t.Errorf("Restarts() = %d, want 2", m.Restarts("ses_a"))
+	}
+	if _, ok := m.Get("ses_b"); ok {
+		t.Error("terminated agent should be gone")
+	}
+	if err := got.SendInput("hi"); !errors.Is(err, agent.ErrUnsupported) {
+		t.Errorf("SendInput() error = %v, want ErrUnsupported", err)
+	}
+
+	// Alerts fire as they did live
+	alerts := alertMgr.List(0, false)
+	if len(alerts) != 1 || alerts[0].Title != "Agent Error" {
+		t.Errorf("alerts = %+v", alerts)
+	}
+}
+
+func TestRecorderSkipsUnchangedOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.ndjson")
+	r, err := NewRecorder(path, true)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	a := agent.NewMockAgent("ses_a", "alpha")
+	a.MockOutput = []byte("same")
+	for i := 0; i < 2; i++ {
+		r.RecordEvent(agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a})
+	}
+	r.Close()
+
+	records := readRecords(t, path)
+	if len(records) != 3 || records[0].Kind != KindStart {
+		t.Fatalf("records = %+v", records)
+	}
+	if e := records[1].Event; e.Agent.Output != "same" || e.SameOutput {
+		t.Errorf("first record = %+v", e)
+	}
+	if e := records[2].Event; e.Agent.Output != "" || !e.SameOutput {
+		t.Errorf("second record should leave the unchanged output out, got %+v", e)
+	}
+
+	// Without output, none is recorded
+	path = filepath.Join(t.TempDir(), "session.ndjson")
+	r, _ = NewRecorder(path, false)
+	r.RecordEvent(agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a})
+	r.Close()
+	if e := readRecords(t, path)[1].Event; e.Agent.Output != "" {
+		t.Errorf("output recorded although off: %q", e.Agent.Output)
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	records := []Record{
+		{Kind: KindStart, Time: base},
+		{Kind: KindEvent, Time: base, Event: &EventRecord{Type: "discovered", AgentID: "a", Timestamp: base, Agent: snapshot("a", base)}},
+		{Kind: KindEvent, Time: base.Add(2 * time.Second), Event: &EventRecord{Type: "updated", AgentID: "a", Timestamp: base.Add(2 * time.Second), Agent: snapshot("a", base.Add(2*time.Second))}},
+		// A second recording session half an hour later follows straight on
+		{Kind: KindStart, Time: base.Add(30 * time.Minute)},
+		{Kind: KindEvent, Time: base.Add(30*time.Minute + 2*time.Second), Event: &EventRecord{Type: "updated", AgentID: "a", Timestamp: base.Add(30*time.Minute + 2*time.Second), Agent: snapshot("a", base.Add(30*time.Minute+2*time.Second))}},
+	}
+	path := writeRecords(t, records)
+
+	start := time.Now()
+	m, _, events := replayManager(t, path, 10)
+	took := time.Since(start)
+	if took < 350*time.Millisecond || took > 3*time.Second {
+		t.Errorf("replay took %v, want about 400ms at 10x", took)
+	}
+	if len(events) != 3 {
+		t.Fatalf("replayed %d events, want 3", len(events))
+	}
+
+	// Recorded times are moved to the replay
+	a, _ := m.Get("a")
+	if d := time.Since(a.LastActivity()); d < 0 || d > 3*time.Second {
+		t.Errorf("LastActivity() is %v ago, want moments ago", d)
+	}
+	if gap := events[1].Timestamp.Sub(events[0].Timestamp); gap < 150*time.Millisecond || gap > 250*time.Millisecond {
+		t.Errorf("gap between events = %v, want 200ms", gap)
+	}
+}
+
+func TestNewProviderErrors(t *testing.T) {
+	if _, err := NewProvider(filepath.Join(t.TempDir(), "missing.ndjson"), 1); err == nil {
+		t.Error("NewProvider() should fail for a missing recording")
+	}
+	if _, err := NewProvider(writeRecords(t, nil), 0); err == nil {
+		t.Error("NewProvider() should reject a zero speed")
+	}
+}
+
+// TestReplayFixture replays the recording in testdata, which doubles as a
+// realistic fixture for other tests and benchmarks
+func TestReplayFixture(t *testing.T) {
+	m, alertMgr, events := replayManager(t, filepath.Join("testdata", "session.ndjson"), 1e6)
+	if len(events) == 0 {
+		t.Fatal("fixture replayed no events")
+	}
+
+	stats := m.Stats()
+	if stats.Total != 3 || stats.ByStatus[agent.StatusCompleted] != 1 || stats.ByStatus[agent.StatusErrored] != 1 || stats.ByStatus[agent.StatusRunning] != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	var titles []string
+	for _, a := range alertMgr.List(0, false) {
+		titles = append(titles, a.Title)
+	}
+	if len(titles) != 2 {
+		t.Errorf("alerts = %v, want an error and a completion", titles)
+	}
+}
+
+func BenchmarkReplay(b *testing.B) {
+	path := filepath.Join("testdata", "session.ndjson")
+	for i := 0; i < b.N; i++ {
+		replayManager(b, path, 1e9)
+	}
+}
+
+func snapshot(id string, activity time.Time) *plugin.AgentSnapshot {
+	return &plugin.AgentSnapshot{ID: id, Name: id, Type: "opencode", Status: "running", StartTime: activity, LastActivity: activity}
+}
+
+func writeRecords(t *testing.T, records []Record) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.ndjson")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []Record
+	dec := json.NewDecoder(f)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return records
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+}
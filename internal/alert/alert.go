@@ -2,19 +2,19 @@
 package alert
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"log"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
 	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
 	"github.com/CastAIPhil/AUTO/internal/store"
 	"github.com/gen2brain/beeep"
-	"github.com/slack-go/slack"
 )
 
 // Level represents alert severity
@@ -29,14 +29,19 @@ const (
 
 // Alert represents an alert message
 type Alert struct {
-	ID        string
-	Level     Level
-	Title     string
-	Message   string
-	AgentID   string
-	Agent     agent.Agent
-	Timestamp time.Time
-	Read      bool
+	ID         string
+	Level      Level
+	Title      string
+	Message    string
+	AgentID    string
+	Agent      agent.Agent
+	Timestamp  time.Time
+	Read       bool
+	Rule       string            // the rule that raised the alert, if any
+	Channels   []string          // channels to send to; empty sends to all
+	DedupKey   string            // alerts with equal keys are duplicates; defaults to level, title and agent
+	Suppressed string            // why the alert was not sent, if it was not
+	Skipped    map[string]string // channel -> why the alert was not sent to it, when it was sent to others
 }
 
 // Channel represents an alert channel
@@ -52,7 +57,11 @@ type Manager struct {
 	channels []Channel
 	alerts   []*Alert
 	mu       sync.RWMutex
-	onAlert  func(*Alert)
+	bus      *eventbus.Bus[*Alert]
+	rules    *Engine
+	limits   *limits
+	outbox   *outbox
+	now      func() time.Time
 }
 
 // NewManager creates a new alert manager
@@ -61,42 +70,150 @@ func NewManager(cfg *config.AlertsConfig, st *store.Store) *Manager {
 		cfg:    cfg,
 		store:  st,
 		alerts: make([]*Alert, 0),
+		bus:    eventbus.New(alertTopic),
+		now:    time.Now,
 	}
 
+	rules, err := NewEngine(cfg.Rules)
+	if err != nil {
+		// Rules are validated when the config is loaded
+		log.Printf("Ignoring alert rules: %v", err)
+		rules, _ = NewEngine(nil)
+	}
+	m.rules = rules
+	quiet, err := parseQuietHours(cfg.QuietHours)
+	if err != nil {
+		log.Printf("Ignoring quiet hours: %v", err)
+	}
+	m.limits = newLimits(quiet)
+	var q queue = &memQueue{}
+	if st != nil {
+		q = st
+	}
+	m.outbox = newOutbox(q, cfg.Delivery)
+
 	// Initialize channels based on config
 	if cfg.DesktopNotifications {
 		m.channels = append(m.channels, &DesktopChannel{})
 	}
-	if cfg.SlackEnabled && cfg.SlackWebhookURL != "" {
-		m.channels = append(m.channels, &SlackChannel{
-			webhookURL: cfg.SlackWebhookURL,
-			channel:    cfg.SlackChannel,
-		})
+	channels, errs := remoteChannels(cfg)
+	for _, err := range errs {
+		// Channels are validated when the config is loaded
+		log.Printf("Ignoring alert channel: %v", err)
 	}
-	if cfg.DiscordEnabled && cfg.DiscordWebhookURL != "" {
-		m.channels = append(m.channels, &DiscordChannel{
-			webhookURL: cfg.DiscordWebhookURL,
-			httpClient: &http.Client{Timeout: 10 * time.Second},
-		})
+	m.channels = append(m.channels, channels...)
+	for _, ch := range m.channels {
+		if _, ok := ch.(remote); ok {
+			m.outbox.start(ch)
+		}
 	}
+	m.outbox.failRemoved()
 
 	return m
 }
 
-// OnAlert sets the callback for new alerts (for TUI)
-func (m *Manager) OnAlert(fn func(*Alert)) {
-	m.onAlert = fn
+// builtinChannels can be named by rules and settings whether or not they
+// are configured
+var builtinChannels = []string{"desktop", "slack", "discord"}
+
+// remoteChannels creates the configured webhook and email channels,
+// returning the errors of those with invalid settings
+func remoteChannels(cfg *config.AlertsConfig) ([]Channel, []error) {
+	var channels []Channel
+	var errs []error
+	for _, hook := range webhookConfigs(cfg) {
+		ch, err := NewWebhookChannel(hook)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		channels = append(channels, ch)
+	}
+	for _, email := range cfg.Emails {
+		ch, err := NewEmailChannel(email)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		channels = append(channels, ch)
+	}
+	return channels, errs
+}
+
+// channelNames returns the channels rules and settings can name
+func channelNames(cfg *config.AlertsConfig) []string {
+	names := slices.Clone(builtinChannels)
+	for _, hook := range cfg.Webhooks {
+		names = append(names, hook.Name)
+	}
+	for _, email := range cfg.Emails {
+		names = append(names, email.Name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
 }
 
-// Send sends an alert to all configured channels
+// validateChannels checks the settings of every webhook and email channel,
+// and that their names are unique
+func validateChannels(cfg *config.AlertsConfig) error {
+	channels, errs := remoteChannels(cfg)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	seen := make(map[string]bool)
+	for _, ch := range channels {
+		if seen[ch.Name()] {
+			return fmt.Errorf("alert channel %s: the name is used twice", ch.Name())
+		}
+		seen[ch.Name()] = true
+	}
+	return nil
+}
+
+// Subscribe calls fn for every new alert that passes the filter, which
+// matches alert levels as types
+func (m *Manager) Subscribe(opts eventbus.Options, fn func(*Alert)) *eventbus.Subscription[*Alert] {
+	return m.bus.Subscribe(opts, fn)
+}
+
+// SubscriberStats returns the queue lengths and counters of alert subscribers
+func (m *Manager) SubscriberStats() []eventbus.Stats {
+	return m.bus.Stats()
+}
+
+// Close delivers pending alerts to subscribers and ends their
+// subscriptions. Alerts held for quiet hours are not sent, and alerts
+// queued for remote channels are sent on the next start.
+func (m *Manager) Close() {
+	m.limits.stop()
+	m.outbox.close()
+	m.bus.Close()
+}
+
+// alertTopic is the topic subscribers filter alerts by
+func alertTopic(a *Alert) eventbus.Topic {
+	t := eventbus.Topic{Type: string(a.Level)}
+	if a.Agent != nil {
+		t.AgentType = a.Agent.Type()
+		t.Project = a.Agent.ProjectID()
+	}
+	return t
+}
+
+// Send sends an alert to its channels, or all configured channels. An
+// alert that is suppressed is still listed and stored, marked as such.
+// Alerts to remote channels are queued and sent in the background, so
+// only local channels and queueing can fail.
 func (m *Manager) Send(ctx context.Context, alert *Alert) error {
+	now := m.now()
 	// Generate ID if not set
 	if alert.ID == "" {
-		alert.ID = fmt.Sprintf("alert-%d", time.Now().UnixNano())
+		alert.ID = fmt.Sprintf("alert-%d", now.UnixNano())
 	}
 	if alert.Timestamp.IsZero() {
-		alert.Timestamp = time.Now()
+		alert.Timestamp = now
 	}
+	deliveries := m.plan(alert, now)
 
 	// Store the alert
 	m.mu.Lock()
@@ -109,25 +226,30 @@ func (m *Manager) Send(ctx context.Context, alert *Alert) error {
 
 	// Persist to database
 	if m.store != nil {
+		var metadata string
+		if alert.Suppressed != "" || len(alert.Skipped) > 0 {
+			data, _ := json.Marshal(struct {
+				Suppressed string            `json:"suppressed,omitempty"`
+				Skipped    map[string]string `json:"skipped,omitempty"`
+			}{alert.Suppressed, alert.Skipped})
+			metadata = string(data)
+		}
 		m.store.SaveAlert(&store.AlertRecord{
 			ID:        alert.ID,
 			AgentID:   alert.AgentID,
 			Level:     string(alert.Level),
 			Message:   fmt.Sprintf("%s: %s", alert.Title, alert.Message),
 			Timestamp: alert.Timestamp,
-			Read:      false,
+			Read:      alert.Read,
+			Metadata:  metadata,
 		})
 	}
 
-	// Notify TUI callback
-	if m.onAlert != nil {
-		m.onAlert(alert)
-	}
+	m.bus.Publish(alert)
 
-	// Send to all channels
 	var lastErr error
-	for _, ch := range m.channels {
-		if err := ch.Send(ctx, alert); err != nil {
+	for _, d := range deliveries {
+		if err := m.deliver(ctx, d.ch, d.alert); err != nil {
 			lastErr = err
 		}
 	}
@@ -135,49 +257,27 @@ func (m *Manager) Send(ctx context.Context, alert *Alert) error {
 	return lastErr
 }
 
-// SendAgentEvent sends an alert for an agent event
-func (m *Manager) SendAgentEvent(ctx context.Context, event agent.Event) error {
-	var level Level
-	var title string
-	var message string
-
-	switch event.Type {
-	case agent.EventAgentErrored:
-		level = LevelError
-		title = "Agent Error"
-		if event.Agent != nil {
-			message = fmt.Sprintf("Agent %s encountered an error", event.Agent.Name())
-			if event.Agent.LastError() != nil {
-				message += ": " + event.Agent.LastError().Error()
-			}
-		}
-	case agent.EventAgentCompleted:
-		level = LevelSuccess
-		title = "Agent Completed"
-		if event.Agent != nil {
-			message = fmt.Sprintf("Agent %s completed its task", event.Agent.Name())
-		}
-	case agent.EventAgentContextLimit:
-		level = LevelWarning
-		title = "Context Limit Warning"
-		if event.Agent != nil {
-			message = fmt.Sprintf("Agent %s is approaching context limit", event.Agent.Name())
-		}
-	default:
-		// Don't alert for other events
-		return nil
+// deliver sends an alert to a local channel, or queues it for a remote one
+func (m *Manager) deliver(ctx context.Context, ch Channel, a *Alert) error {
+	if _, ok := ch.(remote); ok {
+		return m.outbox.enqueue(ch, a)
 	}
+	return ch.Send(ctx, a)
+}
 
-	alert := &Alert{
-		Level:     level,
-		Title:     title,
-		Message:   message,
-		AgentID:   event.AgentID,
-		Agent:     event.Agent,
-		Timestamp: event.Timestamp,
+// SendAgentEvent sends the alerts the alert rules raise for an agent event
+func (m *Manager) SendAgentEvent(ctx context.Context, event agent.Event) error {
+	alerts, err := m.rules.Evaluate(event)
+	if err != nil {
+		log.Printf("Failed to render alert: %v", err)
 	}
-
-	return m.Send(ctx, alert)
+	var lastErr error
+	for _, a := range alerts {
+		if err := m.Send(ctx, a); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
 }
 
 // List returns all alerts
@@ -251,134 +351,3 @@ func (c *DesktopChannel) Name() string {
 func (c *DesktopChannel) Send(ctx context.Context, alert *Alert) error {
 	return beeep.Notify(alert.Title, alert.Message, "")
 }
-
-// SlackChannel sends Slack notifications
-type SlackChannel struct {
-	webhookURL string
-	channel    string
-}
-
-func (c *SlackChannel) Name() string {
-	return "slack"
-}
-
-func (c *SlackChannel) Send(ctx context.Context, alert *Alert) error {
-	color := "#2196F3" // info blue
-	switch alert.Level {
-	case LevelWarning:
-		color = "#FF9800" // warning orange
-	case LevelError:
-		color = "#F44336" // error red
-	case LevelSuccess:
-		color = "#4CAF50" // success green
-	}
-
-	attachment := slack.Attachment{
-		Color:      color,
-		Title:      alert.Title,
-		Text:       alert.Message,
-		Footer:     "AUTO",
-		Ts:         json.Number(fmt.Sprintf("%d", alert.Timestamp.Unix())),
-		FooterIcon: "",
-	}
-
-	if alert.Agent != nil {
-		attachment.Fields = []slack.AttachmentField{
-			{
-				Title: "Agent",
-				Value: alert.Agent.Name(),
-				Short: true,
-			},
-			{
-				Title: "Status",
-				Value: alert.Agent.Status().String(),
-				Short: true,
-			},
-		}
-	}
-
-	msg := slack.WebhookMessage{
-		Channel:     c.channel,
-		Attachments: []slack.Attachment{attachment},
-	}
-
-	return slack.PostWebhookContext(ctx, c.webhookURL, &msg)
-}
-
-// DiscordChannel sends Discord notifications
-type DiscordChannel struct {
-	webhookURL string
-	httpClient *http.Client
-}
-
-func (c *DiscordChannel) Name() string {
-	return "discord"
-}
-
-func (c *DiscordChannel) Send(ctx context.Context, alert *Alert) error {
-	color := 2201331 // info blue
-	switch alert.Level {
-	case LevelWarning:
-		color = 16750592 // warning orange
-	case LevelError:
-		color = 15158332 // error red
-	case LevelSuccess:
-		color = 3066993 // success green
-	}
-
-	embed := map[string]interface{}{
-		"title":       alert.Title,
-		"description": alert.Message,
-		"color":       color,
-		"timestamp":   alert.Timestamp.Format(time.RFC3339),
-		"footer": map[string]string{
-			"text": "AUTO",
-		},
-	}
-
-	if alert.Agent != nil {
-		embed["fields"] = []map[string]interface{}{
-			{
-				"name":   "Agent",
-				"value":  alert.Agent.Name(),
-				"inline": true,
-			},
-			{
-				"name":   "Status",
-				"value":  alert.Agent.Status().String(),
-				"inline": true,
-			},
-		}
-	}
-
-	payload := map[string]interface{}{
-		"embeds": []interface{}{embed},
-	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.webhookURL, bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := c.httpClient
-	if client == nil {
-		client = &http.Client{Timeout: 10 * time.Second}
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("discord webhook returned status %d", resp.StatusCode)
-	}
-
-	return nil
-}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
 	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
 )
 
 func TestNewManager(t *testing.T) {
@@ -77,9 +78,9 @@ func TestManagerSend(t *testing.T) {
 	cfg := &config.AlertsConfig{}
 	m := NewManager(cfg, nil)
 
-	// Track callback
+	// Track subscriber
 	var received *Alert
-	m.OnAlert(func(a *Alert) {
+	m.Subscribe(eventbus.Options{Name: "test"}, func(a *Alert) {
 		received = a
 	})
 
@@ -104,9 +105,10 @@ func TestManagerSend(t *testing.T) {
 		t.Error("Send() did not set timestamp")
 	}
 
-	// Check callback was called
+	// Check subscriber was called
+	m.bus.Flush()
 	if received == nil {
-		t.Error("Send() did not trigger callback")
+		t.Error("Send() did not notify subscriber")
 	}
 	if received.ID != alert.ID {
 		t.Error("Send() callback received different alert")
@@ -291,16 +293,15 @@ func TestDesktopChannelName(t *testing.T) {
 	}
 }
 
-func TestSlackChannelName(t *testing.T) {
-	c := &SlackChannel{}
-	if c.Name() != "slack" {
-		t.Errorf("SlackChannel.Name() = %s, want slack", c.Name())
-	}
-}
-
-func TestDiscordChannelName(t *testing.T) {
-	c := &DiscordChannel{}
-	if c.Name() != "discord" {
-		t.Errorf("DiscordChannel.Name() = %s, want discord", c.Name())
+func TestSlackAndDiscordChannelNames(t *testing.T) {
+	m := NewManager(&config.AlertsConfig{
+		SlackEnabled:      true,
+		SlackWebhookURL:   "https://hooks.slack.com/test",
+		DiscordEnabled:    true,
+		DiscordWebhookURL: "https://discord.com/api/webhooks/test",
+	}, nil)
+	defer m.Close()
+	if len(m.channels) != 2 || m.channels[0].Name() != "slack" || m.channels[1].Name() != "discord" {
+		t.Errorf("channels = %v, want webhooks named slack and discord", m.channels)
 	}
 }
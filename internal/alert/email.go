@@ -0,0 +1,406 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// defaultEmailTimeout bounds an email's SMTP session without a timeout set
+const defaultEmailTimeout = 30 * time.Second
+
+// Ways of securing the connection to the SMTP server
+const (
+	emailSTARTTLS = "starttls" // upgrade a plain connection, failing if the server cannot
+	emailTLS      = "tls"      // connect with TLS, usually on port 465
+	emailNoTLS    = "none"     // send in the clear, e.g. to a local relay
+)
+
+// The bodies of single alert and digest emails, each as text and HTML.
+// Single alert emails are executed with WebhookData, digests with
+// digestData.
+var (
+	emailText = template.Must(template.New("email").Parse(`{{.Title}}
+{{if .Message}}
+{{.Message}}
+{{end}}
+Level:   {{.Level}}
+{{if .AgentName}}Agent:   {{.AgentName}} ({{.AgentStatus}})
+{{end}}{{if .Project}}Project: {{.Project}}
+{{end}}{{if .Rule}}Rule:    {{.Rule}}
+{{end}}Time:    {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`))
+
+	emailHTML = htmltemplate.Must(htmltemplate.New("email").Parse(`<html><body style="font-family:sans-serif">
+<div style="border-left:4px solid {{.Color}};padding-left:12px">
+<h2 style="margin:0">{{.Title}}</h2>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+<table cellpadding="2">
+<tr><td><b>Level</b></td><td>{{.Level}}</td></tr>
+{{if .AgentName}}<tr><td><b>Agent</b></td><td>{{.AgentName}} ({{.AgentStatus}})</td></tr>{{end}}
+{{if .Project}}<tr><td><b>Project</b></td><td>{{.Project}}</td></tr>{{end}}
+{{if .Rule}}<tr><td><b>Rule</b></td><td>{{.Rule}}</td></tr>{{end}}
+<tr><td><b>Time</b></td><td>{{.Timestamp.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+</table>
+</div>
+</body></html>
+`))
+
+	digestText = template.Must(template.New("digest").Parse(`{{len .Alerts}} alerts from {{.Start.Format "15:04"}} to {{.End.Format "15:04 MST"}}
+
+{{printf "%-24s %6s %6s %8s  %s" "Agent" "Alerts" "Errors" "Warnings" "Last alert"}}
+{{range .Agents}}{{printf "%-24s %6d %6d %8d  %s" .Name .Alerts .Errors .Warnings .Last}}
+{{end}}
+{{range .Alerts}}{{.Timestamp.Format "15:04:05"}} {{printf "%-7s" .Level}} {{.Title}}{{if .AgentName}} ({{.AgentName}}){{end}}
+{{if .Message}}    {{.Message}}
+{{end}}{{end}}`))
+
+	digestHTML = htmltemplate.Must(htmltemplate.New("digest").Parse(`<html><body style="font-family:sans-serif">
+<h2>{{len .Alerts}} alerts from {{.Start.Format "15:04"}} to {{.End.Format "15:04 MST"}}</h2>
+<table border="1" cellpadding="4" style="border-collapse:collapse">
+<tr><th align="left">Agent</th><th>Alerts</th><th>Errors</th><th>Warnings</th><th align="left">Last alert</th></tr>
+{{range .Agents}}<tr><td>{{.Name}}</td><td align="right">{{.Alerts}}</td><td align="right">{{.Errors}}</td><td align="right">{{.Warnings}}</td><td>{{.Last}}</td></tr>
+{{end}}</table>
+<h3>Alerts</h3>
+{{range .Alerts}}<div style="border-left:4px solid {{.Color}};padding-left:8px;margin-bottom:8px">
+<b>{{.Title}}</b>{{if .AgentName}} ({{.AgentName}}){{end}}<br>
+<small>{{.Timestamp.Format "15:04:05"}} {{.Level}}</small>
+{{if .Message}}<p style="margin:4px 0">{{.Message}}</p>{{end}}
+</div>
+{{end}}</body></html>
+`))
+)
+
+// digestData is what digest emails are executed with
+type digestData struct {
+	Alerts     []WebhookData // oldest first
+	Agents     []agentSummary
+	Start, End time.Time
+}
+
+// agentSummary is an agent's row in a digest's summary table
+type agentSummary struct {
+	Name     string
+	Alerts   int
+	Errors   int
+	Warnings int
+	Last     string // title of the agent's latest alert
+}
+
+// EmailChannel sends alerts by email over SMTP, each on its own or as
+// digests of the alerts over a window
+type EmailChannel struct {
+	name      string
+	addr      string // host:port
+	host      string
+	security  string // emailSTARTTLS, emailTLS or emailNoTLS
+	tlsConfig *tls.Config
+	auth      smtp.Auth // nil without a username
+	from      *mail.Address
+	to        []*mail.Address
+	digest    time.Duration
+	timeout   time.Duration
+}
+
+// NewEmailChannel validates an email channel's settings and creates it
+func NewEmailChannel(cfg config.EmailConfig) (*EmailChannel, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("email channel has no name")
+	}
+	if cfg.Name == "desktop" {
+		return nil, fmt.Errorf("email %s: desktop is not an email channel name", cfg.Name)
+	}
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("email %s: no host", cfg.Name)
+	}
+
+	c := &EmailChannel{
+		name:      cfg.Name,
+		host:      cfg.Host,
+		security:  strings.ToLower(cfg.TLS),
+		tlsConfig: &tls.Config{ServerName: cfg.Host},
+		digest:    cfg.Digest,
+		timeout:   cfg.Timeout,
+	}
+	port := cfg.Port
+	switch c.security {
+	case "":
+		c.security = emailSTARTTLS
+		fallthrough
+	case emailSTARTTLS, emailNoTLS:
+		if port == 0 {
+			port = 587
+		}
+	case emailTLS:
+		if port == 0 {
+			port = 465
+		}
+	default:
+		return nil, fmt.Errorf("email %s: unknown tls mode %q", cfg.Name, cfg.TLS)
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("email %s: invalid port %d", cfg.Name, port)
+	}
+	c.addr = net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	var err error
+	if c.from, err = mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("email %s: invalid from address %q: %w", cfg.Name, cfg.From, err)
+	}
+	if len(cfg.To) == 0 {
+		return nil, fmt.Errorf("email %s: no recipients", cfg.Name)
+	}
+	for _, to := range cfg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("email %s: invalid to address %q: %w", cfg.Name, to, err)
+		}
+		c.to = append(c.to, addr)
+	}
+	if cfg.Username != "" {
+		c.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	if cfg.Digest < 0 {
+		return nil, fmt.Errorf("email %s: negative digest window", cfg.Name)
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("email %s: negative timeout", cfg.Name)
+	}
+	if cfg.Timeout == 0 {
+		c.timeout = defaultEmailTimeout
+	}
+	return c, nil
+}
+
+// Name returns the email channel's name
+func (c *EmailChannel) Name() string {
+	return c.name
+}
+
+func (c *EmailChannel) remote() {}
+
+func (c *EmailChannel) digestWindow() time.Duration {
+	return c.digest
+}
+
+// Send emails an alert
+func (c *EmailChannel) Send(ctx context.Context, alert *Alert) error {
+	data := alertData(c.name, alert)
+	var text, html bytes.Buffer
+	if err := emailText.Execute(&text, data); err != nil {
+		return err
+	}
+	if err := emailHTML.Execute(&html, data); err != nil {
+		return err
+	}
+	return c.send(ctx, "[AUTO] "+alert.Title, text.Bytes(), html.Bytes())
+}
+
+// SendDigest emails alerts as one message, summing them up per agent. A
+// single alert is sent as by Send.
+func (c *EmailChannel) SendDigest(ctx context.Context, alerts []*Alert) error {
+	if len(alerts) == 1 {
+		return c.Send(ctx, alerts[0])
+	}
+
+	data := c.digestData(alerts)
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, data); err != nil {
+		return err
+	}
+	if err := digestHTML.Execute(&html, data); err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("[AUTO] %d alerts", len(alerts))
+	switch n := countLevel(data.Alerts, LevelError); n {
+	case 0:
+	case 1:
+		subject += " (1 error)"
+	default:
+		subject += fmt.Sprintf(" (%d errors)", n)
+	}
+	return c.send(ctx, subject, text.Bytes(), html.Bytes())
+}
+
+// digestData orders alerts by time and sums them up per agent, in the
+// order the agents first alerted
+func (c *EmailChannel) digestData(alerts []*Alert) digestData {
+	var d digestData
+	for _, a := range alerts {
+		d.Alerts = append(d.Alerts, alertData(c.name, a))
+	}
+	slices.SortStableFunc(d.Alerts, func(a, b WebhookData) int { return a.Timestamp.Compare(b.Timestamp) })
+	d.Start = d.Alerts[0].Timestamp
+	d.End = d.Alerts[len(d.Alerts)-1].Timestamp
+
+	index := make(map[string]int) // agent ID -> row
+	for _, a := range d.Alerts {
+		i, ok := index[a.AgentID]
+		if !ok {
+			name := a.AgentName
+			switch {
+			case name != "":
+			case a.AgentID != "":
+				// Queued by an earlier run, without the agent
+				name = a.AgentID
+			default:
+				name = "(no agent)"
+			}
+			i = len(d.Agents)
+			index[a.AgentID] = i
+			d.Agents = append(d.Agents, agentSummary{Name: name})
+		}
+		s := &d.Agents[i]
+		s.Alerts++
+		switch a.Level {
+		case LevelError:
+			s.Errors++
+		case LevelWarning:
+			s.Warnings++
+		}
+		s.Last = a.Title
+	}
+	return d
+}
+
+// countLevel counts the alerts of a level
+func countLevel(alerts []WebhookData, level Level) int {
+	n := 0
+	for _, a := range alerts {
+		if a.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+// send emails a message with text and HTML alternatives to the recipients
+func (c *EmailChannel) send(ctx context.Context, subject string, text, html []byte) error {
+	msg := c.message(subject, text, html, time.Now())
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if c.security == emailTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tlsConfig}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("%s email: %w", c.name, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+	// Cancelling the context ends the session
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := c.session(conn, msg); err != nil {
+		return fmt.Errorf("%s email: %w", c.name, err)
+	}
+	return nil
+}
+
+// session sends a message over an SMTP connection
+func (c *EmailChannel) session(conn net.Conn, msg []byte) error {
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if c.security == emailSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", c.addr)
+		}
+		if err := client.StartTLS(c.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if c.auth != nil {
+		if err := client.Auth(c.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.from.Address); err != nil {
+		return err
+	}
+	for _, to := range c.to {
+		if err := client.Rcpt(to.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds a multipart/alternative email with a text and an HTML
+// part, both quoted-printable
+func (c *EmailChannel) message(subject string, text, html []byte, now time.Time) []byte {
+	// Writes to a bytes.Buffer cannot fail
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(w)
+		qp.Write(part.content)
+		qp.Close()
+	}
+	mw.Close()
+
+	to := make([]string, len(c.to))
+	for i, addr := range c.to {
+		to[i] = addr.String()
+	}
+	domain := c.from.Address[strings.LastIndex(c.from.Address, "@")+1:]
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <auto-%d@%s>\r\n", now.UnixNano(), domain)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
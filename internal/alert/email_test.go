@@ -0,0 +1,294 @@
+package alert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/store"
+)
+
+// smtpMessage is what a fake SMTP server received in a session
+type smtpMessage struct {
+	tls  bool
+	auth string // the decoded AUTH PLAIN response
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer runs a fake SMTP server, offering STARTTLS when tlsConfig is
+// set, and returns its address and the messages sent to it
+func smtpServer(t *testing.T, tlsConfig *tls.Config) (string, <-chan smtpMessage) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan smtpMessage, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, tlsConfig, messages)
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func serveSMTP(conn net.Conn, tlsConfig *tls.Config, messages chan<- smtpMessage) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	var m smtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-fake")
+			if tlsConfig != nil && !m.tls {
+				tp.PrintfLine("250 STARTTLS")
+			} else {
+				tp.PrintfLine("250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tc := tls.Server(conn, tlsConfig)
+			if tc.Handshake() != nil {
+				return
+			}
+			conn, tp, m.tls = tc, textproto.NewConn(tc), true
+		case "AUTH":
+			resp, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			m.auth = string(resp)
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			m.from = arg
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			m.to = append(m.to, arg)
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.data = string(data)
+			tp.PrintfLine("250 queued")
+			messages <- m
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// testCert returns a server TLS config for 127.0.0.1 and a client config
+// trusting it
+func testCert(t *testing.T) (server, client *tls.Config) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{Certificates: srv.TLS.Certificates}, &tls.Config{ServerName: "127.0.0.1", RootCAs: pool}
+}
+
+// parseEmail returns an email's subject and its text and HTML parts
+func parseEmail(t *testing.T, data string) (subject, text, html string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid email: %v\n%s", err, data)
+	}
+	subject, _ = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(part)
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") {
+			html = string(body)
+		} else {
+			text = string(body)
+		}
+	}
+	return subject, text, html
+}
+
+// emailConfig is the config of an email channel sending to addr
+func emailConfig(addr, security string) config.EmailConfig {
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	return config.EmailConfig{
+		Name: "email",
+		Host: host,
+		Port: p,
+		TLS:  security,
+		From: "AUTO <auto@example.com>",
+		To:   []string{"ops@example.com", "Lead <lead@example.com>"},
+	}
+}
+
+func TestEmailChannel(t *testing.T) {
+	serverTLS, clientTLS := testCert(t)
+	addr, messages := smtpServer(t, serverTLS)
+	cfg := emailConfig(addr, "")
+	cfg.Username, cfg.Password = "auto", "s3cret"
+	ch, err := NewEmailChannel(cfg)
+	if err != nil {
+		t.Fatalf("NewEmailChannel() error = %v", err)
+	}
+	ch.tlsConfig = clientTLS
+	if err := ch.Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	m := <-messages
+	if !m.tls || m.auth != "\x00auto\x00s3cret" || m.from != "FROM:<auto@example.com>" ||
+		strings.Join(m.to, ",") != "TO:<ops@example.com>,TO:<lead@example.com>" {
+		t.Errorf("session = %+v", m)
+	}
+	subject, text, html := parseEmail(t, m.data)
+	if subject != `[AUTO] Agent "fixer" failed` {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(text, "boom") || !strings.Contains(text, "Agent:   fixer (errored)") {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.Contains(html, "Agent &#34;fixer&#34; failed") || !strings.Contains(html, "#F44336") {
+		t.Errorf("html = %q", html)
+	}
+
+	// STARTTLS is required unless turned off
+	plain, messages := smtpServer(t, nil)
+	ch, _ = NewEmailChannel(emailConfig(plain, ""))
+	if err := ch.Send(context.Background(), testAlert()); err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+		t.Errorf("Send() error = %v, want STARTTLS missing", err)
+	}
+	ch, _ = NewEmailChannel(emailConfig(plain, "none"))
+	if err := ch.Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if m := <-messages; m.tls {
+		t.Error("sent over TLS with tls: none")
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	addr, messages := smtpServer(t, nil)
+	cfg := emailConfig(addr, "none")
+	cfg.Digest = 200 * time.Millisecond
+	m := NewManager(&config.AlertsConfig{Emails: []config.EmailConfig{cfg}}, nil)
+	defer m.Close()
+
+	fixer := agent.NewMockAgent("ses_1", "fixer")
+	fixer.MockStatus = agent.StatusErrored
+	builder := agent.NewMockAgent("ses_2", "builder")
+	for _, a := range []*Alert{
+		{Level: LevelWarning, Title: "fixer is slow", AgentID: fixer.ID(), Agent: fixer},
+		{Level: LevelSuccess, Title: "builder done", AgentID: builder.ID(), Agent: builder},
+		{Level: LevelError, Title: "fixer failed", Message: "boom", AgentID: fixer.ID(), Agent: fixer},
+	} {
+		if err := m.Send(context.Background(), a); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	var msg smtpMessage
+	select {
+	case msg = <-messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no digest sent")
+	}
+	subject, text, html := parseEmail(t, msg.data)
+	if subject != "[AUTO] 3 alerts (1 error)" {
+		t.Errorf("subject = %q", subject)
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) < 5 || !strings.HasPrefix(lines[0], "3 alerts from") ||
+		strings.Join(strings.Fields(lines[3]), " ") != "fixer 2 1 1 fixer failed" ||
+		strings.Join(strings.Fields(lines[4]), " ") != "builder 1 0 0 builder done" {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.Contains(html, "<td>fixer</td>") || !strings.Contains(html, "boom") {
+		t.Errorf("html = %q", html)
+	}
+	waitDeliveries(t, m, store.DeliverySent, 3)
+
+	// The next alert opens a new digest, alone in which it is sent as is
+	m.Send(context.Background(), &Alert{Level: LevelInfo, Title: "later"})
+	select {
+	case msg = <-messages:
+		if subject, _, _ := parseEmail(t, msg.data); subject != "[AUTO] later" {
+			t.Errorf("subject = %q", subject)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email sent")
+	}
+}
+
+func TestEmailConfigErrors(t *testing.T) {
+	valid := config.EmailConfig{Name: "a", Host: "smtp.example.com", From: "auto@example.com", To: []string{"ops@example.com"}}
+	tests := []struct {
+		name string
+		edit func(*config.EmailConfig)
+		want string
+	}{
+		{"name", func(c *config.EmailConfig) { c.Name = "" }, "no name"},
+		{"desktop", func(c *config.EmailConfig) { c.Name = "desktop" }, "not an email channel name"},
+		{"host", func(c *config.EmailConfig) { c.Host = "" }, "no host"},
+		{"tls", func(c *config.EmailConfig) { c.TLS = "ssl" }, "unknown tls mode"},
+		{"port", func(c *config.EmailConfig) { c.Port = 70000 }, "invalid port"},
+		{"from", func(c *config.EmailConfig) { c.From = "auto" }, "invalid from address"},
+		{"to", func(c *config.EmailConfig) { c.To = nil }, "no recipients"},
+		{"bad to", func(c *config.EmailConfig) { c.To = []string{"ops@"} }, "invalid to address"},
+		{"digest", func(c *config.EmailConfig) { c.Digest = -time.Minute }, "negative digest"},
+	}
+	for _, tt := range tests {
+		cfg := valid
+		tt.edit(&cfg)
+		if _, err := NewEmailChannel(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NewEmailChannel() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	ch, err := NewEmailChannel(valid)
+	if err != nil || ch.addr != "smtp.example.com:587" || ch.security != emailSTARTTLS {
+		t.Errorf("NewEmailChannel() = %+v, %v", ch, err)
+	}
+
+	err = ValidateConfig(&config.AlertsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "a", URL: "https://example.com"}},
+		Emails:   []config.EmailConfig{valid},
+	})
+	if err == nil || !strings.Contains(err.Error(), "used twice") {
+		t.Errorf("ValidateConfig() error = %v, want the duplicate name", err)
+	}
+}
@@ -0,0 +1,530 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/store"
+)
+
+const (
+	// sendTimeout bounds a single attempt to send to a remote channel
+	sendTimeout = 30 * time.Second
+	// idleWait is how long a worker sleeps with nothing queued, or when it
+	// cannot read its queue
+	idleWait = time.Minute
+	// claimLease is how long a worker holds the deliveries it claimed to
+	// send them before another process sharing the store may claim them
+	claimLease = 2 * sendTimeout
+	// digestBatch is how many due deliveries a digest holds at most
+	digestBatch = 500
+)
+
+// remote is implemented by channels that send over the network. Alerts to
+// them are queued in the outbox and sent by a worker per channel, which
+// retries failed sends, so an outage neither drops alerts nor holds up
+// event processing.
+type remote interface {
+	Channel
+	remote()
+}
+
+// digester is implemented by remote channels that can send the alerts
+// queued over a window as one message
+type digester interface {
+	remote
+	digestWindow() time.Duration // 0 sends alerts one by one
+	SendDigest(ctx context.Context, alerts []*Alert) error
+}
+
+// Delivery is the state of an alert's delivery to a remote channel
+type Delivery struct {
+	ID          int64     `json:"id"`
+	AlertID     string    `json:"alert_id"`
+	Channel     string    `json:"channel"`
+	Level       Level     `json:"level"`
+	Title       string    `json:"title"`
+	Status      string    `json:"status"` // pending, sent or failed
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Updated     time.Time `json:"updated"`
+}
+
+// queue holds deliveries: the store, or memory without one
+type queue interface {
+	SaveDelivery(rec *store.DeliveryRecord) error
+	UpdateDelivery(rec *store.DeliveryRecord) error
+	ClaimDeliveries(channel string, now, until time.Time, limit int) ([]*store.DeliveryRecord, error)
+	NextDelivery(channel string) (time.Time, bool, error)
+	ListDeliveries(status string, limit int) ([]*store.DeliveryRecord, error)
+}
+
+// payload is an alert as queued. Alerts queued by an earlier run are sent
+// from it, without their agent.
+type payload struct {
+	ID        string    `json:"id"`
+	Level     Level     `json:"level"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	AgentID   string    `json:"agent_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Rule      string    `json:"rule,omitempty"`
+}
+
+// outbox sends queued alerts to remote channels
+type outbox struct {
+	queue  queue
+	cfg    config.DeliveryConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	wake   map[string]chan struct{} // channel name -> worker wake-up; set before workers start
+
+	mu      sync.Mutex
+	alerts  map[int64]*Alert     // delivery ID -> alert, for deliveries queued by this run
+	digests map[string]time.Time // channel name -> when its open digest is sent
+}
+
+func newOutbox(q queue, cfg config.DeliveryConfig) *outbox {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &outbox{
+		queue:   q,
+		cfg:     cfg,
+		ctx:     ctx,
+		cancel:  cancel,
+		wake:    make(map[string]chan struct{}),
+		alerts:  make(map[int64]*Alert),
+		digests: make(map[string]time.Time),
+	}
+}
+
+// start runs the worker of a remote channel, which also sends what an
+// earlier run left queued for it
+func (o *outbox) start(ch Channel) {
+	wake := make(chan struct{}, 1)
+	o.wake[ch.Name()] = wake
+	o.wg.Add(1)
+	go o.run(ch, wake)
+}
+
+// failRemoved fails the deliveries an earlier run left pending for
+// channels that are no longer configured, since no worker sends them
+func (o *outbox) failRemoved() {
+	pending, err := o.queue.ListDeliveries(store.DeliveryPending, 0)
+	if err != nil {
+		log.Printf("Failed to read queued alerts: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, rec := range pending {
+		if _, ok := o.wake[rec.Channel]; ok {
+			continue
+		}
+		rec.Status = store.DeliveryFailed
+		rec.LastError = "channel removed"
+		rec.UpdatedAt = now
+		if err := o.queue.UpdateDelivery(rec); err != nil {
+			log.Printf("Failed to record %s delivery: %v", rec.Channel, err)
+			return
+		}
+		log.Printf("Giving up sending alert %s to %s: the channel is no longer configured", rec.AlertID, rec.Channel)
+	}
+}
+
+// close stops the workers. Queued deliveries stay pending in the store and
+// are sent on the next start.
+func (o *outbox) close() {
+	o.cancel()
+	o.wg.Wait()
+}
+
+// enqueue queues an alert for a remote channel and wakes its worker
+func (o *outbox) enqueue(ch Channel, a *Alert) error {
+	data, err := json.Marshal(payload{
+		ID:        a.ID,
+		Level:     a.Level,
+		Title:     a.Title,
+		Message:   a.Message,
+		AgentID:   a.AgentID,
+		Timestamp: a.Timestamp,
+		Rule:      a.Rule,
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rec := &store.DeliveryRecord{
+		AlertID:     a.ID,
+		Channel:     ch.Name(),
+		Payload:     string(data),
+		Status:      store.DeliveryPending,
+		NextAttempt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// Hold the lock until the alert is known, so the worker cannot send
+	// the delivery without it
+	o.mu.Lock()
+	if d, ok := ch.(digester); ok && d.digestWindow() > 0 {
+		// The first alert after a digest is sent opens the next one
+		if at := o.digests[ch.Name()]; at.After(now) {
+			rec.NextAttempt = at
+		} else {
+			rec.NextAttempt = now.Add(d.digestWindow())
+			o.digests[ch.Name()] = rec.NextAttempt
+		}
+	}
+	err = o.queue.SaveDelivery(rec)
+	if err == nil {
+		o.alerts[rec.ID] = a
+	}
+	o.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to queue alert for %s: %w", ch.Name(), err)
+	}
+
+	select {
+	case o.wake[ch.Name()] <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run sends the deliveries of a channel as they become due
+func (o *outbox) run(ch Channel, wake <-chan struct{}) {
+	defer o.wg.Done()
+	for {
+		timer := time.NewTimer(o.sendDue(ch))
+		select {
+		case <-wake:
+		case <-timer.C:
+		case <-o.ctx.Done():
+		}
+		timer.Stop()
+		if o.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// sendDue attempts the due deliveries of a channel and returns how long
+// until the next one is due
+func (o *outbox) sendDue(ch Channel) time.Duration {
+	d, digest := ch.(digester)
+	digest = digest && d.digestWindow() > 0
+	// Claim deliveries one at a time, or a digest's worth at once, so
+	// each claim covers a single send
+	limit := 1
+	if digest {
+		limit = digestBatch
+	}
+
+	for o.ctx.Err() == nil {
+		now := time.Now()
+		due, err := o.queue.ClaimDeliveries(ch.Name(), now, now.Add(claimLease), limit)
+		if err != nil {
+			log.Printf("Failed to read queued %s alerts: %v", ch.Name(), err)
+			return idleWait
+		}
+		if len(due) == 0 {
+			break
+		}
+		if digest {
+			err = o.attemptDigest(d, due)
+		} else {
+			for _, rec := range due {
+				if err = o.attempt(ch, rec); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to record %s delivery: %v", ch.Name(), err)
+			return idleWait
+		}
+	}
+
+	next, ok, err := o.queue.NextDelivery(ch.Name())
+	if err != nil || !ok {
+		return idleWait
+	}
+	return max(time.Until(next), 0)
+}
+
+// attempt sends a delivery once and records the outcome
+func (o *outbox) attempt(ch Channel, rec *store.DeliveryRecord) error {
+	a, err := o.alert(rec)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(o.ctx, sendTimeout)
+		err = ch.Send(ctx, a)
+		cancel()
+		if err != nil && o.ctx.Err() != nil {
+			// Closing; the delivery is attempted again on the next start,
+			// once its claim has run out
+			return nil
+		}
+	}
+	return o.record(ch, rec, err, time.Now())
+}
+
+// attemptDigest sends due deliveries as one digest and records the
+// outcome of each. They are retried together, so they stay one digest.
+func (o *outbox) attemptDigest(ch digester, recs []*store.DeliveryRecord) error {
+	var alerts []*Alert
+	var batch []*store.DeliveryRecord
+	for _, rec := range recs {
+		a, err := o.alert(rec)
+		if err != nil {
+			if err := o.record(ch, rec, err, time.Now()); err != nil {
+				return err
+			}
+			continue
+		}
+		alerts = append(alerts, a)
+		batch = append(batch, rec)
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(o.ctx, sendTimeout)
+	err := ch.SendDigest(ctx, alerts)
+	cancel()
+	if err != nil && o.ctx.Err() != nil {
+		return nil
+	}
+	now := time.Now()
+	for _, rec := range batch {
+		if err := o.record(ch, rec, err, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record saves the outcome of an attempt to send a delivery: sent,
+// retried after a backoff, or failed once out of attempts
+func (o *outbox) record(ch Channel, rec *store.DeliveryRecord, err error, now time.Time) error {
+	retry := o.retry(ch.Name())
+	rec.Attempts++
+	rec.UpdatedAt = now
+	switch {
+	case err == nil:
+		rec.Status = store.DeliverySent
+		rec.LastError = ""
+	case rec.Attempts >= retry.MaxAttempts:
+		rec.Status = store.DeliveryFailed
+		rec.LastError = err.Error()
+		log.Printf("Giving up sending alert %s to %s after %d attempts: %v", rec.AlertID, ch.Name(), rec.Attempts, err)
+	default:
+		rec.LastError = err.Error()
+		rec.NextAttempt = rec.UpdatedAt.Add(backoff(retry, rec.Attempts))
+	}
+	if rec.Status != store.DeliveryPending {
+		o.mu.Lock()
+		delete(o.alerts, rec.ID)
+		o.mu.Unlock()
+	}
+	return o.queue.UpdateDelivery(rec)
+}
+
+// alert returns the alert of a delivery, decoding it when it was queued
+// by an earlier run
+func (o *outbox) alert(rec *store.DeliveryRecord) (*Alert, error) {
+	o.mu.Lock()
+	a, ok := o.alerts[rec.ID]
+	o.mu.Unlock()
+	if ok {
+		return a, nil
+	}
+	var p payload
+	if err := json.Unmarshal([]byte(rec.Payload), &p); err != nil {
+		return nil, fmt.Errorf("invalid queued alert: %w", err)
+	}
+	return &Alert{
+		ID:        p.ID,
+		Level:     p.Level,
+		Title:     p.Title,
+		Message:   p.Message,
+		AgentID:   p.AgentID,
+		Timestamp: p.Timestamp,
+		Rule:      p.Rule,
+	}, nil
+}
+
+// retry returns the retry settings of a channel, filling unset ones from
+// the defaults
+func (o *outbox) retry(channel string) config.RetryConfig {
+	r := o.cfg.RetryConfig
+	if c, ok := o.cfg.Channels[channel]; ok {
+		if c.MaxAttempts > 0 {
+			r.MaxAttempts = c.MaxAttempts
+		}
+		if c.Backoff > 0 {
+			r.Backoff = c.Backoff
+		}
+		if c.MaxBackoff > 0 {
+			r.MaxBackoff = c.MaxBackoff
+		}
+	}
+	defaults := config.DefaultConfig().Alerts.Delivery.RetryConfig
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = defaults.MaxAttempts
+	}
+	if r.Backoff <= 0 {
+		r.Backoff = defaults.Backoff
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = defaults.MaxBackoff
+	}
+	return r
+}
+
+// backoff returns how long to wait after a number of failed attempts
+func backoff(r config.RetryConfig, attempts int) time.Duration {
+	d := r.Backoff
+	for i := 1; i < attempts && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.MaxBackoff)
+}
+
+// validateDelivery checks that delivery overrides are for remote channels
+func validateDelivery(cfg config.DeliveryConfig, channels []string) error {
+	for name := range cfg.Channels {
+		if name == "desktop" || !slices.Contains(channels, name) {
+			return fmt.Errorf("delivery: %q is not a remote channel", name)
+		}
+	}
+	return nil
+}
+
+// Deliveries lists the deliveries to remote channels with a status, or all
+// of them, most recently updated first
+func (m *Manager) Deliveries(status string, limit int) ([]Delivery, error) {
+	records, err := m.outbox.queue.ListDeliveries(status, limit)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]Delivery, 0, len(records))
+	for _, rec := range records {
+		d := Delivery{
+			ID:          rec.ID,
+			AlertID:     rec.AlertID,
+			Channel:     rec.Channel,
+			Status:      rec.Status,
+			Attempts:    rec.Attempts,
+			LastError:   rec.LastError,
+			NextAttempt: rec.NextAttempt,
+			Updated:     rec.UpdatedAt,
+		}
+		var p payload
+		if json.Unmarshal([]byte(rec.Payload), &p) == nil {
+			d.Level = p.Level
+			d.Title = p.Title
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// memQueue holds deliveries in memory when there is no store. Pending
+// deliveries are lost when AUTO exits.
+type memQueue struct {
+	mu      sync.Mutex
+	records []store.DeliveryRecord
+	nextID  int64
+}
+
+// memQueueSize bounds how many deliveries memory holds, dropping the
+// oldest sent and failed ones first
+const memQueueSize = 1000
+
+func (q *memQueue) SaveDelivery(rec *store.DeliveryRecord) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	rec.ID = q.nextID
+	q.records = append(q.records, *rec)
+	if len(q.records) > memQueueSize {
+		if i := slices.IndexFunc(q.records, func(r store.DeliveryRecord) bool { return r.Status != store.DeliveryPending }); i >= 0 {
+			q.records = slices.Delete(q.records, i, i+1)
+		}
+	}
+	return nil
+}
+
+func (q *memQueue) UpdateDelivery(rec *store.DeliveryRecord) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.records {
+		if q.records[i].ID == rec.ID {
+			q.records[i] = *rec
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *memQueue) ClaimDeliveries(channel string, now, until time.Time, limit int) ([]*store.DeliveryRecord, error) {
+	due := q.list(func(r store.DeliveryRecord) bool {
+		return r.Channel == channel && r.Status == store.DeliveryPending && !r.NextAttempt.After(now)
+	})
+	slices.SortStableFunc(due, func(a, b *store.DeliveryRecord) int { return a.NextAttempt.Compare(b.NextAttempt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, rec := range due {
+		rec.NextAttempt = until
+		q.UpdateDelivery(rec)
+	}
+	return due, nil
+}
+
+func (q *memQueue) NextDelivery(channel string) (time.Time, bool, error) {
+	pending := q.list(func(r store.DeliveryRecord) bool {
+		return r.Channel == channel && r.Status == store.DeliveryPending
+	})
+	if len(pending) == 0 {
+		return time.Time{}, false, nil
+	}
+	next := pending[0].NextAttempt
+	for _, r := range pending[1:] {
+		if r.NextAttempt.Before(next) {
+			next = r.NextAttempt
+		}
+	}
+	return next, true, nil
+}
+
+func (q *memQueue) ListDeliveries(status string, limit int) ([]*store.DeliveryRecord, error) {
+	records := q.list(func(r store.DeliveryRecord) bool { return status == "" || r.Status == status })
+	slices.Reverse(records)
+	slices.SortStableFunc(records, func(a, b *store.DeliveryRecord) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// list returns copies of the records that match
+func (q *memQueue) list(match func(store.DeliveryRecord) bool) []*store.DeliveryRecord {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var records []*store.DeliveryRecord
+	for _, r := range q.records {
+		if match(r) {
+			rec := r
+			records = append(records, &rec)
+		}
+	}
+	return records
+}
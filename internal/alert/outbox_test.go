@@ -0,0 +1,218 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/store"
+)
+
+// webhookServer records the titles posted to it, failing requests when
+// fail returns true
+func webhookServer(t *testing.T, fail func(n int64) bool) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var titles []string
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail(n.Add(1)) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Embeds      []struct{ Title string } `json:"embeds"`
+			Attachments []struct{ Title string } `json:"attachments"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range append(body.Embeds, body.Attachments...) {
+			titles = append(titles, e.Title)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), titles...)
+	}
+}
+
+// waitDeliveries waits until a manager has n deliveries with a status
+func waitDeliveries(t *testing.T, m *Manager, status string, n int) []Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d, err := m.Deliveries(status, 0)
+		if err != nil {
+			t.Fatalf("Deliveries() error = %v", err)
+		}
+		if len(d) >= n || time.Now().After(deadline) {
+			if len(d) != n {
+				t.Fatalf("got %d %s deliveries, want %d: %+v", len(d), status, n, d)
+			}
+			return d
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOutboxRetries(t *testing.T) {
+	for _, persist := range []bool{true, false} {
+		var st *store.Store
+		if persist {
+			var err error
+			if st, err = store.New(filepath.Join(t.TempDir(), "auto.db")); err != nil {
+				t.Fatal(err)
+			}
+			defer st.Close()
+		}
+
+		// Discord fails every other request, Slack is down
+		discord, discordTitles := webhookServer(t, func(n int64) bool { return n%2 == 1 })
+		slack, _ := webhookServer(t, func(int64) bool { return true })
+		m := NewManager(&config.AlertsConfig{
+			DiscordEnabled:    true,
+			DiscordWebhookURL: discord.URL,
+			SlackEnabled:      true,
+			SlackWebhookURL:   slack.URL,
+			Delivery: config.DeliveryConfig{
+				RetryConfig: config.RetryConfig{MaxAttempts: 4, Backoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond},
+				Channels:    map[string]config.RetryConfig{"slack": {MaxAttempts: 2}},
+			},
+		}, st)
+
+		for _, title := range []string{"one", "two", "three"} {
+			if err := m.Send(context.Background(), &Alert{Level: LevelError, Title: title}); err != nil {
+				t.Fatalf("Send() error = %v, want slack's outage not to fail it", err)
+			}
+		}
+
+		sent := waitDeliveries(t, m, store.DeliverySent, 3)
+		for _, d := range sent {
+			if d.Channel != "discord" || d.LastError != "" {
+				t.Errorf("sent delivery = %+v", d)
+			}
+		}
+		got := discordTitles()
+		slices.Sort(got)
+		if strings.Join(got, ",") != "one,three,two" {
+			t.Errorf("discord got %q, want each alert once", got)
+		}
+
+		failed := waitDeliveries(t, m, store.DeliveryFailed, 3)
+		for _, d := range failed {
+			if d.Channel != "slack" || d.Attempts != 2 || !strings.Contains(d.LastError, "503") || d.Title == "" {
+				t.Errorf("failed delivery = %+v", d)
+			}
+		}
+		m.Close()
+	}
+}
+
+func TestOutboxResumesAfterRestart(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "auto.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	// Deliveries left pending by an earlier run, one of them to a channel
+	// that has since been removed
+	data, _ := json.Marshal(payload{ID: "alert-1", Level: LevelWarning, Title: "left over"})
+	now := time.Now()
+	for _, channel := range []string{"discord", "old-hook"} {
+		st.SaveDelivery(&store.DeliveryRecord{
+			AlertID:     "alert-1",
+			Channel:     channel,
+			Payload:     string(data),
+			Status:      store.DeliveryPending,
+			Attempts:    1,
+			NextAttempt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	discord, titles := webhookServer(t, func(int64) bool { return false })
+	m := NewManager(&config.AlertsConfig{DiscordEnabled: true, DiscordWebhookURL: discord.URL}, st)
+	defer m.Close()
+
+	sent := waitDeliveries(t, m, store.DeliverySent, 1)
+	if sent[0].Attempts != 2 || sent[0].Level != LevelWarning {
+		t.Errorf("sent delivery = %+v", sent[0])
+	}
+	if got := titles(); len(got) != 1 || got[0] != "left over" {
+		t.Errorf("discord got %v", got)
+	}
+	failed := waitDeliveries(t, m, store.DeliveryFailed, 1)
+	if failed[0].Channel != "old-hook" || failed[0].LastError != "channel removed" {
+		t.Errorf("failed delivery = %+v", failed[0])
+	}
+}
+
+func TestOutboxSharedStore(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "auto.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		data, _ := json.Marshal(payload{ID: "alert", Level: LevelInfo, Title: strconv.Itoa(i)})
+		st.SaveDelivery(&store.DeliveryRecord{
+			AlertID:     "alert",
+			Channel:     "discord",
+			Payload:     string(data),
+			Status:      store.DeliveryPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	// Two processes on one store, such as a daemon and a standalone TUI
+	discord, titles := webhookServer(t, func(int64) bool { return false })
+	cfg := &config.AlertsConfig{DiscordEnabled: true, DiscordWebhookURL: discord.URL}
+	a, b := NewManager(cfg, st), NewManager(cfg, st)
+	defer a.Close()
+	defer b.Close()
+
+	waitDeliveries(t, a, store.DeliverySent, 20)
+	got := titles()
+	slices.Sort(got)
+	if len(slices.Compact(got)) != len(got) {
+		t.Errorf("discord got %v, want each alert once", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := config.RetryConfig{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+		if got := backoff(r, attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+
+	o := newOutbox(&memQueue{}, config.DeliveryConfig{
+		RetryConfig: config.RetryConfig{MaxAttempts: 3},
+		Channels:    map[string]config.RetryConfig{"slack": {Backoff: time.Second}},
+	})
+	if r := o.retry("slack"); r.MaxAttempts != 3 || r.Backoff != time.Second || r.MaxBackoff != 30*time.Minute {
+		t.Errorf("retry(slack) = %+v, want the override over the defaults", r)
+	}
+	if err := validateDelivery(config.DeliveryConfig{Channels: map[string]config.RetryConfig{"desktop": {}}}, builtinChannels); err == nil {
+		t.Error("validateDelivery() should reject desktop overrides")
+	}
+}
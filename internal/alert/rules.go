@@ -0,0 +1,384 @@
+package alert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"text/template"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// outputTail is how much recent output rules with an output pattern see
+const outputTail = 64 * 1024
+
+// DefaultRules apply when no rules are configured
+var DefaultRules = []config.AlertRule{
+	{
+		Name:    "errored",
+		Events:  []string{"errored"},
+		Level:   string(LevelError),
+		Title:   "Agent Error",
+		Message: "Agent {{.Name}} encountered an error{{with .Error}}: {{.}}{{end}}",
+	},
+	{
+		Name:    "completed",
+		Events:  []string{"completed"},
+		Level:   string(LevelSuccess),
+		Title:   "Agent Completed",
+		Message: "Agent {{.Name}} completed its task",
+	},
+	{
+		Name:   "context_limit",
+		Events: []string{"context_limit"},
+		Level:  string(LevelWarning),
+		Title:  `{{if eq .Status "context_limit"}}Context Limit Reached{{else}}Context Limit Warning{{end}}`,
+		Message: `{{if eq .Status "context_limit"}}Agent {{.Name}} has run out of context` +
+			`{{else if .Context}}Agent {{.Name}} is at {{.Context}}% of its context window` +
+			`{{else}}Agent {{.Name}} is approaching context limit{{end}}`,
+	},
+}
+
+// Rule is a compiled alert rule
+type Rule struct {
+	cfg     config.AlertRule
+	name    string
+	events  []agent.EventType
+	from    []agent.Status
+	to      []agent.Status
+	output  *regexp.Regexp
+	level   Level
+	title   *template.Template
+	message *template.Template
+	dedup   *template.Template
+}
+
+// RuleData is what title and message templates are executed with
+type RuleData struct {
+	Rule           string
+	Event          string
+	AgentID        string
+	Name           string
+	Type           string
+	Project        string
+	Directory      string
+	Status         string
+	PreviousStatus string
+	Error          string
+	Task           string
+	Metrics        agent.Metrics
+	Context        int // percentage of the context window used
+}
+
+// CompileRule validates and compiles an alert rule
+func CompileRule(cfg config.AlertRule) (*Rule, error) {
+	r := &Rule{cfg: cfg, name: cfg.Name, level: LevelInfo}
+	for _, name := range cfg.Events {
+		et, ok := agent.ParseEventType(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+		r.events = append(r.events, et)
+	}
+	var err error
+	if r.from, err = parseStatuses(cfg.From); err != nil {
+		return nil, err
+	}
+	if r.to, err = parseStatuses(cfg.To); err != nil {
+		return nil, err
+	}
+	if cfg.Directory != "" {
+		if _, err := filepath.Match(cfg.Directory, ""); err != nil {
+			return nil, fmt.Errorf("invalid directory glob %q: %w", cfg.Directory, err)
+		}
+	}
+	if cfg.Output != "" {
+		if r.output, err = regexp.Compile(cfg.Output); err != nil {
+			return nil, fmt.Errorf("invalid output pattern %q: %w", cfg.Output, err)
+		}
+	}
+	if cfg.Level != "" {
+		r.level = Level(cfg.Level)
+		switch r.level {
+		case LevelInfo, LevelWarning, LevelError, LevelSuccess:
+		default:
+			return nil, fmt.Errorf("unknown level %q", cfg.Level)
+		}
+	}
+	if r.title, err = template.New("title").Option("missingkey=error").Parse(cfg.Title); err != nil {
+		return nil, fmt.Errorf("invalid title: %w", err)
+	}
+	if r.message, err = template.New("message").Option("missingkey=error").Parse(cfg.Message); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	if r.dedup, err = template.New("dedup_key").Option("missingkey=error").Parse(cfg.DedupKey); err != nil {
+		return nil, fmt.Errorf("invalid dedup key: %w", err)
+	}
+	// Catch fields RuleData does not have
+	if err := r.title.Execute(io.Discard, RuleData{}); err != nil {
+		return nil, fmt.Errorf("invalid title: %w", err)
+	}
+	if err := r.message.Execute(io.Discard, RuleData{}); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	if err := r.dedup.Execute(io.Discard, RuleData{}); err != nil {
+		return nil, fmt.Errorf("invalid dedup key: %w", err)
+	}
+	return r, nil
+}
+
+func parseStatuses(names []string) ([]agent.Status, error) {
+	var statuses []agent.Status
+	for _, name := range names {
+		st, ok := agent.ParseStatus(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown status %q", name)
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Name returns the rule name
+func (r *Rule) Name() string {
+	return r.name
+}
+
+// triggered reports whether the rule fires on events by itself. Rules
+// without event types or a status change fire when their conditions
+// become true for an agent instead.
+func (r *Rule) triggered() bool {
+	return len(r.events) > 0 || len(r.from) > 0 || len(r.to) > 0
+}
+
+// Match reports whether an event meets the rule's conditions, given the
+// agent's status before the event
+func (r *Rule) Match(e agent.Event, prev agent.Status) bool {
+	if len(r.events) > 0 && !slices.Contains(r.events, e.Type) {
+		return false
+	}
+	a := e.Agent
+	if a == nil {
+		// Without an agent only the event type can be checked
+		return len(r.from) == 0 && len(r.to) == 0 && r.filtersOnlyEvents()
+	}
+	if len(r.from) > 0 || len(r.to) > 0 {
+		status := a.Status()
+		if status == prev {
+			return false
+		}
+		if len(r.from) > 0 && !slices.Contains(r.from, prev) {
+			return false
+		}
+		if len(r.to) > 0 && !slices.Contains(r.to, status) {
+			return false
+		}
+	}
+	if len(r.cfg.AgentTypes) > 0 && !slices.Contains(r.cfg.AgentTypes, a.Type()) {
+		return false
+	}
+	if len(r.cfg.Projects) > 0 && !slices.Contains(r.cfg.Projects, a.ProjectID()) {
+		return false
+	}
+	if r.cfg.Directory != "" && !matchDirectory(r.cfg.Directory, a.Directory()) {
+		return false
+	}
+	m := a.Metrics()
+	if m.EstimatedCost < r.cfg.MinCost || m.TokensIn+m.TokensOut < r.cfg.MinTokens || m.ErrorCount < r.cfg.MinErrors {
+		return false
+	}
+	if r.output != nil && !r.output.Match(recentOutput(a)) {
+		return false
+	}
+	return true
+}
+
+// filtersOnlyEvents reports whether the rule has no agent conditions
+func (r *Rule) filtersOnlyEvents() bool {
+	c := r.cfg
+	return len(c.AgentTypes) == 0 && len(c.Projects) == 0 && c.Directory == "" &&
+		c.MinCost == 0 && c.MinTokens == 0 && c.MinErrors == 0 && r.output == nil
+}
+
+// matchDirectory reports whether a directory or one of its parents
+// matches a glob
+func matchDirectory(glob, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if ok, _ := filepath.Match(glob, d); ok {
+			return true
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return false
+		}
+	}
+}
+
+// recentOutput returns the end of an agent's output
+func recentOutput(a agent.Agent) []byte {
+	r := a.Output()
+	if r == nil {
+		return nil
+	}
+	data, _ := io.ReadAll(r)
+	if len(data) > outputTail {
+		data = data[len(data)-outputTail:]
+	}
+	return data
+}
+
+// Render builds the alert of an event the rule matched
+func (r *Rule) Render(e agent.Event, prev agent.Status) (*Alert, error) {
+	data := RuleData{Rule: r.name, Event: e.Type.String(), AgentID: e.AgentID}
+	if a := e.Agent; a != nil {
+		data.Name = a.Name()
+		data.Type = a.Type()
+		data.Project = a.ProjectID()
+		data.Directory = a.Directory()
+		data.Status = a.Status().String()
+		data.Task = a.CurrentTask()
+		data.Metrics = a.Metrics()
+		data.Context = int(data.Metrics.ContextUtilization * 100)
+		if err := a.LastError(); err != nil {
+			data.Error = err.Error()
+		}
+		if prev != a.Status() {
+			data.PreviousStatus = prev.String()
+		}
+	}
+	if data.Error == "" && e.Error != nil {
+		data.Error = e.Error.Error()
+	}
+
+	var title, message, dedup bytes.Buffer
+	if err := r.title.Execute(&title, data); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", r.name, err)
+	}
+	if err := r.message.Execute(&message, data); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", r.name, err)
+	}
+	if err := r.dedup.Execute(&dedup, data); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", r.name, err)
+	}
+	return &Alert{
+		Level:     r.level,
+		Title:     title.String(),
+		Message:   message.String(),
+		AgentID:   e.AgentID,
+		Agent:     e.Agent,
+		Timestamp: e.Timestamp,
+		Rule:      r.name,
+		Channels:  r.cfg.Channels,
+		DedupKey:  dedup.String(),
+	}, nil
+}
+
+// Engine turns agent events into alerts by a list of rules. Rules are
+// checked in order, and the first to raise an alert ends the check unless
+// it continues.
+type Engine struct {
+	rules []*Rule
+
+	mu       sync.Mutex
+	statuses map[string]agent.Status   // agent ID -> status at its last event
+	active   map[string]map[*Rule]bool // agent ID -> untriggered rules it matched last
+}
+
+// NewEngine compiles rules into an engine. With no rules, DefaultRules
+// are used.
+func NewEngine(rules []config.AlertRule) (*Engine, error) {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	e := &Engine{
+		statuses: make(map[string]agent.Status),
+		active:   make(map[string]map[*Rule]bool),
+	}
+	for i, cfg := range rules {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		r, err := CompileRule(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s: %w", cfg.Name, err)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// ValidateRules reports the first invalid rule, if any
+func ValidateRules(rules []config.AlertRule) error {
+	_, err := NewEngine(rules)
+	return err
+}
+
+// Evaluate returns the alerts an event raises
+func (e *Engine) Evaluate(event agent.Event) ([]*Alert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	prev, known := e.statuses[event.AgentID]
+	if event.Agent != nil {
+		if !known {
+			prev = event.Agent.Status()
+		}
+		e.statuses[event.AgentID] = event.Agent.Status()
+	}
+	if event.Type == agent.EventAgentTerminated {
+		delete(e.statuses, event.AgentID)
+		delete(e.active, event.AgentID)
+	}
+
+	var alerts []*Alert
+	var errs []error
+	done := false
+	for _, r := range e.rules {
+		if done && r.triggered() {
+			continue
+		}
+		matched := r.Match(event, prev)
+		raise := matched
+		if !r.triggered() {
+			// Fire once when the conditions become true, and again only
+			// after they stop being true
+			raise = matched && !e.swapActive(event, r, matched)
+		}
+		if done || !raise {
+			continue
+		}
+
+		a, err := r.Render(event, prev)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			alerts = append(alerts, a)
+		}
+		done = !r.cfg.Continue
+	}
+	return alerts, errors.Join(errs...)
+}
+
+// swapActive records whether an untriggered rule matched an agent's event
+// and returns whether it matched the agent's previous one
+func (e *Engine) swapActive(event agent.Event, r *Rule, matched bool) bool {
+	active := e.active[event.AgentID]
+	was := active[r]
+	if event.Agent == nil || event.Type == agent.EventAgentTerminated {
+		return was
+	}
+	if active == nil {
+		active = make(map[*Rule]bool)
+		e.active[event.AgentID] = active
+	}
+	active[r] = matched
+	return was
+}
@@ -0,0 +1,208 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// countingChannel records the alerts sent to it
+type countingChannel struct {
+	name string
+	sent []*Alert
+}
+
+func (c *countingChannel) Name() string { return c.name }
+
+func (c *countingChannel) Send(ctx context.Context, a *Alert) error {
+	c.sent = append(c.sent, a)
+	return nil
+}
+
+func TestRuleMatch(t *testing.T) {
+	a := agent.NewMockAgent("ses_1", "fixer")
+	a.MockType = "opencode"
+	a.MockProjectID = "api"
+	a.MockDirectory = "/work/api/cmd"
+	a.MockStatus = agent.StatusErrored
+	a.MockMetrics.EstimatedCost = 6
+	a.MockMetrics.ErrorCount = 2
+	a.MockOutput = []byte("retrying...\nError: rate limit exceeded\n")
+	event := agent.Event{Type: agent.EventAgentErrored, AgentID: a.ID(), Agent: a}
+
+	tests := []struct {
+		name string
+		rule config.AlertRule
+		prev agent.Status
+		want bool
+	}{
+		{"empty", config.AlertRule{}, agent.StatusErrored, true},
+		{"event", config.AlertRule{Events: []string{"completed", "errored"}}, agent.StatusRunning, true},
+		{"other event", config.AlertRule{Events: []string{"completed"}}, agent.StatusRunning, false},
+		{"transition", config.AlertRule{From: []string{"running"}, To: []string{"errored"}}, agent.StatusRunning, true},
+		{"other transition", config.AlertRule{From: []string{"idle"}}, agent.StatusRunning, false},
+		{"no transition", config.AlertRule{To: []string{"errored"}}, agent.StatusErrored, false},
+		{"agent type", config.AlertRule{AgentTypes: []string{"opencode"}}, agent.StatusRunning, true},
+		{"other agent type", config.AlertRule{AgentTypes: []string{"claude"}}, agent.StatusRunning, false},
+		{"project", config.AlertRule{Projects: []string{"web", "api"}}, agent.StatusRunning, true},
+		{"directory", config.AlertRule{Directory: "/work/*"}, agent.StatusRunning, true},
+		{"other directory", config.AlertRule{Directory: "/home/*"}, agent.StatusRunning, false},
+		{"cost", config.AlertRule{MinCost: 5}, agent.StatusRunning, true},
+		{"cost below", config.AlertRule{MinCost: 10}, agent.StatusRunning, false},
+		{"tokens", config.AlertRule{MinTokens: 1500}, agent.StatusRunning, true},
+		{"tokens below", config.AlertRule{MinTokens: 1501}, agent.StatusRunning, false},
+		{"errors", config.AlertRule{MinErrors: 3}, agent.StatusRunning, false},
+		{"output", config.AlertRule{Output: "(?i)rate limit"}, agent.StatusRunning, true},
+		{"other output", config.AlertRule{Output: "overloaded"}, agent.StatusRunning, false},
+	}
+	for _, tt := range tests {
+		r, err := CompileRule(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: CompileRule() error = %v", tt.name, err)
+		}
+		if got := r.Match(event, tt.prev); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileRuleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.AlertRule
+		want string
+	}{
+		{"event", config.AlertRule{Events: []string{"crashed"}}, "unknown event type"},
+		{"status", config.AlertRule{To: []string{"done"}}, "unknown status"},
+		{"directory", config.AlertRule{Directory: "/work/["}, "invalid directory glob"},
+		{"output", config.AlertRule{Output: "("}, "invalid output pattern"},
+		{"level", config.AlertRule{Level: "critical"}, "unknown level"},
+		{"template", config.AlertRule{Title: "{{.Name"}, "invalid title"},
+		{"field", config.AlertRule{Message: "{{.Cost}}"}, "invalid message"},
+	}
+	for _, tt := range tests {
+		if _, err := CompileRule(tt.rule); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: CompileRule() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	err := ValidateRules([]config.AlertRule{{Name: "ok"}, {Level: "loud"}})
+	if err == nil || !strings.Contains(err.Error(), "rule 2") {
+		t.Errorf("ValidateRules() error = %v, want it to name rule 2", err)
+	}
+}
+
+func TestEngineDefaultRules(t *testing.T) {
+	e, err := NewEngine(nil)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	a := agent.NewMockAgent("ses_1", "fixer")
+	a.MockMetrics.ContextUtilization = 0.85
+
+	alerts, _ := e.Evaluate(agent.Event{Type: agent.EventAgentContextLimit, AgentID: a.ID(), Agent: a})
+	if len(alerts) != 1 || alerts[0].Title != "Context Limit Warning" || alerts[0].Message != "Agent fixer is at 85% of its context window" {
+		t.Fatalf("context limit alerts = %+v", alerts)
+	}
+
+	a.MockStatus = agent.StatusErrored
+	a.MockLastError = errors.New("boom")
+	alerts, _ = e.Evaluate(agent.Event{Type: agent.EventAgentErrored, AgentID: a.ID(), Agent: a})
+	if len(alerts) != 1 || alerts[0].Level != LevelError || alerts[0].Message != "Agent fixer encountered an error: boom" || alerts[0].Rule != "errored" {
+		t.Fatalf("errored alerts = %+v", alerts)
+	}
+
+	if alerts, _ := e.Evaluate(agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a}); len(alerts) != 0 {
+		t.Errorf("updated event raised %+v", alerts)
+	}
+}
+
+func TestEngineOrderAndContinue(t *testing.T) {
+	e, err := NewEngine([]config.AlertRule{
+		{Name: "api", Events: []string{"errored"}, Projects: []string{"api"}, Title: "api {{.Event}}", Continue: true},
+		{Name: "first", Events: []string{"errored"}, Title: "first"},
+		{Name: "second", Events: []string{"errored"}, Title: "second"},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	a := agent.NewMockAgent("ses_1", "fixer")
+	a.MockProjectID = "api"
+
+	alerts, _ := e.Evaluate(agent.Event{Type: agent.EventAgentErrored, AgentID: a.ID(), Agent: a})
+	var titles []string
+	for _, al := range alerts {
+		titles = append(titles, al.Title)
+	}
+	if strings.Join(titles, ",") != "api errored,first" {
+		t.Errorf("titles = %v, want the continuing rule and the first match", titles)
+	}
+}
+
+func TestEngineTransitionsAndThresholds(t *testing.T) {
+	e, err := NewEngine([]config.AlertRule{
+		{Name: "stopped", From: []string{"running"}, To: []string{"idle"}, Title: "{{.Name}} went {{.PreviousStatus}} -> {{.Status}}"},
+		{Name: "expensive", MinCost: 5, Level: "warning", Title: "{{.Name}} spent ${{printf \"%.2f\" .Metrics.EstimatedCost}}"},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	a := agent.NewMockAgent("ses_1", "fixer")
+	update := func() []string {
+		alerts, err := e.Evaluate(agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a})
+		if err != nil {
+			t.Fatalf("Evaluate() error = %v", err)
+		}
+		var titles []string
+		for _, al := range alerts {
+			titles = append(titles, al.Title)
+		}
+		return titles
+	}
+
+	if got := update(); len(got) != 0 {
+		t.Errorf("first sight raised %v", got)
+	}
+	a.MockStatus = agent.StatusIdle
+	if got := update(); len(got) != 1 || got[0] != "fixer went running -> idle" {
+		t.Errorf("transition raised %v", got)
+	}
+	if got := update(); len(got) != 0 {
+		t.Errorf("unchanged status raised %v", got)
+	}
+
+	// A threshold without events fires when it is crossed, not on every update
+	a.MockMetrics.EstimatedCost = 5.5
+	if got := update(); len(got) != 1 || got[0] != "fixer spent $5.50" {
+		t.Errorf("crossing the threshold raised %v", got)
+	}
+	a.MockMetrics.EstimatedCost = 7
+	if got := update(); len(got) != 0 {
+		t.Errorf("staying above the threshold raised %v", got)
+	}
+}
+
+func TestManagerRoutesRuleAlerts(t *testing.T) {
+	m := NewManager(&config.AlertsConfig{Rules: []config.AlertRule{
+		{Events: []string{"errored"}, Level: "error", Title: "failed", Channels: []string{"slack"}},
+		{Events: []string{"completed"}, Title: "done"},
+	}}, nil)
+	slack := &countingChannel{name: "slack"}
+	desktop := &countingChannel{name: "desktop"}
+	m.channels = []Channel{desktop, slack}
+
+	a := agent.NewMockAgent("ses_1", "fixer")
+	m.SendAgentEvent(context.Background(), agent.Event{Type: agent.EventAgentErrored, AgentID: a.ID(), Agent: a})
+	m.SendAgentEvent(context.Background(), agent.Event{Type: agent.EventAgentCompleted, AgentID: a.ID(), Agent: a})
+
+	if len(slack.sent) != 2 || len(desktop.sent) != 1 || desktop.sent[0].Title != "done" {
+		t.Errorf("slack got %d alerts, desktop got %d; want 2 and 1", len(slack.sent), len(desktop.sent))
+	}
+	if n := len(m.List(0, false)); n != 2 {
+		t.Errorf("List() = %d alerts, want 2", n)
+	}
+}
@@ -0,0 +1,426 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// Reasons an alert was suppressed
+const (
+	SuppressedDuplicate = "duplicate"
+	SuppressedRateLimit = "rate limited"
+	SuppressedSnoozed   = "snoozed"
+	SuppressedQuiet     = "quiet hours"          // dropped by downgrading quiet hours
+	SuppressedHeld      = "held for quiet hours" // sent in the summary when they end
+)
+
+// Snooze silences the alerts of an agent or a project until a time
+type Snooze struct {
+	AgentID string    `json:"agent_id,omitempty"`
+	Project string    `json:"project,omitempty"`
+	Name    string    `json:"name,omitempty"` // what is snoozed, for display
+	Until   time.Time `json:"until"`
+}
+
+// matches reports whether the snooze covers an alert
+func (s Snooze) matches(a *Alert) bool {
+	if s.AgentID != "" {
+		return s.AgentID == a.AgentID
+	}
+	return s.Project != "" && a.Agent != nil && a.Agent.ProjectID() == s.Project
+}
+
+// limits is the state alerts are suppressed, throttled and batched by
+type limits struct {
+	mu       sync.Mutex
+	quiet    []quietHours
+	sent     map[string]time.Time   // dedup key -> when an alert with it was last sent
+	agents   map[string][]time.Time // agent ID -> when its recent alerts were sent
+	channels map[string][]time.Time // channel name -> when its recent alerts were sent
+	snoozes  []Snooze
+	batches  map[string]*batch // channel name -> alerts held for quiet hours
+}
+
+func newLimits(quiet []quietHours) *limits {
+	return &limits{
+		quiet:    quiet,
+		sent:     make(map[string]time.Time),
+		agents:   make(map[string][]time.Time),
+		channels: make(map[string][]time.Time),
+		batches:  make(map[string]*batch),
+	}
+}
+
+// batch holds the alerts for a channel until its quiet hours end
+type batch struct {
+	alerts []*Alert
+	timer  *time.Timer
+}
+
+// delivery is an alert as it is sent to a channel
+type delivery struct {
+	ch    Channel
+	alert *Alert
+}
+
+// Snooze silences the alerts of an agent or project until s.Until,
+// replacing an earlier snooze of it. A zero or past Until ends the snooze.
+func (m *Manager) Snooze(s Snooze) {
+	l := m.limits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.snoozes = slices.DeleteFunc(l.snoozes, func(o Snooze) bool {
+		return o.AgentID == s.AgentID && o.Project == s.Project
+	})
+	if s.Until.After(m.now()) {
+		l.snoozes = append(l.snoozes, s)
+	}
+}
+
+// Snoozes returns the snoozes in effect, ending soonest first
+func (m *Manager) Snoozes() []Snooze {
+	l := m.limits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := m.now()
+	l.snoozes = slices.DeleteFunc(l.snoozes, func(s Snooze) bool { return !s.Until.After(now) })
+	snoozes := slices.Clone(l.snoozes)
+	sort.Slice(snoozes, func(i, j int) bool { return snoozes[i].Until.Before(snoozes[j].Until) })
+	return snoozes
+}
+
+// plan decides which channels an alert is sent to, and how. An alert
+// sent nowhere is marked suppressed, and read so it does not count as
+// unread; one sent to some of its channels lists why it skipped others.
+// Only channels the alert goes out to count toward their rate limit, and
+// only alerts that go out, now or in a summary, toward dedup and the
+// agent's rate limit.
+func (m *Manager) plan(a *Alert, now time.Time) []delivery {
+	l := m.limits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if a.DedupKey == "" {
+		a.DedupKey = fmt.Sprintf("%s|%s|%s", a.Level, a.Title, a.AgentID)
+	}
+	if a.Suppressed == "" {
+		a.Suppressed = m.suppressed(a, now)
+	}
+	if a.Suppressed != "" {
+		a.Read = true
+		return nil
+	}
+
+	var deliveries []delivery
+	skipped := make(map[string]string)
+	targeted, limited, held := 0, 0, 0
+	for _, ch := range m.channels {
+		name := ch.Name()
+		if len(a.Channels) > 0 && !slices.Contains(a.Channels, name) {
+			continue
+		}
+		targeted++
+		if max := m.cfg.RateLimit.PerChannel; max > 0 {
+			if l.channels[name] = recent(l.channels[name], now, m.cfg.RateLimit.Window); len(l.channels[name]) >= max {
+				limited++
+				skipped[name] = SuppressedRateLimit
+				continue
+			}
+		}
+
+		q := l.quietFor(name, now)
+		switch {
+		case q == nil:
+			deliveries = append(deliveries, delivery{ch, a})
+		case q.action == config.QuietBatch:
+			// The summary counts toward the channel's rate limit when sent
+			m.hold(ch, a, q.until(now).Sub(now))
+			held++
+			skipped[name] = SuppressedHeld
+			continue
+		default:
+			d := downgrade(a)
+			if d == nil {
+				skipped[name] = SuppressedQuiet
+				continue
+			}
+			deliveries = append(deliveries, delivery{ch, d})
+		}
+		l.channels[name] = append(l.channels[name], now)
+	}
+	if targeted > 0 && len(deliveries) == 0 {
+		switch {
+		case limited == targeted:
+			a.Suppressed = SuppressedRateLimit
+		case held > 0:
+			a.Suppressed = SuppressedHeld
+		default:
+			a.Suppressed = SuppressedQuiet
+		}
+		a.Read = true
+		if held == 0 {
+			return nil
+		}
+	} else if len(skipped) > 0 {
+		a.Skipped = skipped
+	}
+
+	l.sent[a.DedupKey] = now
+	if a.AgentID != "" && m.cfg.RateLimit.PerAgent > 0 {
+		l.agents[a.AgentID] = append(l.agents[a.AgentID], now)
+	}
+	return deliveries
+}
+
+// suppressed returns why an alert is not sent at all, if it is not
+func (m *Manager) suppressed(a *Alert, now time.Time) string {
+	l := m.limits
+	for _, s := range l.snoozes {
+		if s.Until.After(now) && s.matches(a) {
+			return SuppressedSnoozed
+		}
+	}
+
+	if window := m.cfg.DedupWindow; window > 0 {
+		if last, ok := l.sent[a.DedupKey]; ok && now.Sub(last) < window {
+			return SuppressedDuplicate
+		}
+		if len(l.sent) > 1000 {
+			for key, t := range l.sent {
+				if now.Sub(t) >= window {
+					delete(l.sent, key)
+				}
+			}
+		}
+	}
+
+	if max := m.cfg.RateLimit.PerAgent; max > 0 && a.AgentID != "" {
+		sent := recent(l.agents[a.AgentID], now, m.cfg.RateLimit.Window)
+		if len(sent) == 0 {
+			delete(l.agents, a.AgentID)
+		} else {
+			l.agents[a.AgentID] = sent
+		}
+		if len(sent) >= max {
+			return SuppressedRateLimit
+		}
+	}
+	return ""
+}
+
+// recent drops the times that are not within window of now
+func recent(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= window {
+		i++
+	}
+	return times[i:]
+}
+
+// downgrade returns a copy of an alert one level lower, or nil for info
+// and success alerts, which quiet hours drop
+func downgrade(a *Alert) *Alert {
+	d := *a
+	switch a.Level {
+	case LevelError:
+		d.Level = LevelWarning
+	case LevelWarning:
+		d.Level = LevelInfo
+	default:
+		return nil
+	}
+	return &d
+}
+
+// hold adds an alert to the batch of a channel, which is sent after wait
+func (m *Manager) hold(ch Channel, a *Alert, wait time.Duration) {
+	l := m.limits
+	b, ok := l.batches[ch.Name()]
+	if !ok {
+		b = &batch{}
+		b.timer = time.AfterFunc(wait, func() { m.flush(ch) })
+		l.batches[ch.Name()] = b
+	}
+	b.alerts = append(b.alerts, a)
+}
+
+// flush sends the batch of a channel as a single summary alert
+func (m *Manager) flush(ch Channel) {
+	l := m.limits
+	l.mu.Lock()
+	b, ok := l.batches[ch.Name()]
+	delete(l.batches, ch.Name())
+	if ok {
+		l.channels[ch.Name()] = append(l.channels[ch.Name()], m.now())
+	}
+	l.mu.Unlock()
+	if !ok || len(b.alerts) == 0 {
+		return
+	}
+
+	if err := m.deliver(context.Background(), ch, summarize(b.alerts)); err != nil {
+		log.Printf("Failed to send quiet hours summary to %s: %v", ch.Name(), err)
+	}
+}
+
+// summarize combines held alerts into one, at the level of the worst
+func summarize(alerts []*Alert) *Alert {
+	s := &Alert{
+		ID:        fmt.Sprintf("alert-%d", time.Now().UnixNano()),
+		Level:     LevelInfo,
+		Title:     fmt.Sprintf("%d alerts during quiet hours", len(alerts)),
+		Timestamp: time.Now(),
+	}
+	if len(alerts) == 1 {
+		s.Title = "1 alert during quiet hours"
+	}
+	var b strings.Builder
+	for _, a := range alerts {
+		if severity(a.Level) > severity(s.Level) {
+			s.Level = a.Level
+		}
+		fmt.Fprintf(&b, "• %s: %s\n", a.Title, a.Message)
+	}
+	s.Message = strings.TrimSuffix(b.String(), "\n")
+	return s
+}
+
+// severity orders levels for summaries
+func severity(l Level) int {
+	switch l {
+	case LevelError:
+		return 3
+	case LevelWarning:
+		return 2
+	case LevelSuccess:
+		return 1
+	}
+	return 0
+}
+
+// stop drops held alerts, which are still listed, when the manager closes
+func (l *limits) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name, b := range l.batches {
+		b.timer.Stop()
+		log.Printf("Dropping %d alerts held for %s quiet hours", len(b.alerts), name)
+		delete(l.batches, name)
+	}
+}
+
+// quietHours is a parsed quiet hours period
+type quietHours struct {
+	channels   []string
+	start, end time.Duration // since midnight
+	action     string
+}
+
+// parseQuietHours validates and parses quiet hours settings
+func parseQuietHours(cfgs []config.QuietHoursConfig) ([]quietHours, error) {
+	var periods []quietHours
+	for i, cfg := range cfgs {
+		q := quietHours{channels: cfg.Channels, action: cfg.Action}
+		var err error
+		if q.start, err = parseClock(cfg.Start); err != nil {
+			return nil, fmt.Errorf("quiet hours %d: invalid start: %w", i+1, err)
+		}
+		if q.end, err = parseClock(cfg.End); err != nil {
+			return nil, fmt.Errorf("quiet hours %d: invalid end: %w", i+1, err)
+		}
+		if q.start == q.end {
+			return nil, fmt.Errorf("quiet hours %d: start and end are both %s", i+1, cfg.Start)
+		}
+		if q.action != config.QuietDowngrade && q.action != config.QuietBatch {
+			return nil, fmt.Errorf("quiet hours %d: unknown action %q", i+1, cfg.Action)
+		}
+		periods = append(periods, q)
+	}
+	return periods, nil
+}
+
+// parseClock parses a time of day such as 22:00
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 22:00", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// sinceMidnight returns how far into its day t is
+func sinceMidnight(t time.Time) time.Duration {
+	y, mo, d := t.Date()
+	return t.Sub(time.Date(y, mo, d, 0, 0, 0, 0, t.Location()))
+}
+
+// active reports whether t is within the quiet hours
+func (q quietHours) active(t time.Time) bool {
+	now := sinceMidnight(t)
+	if q.start < q.end {
+		return now >= q.start && now < q.end
+	}
+	return now >= q.start || now < q.end
+}
+
+// until returns when the quiet hours that t is in end
+func (q quietHours) until(t time.Time) time.Time {
+	wait := q.end - sinceMidnight(t)
+	if wait <= 0 {
+		wait += 24 * time.Hour
+	}
+	return t.Add(wait)
+}
+
+// quietFor returns the quiet hours a channel is in, if any
+func (l *limits) quietFor(channel string, now time.Time) *quietHours {
+	for i := range l.quiet {
+		q := &l.quiet[i]
+		if (len(q.channels) == 0 || slices.Contains(q.channels, channel)) && q.active(now) {
+			return q
+		}
+	}
+	return nil
+}
+
+// ValidateConfig reports the first invalid alert rule, channel, quiet
+// hours or delivery setting, or one naming an unknown channel
+func ValidateConfig(cfg *config.AlertsConfig) error {
+	if err := ValidateRules(cfg.Rules); err != nil {
+		return err
+	}
+	if err := validateChannels(cfg); err != nil {
+		return err
+	}
+	channels := channelNames(cfg)
+	for i, r := range cfg.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		for _, ch := range r.Channels {
+			if !slices.Contains(channels, ch) {
+				return fmt.Errorf("alert rule %s: unknown channel %q", name, ch)
+			}
+		}
+	}
+	if _, err := parseQuietHours(cfg.QuietHours); err != nil {
+		return err
+	}
+	for i, q := range cfg.QuietHours {
+		for _, ch := range q.Channels {
+			if !slices.Contains(channels, ch) {
+				return fmt.Errorf("quiet hours %d: unknown channel %q", i+1, ch)
+			}
+		}
+	}
+	return validateDelivery(cfg.Delivery, channels)
+}
@@ -0,0 +1,285 @@
+package alert
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// testClock is a settable time for suppression tests
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time { return c.t }
+
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestManager returns a manager on a test clock, sending to a desktop
+// and a slack channel
+func newTestManager(cfg *config.AlertsConfig, at time.Time) (*Manager, *testClock, *countingChannel, *countingChannel) {
+	m := NewManager(cfg, nil)
+	clock := &testClock{t: at}
+	m.now = clock.now
+	desktop := &countingChannel{name: "desktop"}
+	slack := &countingChannel{name: "slack"}
+	m.channels = []Channel{desktop, slack}
+	return m, clock, desktop, slack
+}
+
+var noon = time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+
+func send(m *Manager, agentID, title string) *Alert {
+	a := &Alert{Level: LevelError, Title: title, AgentID: agentID}
+	m.Send(context.Background(), a)
+	return a
+}
+
+func TestDedup(t *testing.T) {
+	m, clock, desktop, _ := newTestManager(&config.AlertsConfig{DedupWindow: 5 * time.Minute}, noon)
+
+	first := send(m, "a", "Agent Error")
+	clock.advance(time.Minute)
+	dup := send(m, "a", "Agent Error")
+	other := send(m, "b", "Agent Error")
+	clock.advance(5 * time.Minute)
+	again := send(m, "a", "Agent Error")
+
+	if first.Suppressed != "" || other.Suppressed != "" || again.Suppressed != "" {
+		t.Errorf("suppressed %q, %q, %q; want none", first.Suppressed, other.Suppressed, again.Suppressed)
+	}
+	if dup.Suppressed != SuppressedDuplicate || !dup.Read {
+		t.Errorf("duplicate = %q (read %v), want suppressed and read", dup.Suppressed, dup.Read)
+	}
+	if len(desktop.sent) != 3 {
+		t.Errorf("desktop got %d alerts, want 3", len(desktop.sent))
+	}
+
+	// Suppressed alerts are still listed, without counting as unread
+	if n := len(m.List(0, false)); n != 4 {
+		t.Errorf("List() = %d alerts, want 4", n)
+	}
+	if n := m.UnreadCount(); n != 3 {
+		t.Errorf("UnreadCount() = %d, want 3", n)
+	}
+
+	// Rules can set their own keys
+	e, _ := NewEngine([]config.AlertRule{{Events: []string{"errored"}, DedupKey: "{{.Project}}"}})
+	alerts, _ := e.Evaluate(agent.Event{Type: agent.EventAgentErrored, AgentID: "a", Agent: agent.NewMockAgent("a", "A")})
+	if len(alerts) != 1 || alerts[0].DedupKey != "test-project" {
+		t.Errorf("rule alerts = %+v, want the dedup key rendered", alerts)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	cfg := &config.AlertsConfig{RateLimit: config.RateLimitConfig{Window: time.Hour, PerAgent: 2}}
+	m, clock, desktop, _ := newTestManager(cfg, noon)
+	for i := 0; i < 3; i++ {
+		send(m, "a", "one")
+		clock.advance(time.Minute)
+	}
+	if last := m.List(1, false)[0]; last.Suppressed != SuppressedRateLimit {
+		t.Errorf("third alert = %q, want rate limited", last.Suppressed)
+	}
+	if a := send(m, "b", "one"); a.Suppressed != "" {
+		t.Errorf("other agent suppressed: %q", a.Suppressed)
+	}
+	clock.advance(time.Hour)
+	if a := send(m, "a", "one"); a.Suppressed != "" {
+		t.Errorf("after the window suppressed: %q", a.Suppressed)
+	}
+	if len(desktop.sent) != 4 {
+		t.Errorf("desktop got %d alerts, want 4", len(desktop.sent))
+	}
+
+	// A channel over its limit is skipped; with every channel over, the
+	// alert is suppressed
+	cfg = &config.AlertsConfig{RateLimit: config.RateLimitConfig{Window: time.Hour, PerChannel: 1}}
+	m, _, desktop, slack := newTestManager(cfg, noon)
+	send(m, "a", "one")
+	routed := &Alert{Level: LevelInfo, Title: "two", Channels: []string{"slack"}}
+	m.Send(context.Background(), routed)
+	if routed.Suppressed != SuppressedRateLimit || len(slack.sent) != 1 || len(desktop.sent) != 1 {
+		t.Errorf("routed = %q, slack %d, desktop %d", routed.Suppressed, len(slack.sent), len(desktop.sent))
+	}
+
+	// An alert sent to some of its channels records why it skipped others
+	cfg.RateLimit.PerChannel = 2
+	m, _, desktop, slack = newTestManager(cfg, noon)
+	m.Send(context.Background(), &Alert{Level: LevelInfo, Title: "one", Channels: []string{"slack"}})
+	m.Send(context.Background(), &Alert{Level: LevelInfo, Title: "two", Channels: []string{"slack"}})
+	partial := send(m, "a", "three")
+	if partial.Suppressed != "" || partial.Skipped["slack"] != SuppressedRateLimit || len(partial.Skipped) != 1 || len(desktop.sent) != 1 {
+		t.Errorf("partial = %q %v, desktop %d", partial.Suppressed, partial.Skipped, len(desktop.sent))
+	}
+}
+
+func TestSnooze(t *testing.T) {
+	m, clock, desktop, _ := newTestManager(&config.AlertsConfig{}, noon)
+	a := agent.NewMockAgent("a", "alpha")
+	a.MockProjectID = "api"
+	b := agent.NewMockAgent("b", "beta")
+	b.MockProjectID = "api"
+
+	m.Snooze(Snooze{AgentID: "a", Name: "alpha", Until: noon.Add(time.Hour)})
+	alert := &Alert{Level: LevelError, Title: "Agent Error", AgentID: "a", Agent: a}
+	m.Send(context.Background(), alert)
+	if alert.Suppressed != SuppressedSnoozed {
+		t.Errorf("snoozed agent's alert = %q", alert.Suppressed)
+	}
+
+	m.Snooze(Snooze{Project: "api", Name: "api", Until: noon.Add(30 * time.Minute)})
+	alert = &Alert{Level: LevelError, Title: "Agent Error", AgentID: "b", Agent: b}
+	m.Send(context.Background(), alert)
+	if alert.Suppressed != SuppressedSnoozed {
+		t.Errorf("snoozed project's alert = %q", alert.Suppressed)
+	}
+	if s := m.Snoozes(); len(s) != 2 || s[0].Project != "api" {
+		t.Errorf("Snoozes() = %+v, want the project's first", s)
+	}
+
+	clock.advance(45 * time.Minute)
+	if s := m.Snoozes(); len(s) != 1 || s[0].AgentID != "a" {
+		t.Errorf("Snoozes() = %+v, want only the agent's left", s)
+	}
+	m.Snooze(Snooze{AgentID: "a"})
+	if s := m.Snoozes(); len(s) != 0 {
+		t.Errorf("Snoozes() after ending = %+v", s)
+	}
+	if alert := send(m, "a", "Agent Error"); alert.Suppressed != "" || len(desktop.sent) != 1 {
+		t.Errorf("alert after the snooze = %q, desktop got %d", alert.Suppressed, len(desktop.sent))
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	night := time.Date(2026, 3, 2, 23, 30, 0, 0, time.Local)
+	cfg := &config.AlertsConfig{QuietHours: []config.QuietHoursConfig{
+		{Channels: []string{"desktop"}, Start: "22:00", End: "07:00", Action: config.QuietDowngrade},
+		{Channels: []string{"slack"}, Start: "22:00", End: "07:00", Action: config.QuietBatch},
+	}}
+	m, _, desktop, slack := newTestManager(cfg, night)
+	defer m.Close()
+
+	failed := send(m, "a", "failed")
+	fyi := &Alert{Level: LevelInfo, Title: "fyi", AgentID: "a"}
+	m.Send(context.Background(), fyi)
+	if failed.Suppressed != "" || failed.Skipped["slack"] != SuppressedHeld {
+		t.Errorf("failed = %q %v, want held for slack only", failed.Suppressed, failed.Skipped)
+	}
+	if fyi.Suppressed != SuppressedHeld || !fyi.Read {
+		t.Errorf("fyi = %q (read %v), want held", fyi.Suppressed, fyi.Read)
+	}
+	m.limits.mu.Lock()
+	if n := len(m.limits.channels["slack"]); n != 0 {
+		t.Errorf("slack rate limit counted %d held alerts", n)
+	}
+	m.limits.mu.Unlock()
+
+	// Desktop gets the error as a warning and drops the info alert
+	if len(desktop.sent) != 1 || desktop.sent[0].Level != LevelWarning || desktop.sent[0].Title != "failed" {
+		t.Errorf("desktop got %+v", desktop.sent)
+	}
+	if list := m.List(0, false); list[1].Level != LevelError || list[1].Suppressed != "" {
+		t.Errorf("listed alert = %+v, want it unchanged", list[1])
+	}
+
+	// Slack gets both in one summary when quiet hours end
+	if len(slack.sent) != 0 {
+		t.Fatalf("slack got %d alerts during quiet hours", len(slack.sent))
+	}
+	m.limits.mu.Lock()
+	b := m.limits.batches["slack"]
+	m.limits.mu.Unlock()
+	if b == nil || len(b.alerts) != 2 {
+		t.Fatalf("slack batch = %+v", b)
+	}
+	m.flush(slack)
+	if len(slack.sent) != 1 {
+		t.Fatalf("slack got %d alerts, want a summary", len(slack.sent))
+	}
+	if s := slack.sent[0]; s.Title != "2 alerts during quiet hours" || s.Level != LevelError || !strings.Contains(s.Message, "failed") {
+		t.Errorf("summary = %+v", s)
+	}
+
+	// Info alerts dropped by downgrading quiet hours are suppressed, and
+	// do not count as sent
+	m, _, _, _ = newTestManager(&config.AlertsConfig{DedupWindow: time.Hour, QuietHours: cfg.QuietHours[:1]}, night)
+	dropped := &Alert{Level: LevelInfo, Title: "fyi", AgentID: "a", Channels: []string{"desktop"}}
+	m.Send(context.Background(), dropped)
+	if dropped.Suppressed != SuppressedQuiet || !dropped.Read {
+		t.Errorf("dropped = %q (read %v), want suppressed for quiet hours", dropped.Suppressed, dropped.Read)
+	}
+	m.limits.mu.Lock()
+	if _, ok := m.limits.sent[dropped.DedupKey]; ok {
+		t.Error("dropped alert counted toward dedup")
+	}
+	m.limits.mu.Unlock()
+
+	// Outside quiet hours alerts go straight out
+	m, _, desktop, slack = newTestManager(cfg, noon)
+	send(m, "a", "failed")
+	if len(desktop.sent) != 1 || desktop.sent[0].Level != LevelError || len(slack.sent) != 1 {
+		t.Errorf("desktop %+v, slack %d", desktop.sent, len(slack.sent))
+	}
+}
+
+func TestQuietHoursPeriod(t *testing.T) {
+	periods, err := parseQuietHours([]config.QuietHoursConfig{
+		{Start: "22:00", End: "07:00", Action: config.QuietBatch},
+		{Start: "12:00", End: "13:30", Action: config.QuietDowngrade},
+	})
+	if err != nil {
+		t.Fatalf("parseQuietHours() error = %v", err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, time.Local) }
+	tests := []struct {
+		q    quietHours
+		t    time.Time
+		want bool
+	}{
+		{periods[0], at(23, 0), true},
+		{periods[0], at(3, 0), true},
+		{periods[0], at(7, 0), false},
+		{periods[0], at(21, 59), false},
+		{periods[1], at(12, 0), true},
+		{periods[1], at(13, 30), false},
+	}
+	for _, tt := range tests {
+		if got := tt.q.active(tt.t); got != tt.want {
+			t.Errorf("active(%s) = %v, want %v", tt.t.Format("15:04"), got, tt.want)
+		}
+	}
+	if got := periods[0].until(at(23, 0)); !got.Equal(at(31, 0)) {
+		t.Errorf("until(23:00) = %v, want 07:00 the next day", got)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		q    config.QuietHoursConfig
+		want string
+	}{
+		{"start", config.QuietHoursConfig{Start: "10pm", End: "07:00", Action: "batch"}, "invalid start"},
+		{"equal", config.QuietHoursConfig{Start: "07:00", End: "07:00", Action: "batch"}, "start and end"},
+		{"action", config.QuietHoursConfig{Start: "22:00", End: "07:00", Action: "mute"}, "unknown action"},
+		{"channel", config.QuietHoursConfig{Channels: []string{"pager"}, Start: "22:00", End: "07:00", Action: "batch"}, "unknown channel"},
+	}
+	for _, tt := range tests {
+		err := ValidateConfig(&config.AlertsConfig{QuietHours: []config.QuietHoursConfig{tt.q}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ValidateConfig() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if err := ValidateConfig(&config.AlertsConfig{Rules: []config.AlertRule{{Level: "loud"}}}); err == nil {
+		t.Error("ValidateConfig() should check rules")
+	}
+	err := ValidateConfig(&config.AlertsConfig{Rules: []config.AlertRule{{Name: "page", Channels: []string{"pager"}}}})
+	if err == nil || !strings.Contains(err.Error(), `alert rule page: unknown channel "pager"`) {
+		t.Errorf("ValidateConfig() error = %v, want the rule's unknown channel", err)
+	}
+}
@@ -0,0 +1,271 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+const (
+	// defaultWebhookTimeout bounds a webhook request without a timeout set
+	defaultWebhookTimeout = 10 * time.Second
+	// defaultSignatureHeader carries the body's HMAC when a secret is set
+	defaultSignatureHeader = "X-Auto-Signature"
+)
+
+// presets are the bodies of the services webhooks can mimic
+var presets = map[string]string{
+	"slack": `{"attachments":[{"color":"{{.Color}}","title":{{json .Title}},"text":{{json .Message}},` +
+		`"footer":"AUTO","ts":{{.Timestamp.Unix}}` +
+		`{{if .AgentName}},"fields":[{"title":"Agent","value":{{json .AgentName}},"short":true},` +
+		`{"title":"Status","value":{{json .AgentStatus}},"short":true}]{{end}}}]}`,
+	"discord": `{"embeds":[{"title":{{json .Title}},"description":{{json .Message}},"color":{{.ColorCode}},` +
+		`"timestamp":{{json .Timestamp}},"footer":{"text":"AUTO"}` +
+		`{{if .AgentName}},"fields":[{"name":"Agent","value":{{json .AgentName}},"inline":true},` +
+		`{"name":"Status","value":{{json .AgentStatus}},"inline":true}]{{end}}}]}`,
+}
+
+// templateFuncs are available to webhook templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value, quoting and escaping strings
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// WebhookData is what webhook body and header templates are executed
+// with; without a body the alert is sent as its JSON
+type WebhookData struct {
+	Channel     string    `json:"channel"` // the webhook's name
+	ID          string    `json:"id"`
+	Level       Level     `json:"level"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	Rule        string    `json:"rule,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	AgentID     string    `json:"agent_id,omitempty"`
+	AgentName   string    `json:"agent_name,omitempty"`
+	AgentType   string    `json:"agent_type,omitempty"`
+	AgentStatus string    `json:"agent_status,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Color       string    `json:"color"`      // the level's colour as #RRGGBB
+	ColorCode   int       `json:"color_code"` // the same as a number
+}
+
+// levelColors are the colours of alert levels
+var levelColors = map[Level]int{
+	LevelInfo:    0x2196F3, // blue
+	LevelWarning: 0xFF9800, // orange
+	LevelError:   0xF44336, // red
+	LevelSuccess: 0x4CAF50, // green
+}
+
+// WebhookChannel sends alerts as HTTP requests with a templated body
+type WebhookChannel struct {
+	name            string
+	url             string
+	method          string
+	headers         map[string]*template.Template
+	body            *template.Template // nil sends WebhookData as JSON
+	secret          []byte
+	signatureHeader string
+	httpClient      *http.Client
+}
+
+// NewWebhookChannel validates a webhook's settings and creates its channel
+func NewWebhookChannel(cfg config.WebhookConfig) (*WebhookChannel, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("webhook has no name")
+	}
+	if cfg.Name == "desktop" {
+		return nil, fmt.Errorf("webhook %s: desktop is not a webhook name", cfg.Name)
+	}
+	if u, err := url.Parse(cfg.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook %s: invalid url %q", cfg.Name, cfg.URL)
+	}
+
+	c := &WebhookChannel{
+		name:            cfg.Name,
+		url:             cfg.URL,
+		method:          strings.ToUpper(cfg.Method),
+		headers:         make(map[string]*template.Template),
+		secret:          []byte(cfg.Secret),
+		signatureHeader: cfg.SignatureHeader,
+		httpClient:      &http.Client{Timeout: cfg.Timeout},
+	}
+	if c.method == "" {
+		c.method = http.MethodPost
+	}
+	switch c.method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodGet:
+	default:
+		return nil, fmt.Errorf("webhook %s: unsupported method %q", cfg.Name, cfg.Method)
+	}
+	if c.signatureHeader == "" {
+		c.signatureHeader = defaultSignatureHeader
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("webhook %s: negative timeout", cfg.Name)
+	}
+	if cfg.Timeout == 0 {
+		c.httpClient.Timeout = defaultWebhookTimeout
+	}
+
+	body := cfg.Body
+	if cfg.Preset != "" {
+		preset, ok := presets[cfg.Preset]
+		if !ok {
+			return nil, fmt.Errorf("webhook %s: unknown preset %q", cfg.Name, cfg.Preset)
+		}
+		if body == "" {
+			body = preset
+		}
+	}
+	var err error
+	if body != "" {
+		if c.body, err = parseWebhookTemplate("body", body); err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid body: %w", cfg.Name, err)
+		}
+	}
+	for name, value := range cfg.Headers {
+		if c.headers[name], err = parseWebhookTemplate(name, value); err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid header %s: %w", cfg.Name, name, err)
+		}
+	}
+	return c, nil
+}
+
+// parseWebhookTemplate parses a template and checks it only uses fields
+// WebhookData has
+func parseWebhookTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(io.Discard, WebhookData{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Name returns the webhook's name
+func (c *WebhookChannel) Name() string {
+	return c.name
+}
+
+func (c *WebhookChannel) remote() {}
+
+// Send renders an alert into a request and sends it; a response status of
+// 400 or above is an error
+func (c *WebhookChannel) Send(ctx context.Context, alert *Alert) error {
+	data := alertData(c.name, alert)
+
+	var body []byte
+	if c.body == nil {
+		var err error
+		if body, err = json.Marshal(data); err != nil {
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := c.body.Execute(&buf, data); err != nil {
+			return fmt.Errorf("%s webhook body: %w", c.name, err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, t := range c.headers {
+		var value strings.Builder
+		if err := t.Execute(&value, data); err != nil {
+			return fmt.Errorf("%s webhook header %s: %w", c.name, name, err)
+		}
+		req.Header.Set(name, value.String())
+	}
+	if len(c.secret) > 0 {
+		req.Header.Set(c.signatureHeader, Sign(c.secret, body))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s webhook returned status %d", c.name, resp.StatusCode)
+	}
+	return nil
+}
+
+// alertData builds the template data of an alert sent to a channel
+func alertData(channel string, a *Alert) WebhookData {
+	color := levelColors[a.Level]
+	if color == 0 {
+		color = levelColors[LevelInfo]
+	}
+	d := WebhookData{
+		Channel:   channel,
+		ID:        a.ID,
+		Level:     a.Level,
+		Title:     a.Title,
+		Message:   a.Message,
+		Rule:      a.Rule,
+		Timestamp: a.Timestamp,
+		AgentID:   a.AgentID,
+		Color:     fmt.Sprintf("#%06X", color),
+		ColorCode: color,
+	}
+	if a.Agent != nil {
+		d.AgentName = a.Agent.Name()
+		d.AgentType = a.Agent.Type()
+		d.AgentStatus = a.Agent.Status().String()
+		d.Project = a.Agent.ProjectID()
+	}
+	return d
+}
+
+// Sign returns the signature of a body as sent in the signature header:
+// "sha256=" and the hex HMAC-SHA256 of the body with the secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookConfigs returns the configured webhooks, starting with those the
+// Slack and Discord settings stand for
+func webhookConfigs(cfg *config.AlertsConfig) []config.WebhookConfig {
+	var hooks []config.WebhookConfig
+	if cfg.SlackEnabled && cfg.SlackWebhookURL != "" {
+		hook := config.WebhookConfig{Name: "slack", URL: cfg.SlackWebhookURL, Preset: "slack"}
+		if cfg.SlackChannel != "" {
+			// Post to another channel than the webhook's own
+			channel, _ := json.Marshal(cfg.SlackChannel)
+			hook.Body = `{"channel":` + strings.ReplaceAll(string(channel), "{{", `{{"{{"}}`) + "," + presets["slack"][1:]
+		}
+		hooks = append(hooks, hook)
+	}
+	if cfg.DiscordEnabled && cfg.DiscordWebhookURL != "" {
+		hooks = append(hooks, config.WebhookConfig{Name: "discord", URL: cfg.DiscordWebhookURL, Preset: "discord"})
+	}
+	return append(hooks, cfg.Webhooks...)
+}
@@ -0,0 +1,220 @@
+package alert
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/store"
+)
+
+// request is what a test webhook server received
+type request struct {
+	method string
+	header http.Header
+	body   []byte
+}
+
+// captureServer records the requests sent to it and replies with status
+func captureServer(t *testing.T, status int) (*httptest.Server, <-chan request) {
+	requests := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{r.Method, r.Header, body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func testAlert() *Alert {
+	a := agent.NewMockAgent("ses_1", "fixer")
+	a.MockStatus = agent.StatusErrored
+	return &Alert{
+		ID:        "alert-1",
+		Level:     LevelError,
+		Title:     `Agent "fixer" failed`,
+		Message:   "boom",
+		AgentID:   a.ID(),
+		Agent:     a,
+		Timestamp: time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC),
+		Rule:      "errored",
+	}
+}
+
+func TestWebhookChannel(t *testing.T) {
+	srv, requests := captureServer(t, http.StatusOK)
+	ch, err := NewWebhookChannel(config.WebhookConfig{
+		Name:    "ntfy",
+		URL:     srv.URL,
+		Method:  "put",
+		Headers: map[string]string{"Title": "{{.Title}}", "Priority": `{{if eq .Level "error"}}urgent{{else}}default{{end}}`},
+		Body:    `{"text":{{json .Message}},"agent":{{json .AgentName}},"status":"{{.AgentStatus}}","channel":"{{.Channel}}"}`,
+		Secret:  "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookChannel() error = %v", err)
+	}
+	if err := ch.Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	r := <-requests
+	if r.method != http.MethodPut || r.header.Get("Title") != `Agent "fixer" failed` || r.header.Get("Priority") != "urgent" {
+		t.Errorf("request = %s %v", r.method, r.header)
+	}
+	if string(r.body) != `{"text":"boom","agent":"fixer","status":"errored","channel":"ntfy"}` {
+		t.Errorf("body = %s", r.body)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(r.body)
+	if got, want := r.header.Get("X-Auto-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	// Without a body the alert is sent as JSON
+	ch, _ = NewWebhookChannel(config.WebhookConfig{Name: "dashboard", URL: srv.URL, SignatureHeader: "X-Sig"})
+	ch.Send(context.Background(), testAlert())
+	r = <-requests
+	var data WebhookData
+	if err := json.Unmarshal(r.body, &data); err != nil {
+		t.Fatalf("body %s: %v", r.body, err)
+	}
+	if r.method != http.MethodPost || data.Title != `Agent "fixer" failed` || data.AgentName != "fixer" || data.Color != "#F44336" || data.Rule != "errored" {
+		t.Errorf("request = %s %+v", r.method, data)
+	}
+	if r.header.Get("X-Sig") != "" || r.header.Get("X-Auto-Signature") != "" {
+		t.Error("unsigned webhook sent a signature")
+	}
+
+	// Error statuses fail the send
+	failing, _ := captureServer(t, http.StatusInternalServerError)
+	ch, _ = NewWebhookChannel(config.WebhookConfig{Name: "oncall", URL: failing.URL})
+	if err := ch.Send(context.Background(), testAlert()); err == nil || err.Error() != "oncall webhook returned status 500" {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+func TestWebhookPresets(t *testing.T) {
+	srv, requests := captureServer(t, http.StatusOK)
+	m := NewManager(&config.AlertsConfig{
+		SlackEnabled:      true,
+		SlackWebhookURL:   srv.URL,
+		SlackChannel:      "#ops",
+		DiscordEnabled:    true,
+		DiscordWebhookURL: srv.URL,
+	}, nil)
+	defer m.Close()
+	for _, ch := range m.channels {
+		if err := ch.Send(context.Background(), testAlert()); err != nil {
+			t.Fatalf("%s Send() error = %v", ch.Name(), err)
+		}
+	}
+
+	var slack struct {
+		Channel     string `json:"channel"`
+		Attachments []struct {
+			Color  string `json:"color"`
+			Title  string `json:"title"`
+			Text   string `json:"text"`
+			Ts     int64  `json:"ts"`
+			Fields []struct{ Title, Value string }
+		} `json:"attachments"`
+	}
+	if r := <-requests; json.Unmarshal(r.body, &slack) != nil {
+		t.Fatalf("slack body is not JSON: %s", r.body)
+	}
+	if a := slack.Attachments; slack.Channel != "#ops" || len(a) != 1 || a[0].Color != "#F44336" || a[0].Title != `Agent "fixer" failed` ||
+		a[0].Ts != testAlert().Timestamp.Unix() || len(a[0].Fields) != 2 || a[0].Fields[1].Value != "errored" {
+		t.Errorf("slack body = %+v", slack)
+	}
+
+	var discord struct {
+		Embeds []struct {
+			Title       string    `json:"title"`
+			Description string    `json:"description"`
+			Color       int       `json:"color"`
+			Timestamp   time.Time `json:"timestamp"`
+			Fields      []struct{ Name, Value string }
+		} `json:"embeds"`
+	}
+	if r := <-requests; json.Unmarshal(r.body, &discord) != nil {
+		t.Fatalf("discord body is not JSON: %s", r.body)
+	}
+	if e := discord.Embeds; len(e) != 1 || e[0].Description != "boom" || e[0].Color != 0xF44336 ||
+		!e[0].Timestamp.Equal(testAlert().Timestamp) || len(e[0].Fields) != 2 || e[0].Fields[0].Value != "fixer" {
+		t.Errorf("discord body = %+v", discord)
+	}
+
+	// Alerts without an agent leave its fields out
+	m.channels[1].Send(context.Background(), &Alert{Level: LevelInfo, Title: "fyi"})
+	if r := <-requests; strings.Contains(string(r.body), "fields") {
+		t.Errorf("discord body = %s, want no fields", r.body)
+	}
+}
+
+func TestWebhookRouting(t *testing.T) {
+	srv, requests := captureServer(t, http.StatusOK)
+	cfg := &config.AlertsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "oncall", URL: srv.URL, Body: `{{.Title}}`}},
+		Rules:    []config.AlertRule{{Events: []string{"errored"}, Title: "page", Channels: []string{"oncall"}}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("ValidateConfig() error = %v", err)
+	}
+	m := NewManager(cfg, nil)
+	defer m.Close()
+
+	a := agent.NewMockAgent("ses_1", "fixer")
+	m.SendAgentEvent(context.Background(), agent.Event{Type: agent.EventAgentErrored, AgentID: a.ID(), Agent: a})
+	select {
+	case r := <-requests:
+		if string(r.body) != "page" {
+			t.Errorf("body = %q", r.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+	waitDeliveries(t, m, store.DeliverySent, 1)
+}
+
+func TestWebhookConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hook config.WebhookConfig
+		want string
+	}{
+		{"name", config.WebhookConfig{URL: "https://example.com"}, "no name"},
+		{"desktop", config.WebhookConfig{Name: "desktop", URL: "https://example.com"}, "not a webhook name"},
+		{"url", config.WebhookConfig{Name: "a", URL: "example.com/hook"}, "invalid url"},
+		{"method", config.WebhookConfig{Name: "a", URL: "https://example.com", Method: "DELETE"}, "unsupported method"},
+		{"preset", config.WebhookConfig{Name: "a", URL: "https://example.com", Preset: "teams"}, "unknown preset"},
+		{"body", config.WebhookConfig{Name: "a", URL: "https://example.com", Body: "{{.Cost}}"}, "invalid body"},
+		{"header", config.WebhookConfig{Name: "a", URL: "https://example.com", Headers: map[string]string{"X": "{{"}}, "invalid header X"},
+		{"timeout", config.WebhookConfig{Name: "a", URL: "https://example.com", Timeout: -time.Second}, "negative timeout"},
+	}
+	for _, tt := range tests {
+		if _, err := NewWebhookChannel(tt.hook); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NewWebhookChannel() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	err := ValidateConfig(&config.AlertsConfig{
+		SlackEnabled:    true,
+		SlackWebhookURL: "https://hooks.slack.com/test",
+		Webhooks:        []config.WebhookConfig{{Name: "slack", URL: "https://example.com", Preset: "slack"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "used twice") {
+		t.Errorf("ValidateConfig() error = %v, want the duplicate name", err)
+	}
+}
@@ -0,0 +1,253 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// Outcome is the state of a task in a batch
+type Outcome string
+
+const (
+	OutcomeSpawning  Outcome = "spawning"
+	OutcomeQueued    Outcome = "queued"
+	OutcomeRunning   Outcome = "running"
+	OutcomeCompleted Outcome = "completed"
+	OutcomeErrored   Outcome = "errored"
+	OutcomeCancelled Outcome = "cancelled"
+)
+
+// Finished reports whether the task has reached a final outcome
+func (o Outcome) Finished() bool {
+	return o == OutcomeCompleted || o == OutcomeErrored || o == OutcomeCancelled
+}
+
+// outcomeOf maps an agent's status to a task outcome. Idle counts as
+// completed, since opencode sessions go idle once their prompt has run,
+// unless the agent is only paused.
+func outcomeOf(a agent.Agent) Outcome {
+	status := a.Status()
+	if status == agent.StatusIdle && agent.IsPaused(a) {
+		return OutcomeRunning
+	}
+	switch status {
+	case agent.StatusPending:
+		return OutcomeQueued
+	case agent.StatusIdle, agent.StatusCompleted:
+		return OutcomeCompleted
+	case agent.StatusErrored, agent.StatusContextLimit:
+		return OutcomeErrored
+	case agent.StatusCancelled:
+		return OutcomeCancelled
+	default:
+		return OutcomeRunning
+	}
+}
+
+// Spawner starts and finds agents; session.Manager implements it
+type Spawner interface {
+	Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error)
+	Resolve(id string) (agent.Agent, bool)
+	Terminate(id string) error
+}
+
+// TaskResult is the outcome of one task
+type TaskResult struct {
+	Name       string    `json:"name"`
+	Directory  string    `json:"directory"`
+	Type       string    `json:"type"`
+	Tags       []string  `json:"tags,omitempty"`
+	AgentID    string    `json:"agent_id,omitempty"`
+	Outcome    Outcome   `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// Result summarizes a batch
+type Result struct {
+	Name       string          `json:"name"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at,omitempty"`
+	Summary    map[Outcome]int `json:"summary"`
+	Tasks      []TaskResult    `json:"tasks"`
+}
+
+// Batch tracks the agents spawned for a manifest
+type Batch struct {
+	manifest *Manifest
+	spawner  Spawner
+	cancel   context.CancelFunc
+
+	mu         sync.Mutex
+	tasks      []TaskResult
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// Submit spawns every task of the manifest. Spawns run concurrently and
+// are subject to the spawn queue; Submit returns without waiting for them.
+func Submit(ctx context.Context, spawner Spawner, m *Manifest) *Batch {
+	ctx, cancel := context.WithCancel(ctx)
+	b := &Batch{
+		manifest:  m,
+		spawner:   spawner,
+		cancel:    cancel,
+		tasks:     make([]TaskResult, len(m.Tasks)),
+		startedAt: time.Now(),
+	}
+
+	for i, t := range m.Tasks {
+		b.tasks[i] = TaskResult{
+			Name:      t.Name,
+			Directory: t.Directory,
+			Type:      t.Type,
+			Tags:      t.Tags,
+			Outcome:   OutcomeSpawning,
+		}
+		go b.spawn(ctx, i, t)
+	}
+	return b
+}
+
+func (b *Batch) spawn(ctx context.Context, i int, t Task) {
+	a, err := b.spawner.Spawn(ctx, t.SpawnConfig())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := &b.tasks[i]
+	if r.Outcome.Finished() {
+		// Cancelled while spawning; stop the agent if it started anyway
+		if err == nil {
+			r.AgentID = a.ID()
+			go b.spawner.Terminate(a.ID())
+		}
+		return
+	}
+	if err != nil {
+		b.finishLocked(r, OutcomeErrored, err.Error())
+		return
+	}
+	r.AgentID = a.ID()
+	b.setLocked(r, a)
+}
+
+// Name returns the manifest name
+func (b *Batch) Name() string {
+	return b.manifest.Name
+}
+
+// Update refreshes the outcome of every unfinished task
+func (b *Batch) Update() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.tasks {
+		r := &b.tasks[i]
+		if r.AgentID == "" || r.Outcome.Finished() {
+			continue
+		}
+		a, ok := b.spawner.Resolve(r.AgentID)
+		if !ok {
+			b.finishLocked(r, OutcomeCancelled, "agent went away")
+			continue
+		}
+		r.AgentID = a.ID()
+		b.setLocked(r, a)
+	}
+}
+
+func (b *Batch) setLocked(r *TaskResult, a agent.Agent) {
+	outcome := outcomeOf(a)
+	if !outcome.Finished() {
+		r.Outcome = outcome
+		return
+	}
+
+	msg := ""
+	if outcome == OutcomeErrored {
+		if err := a.LastError(); err != nil {
+			msg = err.Error()
+		}
+	}
+	b.finishLocked(r, outcome, msg)
+}
+
+func (b *Batch) finishLocked(r *TaskResult, outcome Outcome, msg string) {
+	r.Outcome = outcome
+	r.Error = msg
+	r.FinishedAt = time.Now()
+
+	for _, t := range b.tasks {
+		if !t.Outcome.Finished() {
+			return
+		}
+	}
+	b.finishedAt = r.FinishedAt
+	b.cancel()
+}
+
+// Cancel terminates every unfinished task
+func (b *Batch) Cancel() {
+	b.mu.Lock()
+	var ids []string
+	for i := range b.tasks {
+		r := &b.tasks[i]
+		if r.Outcome.Finished() {
+			continue
+		}
+		if r.AgentID != "" {
+			ids = append(ids, r.AgentID)
+		}
+		b.finishLocked(r, OutcomeCancelled, "")
+	}
+	b.mu.Unlock()
+
+	for _, id := range ids {
+		b.spawner.Terminate(id)
+	}
+}
+
+// Done reports whether every task has finished
+func (b *Batch) Done() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.finishedAt.IsZero()
+}
+
+// Result returns a snapshot of the batch
+func (b *Batch) Result() Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := Result{
+		Name:       b.manifest.Name,
+		StartedAt:  b.startedAt,
+		FinishedAt: b.finishedAt,
+		Summary:    make(map[Outcome]int),
+		Tasks:      append([]TaskResult(nil), b.tasks...),
+	}
+	for _, t := range b.tasks {
+		r.Summary[t.Outcome]++
+	}
+	return r
+}
+
+// Succeeded reports whether every task completed
+func (r Result) Succeeded() bool {
+	return r.Summary[OutcomeCompleted] == len(r.Tasks)
+}
+
+// WriteFile writes the result as JSON
+func (r Result) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
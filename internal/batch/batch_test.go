@@ -0,0 +1,300 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// fakeSpawner spawns mock agents, failing for directories named "broken"
+type fakeSpawner struct {
+	mu         sync.Mutex
+	agents     map[string]*agent.MockAgent
+	configs    []agent.SpawnConfig
+	terminated []string
+}
+
+func newFakeSpawner() *fakeSpawner {
+	return &fakeSpawner{agents: make(map[string]*agent.MockAgent)}
+}
+
+func (s *fakeSpawner) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs = append(s.configs, config)
+	if filepath.Base(config.Directory) == "broken" {
+		return nil, errors.New("no such project")
+	}
+	a := agent.NewMockAgent(config.Name+"-id", config.Name)
+	s.agents[a.ID()] = a
+	return a, nil
+}
+
+func (s *fakeSpawner) Resolve(id string) (agent.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[id]
+	return a, ok
+}
+
+func (s *fakeSpawner) Terminate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminated = append(s.terminated, id)
+	return nil
+}
+
+func (s *fakeSpawner) setStatus(id string, status agent.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents[id].MockStatus = status
+}
+
+func (s *fakeSpawner) pause(id string, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if paused {
+		s.agents[id].Pause()
+	} else {
+		s.agents[id].Resume()
+	}
+}
+
+func (s *fakeSpawner) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.agents, id)
+}
+
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// waitSpawned waits until every task has left the spawning state
+func waitSpawned(t *testing.T, b *Batch) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		spawning := false
+		for _, task := range b.Result().Tasks {
+			if task.Outcome == OutcomeSpawning {
+				spawning = true
+			}
+		}
+		if !spawning {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("tasks still spawning")
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeManifest(t, "fanout.yaml", `
+defaults:
+  prompt: Update the dependencies
+  type: process
+  env:
+    CI: "1"
+  tags: [deps]
+tasks:
+  - directory: repos/api
+    tags: [backend]
+  - directory: /srv/web
+    name: web
+    prompt: Update the lockfile
+    env:
+      CI: "0"
+      NODE_ENV: test
+`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Name != "fanout" {
+		t.Errorf("Name = %q, want the file name", m.Name)
+	}
+
+	api := m.Tasks[0]
+	if api.Directory != filepath.Join(filepath.Dir(path), "repos", "api") {
+		t.Errorf("relative directory = %q, want it resolved against the manifest", api.Directory)
+	}
+	if api.Name != "api" || api.Prompt != "Update the dependencies" || api.Type != "process" {
+		t.Errorf("defaults not applied: %+v", api)
+	}
+	if !reflect.DeepEqual(api.Tags, []string{"deps", "backend"}) || api.Env["CI"] != "1" {
+		t.Errorf("tags = %v, env = %v", api.Tags, api.Env)
+	}
+
+	web := m.Tasks[1]
+	if web.Name != "web" || web.Prompt != "Update the lockfile" {
+		t.Errorf("task fields should override defaults: %+v", web)
+	}
+	if web.Env["CI"] != "0" || web.Env["NODE_ENV"] != "test" {
+		t.Errorf("env = %v, want task values over defaults", web.Env)
+	}
+	if cfg := web.SpawnConfig(); cfg.Directory != "/srv/web" || cfg.Type != "process" || cfg.Env["NODE_ENV"] != "test" {
+		t.Errorf("SpawnConfig() = %+v", cfg)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := writeManifest(t, "tasks.json", `{"name": "nightly", "tasks": [{"directory": "/srv/api", "prompt": "Run the tests"}]}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Name != "nightly" || len(m.Tasks) != 1 || m.Tasks[0].Type != DefaultType {
+		t.Errorf("manifest = %+v", m)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"no tasks", "empty.yaml", "name: empty\n", "no tasks"},
+		{"no directory", "nodir.yaml", "tasks:\n  - prompt: hi\n", "task 1: directory is required"},
+		{"unknown field", "typo.yaml", "tasks:\n  - directory: /srv\n    promt: hi\n", "promt"},
+		{"unknown JSON field", "typo.json", `{"tasks": [{"dir": "/srv"}]}`, "dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeManifest(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultPath(t *testing.T) {
+	if got := ResultPath("/srv/fanout.yaml"); got != "/srv/fanout.result.json" {
+		t.Errorf("ResultPath() = %q", got)
+	}
+}
+
+func TestBatchOutcomes(t *testing.T) {
+	s := newFakeSpawner()
+	m := &Manifest{Name: "fanout", Tasks: []Task{
+		{Name: "api", Directory: "/srv/api", Type: "mock"},
+		{Name: "web", Directory: "/srv/web", Type: "mock"},
+		{Name: "broken", Directory: "/srv/broken", Type: "mock"},
+		{Name: "docs", Directory: "/srv/docs", Type: "mock"},
+	}}
+
+	b := Submit(context.Background(), s, m)
+	waitSpawned(t, b)
+	b.Update()
+	if b.Done() {
+		t.Fatal("batch should not be done while agents run")
+	}
+
+	// A paused agent is idle without having finished
+	s.pause("api-id", true)
+	b.Update()
+	if got := b.Result().Tasks[0].Outcome; got != OutcomeRunning {
+		t.Fatalf("paused api outcome = %s, want %s", got, OutcomeRunning)
+	}
+
+	s.pause("api-id", false)
+	s.setStatus("api-id", agent.StatusIdle)
+	s.setStatus("web-id", agent.StatusErrored)
+	s.remove("docs-id")
+	b.Update()
+
+	if !b.Done() {
+		t.Fatal("batch should be done once every task finished")
+	}
+	result := b.Result()
+	want := map[string]Outcome{"api": OutcomeCompleted, "web": OutcomeErrored, "broken": OutcomeErrored, "docs": OutcomeCancelled}
+	for _, task := range result.Tasks {
+		if task.Outcome != want[task.Name] {
+			t.Errorf("%s outcome = %s, want %s", task.Name, task.Outcome, want[task.Name])
+		}
+	}
+	if result.Tasks[2].Error != "no such project" || result.Tasks[2].AgentID != "" {
+		t.Errorf("failed spawn = %+v", result.Tasks[2])
+	}
+	if result.Summary[OutcomeErrored] != 2 || result.Succeeded() {
+		t.Errorf("summary = %v", result.Summary)
+	}
+	if result.FinishedAt.IsZero() {
+		t.Error("FinishedAt should be set")
+	}
+}
+
+func TestBatchCancel(t *testing.T) {
+	s := newFakeSpawner()
+	m := &Manifest{Name: "fanout", Tasks: []Task{
+		{Name: "api", Directory: "/srv/api", Type: "mock"},
+		{Name: "web", Directory: "/srv/web", Type: "mock"},
+	}}
+
+	b := Submit(context.Background(), s, m)
+	waitSpawned(t, b)
+	s.setStatus("api-id", agent.StatusCompleted)
+	b.Update()
+
+	b.Cancel()
+	if !b.Done() {
+		t.Fatal("cancelled batch should be done")
+	}
+	if !reflect.DeepEqual(s.terminated, []string{"web-id"}) {
+		t.Errorf("terminated = %v, want only the unfinished agent", s.terminated)
+	}
+	result := b.Result()
+	if result.Tasks[0].Outcome != OutcomeCompleted || result.Tasks[1].Outcome != OutcomeCancelled {
+		t.Errorf("outcomes = %s, %s", result.Tasks[0].Outcome, result.Tasks[1].Outcome)
+	}
+}
+
+func TestResultWriteFile(t *testing.T) {
+	s := newFakeSpawner()
+	b := Submit(context.Background(), s, &Manifest{Name: "one", Tasks: []Task{{Name: "api", Directory: "/srv/api", Tags: []string{"go"}}}})
+	waitSpawned(t, b)
+	s.setStatus("api-id", agent.StatusCompleted)
+	b.Update()
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := b.Result().WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var got struct {
+		Name    string         `json:"name"`
+		Summary map[string]int `json:"summary"`
+		Tasks   []struct {
+			AgentID string   `json:"agent_id"`
+			Outcome string   `json:"outcome"`
+			Tags    []string `json:"tags"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Name != "one" || got.Summary["completed"] != 1 || len(got.Tasks) != 1 ||
+		got.Tasks[0].AgentID != "api-id" || got.Tasks[0].Outcome != "completed" || got.Tasks[0].Tags[0] != "go" {
+		t.Errorf("result = %s", data)
+	}
+}
@@ -0,0 +1,148 @@
+// Package batch spawns many agents from a manifest and tracks them as a unit
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultType is the agent type used when neither a task nor the manifest defaults name one
+const DefaultType = "opencode"
+
+// Manifest lists the tasks of a batch
+type Manifest struct {
+	Name     string `yaml:"name" json:"name"`
+	Defaults Task   `yaml:"defaults" json:"defaults"`
+	Tasks    []Task `yaml:"tasks" json:"tasks"`
+}
+
+// Task is one agent to spawn
+type Task struct {
+	Name      string            `yaml:"name" json:"name,omitempty"`
+	Directory string            `yaml:"directory" json:"directory,omitempty"`
+	Prompt    string            `yaml:"prompt" json:"prompt,omitempty"`
+	Type      string            `yaml:"type" json:"type,omitempty"`
+	Env       map[string]string `yaml:"env" json:"env,omitempty"`
+	Tags      []string          `yaml:"tags" json:"tags,omitempty"`
+}
+
+// SpawnConfig returns the spawn request for the task
+func (t Task) SpawnConfig() agent.SpawnConfig {
+	return agent.SpawnConfig{
+		Type:      t.Type,
+		Name:      t.Name,
+		Directory: t.Directory,
+		Prompt:    t.Prompt,
+		Env:       t.Env,
+	}
+}
+
+// Load reads a YAML or JSON manifest. Defaults are applied to every task and
+// relative directories are resolved against the manifest's directory.
+func Load(path string) (*Manifest, error) {
+	path = expandHome(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&m)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	if m.Name == "" {
+		m.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := m.normalize(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// normalize applies the defaults to each task and validates it
+func (m *Manifest) normalize(base string) error {
+	if len(m.Tasks) == 0 {
+		return fmt.Errorf("no tasks")
+	}
+
+	d := m.Defaults
+	for i := range m.Tasks {
+		t := &m.Tasks[i]
+		if t.Directory == "" {
+			t.Directory = d.Directory
+		}
+		if t.Directory == "" {
+			return fmt.Errorf("task %d: directory is required", i+1)
+		}
+		t.Directory = expandHome(t.Directory)
+		if !filepath.IsAbs(t.Directory) {
+			t.Directory = filepath.Join(base, t.Directory)
+		}
+		t.Directory = filepath.Clean(t.Directory)
+
+		if t.Prompt == "" {
+			t.Prompt = d.Prompt
+		}
+		if t.Type == "" {
+			t.Type = d.Type
+		}
+		if t.Type == "" {
+			t.Type = DefaultType
+		}
+		if t.Name == "" {
+			t.Name = d.Name
+		}
+		if t.Name == "" {
+			t.Name = filepath.Base(t.Directory)
+		}
+
+		if len(d.Env) > 0 {
+			env := make(map[string]string, len(d.Env)+len(t.Env))
+			for k, v := range d.Env {
+				env[k] = v
+			}
+			for k, v := range t.Env {
+				env[k] = v
+			}
+			t.Env = env
+		}
+		if len(d.Tags) > 0 {
+			t.Tags = append(append([]string(nil), d.Tags...), t.Tags...)
+		}
+	}
+	return nil
+}
+
+// ResultPath is where the result of a manifest's batch is written by default
+func ResultPath(manifestPath string) string {
+	manifestPath = expandHome(manifestPath)
+	return strings.TrimSuffix(manifestPath, filepath.Ext(manifestPath)) + ".result.json"
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
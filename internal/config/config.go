@@ -21,6 +21,12 @@ type Config struct {
 	Metrics   MetricsConfig   `yaml:"metrics"`
 	Plugins   PluginsConfig   `yaml:"plugins"`
 	API       APIConfig       `yaml:"api"`
+	Daemon    DaemonConfig    `yaml:"daemon"`
+	Queue     QueueConfig     `yaml:"queue"`
+	Restart   RestartConfig   `yaml:"restart"`
+	Worktree  WorktreeConfig  `yaml:"worktree"`
+	Conflicts ConflictsConfig `yaml:"conflicts"`
+	Recording RecordingConfig `yaml:"recording"`
 }
 
 // PluginsConfig holds plugin settings
@@ -33,6 +39,70 @@ type PluginsConfig struct {
 type APIConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Address string `yaml:"address"`
+	Token   string `yaml:"token"` // required as a bearer token by every request but health checks when set
+}
+
+// DaemonConfig holds headless daemon settings
+type DaemonConfig struct {
+	Socket string `yaml:"socket"` // Unix socket the daemon listens on and TUIs attach to
+}
+
+// QueueConfig holds spawn queue limits. Agents count against them while
+// pending or running; further spawns wait in the queue.
+type QueueConfig struct {
+	MaxConcurrent int `yaml:"max_concurrent"`  // Across all projects (0 = unlimited)
+	MaxPerProject int `yaml:"max_per_project"` // Per spawn directory (0 = unlimited)
+}
+
+// WorktreeConfig holds settings for agents spawned in their own git worktree
+type WorktreeConfig struct {
+	Dir string `yaml:"dir"` // Worktrees are created under <dir>/<repository>/
+}
+
+// ConflictsConfig holds settings for detecting agents that change the same file
+type ConflictsConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Window  time.Duration `yaml:"window"` // Changes by two agents this close together conflict (0 = any time apart)
+	Watch   bool          `yaml:"watch"`  // Also watch the directories of running agents
+	Ignore  []string      `yaml:"ignore"` // Directory names the watch skips
+}
+
+// RecordingConfig holds settings for recording agent events for replay
+type RecordingConfig struct {
+	Path   string `yaml:"path"`   // NDJSON file events are appended to (empty = off)
+	Output bool   `yaml:"output"` // Also record agent output when it changes
+}
+
+// Restart policies
+const (
+	RestartNever   = "never"    // leave errored agents alone
+	RestartOnError = "on-error" // restart agents that error
+	RestartAlways  = "always"   // restart agents that error or complete
+)
+
+// RestartConfig holds the policy for restarting agents by sending them a
+// prompt again. Attempt N waits Backoff * 2^(N-1), capped at MaxBackoff.
+// Attempts count from 1 again once an agent recovers: it goes idle, or
+// completes when the policy does not restart completed agents.
+type RestartConfig struct {
+	Policy      string            `yaml:"policy"`       // "never", "on-error" or "always"
+	MaxAttempts int               `yaml:"max_attempts"` // per agent between recoveries (0 = unlimited)
+	Backoff     time.Duration     `yaml:"backoff"`
+	MaxBackoff  time.Duration     `yaml:"max_backoff"`
+	Prompt      string            `yaml:"prompt"` // sent instead of the agent's last prompt
+	Overrides   []RestartOverride `yaml:"overrides"`
+}
+
+// RestartOverride replaces restart settings for agents of a type, a
+// project, or both. Empty and zero fields keep the inherited value.
+type RestartOverride struct {
+	Type        string        `yaml:"type"`    // empty matches any type
+	Project     string        `yaml:"project"` // empty matches any project
+	Policy      string        `yaml:"policy"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	Prompt      string        `yaml:"prompt"`
 }
 
 // GeneralConfig holds general settings
@@ -44,7 +114,9 @@ type GeneralConfig struct {
 
 // ProvidersConfig holds provider-specific settings
 type ProvidersConfig struct {
-	OpenCode OpenCodeConfig `yaml:"opencode"`
+	OpenCode OpenCodeConfig  `yaml:"opencode"`
+	Claude   ClaudeConfig    `yaml:"claude"`
+	Process  []ProcessConfig `yaml:"process"`
 }
 
 // OpenCodeConfig holds opencode provider settings
@@ -55,6 +127,45 @@ type OpenCodeConfig struct {
 	MaxAge        time.Duration `yaml:"max_age"` // Max age of sessions to load (0 = no limit)
 }
 
+// ClaudeConfig holds Claude Code provider settings
+type ClaudeConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	ProjectsPath  string        `yaml:"projects_path"` // Directory holding <project>/<session>.jsonl transcripts
+	WatchInterval time.Duration `yaml:"watch_interval"`
+	MaxAge        time.Duration `yaml:"max_age"` // Max age of sessions to load (0 = no limit)
+}
+
+// ProcessConfig defines a generic CLI agent type run inside a pseudo-terminal
+type ProcessConfig struct {
+	Type             string                `yaml:"type"`
+	Name             string                `yaml:"name"`
+	Command          string                `yaml:"command"`
+	Args             []string              `yaml:"args"` // "{prompt}" is replaced with the spawn prompt
+	Env              map[string]string     `yaml:"env"`
+	Patterns         ProcessPatternsConfig `yaml:"patterns"`
+	IdleAfter        time.Duration         `yaml:"idle_after"`        // silence before an agent is considered idle
+	TerminateTimeout time.Duration         `yaml:"terminate_timeout"` // wait between SIGINT, SIGTERM and SIGKILL
+}
+
+// ProcessPatternsConfig holds regular expressions matched against output lines
+type ProcessPatternsConfig struct {
+	Running      []string `yaml:"running"`
+	Idle         []string `yaml:"idle"`
+	Completed    []string `yaml:"completed"`
+	Errored      []string `yaml:"errored"`
+	ContextLimit []string `yaml:"context_limit"`
+}
+
+// ThresholdOverride replaces watchdog thresholds for agents of a type, a
+// project, or both. Zero durations keep the inherited value.
+type ThresholdOverride struct {
+	Type        string        `yaml:"type"`    // empty matches any type
+	Project     string        `yaml:"project"` // empty matches any project
+	LongRunning time.Duration `yaml:"long_running"`
+	ToolTimeout time.Duration `yaml:"tool_timeout"`
+	NoProgress  time.Duration `yaml:"no_progress"`
+}
+
 // AlertsConfig holds alert settings
 type AlertsConfig struct {
 	ContextLimitWarning  int           `yaml:"context_limit_warning"` // percentage
@@ -66,6 +177,118 @@ type AlertsConfig struct {
 	SlackChannel         string        `yaml:"slack_channel"`
 	DiscordEnabled       bool          `yaml:"discord_enabled"`
 	DiscordWebhookURL    string        `yaml:"discord_webhook_url"`
+
+	// Webhooks are further channels, each posting a templated body to a
+	// URL. The Slack and Discord settings above are shorthands for webhooks
+	// named slack and discord with the matching preset.
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	// Emails are channels sending alerts by email over SMTP
+	Emails []EmailConfig `yaml:"emails"`
+
+	// Watchdog thresholds; LongRunningThreshold above is the running-time limit
+	ToolTimeout       time.Duration       `yaml:"tool_timeout"`        // a single tool call running longer is hung
+	NoProgressTimeout time.Duration       `yaml:"no_progress_timeout"` // running without output, or a prompt unanswered
+	WatchdogInterval  time.Duration       `yaml:"watchdog_interval"`
+	Thresholds        []ThresholdOverride `yaml:"thresholds"`
+
+	// Rules turn agent events into alerts; with none, the built-in rules
+	// for errored, completed and context limit events apply
+	Rules []AlertRule `yaml:"rules"`
+
+	// Suppression; suppressed alerts are still listed, marked as such
+	DedupWindow    time.Duration      `yaml:"dedup_window"` // an alert repeating within this is a duplicate (0 = off)
+	RateLimit      RateLimitConfig    `yaml:"rate_limit"`
+	SnoozeDuration time.Duration      `yaml:"snooze_duration"` // how long snoozing from the alerts panel lasts
+	QuietHours     []QuietHoursConfig `yaml:"quiet_hours"`
+
+	// Delivery sets how sends to webhooks are retried
+	Delivery DeliveryConfig `yaml:"delivery"`
+}
+
+// WebhookConfig is a webhook alert channel
+type WebhookConfig struct {
+	Name            string            `yaml:"name"` // the channel name rules, quiet hours and delivery settings use
+	URL             string            `yaml:"url"`
+	Method          string            `yaml:"method"`  // defaults to POST
+	Headers         map[string]string `yaml:"headers"` // values are Go templates
+	Preset          string            `yaml:"preset"`  // "slack" or "discord" fills in their body
+	Body            string            `yaml:"body"`    // Go template; empty sends the preset's body, or the alert as JSON
+	Secret          string            `yaml:"secret"`  // signs the body with HMAC-SHA256 when set
+	SignatureHeader string            `yaml:"signature_header"`
+	Timeout         time.Duration     `yaml:"timeout"`
+}
+
+// EmailConfig is an email alert channel
+type EmailConfig struct {
+	Name     string        `yaml:"name"` // the channel name rules, quiet hours and delivery settings use
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`     // defaults to 465 with tls, else 587
+	TLS      string        `yaml:"tls"`      // "starttls" (the default), "tls" or "none"
+	Username string        `yaml:"username"` // authenticates with PLAIN when set
+	Password string        `yaml:"password"`
+	From     string        `yaml:"from"`
+	To       []string      `yaml:"to"`
+	Digest   time.Duration `yaml:"digest"` // sends the alerts of each such window as one email (0 = one email per alert)
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// DeliveryConfig sets how failed sends to webhooks and email, Slack and
+// Discord included, are retried
+type DeliveryConfig struct {
+	RetryConfig `yaml:",inline"`
+	Channels    map[string]RetryConfig `yaml:"channels"` // per channel overrides; unset fields keep the defaults
+}
+
+// RetryConfig bounds the attempts to deliver an alert to a channel
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"` // wait after the first failure, doubling after each further one
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+}
+
+// RateLimitConfig caps how many alerts are sent within a window
+type RateLimitConfig struct {
+	Window     time.Duration `yaml:"window"`
+	PerAgent   int           `yaml:"per_agent"`   // alerts about one agent (0 = unlimited)
+	PerChannel int           `yaml:"per_channel"` // alerts sent to one channel (0 = unlimited)
+}
+
+// Quiet hours actions
+const (
+	QuietDowngrade = "downgrade" // lower alert levels, dropping info alerts
+	QuietBatch     = "batch"     // hold alerts and send a summary when quiet hours end
+)
+
+// QuietHoursConfig changes how alerts are sent to channels during a daily
+// period, in local time
+type QuietHoursConfig struct {
+	Channels []string `yaml:"channels"` // empty applies to all channels
+	Start    string   `yaml:"start"`    // "22:00"
+	End      string   `yaml:"end"`      // "07:00"; before Start means the next day
+	Action   string   `yaml:"action"`   // "downgrade" or "batch"
+}
+
+// AlertRule raises an alert for agent events that meet all its conditions.
+// Empty and zero conditions match anything.
+type AlertRule struct {
+	Name       string   `yaml:"name"`
+	Events     []string `yaml:"events"`      // event types, e.g. errored
+	From       []string `yaml:"from"`        // status before a status change
+	To         []string `yaml:"to"`          // status after a status change
+	AgentTypes []string `yaml:"agent_types"` // e.g. opencode
+	Projects   []string `yaml:"projects"`
+	Directory  string   `yaml:"directory"` // glob matching the agent's directory or a parent
+	MinCost    float64  `yaml:"min_cost"`
+	MinTokens  int64    `yaml:"min_tokens"` // input and output tokens
+	MinErrors  int      `yaml:"min_errors"`
+	Output     string   `yaml:"output"` // regular expression matched against recent output
+
+	Level    string   `yaml:"level"`     // info, warning, error or success (default info)
+	Title    string   `yaml:"title"`     // Go template
+	Message  string   `yaml:"message"`   // Go template
+	Channels []string `yaml:"channels"`  // channel names; empty sends to all
+	DedupKey string   `yaml:"dedup_key"` // Go template; alerts with equal keys are duplicates
+	Continue bool     `yaml:"continue"`  // keep checking later rules after this one matches
 }
 
 // UIConfig holds UI settings
@@ -124,6 +347,18 @@ type StorageConfig struct {
 type MetricsConfig struct {
 	TokenCostInput  float64 `yaml:"token_cost_input"`  // cost per 1k input tokens
 	TokenCostOutput float64 `yaml:"token_cost_output"` // cost per 1k output tokens
+
+	// Models overrides or extends the built-in model catalog, keyed by provider/model
+	Models map[string]ModelConfig `yaml:"models"`
+}
+
+// ModelConfig holds pricing (per 1k tokens) and limits for a single model
+type ModelConfig struct {
+	Input         float64 `yaml:"input"`
+	Output        float64 `yaml:"output"`
+	CacheRead     float64 `yaml:"cache_read"`
+	CacheWrite    float64 `yaml:"cache_write"`
+	ContextWindow int64   `yaml:"context_window"`
 }
 
 // DefaultConfig returns the default configuration
@@ -143,14 +378,29 @@ func DefaultConfig() *Config {
 				WatchInterval: 5 * time.Second,
 				MaxAge:        24 * time.Hour,
 			},
+			Claude: ClaudeConfig{
+				Enabled:       true,
+				ProjectsPath:  filepath.Join(homeDir, ".claude", "projects"),
+				WatchInterval: 5 * time.Second,
+				MaxAge:        24 * time.Hour,
+			},
 		},
 		Alerts: AlertsConfig{
 			ContextLimitWarning:  90,
 			LongRunningThreshold: 30 * time.Minute,
+			ToolTimeout:          10 * time.Minute,
+			NoProgressTimeout:    10 * time.Minute,
+			WatchdogInterval:     30 * time.Second,
 			SoundEnabled:         false,
 			DesktopNotifications: true,
 			SlackEnabled:         false,
 			DiscordEnabled:       false,
+			DedupWindow:          5 * time.Minute,
+			RateLimit:            RateLimitConfig{Window: time.Hour},
+			SnoozeDuration:       time.Hour,
+			Delivery: DeliveryConfig{
+				RetryConfig: RetryConfig{MaxAttempts: 8, Backoff: 30 * time.Second, MaxBackoff: 30 * time.Minute},
+			},
 		},
 		UI: UIConfig{
 			ShowHeader:      true,
@@ -203,7 +453,28 @@ func DefaultConfig() *Config {
 		},
 		API: APIConfig{
 			Enabled: false,
-			Address: ":8080",
+			Address: "127.0.0.1:8080",
+		},
+		Daemon: DaemonConfig{
+			Socket: filepath.Join(homeDir, ".local", "share", "auto", "auto.sock"),
+		},
+		Restart: RestartConfig{
+			Policy:      RestartNever,
+			MaxAttempts: 3,
+			Backoff:     30 * time.Second,
+			MaxBackoff:  10 * time.Minute,
+		},
+		Worktree: WorktreeConfig{
+			Dir: filepath.Join(homeDir, ".local", "share", "auto", "worktrees"),
+		},
+		Conflicts: ConflictsConfig{
+			Enabled: true,
+			Window:  30 * time.Minute,
+			Watch:   true,
+			Ignore:  []string{".git", "node_modules", "vendor", ".venv", "__pycache__", "dist", "build", "target"},
+		},
+		Recording: RecordingConfig{
+			Output: true,
 		},
 	}
 }
@@ -22,6 +22,14 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("OpenCode provider should be enabled by default")
 	}
 
+	if !cfg.Providers.Claude.Enabled {
+		t.Error("Claude provider should be enabled by default")
+	}
+
+	if cfg.API.Address != "127.0.0.1:8080" {
+		t.Errorf("Default API address should only listen locally, got %v", cfg.API.Address)
+	}
+
 	if cfg.Theme.Mode != "dark" {
 		t.Errorf("Default theme mode should be 'dark', got %v", cfg.Theme.Mode)
 	}
@@ -29,6 +37,26 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.UI.DefaultGrouping != "type" {
 		t.Errorf("Default grouping should be 'type', got %v", cfg.UI.DefaultGrouping)
 	}
+
+	if filepath.Base(cfg.Daemon.Socket) != "auto.sock" {
+		t.Errorf("Default daemon socket should be auto.sock, got %v", cfg.Daemon.Socket)
+	}
+
+	if cfg.Restart.Policy != RestartNever {
+		t.Errorf("Agents should not be restarted by default, got policy %v", cfg.Restart.Policy)
+	}
+
+	if !cfg.Conflicts.Enabled || cfg.Conflicts.Window != 30*time.Minute {
+		t.Errorf("Conflict detection should be on with a 30m window, got %+v", cfg.Conflicts)
+	}
+
+	if cfg.Recording.Path != "" || !cfg.Recording.Output {
+		t.Errorf("Recording should be off and include output once on, got %+v", cfg.Recording)
+	}
+
+	if cfg.Alerts.DedupWindow != 5*time.Minute || cfg.Alerts.RateLimit.PerAgent != 0 || cfg.Alerts.SnoozeDuration != time.Hour {
+		t.Errorf("Unexpected alert suppression defaults: %+v", cfg.Alerts)
+	}
 }
 
 func TestLoadNonexistent(t *testing.T) {
@@ -82,3 +110,181 @@ func TestConfigPath(t *testing.T) {
 		t.Error("ConfigPath should return absolute path")
 	}
 }
+
+func TestLoadProcessProviders(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := `providers:
+  process:
+    - type: aider
+      command: aider
+      args: ["--message", "{prompt}"]
+      patterns:
+        idle: ["^> $"]
+      idle_after: 10s
+`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Providers.Process) != 1 {
+		t.Fatalf("Expected 1 process provider, got %d", len(cfg.Providers.Process))
+	}
+	pc := cfg.Providers.Process[0]
+	if pc.Type != "aider" || pc.Command != "aider" || len(pc.Args) != 2 {
+		t.Errorf("Unexpected process provider: %+v", pc)
+	}
+	if len(pc.Patterns.Idle) != 1 || pc.IdleAfter != 10*time.Second {
+		t.Errorf("Unexpected process patterns/idle: %+v", pc)
+	}
+	if !cfg.Providers.OpenCode.Enabled {
+		t.Error("Defaults should be kept for unspecified providers")
+	}
+}
+
+func TestLoadModelCatalog(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := `metrics:
+  models:
+    anthropic/claude-sonnet-4:
+      input: 0.002
+      output: 0.01
+      context_window: 1000000
+`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	model, ok := cfg.Metrics.Models["anthropic/claude-sonnet-4"]
+	if !ok {
+		t.Fatalf("Expected model override, got %v", cfg.Metrics.Models)
+	}
+	if model.Input != 0.002 || model.Output != 0.01 || model.ContextWindow != 1000000 {
+		t.Errorf("Unexpected model config: %+v", model)
+	}
+	if cfg.Metrics.TokenCostInput != 0.003 {
+		t.Error("Defaults should be kept for unspecified metrics settings")
+	}
+}
+
+func TestLoadWatchdogThresholds(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := `alerts:
+  tool_timeout: 5m
+  thresholds:
+    - type: claude
+      long_running: 1h
+    - project: /work/big
+      tool_timeout: -1s
+`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Alerts.ToolTimeout != 5*time.Minute || cfg.Alerts.NoProgressTimeout != 10*time.Minute {
+		t.Errorf("Unexpected watchdog timeouts: %+v", cfg.Alerts)
+	}
+	if len(cfg.Alerts.Thresholds) != 2 {
+		t.Fatalf("Expected 2 threshold overrides, got %d", len(cfg.Alerts.Thresholds))
+	}
+	if o := cfg.Alerts.Thresholds[0]; o.Type != "claude" || o.LongRunning != time.Hour {
+		t.Errorf("Unexpected type override: %+v", o)
+	}
+	if o := cfg.Alerts.Thresholds[1]; o.Project != "/work/big" || o.ToolTimeout != -time.Second {
+		t.Errorf("Unexpected project override: %+v", o)
+	}
+}
+
+func TestLoadAlertRules(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := `alerts:
+  rules:
+    - name: expensive
+      min_cost: 5
+      projects: [api]
+      level: warning
+      title: "{{.Name}} is expensive"
+      channels: [slack]
+    - events: [errored]
+      to: [errored]
+      output: "(?i)rate limit"
+      dedup_key: "{{.Project}}"
+      continue: true
+  rate_limit:
+    per_channel: 20
+  quiet_hours:
+    - channels: [desktop]
+      start: "22:00"
+      end: "07:00"
+      action: batch
+  webhooks:
+    - name: oncall
+      url: https://oncall.example.com/hook
+      headers:
+        Title: "{{.Title}}"
+      body: '{"text": {{json .Message}}}'
+      secret: s3cret
+      timeout: 5s
+  emails:
+    - name: stakeholders
+      host: smtp.example.com
+      username: auto
+      from: auto@example.com
+      to: [lead@example.com, pm@example.com]
+      digest: 1h
+  delivery:
+    max_attempts: 3
+    channels:
+      slack:
+        backoff: 5s
+`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Alerts.Rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(cfg.Alerts.Rules))
+	}
+	if r := cfg.Alerts.Rules[0]; r.Name != "expensive" || r.MinCost != 5 || r.Projects[0] != "api" || r.Channels[0] != "slack" {
+		t.Errorf("Unexpected rule: %+v", r)
+	}
+	if r := cfg.Alerts.Rules[1]; r.Events[0] != "errored" || r.To[0] != "errored" || r.Output != "(?i)rate limit" || r.DedupKey != "{{.Project}}" || !r.Continue {
+		t.Errorf("Unexpected rule: %+v", r)
+	}
+	if rl := cfg.Alerts.RateLimit; rl.PerChannel != 20 || rl.Window != time.Hour {
+		t.Errorf("Unexpected rate limit, the window should default to 1h: %+v", rl)
+	}
+	if q := cfg.Alerts.QuietHours; len(q) != 1 || q[0].Channels[0] != "desktop" || q[0].Start != "22:00" || q[0].Action != QuietBatch {
+		t.Errorf("Unexpected quiet hours: %+v", q)
+	}
+	if w := cfg.Alerts.Webhooks; len(w) != 1 || w[0].Name != "oncall" || w[0].Headers["Title"] != "{{.Title}}" ||
+		w[0].Body != `{"text": {{json .Message}}}` || w[0].Secret != "s3cret" || w[0].Timeout != 5*time.Second {
+		t.Errorf("Unexpected webhooks: %+v", w)
+	}
+	if e := cfg.Alerts.Emails; len(e) != 1 || e[0].Name != "stakeholders" || e[0].Host != "smtp.example.com" ||
+		len(e[0].To) != 2 || e[0].Digest != time.Hour {
+		t.Errorf("Unexpected emails: %+v", e)
+	}
+	d := cfg.Alerts.Delivery
+	if d.MaxAttempts != 3 || d.Backoff != 30*time.Second || d.Channels["slack"].Backoff != 5*time.Second {
+		t.Errorf("Unexpected delivery settings: %+v", d)
+	}
+}
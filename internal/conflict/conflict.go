@@ -0,0 +1,184 @@
+// Package conflict tracks which files each agent changes and detects agents
+// changing the same file
+package conflict
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sources of a recorded change
+const (
+	SourceAgent = "agent" // reported by the agent itself, e.g. an opencode edit
+	SourceWatch = "watch" // seen by the filesystem watch
+)
+
+// Change is an agent's latest change to a file
+type Change struct {
+	AgentID   string    `json:"agent_id"`
+	AgentName string    `json:"agent_name"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+}
+
+// File is a file changed by at least one agent
+type File struct {
+	Path     string   `json:"path"`
+	Changes  []Change `json:"changes"`  // one per agent, most recent first
+	Conflict bool     `json:"conflict"` // changed by several agents within the window
+}
+
+// Conflict is two agents changing the same file within the window
+type Conflict struct {
+	Path   string
+	First  Change // the earlier change
+	Second Change
+}
+
+// pairKey identifies a conflict so each one is reported once
+type pairKey struct {
+	path   string
+	first  string
+	second string
+}
+
+// Tracker records file changes by agent
+type Tracker struct {
+	mu       sync.Mutex
+	window   time.Duration
+	files    map[string]map[string]Change // path -> agent ID -> latest change
+	reported map[pairKey]bool
+}
+
+// NewTracker creates a tracker. Changes by different agents to the same
+// file conflict when they are at most window apart; zero means any time.
+func NewTracker(window time.Duration) *Tracker {
+	return &Tracker{
+		window:   window,
+		files:    make(map[string]map[string]Change),
+		reported: make(map[pairKey]bool),
+	}
+}
+
+// Record records a change to path and returns the conflicts it causes that
+// were not reported before
+func (t *Tracker) Record(path string, c Change) []Conflict {
+	path = filepath.Clean(path)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changes, ok := t.files[path]
+	if !ok {
+		changes = make(map[string]Change)
+		t.files[path] = changes
+	}
+	if prev, ok := changes[c.AgentID]; ok && !c.Time.After(prev.Time) {
+		return nil
+	}
+	changes[c.AgentID] = c
+
+	var conflicts []Conflict
+	for _, other := range sortedChanges(changes) {
+		if other.AgentID == c.AgentID || !t.overlap(c, other) {
+			continue
+		}
+		first, second := other, c
+		if c.Time.Before(other.Time) {
+			first, second = c, other
+		}
+		key := pairKey{path, first.AgentID, second.AgentID}
+		if first.AgentID > second.AgentID {
+			key.first, key.second = second.AgentID, first.AgentID
+		}
+		if t.reported[key] {
+			continue
+		}
+		t.reported[key] = true
+		conflicts = append(conflicts, Conflict{Path: path, First: first, Second: second})
+	}
+	return conflicts
+}
+
+// overlap reports whether two changes are close enough to conflict
+func (t *Tracker) overlap(a, b Change) bool {
+	if t.window <= 0 {
+		return true
+	}
+	d := a.Time.Sub(b.Time)
+	if d < 0 {
+		d = -d
+	}
+	return d <= t.window
+}
+
+// Files returns the changed files, conflicts first, then by latest change
+func (t *Tracker) Files() []File {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	files := make([]File, 0, len(t.files))
+	for path, changes := range t.files {
+		f := File{Path: path, Changes: sortedChanges(changes)}
+		for i := 0; i < len(f.Changes) && !f.Conflict; i++ {
+			for j := i + 1; j < len(f.Changes); j++ {
+				if t.overlap(f.Changes[i], f.Changes[j]) {
+					f.Conflict = true
+					break
+				}
+			}
+		}
+		files = append(files, f)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Conflict != files[j].Conflict {
+			return files[i].Conflict
+		}
+		ti, tj := files[i].Changes[0].Time, files[j].Changes[0].Time
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// Prune forgets changes made before the given time
+func (t *Tracker) Prune(before time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for path, changes := range t.files {
+		for id, c := range changes {
+			if c.Time.Before(before) {
+				delete(changes, id)
+			}
+		}
+		if len(changes) == 0 {
+			delete(t.files, path)
+		}
+	}
+	for key := range t.reported {
+		if _, ok := t.files[key.path]; !ok {
+			delete(t.reported, key)
+		}
+	}
+}
+
+// sortedChanges returns the changes most recent first
+func sortedChanges(changes map[string]Change) []Change {
+	list := make([]Change, 0, len(changes))
+	for _, c := range changes {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Time.Equal(list[j].Time) {
+			return list[i].Time.After(list[j].Time)
+		}
+		return list[i].AgentID < list[j].AgentID
+	})
+	return list
+}
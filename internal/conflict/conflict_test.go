@@ -0,0 +1,173 @@
+package conflict
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func change(id string, at time.Time) Change {
+	return Change{AgentID: id, AgentName: id, Time: at, Source: SourceAgent}
+}
+
+func TestTrackerConflicts(t *testing.T) {
+	tr := NewTracker(10 * time.Minute)
+	now := time.Now()
+
+	if c := tr.Record("/srv/app/main.go", change("a", now)); len(c) != 0 {
+		t.Fatalf("first change conflicts: %+v", c)
+	}
+	if c := tr.Record("/srv/app/main.go", change("a", now.Add(time.Minute))); len(c) != 0 {
+		t.Fatalf("same agent conflicts with itself: %+v", c)
+	}
+
+	c := tr.Record("/srv/app/./main.go", change("b", now.Add(2*time.Minute)))
+	if len(c) != 1 || c[0].Path != "/srv/app/main.go" || c[0].First.AgentID != "a" || c[0].Second.AgentID != "b" {
+		t.Fatalf("Record() = %+v, want a conflict between a and b", c)
+	}
+
+	// Reported once per pair, whichever agent changes the file next
+	if c := tr.Record("/srv/app/main.go", change("a", now.Add(3*time.Minute))); len(c) != 0 {
+		t.Errorf("conflict reported again: %+v", c)
+	}
+	if c := tr.Record("/srv/app/main.go", change("c", now.Add(4*time.Minute))); len(c) != 2 {
+		t.Errorf("third agent should conflict with both others, got %+v", c)
+	}
+
+	// Older reports do not move a change back in time
+	tr.Record("/srv/app/main.go", change("a", now))
+	if got := tr.Files()[0].Changes[1]; got.AgentID != "a" || !got.Time.Equal(now.Add(3*time.Minute)) {
+		t.Errorf("change of a = %+v", got)
+	}
+}
+
+func TestTrackerWindow(t *testing.T) {
+	tr := NewTracker(time.Minute)
+	now := time.Now()
+
+	tr.Record("/srv/app/a.go", change("a", now))
+	if c := tr.Record("/srv/app/a.go", change("b", now.Add(2*time.Minute))); len(c) != 0 {
+		t.Errorf("changes outside the window conflict: %+v", c)
+	}
+	tr.Record("/srv/app/b.go", change("a", now))
+	tr.Record("/srv/app/b.go", change("b", now.Add(30*time.Second)))
+	tr.Record("/srv/app/c.go", change("b", now.Add(time.Hour)))
+
+	files := tr.Files()
+	if len(files) != 3 {
+		t.Fatalf("Files() = %+v", files)
+	}
+	if files[0].Path != "/srv/app/b.go" || !files[0].Conflict {
+		t.Errorf("conflicting file should come first, got %+v", files[0])
+	}
+	if files[1].Path != "/srv/app/c.go" || files[1].Conflict || files[2].Conflict {
+		t.Errorf("files = %+v", files)
+	}
+	if len(files[2].Changes) != 2 || files[2].Changes[0].AgentID != "b" {
+		t.Errorf("changes should be most recent first, got %+v", files[2].Changes)
+	}
+
+	tr.Prune(now.Add(time.Minute))
+	if files := tr.Files(); len(files) != 2 || files[0].Path != "/srv/app/c.go" {
+		t.Errorf("Files() after Prune = %+v", files)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "node_modules", "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher([]string{"node_modules"})
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	if err := w.Add(root); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, func(path string, at time.Time) {
+		mu.Lock()
+		seen[path] = true
+		mu.Unlock()
+	})
+
+	wait := func(path string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			mu.Lock()
+			ok := seen[path]
+			mu.Unlock()
+			if ok {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("no change reported for %s", path)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	os.WriteFile(filepath.Join(root, "node_modules", "lib", "index.js"), []byte("x"), 0644)
+	top := filepath.Join(root, "main.go")
+	os.WriteFile(top, []byte("package main\n"), 0644)
+	wait(top)
+
+	// Directories created later are watched too
+	sub := filepath.Join(root, "pkg")
+	os.Mkdir(sub, 0755)
+	nested := filepath.Join(sub, "util.go")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		os.WriteFile(nested, []byte("package pkg\n"), 0644)
+		mu.Lock()
+		ok := seen[nested]
+		mu.Unlock()
+		if ok || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	wait(nested)
+
+	mu.Lock()
+	if seen[filepath.Join(root, "node_modules", "lib", "index.js")] {
+		t.Error("ignored directories should not be watched")
+	}
+	mu.Unlock()
+
+	if got := w.Roots(); len(got) != 1 || got[0] != root {
+		t.Errorf("Roots() = %v", got)
+	}
+	w.Remove(root)
+	if got := w.Roots(); len(got) != 0 {
+		t.Errorf("Roots() after Remove = %v", got)
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"/srv/app", "/srv/app/main.go", true},
+		{"/srv/app", "/srv/app", true},
+		{"/srv/app", "/srv/apple/main.go", false},
+		{"/srv/app", "/srv/other/main.go", false},
+		{"/srv/app", "/srv/app/..data", true},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.dir, tt.path); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package conflict
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// maxWatchedDirs bounds the directories watched under one root, so an
+// agent running in a home directory does not exhaust the inotify limit
+const maxWatchedDirs = 2000
+
+// Watcher reports files written under a set of root directories. fsnotify
+// is not recursive, so every directory below a root is watched.
+type Watcher struct {
+	fsw    *fsnotify.Watcher
+	ignore map[string]bool // directory names not descended into
+
+	mu    sync.Mutex
+	roots map[string]bool
+	dirs  map[string]map[string]bool // watched directory -> roots it is under
+}
+
+// NewWatcher creates a watcher that skips directories with the given names
+func NewWatcher(ignore []string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	w := &Watcher{
+		fsw:    fsw,
+		ignore: make(map[string]bool),
+		roots:  make(map[string]bool),
+		dirs:   make(map[string]map[string]bool),
+	}
+	for _, name := range ignore {
+		w.ignore[name] = true
+	}
+	return w, nil
+}
+
+// Add starts watching root and the directories below it
+func (w *Watcher) Add(root string) error {
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.roots[root] {
+		return nil
+	}
+	w.roots[root] = true
+	w.addTreeLocked(root, root)
+	return nil
+}
+
+// addTreeLocked watches dir and the directories below it for root
+func (w *Watcher) addTreeLocked(root, dir string) {
+	count := 0
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != dir && w.ignore[d.Name()] {
+			return filepath.SkipDir
+		}
+		if count >= maxWatchedDirs {
+			log.Printf("Not watching beyond %d directories under %s", maxWatchedDirs, root)
+			return filepath.SkipAll
+		}
+		count++
+		w.watchLocked(root, path)
+		return nil
+	})
+}
+
+func (w *Watcher) watchLocked(root, dir string) {
+	owners, ok := w.dirs[dir]
+	if !ok {
+		if err := w.fsw.Add(dir); err != nil {
+			return
+		}
+		owners = make(map[string]bool)
+		w.dirs[dir] = owners
+	}
+	owners[root] = true
+}
+
+// Remove stops watching root
+func (w *Watcher) Remove(root string) {
+	root = filepath.Clean(root)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.roots[root] {
+		return
+	}
+	delete(w.roots, root)
+	for dir, owners := range w.dirs {
+		delete(owners, root)
+		if len(owners) == 0 {
+			w.fsw.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+}
+
+// Roots returns the watched roots in order
+func (w *Watcher) Roots() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	roots := make([]string, 0, len(w.roots))
+	for root := range w.roots {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Run calls fn for every file created or written under a root until ctx is
+// done, and closes the watcher
+func (w *Watcher) Run(ctx context.Context, fn func(path string, at time.Time)) {
+	defer w.fsw.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				if event.Op&fsnotify.Create != 0 {
+					w.addCreatedDir(event.Name)
+				}
+				continue
+			}
+			fn(event.Name, time.Now())
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("File watch error: %v", err)
+		}
+	}
+}
+
+// addCreatedDir watches a new directory for every root it is under
+func (w *Watcher) addCreatedDir(dir string) {
+	if w.ignore[filepath.Base(dir)] {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for root := range w.roots {
+		if Contains(root, dir) {
+			w.addTreeLocked(root, dir)
+		}
+	}
+}
+
+// Contains reports whether path is dir or lies below it
+func Contains(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
@@ -0,0 +1,618 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+)
+
+// spawnTimeout bounds a spawn request, which waits for the new session to appear
+const spawnTimeout = 2 * time.Minute
+
+// Client is an agent provider backed by a running daemon. Its agents are
+// local views of the daemon's agents kept current by event notifications,
+// and control calls on them are forwarded over the socket.
+type Client struct {
+	conn        net.Conn
+	info        HelloResult
+	callTimeout time.Duration
+
+	writeMu sync.Mutex
+	mu      sync.RWMutex
+	pending map[int64]chan *message
+	nextID  int64
+	agents  map[string]*remoteAgent
+	onAlert func(*alert.Alert)
+
+	events chan agent.Event
+	done   chan struct{}
+}
+
+// Dial connects to the daemon listening on the socket at path and subscribes
+// to its events and alerts
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+
+	c := &Client{
+		conn:        conn,
+		callTimeout: 30 * time.Second,
+		pending:     make(map[int64]chan *message),
+		agents:      make(map[string]*remoteAgent),
+		events:      make(chan agent.Event, 100),
+		done:        make(chan struct{}),
+	}
+	go c.readLoop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.call(ctx, MethodHello, HelloParams{ProtocolVersion: ProtocolVersion, Subscribe: true}, &c.info); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("daemon handshake failed: %w", err)
+	}
+	return c, nil
+}
+
+// Info returns what the daemon reported in the handshake
+func (c *Client) Info() HelloResult {
+	return c.info
+}
+
+// Close disconnects from the daemon
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// Done is closed when the connection to the daemon is lost or closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// OnAlert sets the callback for alerts raised by the daemon
+func (c *Client) OnAlert(fn func(*alert.Alert)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAlert = fn
+}
+
+// Alerts returns up to limit of the daemon's most recent alerts, newest first
+func (c *Client) Alerts(ctx context.Context, limit int) ([]*alert.Alert, error) {
+	var result AlertsResult
+	if err := c.call(ctx, MethodAlerts, AlertsParams{Limit: limit}, &result); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*alert.Alert, 0, len(result.Alerts))
+	for _, params := range result.Alerts {
+		alerts = append(alerts, c.toAlert(params))
+	}
+	return alerts, nil
+}
+
+// Snooze snoozes alerts in the daemon and returns its snoozes
+func (c *Client) Snooze(ctx context.Context, s alert.Snooze) ([]alert.Snooze, error) {
+	var result SnoozesResult
+	if err := c.call(ctx, MethodSnooze, SnoozeParams{Snooze: s}, &result); err != nil {
+		return nil, err
+	}
+	return result.Snoozes, nil
+}
+
+// Snoozes returns the daemon's snoozes in effect
+func (c *Client) Snoozes(ctx context.Context) ([]alert.Snooze, error) {
+	var result SnoozesResult
+	if err := c.call(ctx, MethodSnoozes, struct{}{}, &result); err != nil {
+		return nil, err
+	}
+	return result.Snoozes, nil
+}
+
+// Deliveries returns up to limit of the daemon's alert deliveries with a
+// status, or all of them, most recently updated first
+func (c *Client) Deliveries(ctx context.Context, status string, limit int) ([]alert.Delivery, error) {
+	var result DeliveriesResult
+	if err := c.call(ctx, MethodDeliveries, DeliveriesParams{Status: status, Limit: limit}, &result); err != nil {
+		return nil, err
+	}
+	return result.Deliveries, nil
+}
+
+// Files returns the files the daemon's agents changed, conflicts first
+func (c *Client) Files(ctx context.Context) ([]conflict.File, error) {
+	var result FilesResult
+	if err := c.call(ctx, MethodFiles, struct{}{}, &result); err != nil {
+		return nil, err
+	}
+	return result.Files, nil
+}
+
+// readLoop dispatches responses and notifications until the connection closes
+func (c *Client) readLoop() {
+	defer close(c.done)
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("[daemon] invalid message: %v", err)
+			continue
+		}
+
+		switch {
+		case msg.Method == MethodEvent:
+			var params EventParams
+			if err := json.Unmarshal(msg.Params, &params); err == nil {
+				c.handleEvent(params)
+			}
+		case msg.Method == MethodAlert:
+			var params AlertParams
+			if err := json.Unmarshal(msg.Params, &params); err == nil {
+				c.handleAlert(params)
+			}
+		case msg.ID != nil:
+			c.resolve(*msg.ID, &msg)
+		}
+	}
+}
+
+// handleEvent converts an event notification into an agent.Event
+func (c *Client) handleEvent(params EventParams) {
+	eventType, ok := agent.ParseEventType(params.Type)
+	if !ok {
+		eventType = agent.EventAgentUpdated
+	}
+
+	var a agent.Agent
+	if params.Agent != nil {
+		a = c.upsert(*params.Agent)
+	} else {
+		c.mu.RLock()
+		if existing, ok := c.agents[params.AgentID]; ok {
+			a = existing
+		}
+		c.mu.RUnlock()
+	}
+
+	if eventType == agent.EventAgentTerminated {
+		c.mu.Lock()
+		delete(c.agents, params.AgentID)
+		c.mu.Unlock()
+	}
+
+	event := agent.Event{
+		Type:      eventType,
+		AgentID:   params.AgentID,
+		Agent:     a,
+		Timestamp: params.Timestamp,
+	}
+	if params.Error != "" {
+		event.Error = fmt.Errorf("%s", params.Error)
+	}
+	if params.ReplacedBy != "" {
+		event.Data = agent.Replacement{AgentID: params.ReplacedBy}
+	}
+
+	select {
+	case c.events <- event:
+	default:
+	}
+}
+
+func (c *Client) handleAlert(params AlertParams) {
+	c.mu.RLock()
+	fn := c.onAlert
+	c.mu.RUnlock()
+
+	if fn != nil {
+		fn(c.toAlert(params))
+	}
+}
+
+// toAlert converts a wire alert, linking it to the local view of its agent
+func (c *Client) toAlert(params AlertParams) *alert.Alert {
+	a := &alert.Alert{
+		ID:         params.ID,
+		Level:      alert.Level(params.Level),
+		Title:      params.Title,
+		Message:    params.Message,
+		AgentID:    params.AgentID,
+		Timestamp:  params.Timestamp,
+		Read:       params.Read,
+		Suppressed: params.Suppressed,
+		Skipped:    params.Skipped,
+	}
+
+	c.mu.RLock()
+	if ra, ok := c.agents[params.AgentID]; ok {
+		a.Agent = ra
+	}
+	c.mu.RUnlock()
+	return a
+}
+
+// upsert updates or creates the local view of a snapshot
+func (c *Client) upsert(snap plugin.AgentSnapshot) *remoteAgent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, ok := c.agents[snap.ID]
+	if !ok {
+		a = &remoteAgent{client: c}
+		c.agents[snap.ID] = a
+	}
+	a.update(snap)
+	return a
+}
+
+// call sends a request and waits for the matching response
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.callTimeout)
+		defer cancel()
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(Request{JSONRPC: "2.0", ID: &id, Method: method, Params: raw})
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err = c.conn.Write(append(data, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to write to daemon: %w", err)
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			if msg.Error.Code == CodeUnsupported {
+				return &unsupportedError{msg: msg.Error.Message}
+			}
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return fmt.Errorf("connection to daemon closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unsupportedError carries the daemon's message for an operation the agent
+// cannot perform, matching agent.ErrUnsupported
+type unsupportedError struct {
+	msg string
+}
+
+func (e *unsupportedError) Error() string { return e.msg }
+
+func (e *unsupportedError) Is(target error) bool { return target == agent.ErrUnsupported }
+
+func (c *Client) resolve(id int64, msg *message) {
+	c.mu.Lock()
+	ch, ok := c.pending[id]
+	c.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+// Name returns the provider name
+func (c *Client) Name() string {
+	return "AUTO daemon"
+}
+
+// Type returns the provider type
+func (c *Client) Type() string {
+	return "daemon"
+}
+
+// Forwards reports whether the daemon can spawn agents of a type
+func (c *Client) Forwards(providerType string) bool {
+	for _, t := range c.info.ProviderTypes {
+		if t == providerType {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover fetches the daemon's current agents
+func (c *Client) Discover(ctx context.Context) ([]agent.Agent, error) {
+	var result AgentsResult
+	if err := c.call(ctx, MethodList, struct{}{}, &result); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(result.Agents))
+	agents := make([]agent.Agent, 0, len(result.Agents))
+	for _, snap := range result.Agents {
+		seen[snap.ID] = true
+		agents = append(agents, c.upsert(snap))
+	}
+
+	c.mu.Lock()
+	for id := range c.agents {
+		if !seen[id] {
+			delete(c.agents, id)
+		}
+	}
+	c.mu.Unlock()
+
+	return agents, nil
+}
+
+// Watch streams the daemon's agent events until ctx is done or the connection is lost
+func (c *Client) Watch(ctx context.Context) (<-chan agent.Event, error) {
+	out := make(chan agent.Event, 100)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.done:
+				return
+			case event := <-c.events:
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Spawn asks the daemon to start a new agent
+func (c *Client) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	ctx, cancel := context.WithTimeout(ctx, spawnTimeout)
+	defer cancel()
+
+	var result AgentResult
+	if err := c.call(ctx, MethodSpawn, SpawnParams{Config: config}, &result); err != nil {
+		return nil, err
+	}
+	return c.upsert(result.Agent), nil
+}
+
+// Get returns the local view of one of the daemon's agents
+func (c *Client) Get(id string) (agent.Agent, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if a, ok := c.agents[id]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("agent not found: %s", id)
+}
+
+// List returns the agents last reported by the daemon
+func (c *Client) List() []agent.Agent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	agents := make([]agent.Agent, 0, len(c.agents))
+	for _, a := range c.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// Terminate asks the daemon to terminate an agent
+func (c *Client) Terminate(id string) error {
+	return c.call(context.Background(), MethodTerminate, IDParams{ID: id}, nil)
+}
+
+// SendInput asks the daemon to deliver input to an agent
+func (c *Client) SendInput(id string, input string) error {
+	return c.call(context.Background(), MethodSendInput, InputParams{ID: id, Input: input}, nil)
+}
+
+// remoteAgent is a local view of an agent owned by the daemon
+type remoteAgent struct {
+	client *Client
+
+	mu      sync.RWMutex
+	snap    plugin.AgentSnapshot
+	lastErr error
+}
+
+func (a *remoteAgent) update(snap plugin.AgentSnapshot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.snap = snap
+	a.lastErr = nil
+	if snap.Error != "" {
+		a.lastErr = errors.New(snap.Error)
+	}
+}
+
+// ID returns the agent ID
+func (a *remoteAgent) ID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ID
+}
+
+// Name returns the agent name
+func (a *remoteAgent) Name() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Name
+}
+
+// Type returns the agent type reported by the daemon
+func (a *remoteAgent) Type() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Type
+}
+
+// Directory returns the working directory
+func (a *remoteAgent) Directory() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Directory
+}
+
+// ProjectID returns the project ID
+func (a *remoteAgent) ProjectID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ProjectID
+}
+
+// ParentID returns the parent agent ID
+func (a *remoteAgent) ParentID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ParentID
+}
+
+// IsBackground returns whether this is a background agent
+func (a *remoteAgent) IsBackground() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Background
+}
+
+// Status returns the last reported status
+func (a *remoteAgent) Status() agent.Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	status, _ := agent.ParseStatus(a.snap.Status)
+	return status
+}
+
+// StartTime returns when the agent started
+func (a *remoteAgent) StartTime() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.StartTime
+}
+
+// LastActivity returns the last activity time
+func (a *remoteAgent) LastActivity() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.LastActivity
+}
+
+// Output returns the last reported output
+func (a *remoteAgent) Output() io.Reader {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return bytes.NewReader([]byte(a.snap.Output))
+}
+
+// CurrentTask returns the current task description
+func (a *remoteAgent) CurrentTask() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.CurrentTask
+}
+
+// Metrics returns the last reported metrics
+func (a *remoteAgent) Metrics() agent.Metrics {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Metrics
+}
+
+// IsPaused returns whether the daemon reported the agent as paused
+func (a *remoteAgent) IsPaused() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Paused
+}
+
+// Restarts returns how many times the daemon has restarted the agent
+func (a *remoteAgent) Restarts() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Restarts
+}
+
+// LastError returns the last error
+func (a *remoteAgent) LastError() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastErr
+}
+
+// Refresh fetches the agent's current state from the daemon. Event
+// notifications only carry state changes, so output is refreshed this way.
+func (a *remoteAgent) Refresh() error {
+	var result AgentResult
+	if err := a.client.call(context.Background(), MethodGet, IDParams{ID: a.ID()}, &result); err != nil {
+		return err
+	}
+	a.update(result.Agent)
+	return nil
+}
+
+// SendInput forwards input through the daemon
+func (a *remoteAgent) SendInput(input string) error {
+	return a.client.SendInput(a.ID(), input)
+}
+
+// Terminate asks the daemon to terminate the agent
+func (a *remoteAgent) Terminate() error {
+	return a.client.Terminate(a.ID())
+}
+
+// Pause asks the daemon to pause the agent
+func (a *remoteAgent) Pause() error {
+	return a.client.call(context.Background(), MethodPause, IDParams{ID: a.ID()}, nil)
+}
+
+// Resume asks the daemon to resume the agent
+func (a *remoteAgent) Resume() error {
+	return a.client.call(context.Background(), MethodResume, IDParams{ID: a.ID()}, nil)
+}
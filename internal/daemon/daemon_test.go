@@ -0,0 +1,412 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/session"
+)
+
+// recordingAgent records input under a lock, since the daemon delivers it from another goroutine
+type recordingAgent struct {
+	*agent.MockAgent
+	mu     sync.Mutex
+	inputs []string
+}
+
+func (a *recordingAgent) SendInput(input string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inputs = append(a.inputs, input)
+	return nil
+}
+
+func (a *recordingAgent) Inputs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.inputs...)
+}
+
+// unsupportedAgent rejects pause like file-backed agents do
+type unsupportedAgent struct {
+	*agent.MockAgent
+}
+
+func (a *unsupportedAgent) Pause() error {
+	return fmt.Errorf("pause %w for test sessions", agent.ErrUnsupported)
+}
+
+type testDaemon struct {
+	server   *Server
+	manager  *session.Manager
+	alertMgr *alert.Manager
+	agent    *recordingAgent
+}
+
+// startTestDaemon serves a manager with one agent on a socket in a temp dir
+func startTestDaemon(t *testing.T) *testDaemon {
+	t.Helper()
+
+	registry := agent.NewRegistry()
+	registry.Register(agent.NewMockProvider())
+	manager := session.NewManager(&config.Config{}, nil, registry, nil)
+
+	a := &recordingAgent{MockAgent: agent.NewMockAgent("agent-1", "Worker")}
+	a.MockStatus = agent.StatusRunning
+	manager.AddAgentForTesting(a)
+	manager.AddAgentForTesting(&unsupportedAgent{MockAgent: agent.NewMockAgent("fixed-1", "Fixed")})
+
+	alertMgr := alert.NewManager(&config.AlertsConfig{}, nil)
+
+	// Socket paths are length limited, so keep it short
+	dir, err := os.MkdirTemp("", "auto")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	server := NewServer(manager, alertMgr, filepath.Join(dir, "auto.sock"))
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Stop() })
+
+	return &testDaemon{server: server, manager: manager, alertMgr: alertMgr, agent: a}
+}
+
+func dial(t *testing.T, d *testDaemon) *Client {
+	t.Helper()
+
+	client, err := Dial(d.server.Path())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClientCommands(t *testing.T) {
+	d := startTestDaemon(t)
+	client := dial(t, d)
+
+	if info := client.Info(); info.ProtocolVersion != ProtocolVersion || info.PID != os.Getpid() {
+		t.Errorf("Info() = %+v", info)
+	}
+	if !client.Forwards("mock") || client.Forwards("other") {
+		t.Errorf("Forwards() should match the daemon's provider types %v", client.Info().ProviderTypes)
+	}
+
+	agents, err := client.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("Discover() returned %d agents, want 2", len(agents))
+	}
+
+	a, err := client.Get("agent-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if a.Name() != "Worker" || a.Status() != agent.StatusRunning {
+		t.Errorf("agent = %s/%v, want Worker/%v", a.Name(), a.Status(), agent.StatusRunning)
+	}
+
+	if err := a.SendInput("hello"); err != nil {
+		t.Fatalf("SendInput() error = %v", err)
+	}
+	if got := d.agent.Inputs(); len(got) != 1 || got[0] != "hello" {
+		t.Errorf("daemon agent inputs = %v, want [hello]", got)
+	}
+
+	d.agent.MockCurrentTask = "review"
+	if err := a.(*remoteAgent).Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if a.CurrentTask() != "review" {
+		t.Errorf("CurrentTask() after Refresh() = %q, want review", a.CurrentTask())
+	}
+
+	if err := client.SendInput("missing", "hi"); err == nil {
+		t.Error("SendInput() to unknown agent should fail")
+	}
+
+	spawned, err := client.Spawn(context.Background(), agent.SpawnConfig{Type: "mock", Name: "new", Directory: "/tmp/new"})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	if spawned.ID() != "new-id" || spawned.Directory() != "/tmp/new" {
+		t.Errorf("spawned agent = %s in %s", spawned.ID(), spawned.Directory())
+	}
+	if _, ok := d.manager.Get("new-id"); !ok {
+		t.Error("spawned agent should be tracked by the daemon")
+	}
+
+	if err := client.Terminate("missing"); err == nil {
+		t.Error("Terminate() of unknown agent should fail")
+	}
+}
+
+func TestClientPauseUnsupported(t *testing.T) {
+	d := startTestDaemon(t)
+	client := dial(t, d)
+
+	if _, err := client.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	a, err := client.Get("fixed-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	err = a.Pause()
+	if !errors.Is(err, agent.ErrUnsupported) {
+		t.Fatalf("Pause() error = %v, want ErrUnsupported", err)
+	}
+	if err.Error() != "pause not supported for test sessions" {
+		t.Errorf("Pause() error = %q", err)
+	}
+
+	a, _ = client.Get("agent-1")
+	if err := a.Pause(); err != nil {
+		t.Errorf("Pause() of supporting agent error = %v", err)
+	}
+}
+
+func TestMultipleClientsReceiveEvents(t *testing.T) {
+	d := startTestDaemon(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clients := []*Client{dial(t, d), dial(t, d)}
+	streams := make([]<-chan agent.Event, len(clients))
+	alerts := make([]chan *alert.Alert, len(clients))
+	for i, c := range clients {
+		events, err := c.Watch(ctx)
+		if err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+		streams[i] = events
+		alerts[i] = make(chan *alert.Alert, 1)
+		ch := alerts[i]
+		c.OnAlert(func(a *alert.Alert) { ch <- a })
+	}
+
+	if n := d.server.ClientCount(); n != 2 {
+		t.Errorf("ClientCount() = %d, want 2", n)
+	}
+
+	spawned := agent.NewMockAgent("agent-2", "Helper")
+	spawned.MockStatus = agent.StatusRunning
+	d.server.PublishEvent(agent.Event{Type: agent.EventAgentDiscovered, AgentID: "agent-2", Agent: spawned})
+
+	for i, events := range streams {
+		select {
+		case e := <-events:
+			if e.Type != agent.EventAgentDiscovered || e.Agent == nil || e.Agent.Name() != "Helper" {
+				t.Errorf("client %d got event %v for %v", i, e.Type, e.Agent)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("client %d did not receive event", i)
+		}
+		if _, err := clients[i].Get("agent-2"); err != nil {
+			t.Errorf("client %d should track the discovered agent: %v", i, err)
+		}
+	}
+
+	d.server.PublishEvent(agent.Event{Type: agent.EventAgentTerminated, AgentID: "agent-2", Data: agent.Replacement{AgentID: "agent-3"}})
+	for i, events := range streams {
+		select {
+		case e := <-events:
+			if e.Type != agent.EventAgentTerminated {
+				t.Errorf("client %d got event %v, want terminated", i, e.Type)
+			}
+			if r, ok := e.Data.(agent.Replacement); !ok || r.AgentID != "agent-3" {
+				t.Errorf("client %d got event data %v, want the replacement", i, e.Data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("client %d did not receive event", i)
+		}
+		if _, err := clients[i].Get("agent-2"); err == nil {
+			t.Errorf("client %d should drop the terminated agent", i)
+		}
+	}
+
+	d.server.PublishAlert(&alert.Alert{ID: "a1", Level: alert.LevelWarning, Title: "Stuck", AgentID: "agent-1"})
+	for i, ch := range alerts {
+		select {
+		case a := <-ch:
+			if a.ID != "a1" || a.Level != alert.LevelWarning || a.Title != "Stuck" {
+				t.Errorf("client %d got alert %+v", i, a)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("client %d did not receive alert", i)
+		}
+	}
+}
+
+func TestClientAlertHistory(t *testing.T) {
+	d := startTestDaemon(t)
+	d.alertMgr.Send(context.Background(), &alert.Alert{ID: "old", Level: alert.LevelInfo, Title: "Old"})
+	d.alertMgr.Send(context.Background(), &alert.Alert{ID: "new", Level: alert.LevelError, Title: "New"})
+
+	client := dial(t, d)
+	alerts, err := client.Alerts(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("Alerts() error = %v", err)
+	}
+	if len(alerts) != 2 || alerts[0].ID != "new" || alerts[1].ID != "old" {
+		t.Errorf("Alerts() = %v, want new then old", alerts)
+	}
+}
+
+func TestClientFiles(t *testing.T) {
+	d := startTestDaemon(t)
+	client := dial(t, d)
+
+	// Conflict tracking is off in the test daemon, which lists no files
+	files, err := client.Files(context.Background())
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("Files() = %v, want none", files)
+	}
+}
+
+func TestClientSnooze(t *testing.T) {
+	d := startTestDaemon(t)
+	client := dial(t, d)
+	ctx := context.Background()
+
+	until := time.Now().Add(time.Hour)
+	snoozes, err := client.Snooze(ctx, alert.Snooze{AgentID: "agent-1", Name: "Worker", Until: until})
+	if err != nil {
+		t.Fatalf("Snooze() error = %v", err)
+	}
+	if len(snoozes) != 1 || snoozes[0].AgentID != "agent-1" || !snoozes[0].Until.Equal(until) {
+		t.Fatalf("Snooze() = %+v", snoozes)
+	}
+
+	// The daemon suppresses the agent's alerts, and clients see them marked
+	d.alertMgr.Send(ctx, &alert.Alert{Level: alert.LevelError, Title: "Agent Error", AgentID: "agent-1"})
+	alerts, err := client.Alerts(ctx, 1)
+	if err != nil {
+		t.Fatalf("Alerts() error = %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Suppressed != alert.SuppressedSnoozed {
+		t.Errorf("Alerts() = %+v, want a snoozed alert", alerts)
+	}
+
+	if _, err := client.Snooze(ctx, alert.Snooze{AgentID: "agent-1"}); err != nil {
+		t.Fatalf("Snooze() to end error = %v", err)
+	}
+	if snoozes, err := client.Snoozes(ctx); err != nil || len(snoozes) != 0 {
+		t.Errorf("Snoozes() = %+v, %v; want none", snoozes, err)
+	}
+}
+
+func TestClientDeliveries(t *testing.T) {
+	d := startTestDaemon(t)
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer discord.Close()
+
+	// A daemon whose Discord webhook is down
+	alertMgr := alert.NewManager(&config.AlertsConfig{
+		DiscordEnabled:    true,
+		DiscordWebhookURL: discord.URL,
+		Delivery:          config.DeliveryConfig{RetryConfig: config.RetryConfig{MaxAttempts: 1}},
+	}, nil)
+	defer alertMgr.Close()
+	server := NewServer(d.manager, alertMgr, filepath.Join(filepath.Dir(d.server.Path()), "deliveries.sock"))
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go server.Serve()
+	defer server.Stop()
+	client, err := Dial(server.Path())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	alertMgr.Send(ctx, &alert.Alert{Level: alert.LevelError, Title: "Agent Error"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		failed, err := client.Deliveries(ctx, "failed", 10)
+		if err != nil {
+			t.Fatalf("Deliveries() error = %v", err)
+		}
+		if len(failed) == 1 {
+			if failed[0].Channel != "discord" || failed[0].Title != "Agent Error" || failed[0].LastError == "" {
+				t.Errorf("Deliveries() = %+v", failed)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Deliveries() = %+v, want the failed delivery", failed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListen(t *testing.T) {
+	d := startTestDaemon(t)
+
+	other := NewServer(d.manager, d.alertMgr, d.server.Path())
+	if err := other.Listen(); err == nil {
+		other.Stop()
+		t.Fatal("Listen() should fail while another daemon serves the socket")
+	}
+
+	// A socket left behind by a crashed daemon is replaced
+	path := filepath.Join(filepath.Dir(d.server.Path()), "stale.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	stale := NewServer(d.manager, d.alertMgr, path)
+	if err := stale.Listen(); err != nil {
+		t.Fatalf("Listen() over stale socket error = %v", err)
+	}
+	stale.Stop()
+}
+
+func TestStopDisconnectsClients(t *testing.T) {
+	d := startTestDaemon(t)
+	client := dial(t, d)
+
+	d.server.Stop()
+
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client not disconnected when the daemon stopped")
+	}
+	if err := client.SendInput("agent-1", "hi"); err == nil {
+		t.Error("SendInput() after disconnect should fail")
+	}
+	if _, err := os.Stat(d.server.Path()); !os.IsNotExist(err) {
+		t.Error("Stop() should remove the socket")
+	}
+}
@@ -0,0 +1,200 @@
+// Package daemon runs AUTO's monitoring headless behind a Unix socket and
+// lets TUIs attach to it as clients
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+)
+
+// ProtocolVersion is the version of the socket protocol spoken by the daemon
+const ProtocolVersion = 1
+
+// Method names of the socket protocol. Requests flow from clients to the
+// daemon; MethodEvent and MethodAlert are notifications sent to subscribers.
+const (
+	MethodHello      = "hello"
+	MethodList       = "list"
+	MethodGet        = "get"
+	MethodAlerts     = "alerts"
+	MethodFiles      = "files"
+	MethodSnooze     = "snooze"
+	MethodSnoozes    = "snoozes"
+	MethodDeliveries = "deliveries"
+	MethodSpawn      = "spawn"
+	MethodTerminate  = "terminate"
+	MethodSendInput  = "send_input"
+	MethodPause      = "pause"
+	MethodResume     = "resume"
+	MethodEvent      = "event"
+	MethodAlert      = "alert"
+)
+
+// JSON-RPC 2.0 error codes, plus CodeUnsupported for agent.ErrUnsupported
+const (
+	CodeParseError     = -32700
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeUnsupported    = -32001
+)
+
+// maxMessageSize bounds a single protocol line
+const maxMessageSize = 16 * 1024 * 1024
+
+// Request is a JSON-RPC 2.0 request or notification (when ID is nil)
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// message is used to decode any incoming line before deciding what it is
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC 2.0 error object
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("daemon error %d: %s", e.Code, e.Message)
+}
+
+// HelloParams is sent by a client in the handshake
+type HelloParams struct {
+	ProtocolVersion int  `json:"protocol_version"`
+	Subscribe       bool `json:"subscribe"` // receive event and alert notifications
+}
+
+// HelloResult is returned by the daemon in the handshake
+type HelloResult struct {
+	ProtocolVersion int       `json:"protocol_version"`
+	PID             int       `json:"pid"`
+	StartTime       time.Time `json:"start_time"`
+	ProviderTypes   []string  `json:"provider_types"` // agent types the daemon can spawn
+}
+
+// IDParams identifies a single agent
+type IDParams struct {
+	ID string `json:"id"`
+}
+
+// InputParams carries input for an agent
+type InputParams struct {
+	ID    string `json:"id"`
+	Input string `json:"input"`
+}
+
+// SpawnParams carries the spawn configuration
+type SpawnParams struct {
+	Config agent.SpawnConfig `json:"config"`
+}
+
+// AlertsParams limits the alerts returned by MethodAlerts
+type AlertsParams struct {
+	Limit int `json:"limit"`
+}
+
+// SnoozeParams carries a snooze; a zero or past until ends it
+type SnoozeParams struct {
+	Snooze alert.Snooze `json:"snooze"`
+}
+
+// SnoozesResult wraps the snoozes in effect, ending soonest first
+type SnoozesResult struct {
+	Snoozes []alert.Snooze `json:"snoozes"`
+}
+
+// DeliveriesParams selects the alert deliveries returned by MethodDeliveries
+type DeliveriesParams struct {
+	Status string `json:"status,omitempty"` // pending, sent or failed; empty for all
+	Limit  int    `json:"limit"`
+}
+
+// DeliveriesResult wraps alert deliveries to remote channels, most
+// recently updated first
+type DeliveriesResult struct {
+	Deliveries []alert.Delivery `json:"deliveries"`
+}
+
+// AgentResult wraps a single agent snapshot
+type AgentResult struct {
+	Agent plugin.AgentSnapshot `json:"agent"`
+}
+
+// AgentsResult wraps a list of agent snapshots
+type AgentsResult struct {
+	Agents []plugin.AgentSnapshot `json:"agents"`
+}
+
+// AlertsResult wraps a list of alerts, newest first
+type AlertsResult struct {
+	Alerts []AlertParams `json:"alerts"`
+}
+
+// FilesResult wraps the files agents changed, conflicts first
+type FilesResult struct {
+	Files []conflict.File `json:"files"`
+}
+
+// EventParams is the payload of an event notification
+type EventParams struct {
+	Type       string                `json:"type"`
+	AgentID    string                `json:"agent_id"`
+	Agent      *plugin.AgentSnapshot `json:"agent,omitempty"`
+	Timestamp  time.Time             `json:"timestamp"`
+	Error      string                `json:"error,omitempty"`
+	ReplacedBy string                `json:"replaced_by,omitempty"` // agent that replaced a terminated queued spawn
+}
+
+// AlertParams is the wire representation of an alert
+type AlertParams struct {
+	ID         string            `json:"id"`
+	Level      string            `json:"level"`
+	Title      string            `json:"title"`
+	Message    string            `json:"message"`
+	AgentID    string            `json:"agent_id,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Read       bool              `json:"read,omitempty"`
+	Suppressed string            `json:"suppressed,omitempty"`
+	Skipped    map[string]string `json:"skipped,omitempty"`
+}
+
+// alertParams converts an alert into its wire representation
+func alertParams(a *alert.Alert) AlertParams {
+	return AlertParams{
+		ID:         a.ID,
+		Level:      string(a.Level),
+		Title:      a.Title,
+		Message:    a.Message,
+		AgentID:    a.AgentID,
+		Timestamp:  a.Timestamp,
+		Read:       a.Read,
+		Suppressed: a.Suppressed,
+		Skipped:    a.Skipped,
+	}
+}
@@ -0,0 +1,533 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+	"github.com/CastAIPhil/AUTO/internal/plugin"
+	"github.com/CastAIPhil/AUTO/internal/session"
+)
+
+// clientBufferSize is how many notifications a subscriber may lag behind before it is evicted
+const clientBufferSize = 256
+
+// Server exposes a session manager and alert manager on a Unix socket
+type Server struct {
+	manager   *session.Manager
+	alertMgr  *alert.Manager
+	path      string
+	ctx       context.Context
+	startTime time.Time
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*serverConn]bool
+	wg       sync.WaitGroup
+}
+
+// serverConn is a single attached client
+type serverConn struct {
+	conn       net.Conn
+	send       chan []byte
+	subscribed bool // guarded by Server.mu
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewServer creates a daemon server listening on the socket at path
+func NewServer(manager *session.Manager, alertMgr *alert.Manager, path string) *Server {
+	return &Server{
+		manager:   manager,
+		alertMgr:  alertMgr,
+		path:      path,
+		ctx:       context.Background(),
+		startTime: time.Now(),
+		conns:     make(map[*serverConn]bool),
+	}
+}
+
+// SetContext sets the context that spawned agents and input runs are bound to
+func (s *Server) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Path returns the socket path
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Listen creates the socket, replacing a stale one left by a daemon that
+// did not shut down cleanly. It fails if another daemon is serving the path.
+func (s *Server) Listen() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	if _, err := os.Stat(s.path); err == nil {
+		if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("daemon already running on %s", s.path)
+		}
+		os.Remove(s.path)
+	}
+
+	l, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.path, err)
+	}
+	// Anyone who can connect can control agents
+	if err := os.Chmod(s.path, 0600); err != nil {
+		l.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+	return nil
+}
+
+// Serve accepts clients until the server is stopped
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	l := s.listener
+	s.mu.Unlock()
+	if l == nil {
+		return fmt.Errorf("server is not listening")
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		c := &serverConn{conn: conn, send: make(chan []byte, clientBufferSize)}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(c)
+		}()
+	}
+}
+
+// Start listens on the socket and serves clients until the server is stopped
+func (s *Server) Start() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Stop closes the socket and disconnects all clients
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	l := s.listener
+	s.listener = nil
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	var err error
+	if l != nil {
+		err = l.Close()
+		os.Remove(s.path)
+	}
+	for _, c := range conns {
+		c.close()
+	}
+	s.wg.Wait()
+	return err
+}
+
+// ClientCount returns the number of attached clients
+func (s *Server) ClientCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// PublishEvent sends an agent event to all subscribed clients without blocking
+func (s *Server) PublishEvent(event agent.Event) {
+	params := EventParams{
+		Type:      event.Type.String(),
+		AgentID:   event.AgentID,
+		Timestamp: event.Timestamp,
+	}
+	if params.Timestamp.IsZero() {
+		params.Timestamp = time.Now()
+	}
+	if event.Agent != nil {
+		snap := s.snapshot(event.Agent)
+		params.Agent = &snap
+	}
+	if event.Error != nil {
+		params.Error = event.Error.Error()
+	}
+	if r, ok := event.Data.(agent.Replacement); ok {
+		params.ReplacedBy = r.AgentID
+	}
+	s.broadcast(MethodEvent, params)
+}
+
+// PublishAlert sends an alert to all subscribed clients without blocking
+func (s *Server) PublishAlert(a *alert.Alert) {
+	s.broadcast(MethodAlert, alertParams(a))
+}
+
+func (s *Server) broadcast(method string, params interface{}) {
+	data, err := encodeNotification(method, params)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		if !c.subscribed {
+			continue
+		}
+		if !c.queue(data) {
+			// The client is not keeping up; drop it rather than block the publisher
+			delete(s.conns, c)
+			c.close()
+			log.Printf("Daemon: evicted slow client")
+		}
+	}
+}
+
+// serveConn reads requests from a client until it disconnects
+func (s *Server) serveConn(c *serverConn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+
+	go c.writeLoop()
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			c.reply(nil, nil, &RPCError{Code: CodeParseError, Message: err.Error()})
+			continue
+		}
+		if req.ID == nil {
+			continue // clients send no notifications
+		}
+
+		// The handshake is answered inline so subscribing happens before any other request
+		if req.Method == MethodHello {
+			result, err := s.hello(c, req)
+			c.reply(req.ID, result, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(req Request) {
+			defer wg.Done()
+			result, err := s.handle(req)
+			c.reply(req.ID, result, err)
+		}(req)
+	}
+}
+
+// hello performs the handshake and subscribes the client if asked
+func (s *Server) hello(c *serverConn, req Request) (interface{}, error) {
+	var params HelloParams
+	if err := decodeParams(req.Params, &params); err != nil {
+		return nil, err
+	}
+	if params.ProtocolVersion != ProtocolVersion {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("protocol version %d not supported, want %d", params.ProtocolVersion, ProtocolVersion)}
+	}
+
+	s.mu.Lock()
+	c.subscribed = params.Subscribe
+	s.mu.Unlock()
+
+	return HelloResult{
+		ProtocolVersion: ProtocolVersion,
+		PID:             os.Getpid(),
+		StartTime:       s.startTime,
+		ProviderTypes:   s.manager.ProviderTypes(),
+	}, nil
+}
+
+func (s *Server) handle(req Request) (interface{}, error) {
+	switch req.Method {
+	case MethodList:
+		return s.snapshots(s.manager.List()), nil
+
+	case MethodGet:
+		var params IDParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		a, ok := s.manager.Get(params.ID)
+		if !ok {
+			return nil, fmt.Errorf("agent not found: %s", params.ID)
+		}
+		return AgentResult{Agent: s.snapshot(a)}, nil
+
+	case MethodAlerts:
+		var params AlertsParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		result := AlertsResult{Alerts: []AlertParams{}}
+		if s.alertMgr != nil {
+			for _, a := range s.alertMgr.List(params.Limit, false) {
+				result.Alerts = append(result.Alerts, alertParams(a))
+			}
+		}
+		return result, nil
+
+	case MethodSnooze, MethodSnoozes:
+		if s.alertMgr == nil {
+			return SnoozesResult{Snoozes: []alert.Snooze{}}, nil
+		}
+		if req.Method == MethodSnooze {
+			var params SnoozeParams
+			if err := decodeParams(req.Params, &params); err != nil {
+				return nil, err
+			}
+			s.alertMgr.Snooze(params.Snooze)
+		}
+		snoozes := s.alertMgr.Snoozes()
+		if snoozes == nil {
+			snoozes = []alert.Snooze{}
+		}
+		return SnoozesResult{Snoozes: snoozes}, nil
+
+	case MethodDeliveries:
+		var params DeliveriesParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		result := DeliveriesResult{Deliveries: []alert.Delivery{}}
+		if s.alertMgr != nil {
+			deliveries, err := s.alertMgr.Deliveries(params.Status, params.Limit)
+			if err != nil {
+				return nil, err
+			}
+			result.Deliveries = deliveries
+		}
+		return result, nil
+
+	case MethodFiles:
+		files := s.manager.Files()
+		if files == nil {
+			files = []conflict.File{}
+		}
+		return FilesResult{Files: files}, nil
+
+	case MethodSpawn:
+		var params SpawnParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		a, err := s.manager.Spawn(s.ctx, params.Config)
+		if err != nil {
+			return nil, err
+		}
+		return AgentResult{Agent: s.snapshot(a)}, nil
+
+	case MethodTerminate:
+		var params IDParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if _, ok := s.manager.Get(params.ID); !ok {
+			return nil, fmt.Errorf("agent not found: %s", params.ID)
+		}
+		return struct{}{}, controlError(s.manager.Terminate(params.ID))
+
+	case MethodSendInput:
+		var params InputParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return struct{}{}, controlError(s.sendInput(params.ID, params.Input))
+
+	case MethodPause, MethodResume:
+		var params IDParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if req.Method == MethodPause {
+			return struct{}{}, controlError(s.manager.Pause(params.ID))
+		}
+		return struct{}{}, controlError(s.manager.Resume(params.ID))
+
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method: %s", req.Method)}
+	}
+}
+
+// sendInput delivers input to an agent. Streaming agents run in the
+// background so the run continues after the client detaches.
+func (s *Server) sendInput(id, input string) error {
+	a, ok := s.manager.Get(id)
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+	if input == "" {
+		return fmt.Errorf("empty input")
+	}
+
+	if _, streaming := a.(agent.StreamingAgent); !streaming {
+		return s.manager.SendInput(id, input)
+	}
+
+	events, err := s.manager.SendInputAsync(s.ctx, id, input)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for range events {
+		}
+	}()
+	return nil
+}
+
+// controlError marks unsupported operations so clients can restore agent.ErrUnsupported
+func controlError(err error) error {
+	if errors.Is(err, agent.ErrUnsupported) {
+		return &RPCError{Code: CodeUnsupported, Message: err.Error()}
+	}
+	return err
+}
+
+// reply writes the response to a request
+func (c *serverConn) reply(id *int64, result interface{}, err error) {
+	resp := Response{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		rpcErr, ok := err.(*RPCError)
+		if !ok {
+			rpcErr = &RPCError{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &RPCError{Code: CodeInternalError, Message: err.Error()}
+		} else {
+			resp.Result = data
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if !c.queue(append(data, '\n')) {
+		c.close()
+	}
+}
+
+// queue adds a newline-terminated line to the client's send buffer,
+// reporting false if the client is gone or too far behind
+func (c *serverConn) queue(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *serverConn) writeLoop() {
+	for data := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if _, err := c.conn.Write(data); err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *serverConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+		c.conn.Close()
+	}
+}
+
+func encodeNotification(method string, params interface{}) ([]byte, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(Request{JSONRPC: "2.0", Method: method, Params: raw})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func decodeParams(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// snapshot converts an agent into its wire representation, including the
+// restarts the daemon's manager has made
+func (s *Server) snapshot(a agent.Agent) plugin.AgentSnapshot {
+	snap := plugin.Snapshot(a)
+	snap.Restarts = s.manager.Restarts(a.ID())
+	return snap
+}
+
+func (s *Server) snapshots(agents []agent.Agent) AgentsResult {
+	result := AgentsResult{Agents: make([]plugin.AgentSnapshot, 0, len(agents))}
+	for _, a := range agents {
+		result.Agents = append(result.Agents, s.snapshot(a))
+	}
+	return result
+}
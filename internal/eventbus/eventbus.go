@@ -0,0 +1,322 @@
+// Package eventbus delivers published messages to any number of subscribers,
+// each with its own filter and bounded queue
+package eventbus
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// DefaultQueueSize is the queue size of subscribers that do not set one
+const DefaultQueueSize = 256
+
+// Policy decides what publishing does when a subscriber's queue is full
+type Policy int
+
+const (
+	// Drop discards the message and counts it; the publisher never waits
+	Drop Policy = iota
+	// Block makes the publisher wait for room, for subscribers that must
+	// see every message
+	Block
+)
+
+// Topic is what filters match a message against
+type Topic struct {
+	Type      string // event type, or level for alerts
+	AgentType string
+	Project   string
+}
+
+// EventTopic is the topic of an agent event
+func EventTopic(e agent.Event) Topic {
+	t := Topic{Type: e.Type.String()}
+	if e.Agent != nil {
+		t.AgentType = e.Agent.Type()
+		t.Project = e.Agent.ProjectID()
+	}
+	return t
+}
+
+// Filter selects messages by topic. An empty list matches anything.
+type Filter struct {
+	Types      []string
+	AgentTypes []string
+	Projects   []string
+}
+
+// Match reports whether a topic passes the filter
+func (f Filter) Match(t Topic) bool {
+	return matchAny(f.Types, t.Type) && matchAny(f.AgentTypes, t.AgentType) && matchAny(f.Projects, t.Project)
+}
+
+func matchAny(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures a subscription
+type Options struct {
+	Name      string // shown in logs and stats
+	Filter    Filter
+	QueueSize int // 0 means DefaultQueueSize
+	Policy    Policy
+}
+
+// Stats describes a subscriber
+type Stats struct {
+	Name      string `json:"name"`
+	Queued    int    `json:"queued"`
+	Delivered uint64 `json:"delivered"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// Bus delivers messages of type T. Every subscriber has its own goroutine,
+// so a slow one only holds up the publisher when its policy is Block.
+type Bus[T any] struct {
+	topic func(T) Topic
+
+	mu     sync.RWMutex
+	subs   []*Subscription[T] // replaced, never modified, so publishing can iterate without the lock
+	closed bool
+}
+
+// New creates a bus that filters messages by the topic the function gives
+func New[T any](topic func(T) Topic) *Bus[T] {
+	return &Bus[T]{topic: topic}
+}
+
+// Subscribe calls fn for every published message that passes the filter,
+// in publishing order, until the subscription ends
+func (b *Bus[T]) Subscribe(opts Options, fn func(T)) *Subscription[T] {
+	size := opts.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	s := &Subscription[T]{
+		bus:    b,
+		name:   opts.Name,
+		filter: opts.Filter,
+		policy: opts.Policy,
+		fn:     fn,
+		queue:  make(chan T, size),
+		done:   make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.stop()
+		return s
+	}
+	subs := make([]*Subscription[T], len(b.subs), len(b.subs)+1)
+	copy(subs, b.subs)
+	b.subs = append(subs, s)
+
+	go s.run()
+	return s
+}
+
+// Publish queues a message for every matching subscriber
+func (b *Bus[T]) Publish(msg T) {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	if len(subs) == 0 {
+		return
+	}
+
+	topic := b.topic(msg)
+	for _, s := range subs {
+		if s.filter.Match(topic) {
+			s.enqueue(msg)
+		}
+	}
+}
+
+// Flush waits until the subscribers have handled every message published
+// so far. It must not be called from a subscriber.
+func (b *Bus[T]) Flush() {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.wait()
+	}
+}
+
+// Close delivers what is queued and ends every subscription. Later
+// messages are ignored.
+func (b *Bus[T]) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.wait()
+		s.stop()
+	}
+}
+
+// Stats returns the subscribers' queue lengths and counters
+func (b *Bus[T]) Stats() []Stats {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	stats := make([]Stats, 0, len(subs))
+	for _, s := range subs {
+		stats = append(stats, Stats{
+			Name:      s.name,
+			Queued:    len(s.queue),
+			Delivered: s.delivered.Load(),
+			Dropped:   s.dropped.Load(),
+		})
+	}
+	return stats
+}
+
+// remove forgets a subscription
+func (b *Bus[T]) remove(s *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := make([]*Subscription[T], 0, len(b.subs))
+	for _, other := range b.subs {
+		if other != s {
+			subs = append(subs, other)
+		}
+	}
+	b.subs = subs
+}
+
+// Subscription is a subscriber of a bus
+type Subscription[T any] struct {
+	bus    *Bus[T]
+	name   string
+	filter Filter
+	policy Policy
+	fn     func(T)
+	queue  chan T
+	done   chan struct{}
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending int // queued or being handled
+	stopped bool
+}
+
+// Name returns the subscriber's name
+func (s *Subscription[T]) Name() string {
+	return s.name
+}
+
+// Delivered returns how many messages the subscriber handled
+func (s *Subscription[T]) Delivered() uint64 {
+	return s.delivered.Load()
+}
+
+// Dropped returns how many messages were dropped because the queue was full
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe ends the subscription; queued messages are discarded. It
+// may be called from the subscriber itself.
+func (s *Subscription[T]) Unsubscribe() {
+	s.bus.remove(s)
+	s.stop()
+}
+
+// enqueue queues a message according to the policy
+func (s *Subscription[T]) enqueue(msg T) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.pending++
+	s.mu.Unlock()
+
+	if s.policy == Block {
+		select {
+		case s.queue <- msg:
+		case <-s.done:
+			s.settle()
+		}
+		return
+	}
+
+	select {
+	case s.queue <- msg:
+	default:
+		s.settle()
+		if s.dropped.Add(1) == 1 {
+			log.Printf("Event subscriber %s is falling behind; dropping messages", s.name)
+		}
+	}
+}
+
+// run delivers queued messages until the subscription ends
+func (s *Subscription[T]) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.queue:
+			s.fn(msg)
+			s.delivered.Add(1)
+			s.settle()
+		}
+	}
+}
+
+// settle marks one pending message as handled or dropped
+func (s *Subscription[T]) settle() {
+	s.mu.Lock()
+	s.pending--
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// wait blocks until nothing is pending or the subscription ended
+func (s *Subscription[T]) wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.pending > 0 && !s.stopped {
+		s.cond.Wait()
+	}
+}
+
+// stop ends delivery
+func (s *Subscription[T]) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	close(s.done)
+	s.cond.Broadcast()
+}
@@ -0,0 +1,219 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// message is a test message with its topic built in
+type message struct {
+	n     int
+	topic Topic
+}
+
+func newBus() *Bus[message] {
+	return New(func(m message) Topic { return m.topic })
+}
+
+// collector records the messages a subscriber receives
+type collector struct {
+	mu  sync.Mutex
+	got []int
+}
+
+func (c *collector) add(m message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.got = append(c.got, m.n)
+}
+
+func (c *collector) values() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.got...)
+}
+
+func TestFilter(t *testing.T) {
+	topic := Topic{Type: "errored", AgentType: "opencode", Project: "p1"}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"type", Filter{Types: []string{"completed", "errored"}}, true},
+		{"other type", Filter{Types: []string{"completed"}}, false},
+		{"agent type", Filter{AgentTypes: []string{"opencode"}}, true},
+		{"other agent type", Filter{AgentTypes: []string{"claude"}}, false},
+		{"project", Filter{Projects: []string{"p1"}}, true},
+		{"all", Filter{Types: []string{"errored"}, AgentTypes: []string{"opencode"}, Projects: []string{"p2"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(topic); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventTopic(t *testing.T) {
+	a := agent.NewMockAgent("a", "A")
+	a.MockType = "opencode"
+	a.MockProjectID = "p1"
+	got := EventTopic(agent.Event{Type: agent.EventAgentErrored, Agent: a})
+	if want := (Topic{Type: "errored", AgentType: "opencode", Project: "p1"}); got != want {
+		t.Errorf("EventTopic() = %+v, want %+v", got, want)
+	}
+	if got := EventTopic(agent.Event{Type: agent.EventAgentTerminated}); got != (Topic{Type: "terminated"}) {
+		t.Errorf("EventTopic() without agent = %+v", got)
+	}
+}
+
+func TestPublishInOrder(t *testing.T) {
+	b := newBus()
+	var all, errored collector
+	b.Subscribe(Options{Name: "all"}, all.add)
+	b.Subscribe(Options{Name: "errored", Filter: Filter{Types: []string{"errored"}}}, errored.add)
+
+	for i := 0; i < 100; i++ {
+		topic := Topic{Type: "updated"}
+		if i%10 == 0 {
+			topic.Type = "errored"
+		}
+		b.Publish(message{n: i, topic: topic})
+	}
+	b.Flush()
+
+	got := all.values()
+	if len(got) != 100 {
+		t.Fatalf("got %d messages, want 100", len(got))
+	}
+	for i, n := range got {
+		if n != i {
+			t.Fatalf("message %d = %d, out of order", i, n)
+		}
+	}
+	if got := errored.values(); len(got) != 10 || got[1] != 10 {
+		t.Errorf("filtered messages = %v", got)
+	}
+}
+
+func TestDropPolicy(t *testing.T) {
+	b := newBus()
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var got collector
+	sub := b.Subscribe(Options{Name: "slow", QueueSize: 2}, func(m message) {
+		started <- struct{}{}
+		<-release
+		got.add(m)
+	})
+
+	// One message is being handled, two fill the queue, the rest drop
+	b.Publish(message{n: 0})
+	<-started
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i < 10; i++ {
+			b.Publish(message{n: i})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publishing blocked on a full drop queue")
+	}
+
+	close(release)
+	b.Flush()
+	if sub.Dropped() != 7 || sub.Delivered() != 3 {
+		t.Errorf("dropped %d, delivered %d; want 7 and 3", sub.Dropped(), sub.Delivered())
+	}
+	if stats := b.Stats(); len(stats) != 1 || stats[0].Name != "slow" || stats[0].Dropped != 7 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestBlockPolicy(t *testing.T) {
+	b := newBus()
+	release := make(chan struct{})
+	var got collector
+	sub := b.Subscribe(Options{Name: "store", QueueSize: 1, Policy: Block}, func(m message) {
+		<-release
+		got.add(m)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			b.Publish(message{n: i})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("publishing should wait for a blocking subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+	b.Flush()
+	if got := got.values(); len(got) != 5 || sub.Dropped() != 0 {
+		t.Errorf("got %v with %d dropped, want all 5", got, sub.Dropped())
+	}
+}
+
+func TestUnsubscribeAndClose(t *testing.T) {
+	b := newBus()
+	var first, second collector
+	sub := b.Subscribe(Options{Name: "first"}, first.add)
+	b.Subscribe(Options{Name: "second", Policy: Block}, second.add)
+
+	b.Publish(message{n: 1})
+	b.Flush()
+	sub.Unsubscribe()
+	b.Publish(message{n: 2})
+	b.Close()
+	b.Publish(message{n: 3})
+
+	if got := first.values(); len(got) != 1 {
+		t.Errorf("unsubscribed subscriber got %v", got)
+	}
+	if got := second.values(); len(got) != 2 || got[1] != 2 {
+		t.Errorf("Close() should deliver queued messages, got %v", got)
+	}
+
+	// Subscribing to a closed bus delivers nothing
+	var late collector
+	b.Subscribe(Options{Name: "late"}, late.add)
+	b.Publish(message{n: 4})
+	b.Flush()
+	if got := late.values(); len(got) != 0 {
+		t.Errorf("subscriber of a closed bus got %v", got)
+	}
+}
+
+func TestUnsubscribeFromSubscriber(t *testing.T) {
+	b := newBus()
+	var got collector
+	var sub *Subscription[message]
+	ready := make(chan struct{})
+	sub = b.Subscribe(Options{Name: "once"}, func(m message) {
+		<-ready
+		got.add(m)
+		sub.Unsubscribe()
+	})
+	close(ready)
+
+	b.Publish(message{n: 1})
+	b.Flush()
+	b.Publish(message{n: 2})
+	b.Flush()
+	if got := got.values(); len(got) != 1 {
+		t.Errorf("got %v, want only the first message", got)
+	}
+}
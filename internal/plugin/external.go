@@ -0,0 +1,801 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// ExternalPlugin is a provider implemented by an executable speaking the
+// stdio JSON-RPC protocol. The process is restarted with exponential backoff
+// when it exits unexpectedly, and its agents are marked errored meanwhile.
+type ExternalPlugin struct {
+	path string
+	info Info
+
+	mu       sync.RWMutex
+	proc     *pluginProcess
+	agents   map[string]*remoteAgent
+	watching bool
+	closed   bool
+	catalog  *agent.ModelCatalog
+
+	events chan agent.Event // updates and output, dropped when Watch lags
+	stop   chan struct{}
+
+	// Other events are never dropped: each agent's latest one waits here
+	// until Watch sends it
+	transitions map[string]agent.Event
+	pending     []string // agent IDs with a transition, oldest first
+	signal      chan struct{}
+
+	handshakeTimeout time.Duration
+	callTimeout      time.Duration
+	minBackoff       time.Duration
+	maxBackoff       time.Duration
+}
+
+// pluginProcess is a single running instance of a plugin executable
+type pluginProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	started time.Time
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int64]chan *message
+	nextID  int64
+
+	exited chan struct{}
+	err    error
+}
+
+// NewExternal creates an external plugin for the executable at path
+func NewExternal(path string) *ExternalPlugin {
+	return &ExternalPlugin{
+		path:             path,
+		agents:           make(map[string]*remoteAgent),
+		events:           make(chan agent.Event, 100),
+		stop:             make(chan struct{}),
+		transitions:      make(map[string]agent.Event),
+		signal:           make(chan struct{}, 1),
+		handshakeTimeout: 5 * time.Second,
+		callTimeout:      30 * time.Second,
+		minBackoff:       time.Second,
+		maxBackoff:       time.Minute,
+	}
+}
+
+// SetCatalog sets the model catalog used to price agents whose plugin reports no cost
+func (p *ExternalPlugin) SetCatalog(catalog *agent.ModelCatalog) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.catalog = catalog
+}
+
+// Start launches the plugin, performs the handshake and begins supervising it
+func (p *ExternalPlugin) Start() error {
+	proc, info, err := p.launch()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.info = info
+	p.proc = proc
+	p.mu.Unlock()
+
+	go p.supervise()
+	return nil
+}
+
+// Close stops the plugin process and disables restarts
+func (p *ExternalPlugin) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	proc := p.proc
+	p.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+
+	// Closing stdin asks the plugin to exit; kill it if it does not
+	proc.stdin.Close()
+	select {
+	case <-proc.exited:
+	case <-time.After(2 * time.Second):
+		proc.cmd.Process.Kill()
+		<-proc.exited
+	}
+	return nil
+}
+
+// Info returns the metadata reported by the plugin handshake
+func (p *ExternalPlugin) Info() Info {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.info
+}
+
+// Name returns the plugin name
+func (p *ExternalPlugin) Name() string {
+	return p.Info().Name
+}
+
+// Type returns the provider type, falling back to the plugin name
+func (p *ExternalPlugin) Type() string {
+	info := p.Info()
+	if info.Type != "" {
+		return info.Type
+	}
+	return info.Name
+}
+
+// launch starts the executable and performs the handshake
+func (p *ExternalPlugin) launch() (*pluginProcess, Info, error) {
+	cmd := exec.Command(p.path)
+	cmd.Dir = filepath.Dir(p.path)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, Info{}, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, Info{}, err
+	}
+	cmd.Stderr = &logWriter{prefix: fmt.Sprintf("[plugin %s] ", filepath.Base(p.path))}
+
+	if err := cmd.Start(); err != nil {
+		return nil, Info{}, fmt.Errorf("failed to start plugin %s: %w", p.path, err)
+	}
+
+	proc := &pluginProcess{
+		cmd:     cmd,
+		stdin:   stdin,
+		started: time.Now(),
+		pending: make(map[int64]chan *message),
+		exited:  make(chan struct{}),
+	}
+	go p.readLoop(proc, stdout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.handshakeTimeout)
+	defer cancel()
+
+	var result InitializeResult
+	if err := proc.call(ctx, MethodInitialize, InitializeParams{ProtocolVersion: ProtocolVersion}, &result); err != nil {
+		proc.kill()
+		return nil, Info{}, fmt.Errorf("plugin %s handshake failed: %w", p.path, err)
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		proc.kill()
+		return nil, Info{}, fmt.Errorf("plugin %s speaks protocol version %d, want %d", p.path, result.ProtocolVersion, ProtocolVersion)
+	}
+	if result.Info.Name == "" {
+		proc.kill()
+		return nil, Info{}, fmt.Errorf("plugin %s reported an empty name", p.path)
+	}
+
+	return proc, result.Info, nil
+}
+
+// readLoop dispatches responses and notifications until the plugin's stdout closes
+func (p *ExternalPlugin) readLoop(proc *pluginProcess, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("[plugin %s] invalid message: %v", filepath.Base(p.path), err)
+			continue
+		}
+
+		if msg.Method != "" {
+			if msg.Method == MethodEvent {
+				var params EventParams
+				if err := json.Unmarshal(msg.Params, &params); err == nil {
+					p.handleEvent(params)
+				}
+			}
+			continue
+		}
+
+		if msg.ID != nil {
+			proc.resolve(*msg.ID, &msg)
+		}
+	}
+
+	proc.err = proc.cmd.Wait()
+	close(proc.exited)
+}
+
+// supervise restarts the plugin with backoff whenever it exits unexpectedly
+func (p *ExternalPlugin) supervise() {
+	backoff := p.minBackoff
+
+	for {
+		p.mu.RLock()
+		proc := p.proc
+		p.mu.RUnlock()
+
+		select {
+		case <-proc.exited:
+		case <-p.stop:
+			return
+		}
+
+		p.mu.RLock()
+		closed := p.closed
+		p.mu.RUnlock()
+		if closed {
+			return
+		}
+
+		if time.Since(proc.started) > p.maxBackoff {
+			backoff = p.minBackoff
+		}
+
+		log.Printf("[plugin %s] exited unexpectedly (%v), restarting in %v", p.Name(), proc.err, backoff)
+		p.markAllErrored(fmt.Errorf("plugin %s exited: %v", p.Name(), proc.err))
+
+		for {
+			select {
+			case <-time.After(backoff):
+			case <-p.stop:
+				return
+			}
+
+			backoff *= 2
+			if backoff > p.maxBackoff {
+				backoff = p.maxBackoff
+			}
+
+			newProc, _, err := p.launch()
+			if err != nil {
+				log.Printf("[plugin %s] restart failed: %v", p.Name(), err)
+				continue
+			}
+
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				newProc.kill()
+				return
+			}
+			p.proc = newProc
+			watching := p.watching
+			p.mu.Unlock()
+
+			p.resync(watching)
+			break
+		}
+	}
+}
+
+// resync re-subscribes to events and refreshes agents after a restart
+func (p *ExternalPlugin) resync(watching bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.callTimeout)
+	defer cancel()
+
+	if watching {
+		if err := p.call(ctx, MethodWatch, struct{}{}, nil); err != nil {
+			log.Printf("[plugin %s] failed to resume watch: %v", p.Name(), err)
+		}
+	}
+	if _, err := p.Discover(ctx); err != nil {
+		log.Printf("[plugin %s] failed to rediscover agents: %v", p.Name(), err)
+	}
+}
+
+// markAllErrored flags every known agent as errored and emits an event for each
+func (p *ExternalPlugin) markAllErrored(err error) {
+	p.mu.RLock()
+	agents := make([]*remoteAgent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	p.mu.RUnlock()
+
+	for _, a := range agents {
+		a.setErrored(err)
+		p.emit(agent.Event{
+			Type:      agent.EventAgentErrored,
+			AgentID:   a.ID(),
+			Agent:     a,
+			Timestamp: time.Now(),
+			Error:     err,
+		})
+	}
+}
+
+// handleEvent converts an event notification into an agent.Event
+func (p *ExternalPlugin) handleEvent(params EventParams) {
+	eventType, ok := agent.ParseEventType(params.Type)
+	if !ok {
+		eventType = agent.EventAgentUpdated
+	}
+
+	var a agent.Agent
+	if params.Agent != nil {
+		a = p.upsert(*params.Agent)
+	} else {
+		p.mu.RLock()
+		if existing, ok := p.agents[params.AgentID]; ok {
+			a = existing
+		}
+		p.mu.RUnlock()
+	}
+
+	if eventType == agent.EventAgentTerminated {
+		p.mu.Lock()
+		delete(p.agents, params.AgentID)
+		p.mu.Unlock()
+	}
+
+	event := agent.Event{
+		Type:      eventType,
+		AgentID:   params.AgentID,
+		Agent:     a,
+		Timestamp: params.Timestamp,
+	}
+	if params.Error != "" {
+		event.Error = fmt.Errorf("%s", params.Error)
+	}
+	p.emit(event)
+}
+
+// emit queues an event for Watch without blocking the plugin's reader.
+// Updates and output are dropped when Watch lags, or before it starts; any
+// other event replaces the agent's earlier unsent one.
+func (p *ExternalPlugin) emit(event agent.Event) {
+	if event.Type == agent.EventAgentUpdated || event.Type == agent.EventAgentOutput {
+		select {
+		case p.events <- event:
+		default:
+		}
+		return
+	}
+
+	p.mu.Lock()
+	if _, ok := p.transitions[event.AgentID]; !ok {
+		p.pending = append(p.pending, event.AgentID)
+	}
+	p.transitions[event.AgentID] = event
+	p.mu.Unlock()
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+// takeTransitions returns the unsent transitions, oldest first
+func (p *ExternalPlugin) takeTransitions() []agent.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := make([]agent.Event, 0, len(p.pending))
+	for _, id := range p.pending {
+		events = append(events, p.transitions[id])
+		delete(p.transitions, id)
+	}
+	p.pending = p.pending[:0]
+	return events
+}
+
+// upsert updates or creates the proxy for a snapshot
+func (p *ExternalPlugin) upsert(snap AgentSnapshot) *remoteAgent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	a, ok := p.agents[snap.ID]
+	if !ok {
+		a = &remoteAgent{plugin: p}
+		p.agents[snap.ID] = a
+	}
+	a.update(snap)
+	return a
+}
+
+// call sends a request to the current plugin process
+func (p *ExternalPlugin) call(ctx context.Context, method string, params, result interface{}) error {
+	p.mu.RLock()
+	proc := p.proc
+	p.mu.RUnlock()
+
+	if proc == nil {
+		return fmt.Errorf("plugin %s is not running", p.path)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.callTimeout)
+		defer cancel()
+	}
+	return proc.call(ctx, method, params, result)
+}
+
+// Discover asks the plugin for its agents
+func (p *ExternalPlugin) Discover(ctx context.Context) ([]agent.Agent, error) {
+	var result AgentsResult
+	if err := p.call(ctx, MethodDiscover, struct{}{}, &result); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(result.Agents))
+	agents := make([]agent.Agent, 0, len(result.Agents))
+	for _, snap := range result.Agents {
+		seen[snap.ID] = true
+		agents = append(agents, p.upsert(snap))
+	}
+
+	p.mu.Lock()
+	for id := range p.agents {
+		if !seen[id] {
+			delete(p.agents, id)
+		}
+	}
+	p.mu.Unlock()
+
+	return agents, nil
+}
+
+// Watch subscribes to the plugin's event notifications
+func (p *ExternalPlugin) Watch(ctx context.Context) (<-chan agent.Event, error) {
+	if err := p.call(ctx, MethodWatch, struct{}{}, nil); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.watching = true
+	p.mu.Unlock()
+
+	out := make(chan agent.Event, 100)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-p.events:
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-p.signal:
+				for _, event := range p.takeTransitions() {
+					select {
+					case out <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Spawn asks the plugin to start a new agent
+func (p *ExternalPlugin) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	var result AgentResult
+	if err := p.call(ctx, MethodSpawn, SpawnParams{Config: config}, &result); err != nil {
+		return nil, err
+	}
+	return p.upsert(result.Agent), nil
+}
+
+// Get returns a known agent, asking the plugin if it is not cached
+func (p *ExternalPlugin) Get(id string) (agent.Agent, error) {
+	p.mu.RLock()
+	a, ok := p.agents[id]
+	p.mu.RUnlock()
+	if ok {
+		return a, nil
+	}
+
+	var result AgentResult
+	if err := p.call(context.Background(), MethodGet, IDParams{ID: id}, &result); err != nil {
+		return nil, fmt.Errorf("agent not found: %s", id)
+	}
+	return p.upsert(result.Agent), nil
+}
+
+// List returns the agents last reported by the plugin
+func (p *ExternalPlugin) List() []agent.Agent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	agents := make([]agent.Agent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// Terminate asks the plugin to terminate an agent
+func (p *ExternalPlugin) Terminate(id string) error {
+	return p.call(context.Background(), MethodTerminate, IDParams{ID: id}, nil)
+}
+
+// SendInput forwards input to an agent through the plugin
+func (p *ExternalPlugin) SendInput(id string, input string) error {
+	return p.call(context.Background(), MethodSendInput, InputParams{ID: id, Input: input}, nil)
+}
+
+// call writes a request and waits for the matching response
+func (proc *pluginProcess) call(ctx context.Context, method string, params, result interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	proc.mu.Lock()
+	proc.nextID++
+	id := proc.nextID
+	ch := make(chan *message, 1)
+	proc.pending[id] = ch
+	proc.mu.Unlock()
+
+	defer func() {
+		proc.mu.Lock()
+		delete(proc.pending, id)
+		proc.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(Request{JSONRPC: "2.0", ID: &id, Method: method, Params: raw})
+	if err != nil {
+		return err
+	}
+
+	proc.writeMu.Lock()
+	_, err = proc.stdin.Write(append(data, '\n'))
+	proc.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to write to plugin: %w", err)
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-proc.exited:
+		return fmt.Errorf("plugin exited")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (proc *pluginProcess) resolve(id int64, msg *message) {
+	proc.mu.Lock()
+	ch, ok := proc.pending[id]
+	proc.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (proc *pluginProcess) kill() {
+	proc.cmd.Process.Kill()
+	<-proc.exited
+}
+
+// remoteAgent is a local view of an agent owned by an external plugin
+type remoteAgent struct {
+	plugin *ExternalPlugin
+
+	mu      sync.RWMutex
+	snap    AgentSnapshot
+	lastErr error
+}
+
+func (a *remoteAgent) update(snap AgentSnapshot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.snap = snap
+	a.lastErr = nil
+	if snap.Error != "" {
+		a.lastErr = fmt.Errorf("%s", snap.Error)
+	}
+}
+
+func (a *remoteAgent) setErrored(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.snap.Status = agent.StatusErrored.String()
+	a.lastErr = err
+}
+
+// ID returns the agent ID
+func (a *remoteAgent) ID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ID
+}
+
+// Name returns the agent name
+func (a *remoteAgent) Name() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Name
+}
+
+// Type returns the agent type
+func (a *remoteAgent) Type() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.snap.Type != "" {
+		return a.snap.Type
+	}
+	return a.plugin.Type()
+}
+
+// Directory returns the working directory
+func (a *remoteAgent) Directory() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Directory
+}
+
+// ProjectID returns the project ID
+func (a *remoteAgent) ProjectID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ProjectID
+}
+
+// ParentID returns the parent agent ID
+func (a *remoteAgent) ParentID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.ParentID
+}
+
+// IsBackground returns whether this is a background agent
+func (a *remoteAgent) IsBackground() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Background
+}
+
+// Status returns the last reported status
+func (a *remoteAgent) Status() agent.Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	status, _ := agent.ParseStatus(a.snap.Status)
+	return status
+}
+
+// IsPaused returns whether the plugin reported the agent as paused
+func (a *remoteAgent) IsPaused() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.Paused
+}
+
+// StartTime returns when the agent started
+func (a *remoteAgent) StartTime() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.StartTime
+}
+
+// LastActivity returns the last activity time
+func (a *remoteAgent) LastActivity() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.LastActivity
+}
+
+// Output returns the last reported output
+func (a *remoteAgent) Output() io.Reader {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return bytes.NewReader([]byte(a.snap.Output))
+}
+
+// CurrentTask returns the current task description
+func (a *remoteAgent) CurrentTask() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snap.CurrentTask
+}
+
+// Metrics returns the last reported metrics, pricing them from the
+// catalog when the plugin reports a model but no cost
+func (a *remoteAgent) Metrics() agent.Metrics {
+	a.mu.RLock()
+	m := a.snap.Metrics
+	a.mu.RUnlock()
+
+	if m.EstimatedCost == 0 && m.Model != "" {
+		a.plugin.mu.RLock()
+		catalog := a.plugin.catalog
+		a.plugin.mu.RUnlock()
+		m.EstimatedCost = catalog.Cost("", m.Model, agent.TokenUsage{Input: m.TokensIn, Output: m.TokensOut})
+	}
+	return m
+}
+
+// LastError returns the last error
+func (a *remoteAgent) LastError() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastErr
+}
+
+// SendInput forwards input through the plugin
+func (a *remoteAgent) SendInput(input string) error {
+	return a.plugin.SendInput(a.ID(), input)
+}
+
+// Terminate asks the plugin to terminate the agent
+func (a *remoteAgent) Terminate() error {
+	return a.plugin.Terminate(a.ID())
+}
+
+// Pause asks the plugin to pause the agent
+func (a *remoteAgent) Pause() error {
+	return a.control(MethodPause)
+}
+
+// Resume asks the plugin to resume the agent
+func (a *remoteAgent) Resume() error {
+	return a.control(MethodResume)
+}
+
+// control calls an optional per-agent method, mapping a missing method to agent.ErrUnsupported
+func (a *remoteAgent) control(method string) error {
+	err := a.plugin.call(context.Background(), method, IDParams{ID: a.ID()}, nil)
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == CodeMethodNotFound {
+		return fmt.Errorf("%s %w by plugin %s", method, agent.ErrUnsupported, a.plugin.Name())
+	}
+	return err
+}
+
+// logWriter forwards plugin stderr lines to the application log
+type logWriter struct {
+	prefix string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		log.Printf("%s%s", w.prefix, strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
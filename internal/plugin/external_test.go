@@ -0,0 +1,337 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// TestMain lets the test binary double as a plugin executable
+func TestMain(m *testing.M) {
+	if os.Getenv("AUTO_TEST_PLUGIN") == "1" {
+		runHelperPlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// helperProvider is the provider served by the helper plugin process
+type helperProvider struct {
+	*BasePlugin
+	mu     sync.Mutex
+	agents map[string]*agent.MockAgent
+	inputs map[string]string
+	events chan agent.Event
+}
+
+func runHelperPlugin() {
+	p := &helperProvider{
+		BasePlugin: NewBasePlugin(Info{Name: "helper", Version: "1.0.0", Type: "helper"}),
+		agents:     make(map[string]*agent.MockAgent),
+		inputs:     make(map[string]string),
+		events:     make(chan agent.Event, 10),
+	}
+	seed := agent.NewMockAgent("seed-1", "seed")
+	seed.MockType = "helper"
+	p.agents[seed.ID()] = seed
+
+	Serve(context.Background(), p, os.Stdin, os.Stdout)
+}
+
+func (p *helperProvider) Discover(ctx context.Context) ([]agent.Agent, error) {
+	return p.List(), nil
+}
+
+func (p *helperProvider) Watch(ctx context.Context) (<-chan agent.Event, error) {
+	return p.events, nil
+}
+
+func (p *helperProvider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	if config.Prompt == "crash" {
+		fmt.Fprintln(os.Stderr, "crashing on request")
+		os.Exit(2)
+	}
+
+	a := agent.NewMockAgent("spawned-"+config.Name, config.Name)
+	a.MockType = "helper"
+	a.MockDirectory = config.Directory
+
+	p.mu.Lock()
+	p.agents[a.ID()] = a
+	p.mu.Unlock()
+
+	p.events <- agent.Event{Type: agent.EventAgentStarted, AgentID: a.ID(), Agent: a, Timestamp: time.Now()}
+	return a, nil
+}
+
+func (p *helperProvider) Get(id string) (agent.Agent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a, ok := p.agents[id]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("agent not found: %s", id)
+}
+
+func (p *helperProvider) List() []agent.Agent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	agents := make([]agent.Agent, 0, len(p.agents))
+	for _, a := range p.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+func (p *helperProvider) Terminate(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.agents[id]; !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+	delete(p.agents, id)
+	p.events <- agent.Event{Type: agent.EventAgentTerminated, AgentID: id, Timestamp: time.Now()}
+	return nil
+}
+
+func (p *helperProvider) SendInput(id string, input string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.agents[id]; !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+	p.inputs[id] = input
+	return nil
+}
+
+// writeHelperPlugin writes an executable wrapper that runs the test binary as a plugin
+func writeHelperPlugin(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable() error = %v", err)
+	}
+	path := filepath.Join(dir, name)
+	script := fmt.Sprintf("#!/bin/sh\nAUTO_TEST_PLUGIN=1 exec %q\n", exe)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func startHelperPlugin(t *testing.T) *ExternalPlugin {
+	t.Helper()
+
+	p := NewExternal(writeHelperPlugin(t, t.TempDir(), "helper"))
+	p.minBackoff = 50 * time.Millisecond
+	p.maxBackoff = 200 * time.Millisecond
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func waitForEvent(t *testing.T, events <-chan agent.Event, eventType agent.EventType, agentID string) agent.Event {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == eventType && event.AgentID == agentID {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s event for %s", eventType, agentID)
+			return agent.Event{}
+		}
+	}
+}
+
+func TestExternalPlugin_Handshake(t *testing.T) {
+	p := startHelperPlugin(t)
+
+	info := p.Info()
+	if info.Name != "helper" || info.Version != "1.0.0" {
+		t.Errorf("Info() = %+v, want helper 1.0.0", info)
+	}
+	if p.Name() != "helper" || p.Type() != "helper" {
+		t.Errorf("Name()/Type() = %s/%s, want helper/helper", p.Name(), p.Type())
+	}
+}
+
+func TestExternalPlugin_ProviderMethods(t *testing.T) {
+	p := startHelperPlugin(t)
+	ctx := context.Background()
+
+	agents, err := p.Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(agents) != 1 || agents[0].ID() != "seed-1" {
+		t.Fatalf("Discover() = %v, want seed-1", agents)
+	}
+	if agents[0].Status() != agent.StatusRunning || agents[0].Metrics().TokensIn != 1000 {
+		t.Errorf("seed agent not decoded: status=%v metrics=%+v", agents[0].Status(), agents[0].Metrics())
+	}
+
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	spawned, err := p.Spawn(ctx, agent.SpawnConfig{Name: "worker", Directory: "/tmp/work"})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	if spawned.ID() != "spawned-worker" || spawned.Directory() != "/tmp/work" {
+		t.Errorf("Spawn() = %s in %s", spawned.ID(), spawned.Directory())
+	}
+	event := waitForEvent(t, events, agent.EventAgentStarted, "spawned-worker")
+	if event.Agent == nil || event.Agent.Name() != "worker" {
+		t.Errorf("event agent = %v, want worker", event.Agent)
+	}
+
+	if err := p.SendInput("spawned-worker", "do things"); err != nil {
+		t.Fatalf("SendInput() error = %v", err)
+	}
+	if err := p.SendInput("missing", "x"); err == nil {
+		t.Error("SendInput() should fail for unknown agent")
+	}
+
+	if err := spawned.Pause(); err != nil {
+		t.Errorf("Pause() error = %v", err)
+	}
+
+	if len(p.List()) != 2 {
+		t.Errorf("List() = %d agents, want 2", len(p.List()))
+	}
+
+	if err := p.Terminate("spawned-worker"); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+	waitForEvent(t, events, agent.EventAgentTerminated, "spawned-worker")
+	if _, err := p.Get("spawned-worker"); err == nil {
+		t.Error("Get() should fail after terminate")
+	}
+	if a, err := p.Get("seed-1"); err != nil || a.Name() != "seed" {
+		t.Errorf("Get(seed-1) = %v, %v", a, err)
+	}
+}
+
+func TestExternalPlugin_RestartsAfterCrash(t *testing.T) {
+	p := startHelperPlugin(t)
+	ctx := context.Background()
+
+	if _, err := p.Discover(ctx); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	if _, err := p.Spawn(ctx, agent.SpawnConfig{Name: "boom", Prompt: "crash"}); err == nil {
+		t.Fatal("Spawn() should fail when the plugin crashes")
+	}
+
+	event := waitForEvent(t, events, agent.EventAgentErrored, "seed-1")
+	if event.Agent == nil || event.Error == nil || !strings.Contains(event.Error.Error(), "exited") {
+		t.Errorf("crash event = %+v, want agent and exit error", event)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a, err := p.Get("seed-1")
+		if err == nil && a.Status() == agent.StatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("plugin was not restarted")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The watch subscription survives the restart
+	if _, err := p.Spawn(ctx, agent.SpawnConfig{Name: "again"}); err != nil {
+		t.Fatalf("Spawn() after restart error = %v", err)
+	}
+	waitForEvent(t, events, agent.EventAgentStarted, "spawned-again")
+}
+
+func TestExternalPlugin_KeepsErroredEvents(t *testing.T) {
+	p := startHelperPlugin(t)
+	ctx := context.Background()
+
+	// More agents than the event buffer holds, errored before Watch drains it
+	for i := 0; i < 150; i++ {
+		p.upsert(AgentSnapshot{ID: fmt.Sprintf("agent-%d", i), Status: agent.StatusRunning.String()})
+	}
+	p.markAllErrored(errors.New("plugin helper exited"))
+
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	errored := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(errored) < 150 {
+		select {
+		case event := <-events:
+			if event.Type == agent.EventAgentErrored {
+				errored[event.AgentID] = true
+			}
+		case <-timeout:
+			t.Fatalf("got %d errored events, want one per agent", len(errored))
+		}
+	}
+}
+
+func TestExternalPlugin_ProtocolMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old")
+	script := "#!/bin/sh\nread line\necho '{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"protocol_version\":99,\"info\":{\"name\":\"old\"}}}'\nread line\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+
+	err := NewExternal(path).Start()
+	if err == nil || !strings.Contains(err.Error(), "protocol version 99") {
+		t.Errorf("Start() error = %v, want protocol version mismatch", err)
+	}
+}
+
+func TestManagerLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writeHelperPlugin(t, dir, "helper")
+	writeHelperPlugin(t, dir, "skipped")
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0644)
+
+	registry := agent.NewRegistry()
+	m := NewManager(registry)
+	t.Cleanup(func() { m.Close() })
+
+	if err := m.LoadDir(dir, []string{"helper"}); err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+
+	if len(m.List()) != 1 {
+		t.Fatalf("List() = %d plugins, want 1", len(m.List()))
+	}
+	if _, ok := registry.Get("helper"); !ok {
+		t.Error("plugin should be registered with the agent registry")
+	}
+
+	if err := m.LoadDir(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("LoadDir() should fail for a missing directory")
+	}
+}
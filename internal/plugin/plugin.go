@@ -3,7 +3,13 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
@@ -28,6 +34,7 @@ type Plugin interface {
 type Manager struct {
 	plugins  map[string]Plugin
 	registry *agent.Registry
+	catalog  *agent.ModelCatalog
 	mu       sync.RWMutex
 }
 
@@ -125,6 +132,74 @@ func (m *Manager) DiscoverAll(ctx context.Context) ([]agent.Agent, error) {
 	return allAgents, nil
 }
 
+// SetCatalog sets the model catalog handed to external plugins loaded afterwards
+func (m *Manager) SetCatalog(catalog *agent.ModelCatalog) {
+	m.catalog = catalog
+}
+
+// LoadDir starts every executable in dir as an external plugin and registers
+// it. When enabled is non-empty only executables whose file name (with or
+// without extension) is listed are loaded. A plugin that fails to start is
+// skipped and reported in the returned error without affecting the others.
+func (m *Manager) LoadDir(dir string, enabled []string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	allowed := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		allowed[name] = true
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || entry.IsDir() {
+			continue
+		}
+		if len(allowed) > 0 && !allowed[name] && !allowed[strings.TrimSuffix(name, filepath.Ext(name))] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		ext := NewExternal(filepath.Join(dir, name))
+		ext.SetCatalog(m.catalog)
+		if err := ext.Start(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := m.Register(ext); err != nil {
+			ext.Close()
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Loaded plugin %s (%s) from %s", ext.Info().Name, ext.Info().Version, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close stops all plugins that hold external resources
+func (m *Manager) Close() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var errs []error
+	for _, p := range m.plugins {
+		if closer, ok := p.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // BasePlugin provides a base implementation for plugins
 type BasePlugin struct {
 	info Info
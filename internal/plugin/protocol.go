@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// ProtocolVersion is the version of the stdio plugin protocol spoken by AUTO
+const ProtocolVersion = 1
+
+// Method names of the plugin protocol. Requests flow from AUTO to the plugin;
+// MethodEvent is a notification sent from the plugin to AUTO.
+const (
+	MethodInitialize = "initialize"
+	MethodDiscover   = "discover"
+	MethodWatch      = "watch"
+	MethodSpawn      = "spawn"
+	MethodGet        = "get"
+	MethodList       = "list"
+	MethodTerminate  = "terminate"
+	MethodSendInput  = "send_input"
+	MethodPause      = "pause"
+	MethodResume     = "resume"
+	MethodEvent      = "event"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC 2.0 request or notification (when ID is nil)
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// message is used to decode any incoming line before deciding what it is
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC 2.0 error object
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// InitializeParams is sent by AUTO in the handshake
+type InitializeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// InitializeResult is returned by the plugin in the handshake
+type InitializeResult struct {
+	ProtocolVersion int  `json:"protocol_version"`
+	Info            Info `json:"info"`
+}
+
+// IDParams identifies a single agent
+type IDParams struct {
+	ID string `json:"id"`
+}
+
+// InputParams carries input for an agent
+type InputParams struct {
+	ID    string `json:"id"`
+	Input string `json:"input"`
+}
+
+// SpawnParams carries the spawn configuration
+type SpawnParams struct {
+	Config agent.SpawnConfig `json:"config"`
+}
+
+// AgentResult wraps a single agent snapshot
+type AgentResult struct {
+	Agent AgentSnapshot `json:"agent"`
+}
+
+// AgentsResult wraps a list of agent snapshots
+type AgentsResult struct {
+	Agents []AgentSnapshot `json:"agents"`
+}
+
+// EventParams is the payload of an event notification
+type EventParams struct {
+	Type      string         `json:"type"`
+	AgentID   string         `json:"agent_id"`
+	Agent     *AgentSnapshot `json:"agent,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// AgentSnapshot is the wire representation of an agent
+type AgentSnapshot struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	Directory    string        `json:"directory"`
+	ProjectID    string        `json:"project_id,omitempty"`
+	ParentID     string        `json:"parent_id,omitempty"`
+	Background   bool          `json:"background,omitempty"`
+	Status       string        `json:"status"`
+	StartTime    time.Time     `json:"start_time"`
+	LastActivity time.Time     `json:"last_activity"`
+	CurrentTask  string        `json:"current_task,omitempty"`
+	Output       string        `json:"output,omitempty"`
+	Metrics      agent.Metrics `json:"metrics"`
+	Error        string        `json:"error,omitempty"`
+	Restarts     int           `json:"restarts,omitempty"` // restart prompts AUTO has sent the agent
+	Paused       bool          `json:"paused,omitempty"`
+}
+
+// maxSnapshotOutput caps the output carried in a snapshot, keeping the tail
+const maxSnapshotOutput = 64 * 1024
+
+// Snapshot converts an agent into its wire representation
+func Snapshot(a agent.Agent) AgentSnapshot {
+	s := AgentSnapshot{
+		ID:           a.ID(),
+		Name:         a.Name(),
+		Type:         a.Type(),
+		Directory:    a.Directory(),
+		ProjectID:    a.ProjectID(),
+		ParentID:     a.ParentID(),
+		Background:   a.IsBackground(),
+		Status:       a.Status().String(),
+		StartTime:    a.StartTime(),
+		LastActivity: a.LastActivity(),
+		CurrentTask:  a.CurrentTask(),
+		Metrics:      a.Metrics(),
+		Paused:       agent.IsPaused(a),
+	}
+	if out := a.Output(); out != nil {
+		data, _ := io.ReadAll(out)
+		if len(data) > maxSnapshotOutput {
+			data = data[len(data)-maxSnapshotOutput:]
+		}
+		s.Output = string(data)
+	}
+	if err := a.LastError(); err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
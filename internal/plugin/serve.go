@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// maxMessageSize bounds a single protocol line
+const maxMessageSize = 16 * 1024 * 1024
+
+// Serve runs the plugin side of the stdio protocol, answering requests read
+// from r with calls into p and writing responses and event notifications to w.
+// It returns when r is exhausted or ctx is cancelled. Plugin binaries written
+// in Go typically call Serve(ctx, p, os.Stdin, os.Stdout) from main.
+func Serve(ctx context.Context, p Plugin, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &server{plugin: p, w: w}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			s.writeError(nil, CodeParseError, err.Error())
+			continue
+		}
+		if req.ID == nil {
+			continue // AUTO sends no notifications
+		}
+
+		wg.Add(1)
+		go func(req Request) {
+			defer wg.Done()
+			result, err := s.handle(ctx, req)
+			if err != nil {
+				code := CodeInternalError
+				if rpcErr, ok := err.(*RPCError); ok {
+					code = rpcErr.Code
+				}
+				s.writeError(req.ID, code, err.Error())
+				return
+			}
+			s.writeResult(req.ID, result)
+		}(req)
+	}
+
+	return scanner.Err()
+}
+
+type server struct {
+	plugin   Plugin
+	w        io.Writer
+	writeMu  sync.Mutex
+	watchMu  sync.Mutex
+	watching bool
+}
+
+func (s *server) handle(ctx context.Context, req Request) (interface{}, error) {
+	switch req.Method {
+	case MethodInitialize:
+		return InitializeResult{ProtocolVersion: ProtocolVersion, Info: s.plugin.Info()}, nil
+
+	case MethodDiscover:
+		agents, err := s.plugin.Discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return snapshots(agents), nil
+
+	case MethodList:
+		return snapshots(s.plugin.List()), nil
+
+	case MethodWatch:
+		return struct{}{}, s.startWatch(ctx)
+
+	case MethodSpawn:
+		var params SpawnParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		a, err := s.plugin.Spawn(ctx, params.Config)
+		if err != nil {
+			return nil, err
+		}
+		return AgentResult{Agent: Snapshot(a)}, nil
+
+	case MethodGet:
+		var params IDParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		a, err := s.plugin.Get(params.ID)
+		if err != nil {
+			return nil, err
+		}
+		return AgentResult{Agent: Snapshot(a)}, nil
+
+	case MethodTerminate:
+		var params IDParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return struct{}{}, s.plugin.Terminate(params.ID)
+
+	case MethodSendInput:
+		var params InputParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return struct{}{}, s.plugin.SendInput(params.ID, params.Input)
+
+	case MethodPause, MethodResume:
+		var params IDParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		a, err := s.plugin.Get(params.ID)
+		if err != nil {
+			return nil, err
+		}
+		if req.Method == MethodPause {
+			return struct{}{}, a.Pause()
+		}
+		return struct{}{}, a.Resume()
+
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method: %s", req.Method)}
+	}
+}
+
+// startWatch subscribes to the plugin's events once and forwards them as notifications
+func (s *server) startWatch(ctx context.Context) error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+
+	if s.watching {
+		return nil
+	}
+
+	events, err := s.plugin.Watch(ctx)
+	if err != nil {
+		return err
+	}
+	s.watching = true
+
+	go func() {
+		for event := range events {
+			params := EventParams{
+				Type:      event.Type.String(),
+				AgentID:   event.AgentID,
+				Timestamp: event.Timestamp,
+			}
+			if params.Timestamp.IsZero() {
+				params.Timestamp = time.Now()
+			}
+			if event.Agent != nil {
+				snap := Snapshot(event.Agent)
+				params.Agent = &snap
+			}
+			if event.Error != nil {
+				params.Error = event.Error.Error()
+			}
+			s.notify(MethodEvent, params)
+		}
+	}()
+	return nil
+}
+
+func (s *server) notify(method string, params interface{}) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	s.write(Request{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *server) writeResult(id *int64, result interface{}) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		s.writeError(id, CodeInternalError, err.Error())
+		return
+	}
+	s.write(Response{JSONRPC: "2.0", ID: id, Result: data})
+}
+
+func (s *server) writeError(id *int64, code int, msg string) {
+	s.write(Response{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: msg}})
+}
+
+func (s *server) write(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.w.Write(append(data, '\n'))
+}
+
+func decodeParams(raw json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func snapshots(agents []agent.Agent) AgentsResult {
+	result := AgentsResult{Agents: make([]AgentSnapshot, 0, len(agents))}
+	for _, a := range agents {
+		result.Agents = append(result.Agents, Snapshot(a))
+	}
+	return result
+}
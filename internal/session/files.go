@@ -0,0 +1,166 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+)
+
+// fileHistory is how long file changes are kept for the ownership view
+const fileHistory = 24 * time.Hour
+
+// fileWatchInterval is how often the watched directories are matched to
+// the running agents
+const fileWatchInterval = 5 * time.Second
+
+// Files returns the files agents changed recently, conflicts first
+func (m *Manager) Files() []conflict.File {
+	if m.files == nil {
+		return nil
+	}
+	return m.files.Files()
+}
+
+// recordAgentFiles records the files an agent reports it changed
+func (m *Manager) recordAgentFiles(ctx context.Context, event agent.Event) {
+	if m.files == nil || event.Agent == nil {
+		return
+	}
+	reporter, ok := event.Agent.(agent.FileReporter)
+	if !ok {
+		return
+	}
+	// Only agents at work can be changing files; this also keeps events for
+	// old sessions from loading their history
+	if status := event.Agent.Status(); status != agent.StatusRunning && status != agent.StatusIdle {
+		return
+	}
+
+	since := time.Now().Add(-fileHistory)
+	for _, fc := range reporter.FileChanges() {
+		if fc.Time.Before(since) {
+			continue
+		}
+		m.recordFile(ctx, fc.Path, event.Agent, fc.Time, conflict.SourceAgent)
+	}
+}
+
+// recordWatchedFile attributes a change seen by the filesystem watch to the
+// one running agent whose directory contains it. With several candidates
+// the writer is unknown, and only the agents' own reports count.
+func (m *Manager) recordWatchedFile(ctx context.Context, path string, at time.Time) {
+	var writer agent.Agent
+	m.mu.RLock()
+	for _, a := range m.agents {
+		if a.Status() != agent.StatusRunning || a.Directory() == "" || !conflict.Contains(a.Directory(), path) {
+			continue
+		}
+		if writer != nil {
+			m.mu.RUnlock()
+			return
+		}
+		writer = a
+	}
+	m.mu.RUnlock()
+
+	if writer != nil {
+		m.recordFile(ctx, path, writer, at, conflict.SourceWatch)
+	}
+}
+
+// recordFile records a change and alerts on the conflicts it causes
+func (m *Manager) recordFile(ctx context.Context, path string, a agent.Agent, at time.Time, source string) {
+	conflicts := m.files.Record(path, conflict.Change{
+		AgentID:   a.ID(),
+		AgentName: a.Name(),
+		Time:      at,
+		Source:    source,
+	})
+	for _, c := range conflicts {
+		log.Printf("File conflict on %s between agents %s and %s", c.Path, c.First.AgentID, c.Second.AgentID)
+		if m.alertMgr == nil {
+			continue
+		}
+		m.alertMgr.Send(ctx, &alert.Alert{
+			Level:   alert.LevelError,
+			Title:   "File Conflict",
+			Message: fmt.Sprintf("Agents %s and %s both changed %s", c.First.AgentName, c.Second.AgentName, c.Path),
+			AgentID: a.ID(),
+			Agent:   a,
+		})
+	}
+}
+
+// runFileWatch watches the directories of running agents for changed files
+// and forgets old changes
+func (m *Manager) runFileWatch(ctx context.Context) {
+	if m.files == nil {
+		return
+	}
+
+	var watcher *conflict.Watcher
+	if m.cfg.Conflicts.Watch {
+		w, err := conflict.NewWatcher(m.cfg.Conflicts.Ignore)
+		if err != nil {
+			log.Printf("File conflict watch disabled: %v", err)
+		} else {
+			watcher = w
+			go watcher.Run(ctx, func(path string, at time.Time) {
+				m.recordWatchedFile(ctx, path, at)
+			})
+		}
+	}
+
+	ticker := time.NewTicker(fileWatchInterval)
+	defer ticker.Stop()
+
+	failed := make(map[string]bool)
+	for {
+		if watcher != nil {
+			m.syncWatches(watcher, failed)
+		}
+		m.files.Prune(time.Now().Add(-fileHistory))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncWatches watches exactly the directories of running agents. Directories
+// that cannot be watched are logged once and retried.
+func (m *Manager) syncWatches(w *conflict.Watcher, failed map[string]bool) {
+	want := make(map[string]bool)
+	m.mu.RLock()
+	for _, a := range m.agents {
+		if a.Status() == agent.StatusRunning && a.Directory() != "" {
+			want[filepath.Clean(a.Directory())] = true
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, root := range w.Roots() {
+		if !want[root] {
+			w.Remove(root)
+		}
+		delete(want, root)
+	}
+	for root := range want {
+		if err := w.Add(root); err != nil {
+			if !failed[root] {
+				log.Printf("Cannot watch %s for file conflicts: %v", root, err)
+			}
+			failed[root] = true
+			continue
+		}
+		delete(failed, root)
+	}
+}
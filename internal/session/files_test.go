@@ -0,0 +1,104 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// fileAgent is a mock agent that reports the files it changed
+type fileAgent struct {
+	*agent.MockAgent
+	changes []agent.FileChange
+}
+
+func (a *fileAgent) FileChanges() []agent.FileChange { return a.changes }
+
+func newFileAgent(id, dir string) *fileAgent {
+	a := &fileAgent{MockAgent: agent.NewMockAgent(id, "Agent "+id)}
+	a.MockStatus = agent.StatusRunning
+	a.MockDirectory = dir
+	return a
+}
+
+func newFilesManager() (*Manager, *alert.Manager) {
+	alertMgr := alert.NewManager(&config.AlertsConfig{}, nil)
+	cfg := &config.Config{Conflicts: config.ConflictsConfig{Enabled: true, Window: 30 * time.Minute}}
+	return NewManager(cfg, nil, agent.NewRegistry(), alertMgr), alertMgr
+}
+
+func TestRecordAgentFiles(t *testing.T) {
+	m, alertMgr := newFilesManager()
+	ctx := context.Background()
+	now := time.Now()
+
+	a := newFileAgent("a", "/srv/app")
+	a.changes = []agent.FileChange{{Path: "/srv/app/main.go", Time: now}, {Path: "/srv/app/a.go", Time: now}}
+	b := newFileAgent("b", "/srv/app")
+	b.changes = []agent.FileChange{{Path: "/srv/app/main.go", Time: now.Add(time.Minute)}}
+	old := newFileAgent("old", "/srv/app")
+	old.MockStatus = agent.StatusCompleted
+	old.changes = []agent.FileChange{{Path: "/srv/app/a.go", Time: now}}
+
+	for _, x := range []*fileAgent{a, b, old} {
+		m.handleEvent(ctx, agent.Event{Type: agent.EventAgentUpdated, AgentID: x.ID(), Agent: x, Timestamp: now})
+	}
+
+	files := m.Files()
+	if len(files) != 2 || files[0].Path != "/srv/app/main.go" || !files[0].Conflict || files[1].Conflict {
+		t.Fatalf("Files() = %+v", files)
+	}
+
+	alerts := alertMgr.List(0, false)
+	if len(alerts) != 1 || alerts[0].Title != "File Conflict" {
+		t.Fatalf("alerts = %v", alertTitles(alertMgr))
+	}
+	if msg := alerts[0].Message; !strings.Contains(msg, "Agent a") || !strings.Contains(msg, "Agent b") || !strings.Contains(msg, "/srv/app/main.go") {
+		t.Errorf("alert message = %q", msg)
+	}
+
+	// The same changes reported again do not alert again
+	m.handleEvent(ctx, agent.Event{Type: agent.EventAgentUpdated, AgentID: b.ID(), Agent: b, Timestamp: now})
+	if n := len(alertMgr.List(0, false)); n != 1 {
+		t.Errorf("got %d alerts, want 1", n)
+	}
+}
+
+func TestRecordWatchedFile(t *testing.T) {
+	m, _ := newFilesManager()
+	ctx := context.Background()
+	now := time.Now()
+
+	a := newFileAgent("a", "/srv/app")
+	m.agents[a.ID()] = a
+	m.recordWatchedFile(ctx, "/srv/app/main.go", now)
+	m.recordWatchedFile(ctx, "/srv/other/main.go", now)
+
+	files := m.Files()
+	if len(files) != 1 || files[0].Changes[0].AgentID != "a" || files[0].Changes[0].Source != "watch" {
+		t.Fatalf("Files() = %+v", files)
+	}
+
+	// With two agents in the directory the writer is unknown
+	b := newFileAgent("b", "/srv/app")
+	m.agents[b.ID()] = b
+	m.recordWatchedFile(ctx, "/srv/app/util.go", now)
+	if files := m.Files(); len(files) != 1 {
+		t.Errorf("ambiguous change recorded: %+v", files)
+	}
+}
+
+func TestFilesDisabled(t *testing.T) {
+	m := NewManager(&config.Config{}, nil, agent.NewRegistry(), nil)
+	a := newFileAgent("a", "/srv/app")
+	a.changes = []agent.FileChange{{Path: "/srv/app/main.go", Time: time.Now()}}
+	m.handleEvent(context.Background(), agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a})
+	if files := m.Files(); files != nil {
+		t.Errorf("Files() = %+v, want nil", files)
+	}
+}
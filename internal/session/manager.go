@@ -3,6 +3,7 @@ package session
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -10,6 +11,8 @@ import (
 	"github.com/CastAIPhil/AUTO/internal/agent"
 	"github.com/CastAIPhil/AUTO/internal/alert"
 	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
 	"github.com/CastAIPhil/AUTO/internal/store"
 )
 
@@ -21,29 +24,80 @@ type Manager struct {
 	alertMgr *alert.Manager
 	agents   map[string]agent.Agent
 	mu       sync.RWMutex
-	onEvent  func(agent.Event)
+	events   *eventbus.Bus[agent.Event]
+	streams  *eventbus.Bus[agent.StreamEvent]
+	ctx      context.Context
 	cancel   context.CancelFunc
+
+	contextState map[string]*contextState
+	replay       bool // events come from a recording, see SetReplay
+	watch        map[string]*watchState
+	queue        spawnQueue
+	restart      map[string]*restartState
+	files        *conflict.Tracker // nil when conflict detection is off
+}
+
+// contextWarningHysteresis is how far (0.0 - 1.0) utilization must fall below
+// the warning threshold before another warning can fire for the same agent
+const contextWarningHysteresis = 0.1
+
+// contextState tracks which context alerts have already fired for an agent
+type contextState struct {
+	warned  bool // utilization is above the warning threshold
+	limited bool // agent is in StatusContextLimit
 }
 
 // NewManager creates a new session manager
 func NewManager(cfg *config.Config, st *store.Store, registry *agent.Registry, alertMgr *alert.Manager) *Manager {
-	return &Manager{
+	var files *conflict.Tracker
+	if cfg.Conflicts.Enabled {
+		files = conflict.NewTracker(cfg.Conflicts.Window)
+	}
+
+	m := &Manager{
 		cfg:      cfg,
 		store:    st,
 		registry: registry,
 		alertMgr: alertMgr,
 		agents:   make(map[string]agent.Agent),
+		events:   eventbus.New(eventbus.EventTopic),
+		ctx:      context.Background(),
+
+		contextState: make(map[string]*contextState),
+		watch:        make(map[string]*watchState),
+		queue:        newSpawnQueue(),
+		restart:      make(map[string]*restartState),
+		files:        files,
+	}
+	m.streams = eventbus.New(m.streamTopic)
+
+	// Persistence and alerting must see every event, so they hold up
+	// event handling rather than miss any
+	if st != nil {
+		m.Subscribe(eventbus.Options{Name: "store", Policy: eventbus.Block}, m.saveSession)
+	}
+	if alertMgr != nil {
+		m.Subscribe(eventbus.Options{Name: "alerts", Policy: eventbus.Block}, func(event agent.Event) {
+			alertMgr.SendAgentEvent(m.ctx, event)
+		})
 	}
+	return m
+}
+
+// Subscribe calls fn for every agent event that passes the filter
+func (m *Manager) Subscribe(opts eventbus.Options, fn func(agent.Event)) *eventbus.Subscription[agent.Event] {
+	return m.events.Subscribe(opts, fn)
 }
 
-// OnEvent sets the callback for agent events
-func (m *Manager) OnEvent(fn func(agent.Event)) {
-	m.onEvent = fn
+// SubscriberStats returns the queue lengths and counters of event subscribers
+func (m *Manager) SubscriberStats() []eventbus.Stats {
+	return m.events.Stats()
 }
 
 // Start starts the session manager
 func (m *Manager) Start(ctx context.Context) error {
 	ctx, m.cancel = context.WithCancel(ctx)
+	m.ctx = ctx
 
 	// Initial discovery
 	t := time.Now()
@@ -60,19 +114,7 @@ func (m *Manager) Start(ctx context.Context) error {
 		m.agents[a.ID()] = a
 		// Persist to store
 		if m.store != nil {
-			m.store.SaveSession(&store.SessionRecord{
-				ID:           a.ID(),
-				AgentID:      a.ID(),
-				AgentType:    a.Type(),
-				AgentName:    a.Name(),
-				Directory:    a.Directory(),
-				ProjectID:    a.ProjectID(),
-				Status:       a.Status().String(),
-				StartTime:    a.StartTime(),
-				LastActivity: a.LastActivity(),
-				TokensIn:     a.Metrics().TokensIn,
-				TokensOut:    a.Metrics().TokensOut,
-			})
+			m.persist(a)
 		}
 		if (i+1)%50 == 0 {
 			log.Printf("[TIMING] Manager: Processed %d/%d agents so far...", i+1, len(agents))
@@ -90,15 +132,20 @@ func (m *Manager) Start(ctx context.Context) error {
 	log.Printf("[TIMING] Manager: WatchAll setup completed in %v", time.Since(t))
 
 	go m.processEvents(ctx, events)
+	go m.runWatchdog(ctx)
+	go m.runQueue(ctx)
+	go m.runFileWatch(ctx)
 
 	return nil
 }
 
-// Stop stops the session manager
+// Stop stops the session manager, after delivering pending events
 func (m *Manager) Stop() {
 	if m.cancel != nil {
 		m.cancel()
 	}
+	m.events.Close()
+	m.streams.Close()
 }
 
 // processEvents processes agent events
@@ -127,6 +174,10 @@ func (m *Manager) handleEvent(ctx context.Context, event agent.Event) {
 		}
 	case agent.EventAgentTerminated:
 		delete(m.agents, event.AgentID)
+		m.queue.forget(event.AgentID)
+		if r, ok := event.Data.(agent.Replacement); ok {
+			m.queue.replaced[event.AgentID] = r.AgentID
+		}
 	default:
 		if event.Agent != nil {
 			m.agents[event.AgentID] = event.Agent
@@ -134,33 +185,118 @@ func (m *Manager) handleEvent(ctx context.Context, event agent.Event) {
 	}
 	m.mu.Unlock()
 
-	// Update store
-	if m.store != nil && event.Agent != nil {
-		a := event.Agent
-		m.store.SaveSession(&store.SessionRecord{
-			ID:           a.ID(),
-			AgentID:      a.ID(),
-			AgentType:    a.Type(),
-			AgentName:    a.Name(),
-			Directory:    a.Directory(),
-			ProjectID:    a.ProjectID(),
-			Status:       a.Status().String(),
-			StartTime:    a.StartTime(),
-			LastActivity: a.LastActivity(),
-			TokensIn:     a.Metrics().TokensIn,
-			TokensOut:    a.Metrics().TokensOut,
-		})
+	// Store, alerts and the UI are subscribers
+	m.events.Publish(event)
+
+	if limitEvent, ok := m.checkContextLimit(event); ok {
+		m.handleEvent(ctx, limitEvent)
+	}
+
+	m.checkRestart(event)
+	m.recordAgentFiles(ctx, event)
+
+	// A status change may have freed a slot
+	m.pumpQueue()
+}
+
+// saveSession persists the agent of an event
+func (m *Manager) saveSession(event agent.Event) {
+	if event.Agent != nil {
+		m.persist(event.Agent)
+	}
+}
+
+// persist saves an agent's session record
+func (m *Manager) persist(a agent.Agent) {
+	m.store.SaveSession(&store.SessionRecord{
+		ID:           a.ID(),
+		AgentID:      a.ID(),
+		AgentType:    a.Type(),
+		AgentName:    a.Name(),
+		Directory:    a.Directory(),
+		ProjectID:    a.ProjectID(),
+		Status:       a.Status().String(),
+		StartTime:    a.StartTime(),
+		LastActivity: a.LastActivity(),
+		TokensIn:     a.Metrics().TokensIn,
+		TokensOut:    a.Metrics().TokensOut,
+	})
+}
+
+// checkContextLimit returns a context limit event when an agent's context
+// utilization crosses the warning threshold or it enters StatusContextLimit.
+// Each crossing fires once; utilization must drop back below the threshold
+// by contextWarningHysteresis before the warning can fire again.
+func (m *Manager) checkContextLimit(event agent.Event) (agent.Event, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.replay {
+		return agent.Event{}, false
+	}
+
+	if event.Type == agent.EventAgentTerminated {
+		delete(m.contextState, event.AgentID)
+		return agent.Event{}, false
+	}
+
+	a := event.Agent
+	if a == nil {
+		return agent.Event{}, false
+	}
+
+	st, ok := m.contextState[event.AgentID]
+	if !ok {
+		st = &contextState{}
+		m.contextState[event.AgentID] = st
+	}
+
+	limitEvent := agent.Event{
+		Type:      agent.EventAgentContextLimit,
+		AgentID:   event.AgentID,
+		Agent:     a,
+		Timestamp: time.Now(),
 	}
 
-	// Send alerts for important events
-	if m.alertMgr != nil {
-		m.alertMgr.SendAgentEvent(ctx, event)
+	limited := a.Status() == agent.StatusContextLimit
+	if event.Type == agent.EventAgentContextLimit {
+		// Already reported by the provider or by us
+		st.limited = st.limited || limited
+		st.warned = st.warned || !limited
+		return agent.Event{}, false
 	}
 
-	// Notify callback
-	if m.onEvent != nil {
-		m.onEvent(event)
+	if limited && !st.limited {
+		st.limited = true
+		return limitEvent, true
 	}
+	if !limited {
+		st.limited = false
+	}
+
+	threshold := float64(m.cfg.Alerts.ContextLimitWarning) / 100
+	if threshold <= 0 {
+		return agent.Event{}, false
+	}
+
+	utilization := a.Metrics().ContextUtilization
+	switch {
+	case !st.warned && utilization >= threshold:
+		st.warned = true
+		return limitEvent, true
+	case st.warned && utilization < threshold-contextWarningHysteresis:
+		st.warned = false
+	}
+
+	return agent.Event{}, false
+}
+
+// SetReplay tells the manager that its events are replayed from a
+// recording, which already holds the context limit events it raised live
+func (m *Manager) SetReplay() {
+	m.mu.Lock()
+	m.replay = true
+	m.mu.Unlock()
 }
 
 // List returns all agents
@@ -184,36 +320,48 @@ func (m *Manager) Get(id string) (agent.Agent, bool) {
 	return a, ok
 }
 
-// Spawn spawns a new agent session
-func (m *Manager) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
-	provider, ok := m.registry.Get(config.Type)
+// ProviderTypes returns the agent types that can be spawned
+func (m *Manager) ProviderTypes() []string {
+	return m.registry.Types()
+}
+
+// Terminate terminates an agent
+func (m *Manager) Terminate(id string) error {
+	m.mu.RLock()
+	a, ok := m.agents[id]
+	m.mu.RUnlock()
+
 	if !ok {
-		provider = m.registry.List()[0] // Use first available provider
+		return nil
 	}
 
-	a, err := provider.Spawn(ctx, config)
-	if err != nil {
-		return nil, err
-	}
+	return a.Terminate()
+}
 
-	m.mu.Lock()
-	m.agents[a.ID()] = a
-	m.mu.Unlock()
+// Pause pauses an agent
+func (m *Manager) Pause(id string) error {
+	m.mu.RLock()
+	a, ok := m.agents[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
 
-	return a, nil
+	return a.Pause()
 }
 
-// Terminate terminates an agent
-func (m *Manager) Terminate(id string) error {
+// Resume resumes a paused agent
+func (m *Manager) Resume(id string) error {
 	m.mu.RLock()
 	a, ok := m.agents[id]
 	m.mu.RUnlock()
 
 	if !ok {
-		return nil
+		return fmt.Errorf("agent not found: %s", id)
 	}
 
-	return a.Terminate()
+	return a.Resume()
 }
 
 // SendInput sends input to an agent
@@ -226,7 +374,11 @@ func (m *Manager) SendInput(id string, input string) error {
 		return nil
 	}
 
-	return a.SendInput(input)
+	if err := a.SendInput(input); err != nil {
+		return err
+	}
+	m.RecordInput(id, input)
+	return nil
 }
 
 // Stats returns aggregate statistics
@@ -235,10 +387,11 @@ func (m *Manager) Stats() *Stats {
 	defer m.mu.RUnlock()
 
 	stats := &Stats{
-		Total:     len(m.agents),
-		ByStatus:  make(map[agent.Status]int),
-		ByType:    make(map[string]int),
-		ByProject: make(map[string]int),
+		Total:       len(m.agents),
+		ByStatus:    make(map[agent.Status]int),
+		ByType:      make(map[string]int),
+		ByProject:   make(map[string]int),
+		CostByModel: make(map[string]float64),
 	}
 
 	for _, a := range m.agents {
@@ -250,6 +403,13 @@ func (m *Manager) Stats() *Stats {
 		stats.TotalTokensIn += metrics.TokensIn
 		stats.TotalTokensOut += metrics.TokensOut
 		stats.TotalCost += metrics.EstimatedCost
+		if metrics.Model != "" || metrics.EstimatedCost > 0 {
+			model := metrics.Model
+			if model == "" {
+				model = "unknown"
+			}
+			stats.CostByModel[model] += metrics.EstimatedCost
+		}
 		stats.TotalToolCalls += metrics.ToolCalls
 		stats.TotalErrors += metrics.ErrorCount
 	}
@@ -268,6 +428,7 @@ type Stats struct {
 	TotalCost      float64
 	TotalToolCalls int
 	TotalErrors    int
+	CostByModel    map[string]float64
 }
 
 // GroupMode represents how agents are grouped
@@ -414,10 +575,11 @@ func (m *Manager) Refresh(ctx context.Context) error {
 		seen[a.ID()] = true
 	}
 
-	// Remove agents that no longer exist
-	for id := range m.agents {
-		if !seen[id] {
+	// Remove agents that no longer exist, keeping queued spawns
+	for id, a := range m.agents {
+		if _, queued := a.(*queuedAgent); !seen[id] && !queued {
 			delete(m.agents, id)
+			m.queue.forget(id)
 		}
 	}
 
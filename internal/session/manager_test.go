@@ -8,6 +8,7 @@ import (
 	"github.com/CastAIPhil/AUTO/internal/agent"
 	"github.com/CastAIPhil/AUTO/internal/alert"
 	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
 )
 
 func TestNewManager(t *testing.T) {
@@ -75,6 +76,8 @@ func TestManagerStats(t *testing.T) {
 	agent1.MockProjectID = "proj-1"
 	agent1.MockMetrics.TokensIn = 1000
 	agent1.MockMetrics.TokensOut = 500
+	agent1.MockMetrics.Model = "claude-sonnet-4"
+	agent1.MockMetrics.EstimatedCost = 0.5
 
 	agent2 := agent.NewMockAgent("agent-2", "Agent 2")
 	agent2.MockStatus = agent.StatusIdle
@@ -82,6 +85,8 @@ func TestManagerStats(t *testing.T) {
 	agent2.MockProjectID = "proj-1"
 	agent2.MockMetrics.TokensIn = 2000
 	agent2.MockMetrics.TokensOut = 1000
+	agent2.MockMetrics.Model = "claude-sonnet-4"
+	agent2.MockMetrics.EstimatedCost = 0.25
 
 	agent3 := agent.NewMockAgent("agent-3", "Agent 3")
 	agent3.MockStatus = agent.StatusErrored
@@ -116,6 +121,12 @@ func TestManagerStats(t *testing.T) {
 	if stats.TotalTokensIn != 3500 {
 		t.Errorf("Stats().TotalTokensIn = %d, want 3500", stats.TotalTokensIn)
 	}
+	if stats.CostByModel["claude-sonnet-4"] != 0.75 {
+		t.Errorf("Stats().CostByModel[claude-sonnet-4] = %f, want 0.75", stats.CostByModel["claude-sonnet-4"])
+	}
+	if stats.CostByModel["unknown"] != 0.01 {
+		t.Errorf("Stats().CostByModel[unknown] = %f, want 0.01", stats.CostByModel["unknown"])
+	}
 }
 
 func TestManagerGroupBy(t *testing.T) {
@@ -358,33 +369,155 @@ func TestManagerTerminate(t *testing.T) {
 	}
 }
 
-func TestManagerOnEvent(t *testing.T) {
+func TestManagerPauseResume(t *testing.T) {
 	cfg := &config.Config{}
 	registry := agent.NewRegistry()
 	m := NewManager(cfg, nil, registry, nil)
 
-	var received agent.Event
-	m.OnEvent(func(e agent.Event) {
-		received = e
-	})
+	mockAgent := agent.NewMockAgent("agent-1", "Agent 1")
+	m.AddAgentForTesting(mockAgent)
 
-	if m.onEvent == nil {
-		t.Error("OnEvent() did not set callback")
+	if err := m.Pause("agent-1"); err != nil {
+		t.Errorf("Pause() error = %v", err)
+	}
+	if mockAgent.Status() != agent.StatusIdle {
+		t.Errorf("Pause() status = %v, want idle", mockAgent.Status())
 	}
 
+	if err := m.Resume("agent-1"); err != nil {
+		t.Errorf("Resume() error = %v", err)
+	}
+	if mockAgent.Status() != agent.StatusRunning {
+		t.Errorf("Resume() status = %v, want running", mockAgent.Status())
+	}
+
+	if err := m.Pause("non-existent"); err == nil {
+		t.Error("Pause() should error for non-existent agent")
+	}
+}
+
+func TestManagerSpawnWithoutProviders(t *testing.T) {
+	m := NewManager(&config.Config{}, nil, agent.NewRegistry(), nil)
+
+	if _, err := m.Spawn(context.Background(), agent.SpawnConfig{Name: "x"}); err == nil {
+		t.Error("Spawn() should error when no providers are registered")
+	}
+}
+
+func TestManagerSubscribe(t *testing.T) {
+	cfg := &config.Config{}
+	registry := agent.NewRegistry()
+	m := NewManager(cfg, nil, registry, nil)
+
+	var received []agent.Event
+	m.Subscribe(eventbus.Options{Name: "all"}, func(e agent.Event) {
+		received = append(received, e)
+	})
+	var completed []agent.Event
+	m.Subscribe(eventbus.Options{
+		Name:   "completed",
+		Filter: eventbus.Filter{Types: []string{agent.EventAgentCompleted.String()}},
+	}, func(e agent.Event) {
+		completed = append(completed, e)
+	})
+
 	// Simulate event handling
 	mockAgent := agent.NewMockAgent("agent-1", "Agent 1")
-	event := agent.Event{
-		Type:      agent.EventAgentUpdated,
-		AgentID:   "agent-1",
-		Agent:     mockAgent,
-		Timestamp: time.Now(),
+	for _, eventType := range []agent.EventType{agent.EventAgentUpdated, agent.EventAgentCompleted} {
+		m.handleEvent(context.Background(), agent.Event{
+			Type:      eventType,
+			AgentID:   "agent-1",
+			Agent:     mockAgent,
+			Timestamp: time.Now(),
+		})
+	}
+	m.events.Flush()
+
+	if len(received) != 2 || received[0].Type != agent.EventAgentUpdated {
+		t.Errorf("subscriber received %v, want both events in order", received)
+	}
+	if len(completed) != 1 || completed[0].Type != agent.EventAgentCompleted {
+		t.Errorf("filtered subscriber received %v, want the completed event", completed)
+	}
+	if stats := m.SubscriberStats(); len(stats) != 2 || stats[0].Delivered != 2 || stats[1].Delivered != 1 {
+		t.Errorf("SubscriberStats() = %+v", stats)
+	}
+}
+
+func TestManagerContextLimit(t *testing.T) {
+	cfg := &config.Config{Alerts: config.AlertsConfig{ContextLimitWarning: 80}}
+	registry := agent.NewRegistry()
+	alertMgr := alert.NewManager(&config.AlertsConfig{}, nil)
+	m := NewManager(cfg, nil, registry, alertMgr)
+
+	var limitEvents int
+	m.Subscribe(eventbus.Options{Name: "test"}, func(e agent.Event) {
+		if e.Type == agent.EventAgentContextLimit {
+			limitEvents++
+		}
+	})
+
+	mockAgent := agent.NewMockAgent("agent-1", "Agent 1")
+	update := func(status agent.Status, utilization float64) {
+		mockAgent.MockStatus = status
+		mockAgent.MockMetrics.ContextUtilization = utilization
+		m.handleEvent(context.Background(), agent.Event{
+			Type:    agent.EventAgentUpdated,
+			AgentID: mockAgent.ID(),
+			Agent:   mockAgent,
+		})
+		m.events.Flush()
+	}
+
+	steps := []struct {
+		status      agent.Status
+		utilization float64
+		wantTotal   int
+	}{
+		{agent.StatusRunning, 0.5, 0},
+		{agent.StatusRunning, 0.85, 1}, // crosses the threshold
+		{agent.StatusRunning, 0.9, 1},
+		{agent.StatusRunning, 0.75, 1}, // within hysteresis, still armed
+		{agent.StatusRunning, 0.85, 1},
+		{agent.StatusRunning, 0.6, 1}, // re-arms
+		{agent.StatusRunning, 0.82, 2},
+		{agent.StatusContextLimit, 0.82, 3}, // provider reported overflow
+		{agent.StatusContextLimit, 0.82, 3},
+		{agent.StatusRunning, 0.3, 3},
+		{agent.StatusContextLimit, 0.3, 4},
+	}
+
+	for i, step := range steps {
+		update(step.status, step.utilization)
+		if limitEvents != step.wantTotal {
+			t.Fatalf("step %d: context limit events = %d, want %d", i, limitEvents, step.wantTotal)
+		}
 	}
 
-	m.handleEvent(context.Background(), event)
+	if alerts := alertMgr.List(0, false); len(alerts) != 4 {
+		t.Errorf("alerts = %d, want 4", len(alerts))
+	}
 
-	if received.AgentID != "agent-1" {
-		t.Error("OnEvent callback was not called")
+	// A context limit event from the provider itself is not repeated
+	update(agent.StatusRunning, 0.1)
+	mockAgent.MockStatus = agent.StatusContextLimit
+	m.handleEvent(context.Background(), agent.Event{
+		Type:    agent.EventAgentContextLimit,
+		AgentID: mockAgent.ID(),
+		Agent:   mockAgent,
+	})
+	m.events.Flush()
+	update(agent.StatusContextLimit, 0.1)
+	if limitEvents != 5 {
+		t.Errorf("context limit events = %d, want 5", limitEvents)
+	}
+
+	// A replay holds the events raised live, so none are raised again
+	m.SetReplay()
+	update(agent.StatusRunning, 0.1)
+	update(agent.StatusContextLimit, 0.95)
+	if limitEvents != 5 {
+		t.Errorf("context limit events in a replay = %d, want 5", limitEvents)
 	}
 }
 
@@ -0,0 +1,481 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/worktree"
+)
+
+// ErrUnknownAgentType is returned when no provider serves a spawn's type
+var ErrUnknownAgentType = errors.New("unknown agent type")
+
+// queueInterval is how often the queue rechecks for free slots, since not
+// every status change produces an event
+const queueInterval = 2 * time.Second
+
+// spawnQueue tracks spawns against the concurrency limits. It is guarded by Manager.mu.
+type spawnQueue struct {
+	waiting   []*queuedAgent    // in arrival order
+	spawned   map[string]string // agent ID -> project, for agents started by Spawn
+	starting  map[string]int    // project -> spawns being started
+	lastStart map[string]uint64 // project -> startSeq of its latest start
+	replaced  map[string]string // placeholder ID -> ID of the agent that replaced it
+	queueSeq  uint64
+	startSeq  uint64
+}
+
+func newSpawnQueue() spawnQueue {
+	return spawnQueue{
+		spawned:   make(map[string]string),
+		starting:  make(map[string]int),
+		lastStart: make(map[string]uint64),
+		replaced:  make(map[string]string),
+	}
+}
+
+// forget drops the replacements that lead to an agent that is gone, so
+// Resolve no longer follows them
+func (q *spawnQueue) forget(id string) {
+	for from, to := range q.replaced {
+		if to == id {
+			delete(q.replaced, from)
+			q.forget(from)
+		}
+	}
+}
+
+// projectKey is the project a spawn counts against
+func projectKey(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Clean(dir)
+}
+
+// holdsSlot reports whether an agent in this status counts against the limits
+func holdsSlot(status agent.Status) bool {
+	return status == agent.StatusPending || status == agent.StatusRunning
+}
+
+// Spawn starts a new agent, or queues it when the concurrency limits are
+// reached. A queued spawn is returned as a pending placeholder agent that
+// is replaced by the real agent once it starts.
+func (m *Manager) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	provider, ok := m.registry.Get(config.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAgentType, config.Type)
+	}
+
+	q := &queuedAgent{
+		manager:  m,
+		provider: provider,
+		ctx:      ctx,
+		config:   config,
+		project:  projectKey(config.Directory),
+		queuedAt: time.Now(),
+		status:   agent.StatusPending,
+	}
+
+	m.mu.Lock()
+	m.queue.queueSeq++
+	q.seq = m.queue.queueSeq
+	q.id = fmt.Sprintf("queued-%d", q.seq)
+	m.queue.waiting = append(m.queue.waiting, q)
+
+	// Older or higher priority spawns may go first
+	var others []*queuedAgent
+	self := false
+	for next := m.nextQueuedLocked(); next != nil; next = m.nextQueuedLocked() {
+		if next == q {
+			self = true
+		} else {
+			others = append(others, next)
+		}
+	}
+	if !self {
+		m.agents[q.id] = q
+	}
+	m.mu.Unlock()
+
+	for _, other := range others {
+		go m.startQueued(other)
+	}
+
+	if self {
+		a, err := m.launch(q)
+		m.pumpQueue()
+		return a, err
+	}
+
+	m.handleEvent(ctx, agent.Event{Type: agent.EventAgentDiscovered, AgentID: q.id, Agent: q, Timestamp: q.queuedAt})
+	return q, nil
+}
+
+// Queued returns the spawns waiting for a free slot, in the order they would start
+func (m *Manager) Queued() []agent.Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	waiting := append([]*queuedAgent(nil), m.queue.waiting...)
+	sort.SliceStable(waiting, func(i, j int) bool {
+		return m.beforeLocked(waiting[i], waiting[j])
+	})
+
+	agents := make([]agent.Agent, len(waiting))
+	for i, q := range waiting {
+		agents[i] = q
+	}
+	return agents
+}
+
+// nextQueuedLocked removes and returns the waiting spawn that should start
+// now, or nil if none fits within the limits
+func (m *Manager) nextQueuedLocked() *queuedAgent {
+	if len(m.queue.waiting) == 0 {
+		return nil
+	}
+
+	limits := m.cfg.Queue
+	total, byProject := m.slotsLocked()
+	if limits.MaxConcurrent > 0 && total >= limits.MaxConcurrent {
+		return nil
+	}
+
+	best := -1
+	for i, q := range m.queue.waiting {
+		if limits.MaxPerProject > 0 && byProject[q.project] >= limits.MaxPerProject {
+			continue
+		}
+		if best < 0 || m.beforeLocked(q, m.queue.waiting[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	q := m.queue.waiting[best]
+	m.queue.waiting = append(m.queue.waiting[:best], m.queue.waiting[best+1:]...)
+	m.queue.starting[q.project]++
+	m.queue.startSeq++
+	m.queue.lastStart[q.project] = m.queue.startSeq
+	return q
+}
+
+// beforeLocked orders waiting spawns: by priority, then the project that
+// started least recently, then arrival
+func (m *Manager) beforeLocked(a, b *queuedAgent) bool {
+	if a.config.Priority != b.config.Priority {
+		return a.config.Priority > b.config.Priority
+	}
+	if la, lb := m.queue.lastStart[a.project], m.queue.lastStart[b.project]; la != lb {
+		return la < lb
+	}
+	return a.seq < b.seq
+}
+
+// slotsLocked counts the slots in use, in total and by project
+func (m *Manager) slotsLocked() (int, map[string]int) {
+	total := 0
+	byProject := make(map[string]int)
+	for project, n := range m.queue.starting {
+		total += n
+		byProject[project] += n
+	}
+	for id, project := range m.queue.spawned {
+		a, ok := m.agents[id]
+		if !ok {
+			delete(m.queue.spawned, id)
+			continue
+		}
+		if holdsSlot(a.Status()) {
+			total++
+			byProject[project]++
+		}
+	}
+	return total, byProject
+}
+
+// launch spawns a spawn that was given a slot
+func (m *Manager) launch(q *queuedAgent) (agent.Agent, error) {
+	config := q.config
+	var wt *worktree.Worktree
+	if config.Worktree {
+		var err error
+		wt, config.Directory, err = worktree.Create(config.Directory, m.worktreeDir(), config.Name)
+		if err != nil {
+			m.mu.Lock()
+			m.releaseStartingLocked(q.project)
+			m.mu.Unlock()
+			return nil, err
+		}
+		// The provider runs the agent in the worktree like any other directory
+		config.Worktree = false
+	}
+
+	a, err := q.provider.Spawn(q.ctx, config)
+	if err != nil && wt != nil {
+		if rmErr := wt.Remove(); rmErr != nil {
+			log.Printf("Failed to remove worktree %s: %v", wt.Path, rmErr)
+		}
+	}
+
+	m.mu.Lock()
+	m.releaseStartingLocked(q.project)
+	if err == nil {
+		m.agents[a.ID()] = a
+		m.queue.spawned[a.ID()] = q.project
+		if q.config.Prompt != "" {
+			m.restartStateLocked(a.ID()).prompt = q.config.Prompt
+		}
+	}
+	m.mu.Unlock()
+
+	return a, err
+}
+
+// worktreeDir is where worktrees for isolated spawns are created
+func (m *Manager) worktreeDir() string {
+	if m.cfg.Worktree.Dir != "" {
+		return m.cfg.Worktree.Dir
+	}
+	return config.DefaultConfig().Worktree.Dir
+}
+
+func (m *Manager) releaseStartingLocked(project string) {
+	m.queue.starting[project]--
+	if m.queue.starting[project] <= 0 {
+		delete(m.queue.starting, project)
+	}
+}
+
+// startQueued starts a spawn that waited in the queue and replaces its placeholder
+func (m *Manager) startQueued(q *queuedAgent) {
+	if err := q.ctx.Err(); err != nil {
+		m.mu.Lock()
+		m.releaseStartingLocked(q.project)
+		m.mu.Unlock()
+		m.removeQueued(q, agent.StatusCancelled, "")
+		return
+	}
+
+	a, err := m.launch(q)
+	if err != nil {
+		log.Printf("Queued spawn %s failed: %v", q.id, err)
+		// Keep the placeholder as errored so the failure is visible until dismissed
+		q.fail(err)
+		m.handleEvent(q.ctx, agent.Event{Type: agent.EventAgentErrored, AgentID: q.id, Agent: q, Error: err, Timestamp: time.Now()})
+		m.pumpQueue()
+		return
+	}
+
+	m.handleEvent(q.ctx, agent.Event{Type: agent.EventAgentDiscovered, AgentID: a.ID(), Agent: a, Timestamp: time.Now()})
+	m.removeQueued(q, agent.StatusCompleted, a.ID())
+	m.pumpQueue()
+}
+
+// removeQueued drops a placeholder from the agent list, noting the agent
+// that replaced it if it started
+func (m *Manager) removeQueued(q *queuedAgent, status agent.Status, replacement string) {
+	q.mu.Lock()
+	q.status = status
+	q.mu.Unlock()
+
+	m.mu.Lock()
+	if m.agents[q.id] == agent.Agent(q) {
+		delete(m.agents, q.id)
+	}
+	m.mu.Unlock()
+
+	event := agent.Event{Type: agent.EventAgentTerminated, AgentID: q.id, Timestamp: time.Now()}
+	if replacement != "" {
+		event.Data = agent.Replacement{AgentID: replacement}
+	}
+	m.handleEvent(q.ctx, event)
+}
+
+// cancelQueued removes a waiting or failed spawn
+func (m *Manager) cancelQueued(q *queuedAgent) error {
+	m.mu.Lock()
+	found := false
+	for i, w := range m.queue.waiting {
+		if w == q {
+			m.queue.waiting = append(m.queue.waiting[:i], m.queue.waiting[i+1:]...)
+			found = true
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if !found && q.Status() != agent.StatusErrored {
+		return fmt.Errorf("spawn %s has already started", q.id)
+	}
+	m.removeQueued(q, agent.StatusCancelled, "")
+	return nil
+}
+
+// Resolve returns an agent by ID, following queued spawns to the agent
+// that replaced them once they started
+func (m *Manager) Resolve(id string) (agent.Agent, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := 0; i < len(m.queue.replaced); i++ {
+		next, ok := m.queue.replaced[id]
+		if !ok {
+			break
+		}
+		id = next
+	}
+	a, ok := m.agents[id]
+	return a, ok
+}
+
+// pumpQueue starts waiting spawns that now fit within the limits
+func (m *Manager) pumpQueue() {
+	m.mu.Lock()
+	var ready []*queuedAgent
+	for next := m.nextQueuedLocked(); next != nil; next = m.nextQueuedLocked() {
+		ready = append(ready, next)
+	}
+	m.mu.Unlock()
+
+	for _, q := range ready {
+		go m.startQueued(q)
+	}
+}
+
+// runQueue periodically rechecks the queue
+func (m *Manager) runQueue(ctx context.Context) {
+	ticker := time.NewTicker(queueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.pumpQueue()
+		}
+	}
+}
+
+// queuedAgent stands in for a spawn waiting in the queue, so it is listed
+// as pending and can be cancelled by terminating it
+type queuedAgent struct {
+	manager  *Manager
+	provider agent.Provider
+	ctx      context.Context
+	config   agent.SpawnConfig
+	id       string
+	project  string
+	seq      uint64
+	queuedAt time.Time
+
+	mu     sync.RWMutex
+	status agent.Status
+	err    error
+}
+
+func (q *queuedAgent) fail(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.status = agent.StatusErrored
+	q.err = err
+}
+
+// ID returns the placeholder ID
+func (q *queuedAgent) ID() string { return q.id }
+
+// Name returns the requested name
+func (q *queuedAgent) Name() string {
+	if q.config.Name != "" {
+		return q.config.Name
+	}
+	return "queued " + q.config.Type
+}
+
+// Type returns the requested agent type
+func (q *queuedAgent) Type() string { return q.config.Type }
+
+// Directory returns the requested working directory
+func (q *queuedAgent) Directory() string { return q.config.Directory }
+
+// ProjectID returns the project the spawn counts against
+func (q *queuedAgent) ProjectID() string { return q.project }
+
+// ParentID returns an empty string; queued spawns have no parent
+func (q *queuedAgent) ParentID() string { return "" }
+
+// IsBackground returns false
+func (q *queuedAgent) IsBackground() bool { return false }
+
+// Status returns StatusPending while waiting
+func (q *queuedAgent) Status() agent.Status {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.status
+}
+
+// StartTime returns when the spawn was queued
+func (q *queuedAgent) StartTime() time.Time { return q.queuedAt }
+
+// LastActivity returns when the spawn was queued
+func (q *queuedAgent) LastActivity() time.Time { return q.queuedAt }
+
+// Output describes the queued spawn
+func (q *queuedAgent) Output() io.Reader {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Queued at %s with %s priority, waiting for a free slot\n", q.queuedAt.Format("15:04:05"), q.config.Priority)
+	if q.config.Prompt != "" {
+		fmt.Fprintf(&b, "\nPrompt: %s\n", q.config.Prompt)
+	}
+	if err := q.LastError(); err != nil {
+		fmt.Fprintf(&b, "\nSpawn failed: %v\n", err)
+	}
+	return strings.NewReader(b.String())
+}
+
+// CurrentTask returns the spawn prompt
+func (q *queuedAgent) CurrentTask() string { return q.config.Prompt }
+
+// Metrics returns empty metrics
+func (q *queuedAgent) Metrics() agent.Metrics { return agent.Metrics{} }
+
+// LastError returns why the spawn failed, if it did
+func (q *queuedAgent) LastError() error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.err
+}
+
+// SendInput fails; input can only be sent once the agent has started
+func (q *queuedAgent) SendInput(input string) error {
+	return fmt.Errorf("agent %s is queued and has not started", q.id)
+}
+
+// Terminate cancels the queued spawn
+func (q *queuedAgent) Terminate() error {
+	return q.manager.cancelQueued(q)
+}
+
+// Pause is not supported for queued spawns
+func (q *queuedAgent) Pause() error {
+	return fmt.Errorf("pause %w for queued agents", agent.ErrUnsupported)
+}
+
+// Resume is not supported for queued spawns
+func (q *queuedAgent) Resume() error {
+	return fmt.Errorf("resume %w for queued agents", agent.ErrUnsupported)
+}
@@ -0,0 +1,379 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+	"github.com/CastAIPhil/AUTO/internal/worktree"
+)
+
+// slotAgent is a mock agent whose status can change while the queue reads it
+type slotAgent struct {
+	*agent.MockAgent
+	mu     sync.Mutex
+	status agent.Status
+}
+
+func (a *slotAgent) Status() agent.Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.status
+}
+
+func (a *slotAgent) finish() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.status = agent.StatusCompleted
+}
+
+// queueProvider spawns running agents and records the order they started in
+type queueProvider struct {
+	*agent.MockProvider
+	mu      sync.Mutex
+	started []*slotAgent
+	fail    bool
+}
+
+func (p *queueProvider) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return nil, errors.New("spawn failed")
+	}
+	a := &slotAgent{MockAgent: agent.NewMockAgent(config.Name+"-id", config.Name), status: agent.StatusRunning}
+	a.MockDirectory = config.Directory
+	p.started = append(p.started, a)
+	return a, nil
+}
+
+func (p *queueProvider) names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	for _, a := range p.started {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+func (p *queueProvider) last() *slotAgent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.started[len(p.started)-1]
+}
+
+// waitingLocked reports whether the placeholder is still in the queue
+func (q *queuedAgent) waitingLocked() bool {
+	for _, w := range q.manager.queue.waiting {
+		if w == q {
+			return true
+		}
+	}
+	return false
+}
+
+func newQueueManager(limits config.QueueConfig) (*Manager, *queueProvider) {
+	provider := &queueProvider{MockProvider: agent.NewMockProvider()}
+	registry := agent.NewRegistry()
+	registry.Register(provider)
+	return NewManager(&config.Config{Queue: limits}, nil, registry, nil), provider
+}
+
+func spawn(t *testing.T, m *Manager, name, dir string, priority agent.Priority) agent.Agent {
+	t.Helper()
+	a, err := m.Spawn(context.Background(), agent.SpawnConfig{Type: "mock", Name: name, Directory: dir, Priority: priority})
+	if err != nil {
+		t.Fatalf("Spawn(%s) error = %v", name, err)
+	}
+	return a
+}
+
+// waitStarted waits for the queue to have started n agents in the background
+// and replaced their placeholders
+func waitStarted(t *testing.T, m *Manager, p *queueProvider, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.RLock()
+		busy := len(m.queue.starting) > 0
+		for _, a := range m.agents {
+			if q, ok := a.(*queuedAgent); ok && !q.waitingLocked() {
+				busy = true
+			}
+		}
+		m.mu.RUnlock()
+		if len(p.names()) >= n && !busy {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("started %v, want %d agents", p.names(), n)
+}
+
+func TestSpawnUnknownType(t *testing.T) {
+	m, _ := newQueueManager(config.QueueConfig{})
+
+	_, err := m.Spawn(context.Background(), agent.SpawnConfig{Type: "other", Name: "x"})
+	if !errors.Is(err, ErrUnknownAgentType) {
+		t.Errorf("Spawn() error = %v, want ErrUnknownAgentType", err)
+	}
+}
+
+func TestSpawnQueueConcurrencyLimit(t *testing.T) {
+	m, p := newQueueManager(config.QueueConfig{MaxConcurrent: 1})
+
+	first := spawn(t, m, "first", "/work/a", agent.PriorityNormal)
+	if first.ID() != "first-id" {
+		t.Fatalf("first spawn = %s, want it to start immediately", first.ID())
+	}
+
+	queued := spawn(t, m, "second", "/work/a", agent.PriorityNormal)
+	if queued.Status() != agent.StatusPending {
+		t.Fatalf("second spawn status = %v, want pending", queued.Status())
+	}
+	if _, ok := m.Get(queued.ID()); !ok {
+		t.Error("queued spawn should be listed")
+	}
+	if got := m.Queued(); len(got) != 1 || got[0].ID() != queued.ID() {
+		t.Errorf("Queued() = %v", got)
+	}
+
+	// The slot is still held, so nothing starts
+	m.pumpQueue()
+	if names := p.names(); len(names) != 1 {
+		t.Fatalf("started %v while the slot was held", names)
+	}
+
+	p.last().finish()
+	m.pumpQueue()
+	waitStarted(t, m, p, 2)
+
+	if _, ok := m.Get(queued.ID()); ok {
+		t.Error("placeholder should be replaced once the spawn starts")
+	}
+	if _, ok := m.Get("second-id"); !ok {
+		t.Error("started spawn should be tracked")
+	}
+	if a, ok := m.Resolve(queued.ID()); !ok || a.ID() != "second-id" {
+		t.Errorf("Resolve(%s) = %v, want the started agent", queued.ID(), a)
+	}
+	if len(m.Queued()) != 0 {
+		t.Error("queue should be empty")
+	}
+
+	// Once the agent is gone, so is the way to it
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, ok := m.Resolve(queued.ID()); ok {
+		t.Error("Resolve() found an agent that is gone")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.queue.replaced) != 0 {
+		t.Errorf("replaced = %v, want it pruned", m.queue.replaced)
+	}
+}
+
+func TestSpawnQueueForgetsTerminated(t *testing.T) {
+	m, p := newQueueManager(config.QueueConfig{MaxConcurrent: 1})
+
+	spawn(t, m, "first", "/work/a", agent.PriorityNormal)
+	queued := spawn(t, m, "second", "/work/a", agent.PriorityNormal)
+	p.last().finish()
+	m.pumpQueue()
+	waitStarted(t, m, p, 2)
+
+	m.handleEvent(context.Background(), agent.Event{Type: agent.EventAgentTerminated, AgentID: "second-id", Timestamp: time.Now()})
+	if _, ok := m.Resolve(queued.ID()); ok {
+		t.Error("Resolve() found a terminated agent")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.queue.replaced) != 0 {
+		t.Errorf("replaced = %v, want it pruned", m.queue.replaced)
+	}
+}
+
+func TestSpawnQueueOrder(t *testing.T) {
+	m, p := newQueueManager(config.QueueConfig{MaxConcurrent: 1})
+
+	spawn(t, m, "a1", "/work/a", agent.PriorityNormal)
+	spawn(t, m, "a2", "/work/a", agent.PriorityNormal)
+	spawn(t, m, "a3", "/work/a", agent.PriorityNormal)
+	spawn(t, m, "b1", "/work/b", agent.PriorityNormal)
+	spawn(t, m, "low", "/work/c", agent.PriorityLow)
+	spawn(t, m, "urgent", "/work/d", agent.PriorityHigh)
+
+	var queued []string
+	for _, a := range m.Queued() {
+		queued = append(queued, a.Name())
+	}
+	want := []string{"urgent", "b1", "a2", "a3", "low"}
+	if len(queued) != len(want) {
+		t.Fatalf("Queued() = %v, want %v", queued, want)
+	}
+	for i := range want {
+		if queued[i] != want[i] {
+			t.Fatalf("Queued() = %v, want %v", queued, want)
+		}
+	}
+
+	for i := 1; i <= len(want); i++ {
+		p.last().finish()
+		m.pumpQueue()
+		waitStarted(t, m, p, i+1)
+	}
+
+	started := p.names()
+	for i, name := range append([]string{"a1"}, want...) {
+		if started[i] != name {
+			t.Fatalf("started %v, want a1 then %v", started, want)
+		}
+	}
+}
+
+func TestSpawnQueuePerProjectLimit(t *testing.T) {
+	m, p := newQueueManager(config.QueueConfig{MaxPerProject: 1})
+
+	spawn(t, m, "a1", "/work/a", agent.PriorityNormal)
+	if queued := spawn(t, m, "a2", "/work/a/", agent.PriorityNormal); queued.Status() != agent.StatusPending {
+		t.Errorf("second spawn in a project status = %v, want pending", queued.Status())
+	}
+	if b := spawn(t, m, "b1", "/work/b", agent.PriorityNormal); b.ID() != "b1-id" {
+		t.Errorf("spawn in another project = %s, want it to start immediately", b.ID())
+	}
+
+	p.started[0].finish()
+	m.pumpQueue()
+	waitStarted(t, m, p, 3)
+}
+
+func TestCancelQueuedSpawn(t *testing.T) {
+	m, p := newQueueManager(config.QueueConfig{MaxConcurrent: 1})
+
+	var mu sync.Mutex
+	var terminated []string
+	m.Subscribe(eventbus.Options{Name: "test"}, func(e agent.Event) {
+		if e.Type == agent.EventAgentTerminated {
+			mu.Lock()
+			terminated = append(terminated, e.AgentID)
+			mu.Unlock()
+		}
+	})
+
+	spawn(t, m, "first", "/work/a", agent.PriorityNormal)
+	queued := spawn(t, m, "second", "/work/a", agent.PriorityNormal)
+
+	if err := m.Terminate(queued.ID()); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+	if _, ok := m.Get(queued.ID()); ok {
+		t.Error("cancelled spawn should be removed")
+	}
+	if len(m.Queued()) != 0 {
+		t.Error("cancelled spawn should leave the queue")
+	}
+	m.events.Flush()
+	mu.Lock()
+	if len(terminated) != 1 || terminated[0] != queued.ID() {
+		t.Errorf("terminated events = %v, want [%s]", terminated, queued.ID())
+	}
+	mu.Unlock()
+
+	p.last().finish()
+	m.pumpQueue()
+	time.Sleep(20 * time.Millisecond)
+	if names := p.names(); len(names) != 1 {
+		t.Errorf("cancelled spawn was started: %v", names)
+	}
+}
+
+func TestQueuedSpawnFailure(t *testing.T) {
+	m, p := newQueueManager(config.QueueConfig{MaxConcurrent: 1})
+
+	spawn(t, m, "first", "/work/a", agent.PriorityNormal)
+	queued := spawn(t, m, "second", "/work/a", agent.PriorityNormal)
+
+	p.mu.Lock()
+	p.fail = true
+	p.mu.Unlock()
+	p.last().finish()
+	m.pumpQueue()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for queued.Status() != agent.StatusErrored && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if queued.Status() != agent.StatusErrored || queued.LastError() == nil {
+		t.Fatalf("failed spawn status = %v, error = %v", queued.Status(), queued.LastError())
+	}
+	if _, ok := m.Get(queued.ID()); !ok {
+		t.Error("failed spawn should stay listed until dismissed")
+	}
+
+	if err := queued.Terminate(); err != nil {
+		t.Fatalf("Terminate() of failed spawn error = %v", err)
+	}
+	if _, ok := m.Get(queued.ID()); ok {
+		t.Error("dismissed spawn should be removed")
+	}
+}
+
+func TestSpawnInWorktree(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "--quiet"}, {"commit", "--quiet", "--allow-empty", "--message", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	m, p := newQueueManager(config.QueueConfig{MaxPerProject: 1})
+	m.cfg.Worktree.Dir = t.TempDir()
+
+	a, err := m.Spawn(context.Background(), agent.SpawnConfig{Type: "mock", Name: "fix", Directory: repo, Worktree: true})
+	if err != nil {
+		t.Fatalf("Spawn() error = %v", err)
+	}
+	if !strings.HasPrefix(a.Directory(), m.cfg.Worktree.Dir) {
+		t.Errorf("agent directory = %s, want a worktree under %s", a.Directory(), m.cfg.Worktree.Dir)
+	}
+	w, err := worktree.Open(a.Directory())
+	if err != nil {
+		t.Fatalf("worktree.Open() error = %v", err)
+	}
+
+	// The worktree counts against the project it was created from
+	if q := spawn(t, m, "next", repo, 0); q.Status() != agent.StatusPending {
+		t.Errorf("second spawn in %s should be queued, got %s", repo, q.Status())
+	}
+	w.Remove()
+
+	// A failed spawn leaves no worktree behind
+	m, p = newQueueManager(config.QueueConfig{})
+	m.cfg.Worktree.Dir = t.TempDir()
+	p.fail = true
+	if _, err := m.Spawn(context.Background(), agent.SpawnConfig{Type: "mock", Name: "broken", Directory: repo, Worktree: true}); err == nil {
+		t.Fatal("Spawn() should fail")
+	}
+	if branches, _ := exec.Command("git", "-C", repo, "branch", "--list", worktree.BranchPrefix+"broken-*").Output(); len(branches) != 0 {
+		t.Errorf("branch left behind: %s", branches)
+	}
+}
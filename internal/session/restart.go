@@ -0,0 +1,271 @@
+package session
+
+import (
+	"log"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/store"
+)
+
+// defaultRestartPrompt is sent when no prompt is configured and the
+// agent's last prompt is unknown
+const defaultRestartPrompt = "continue"
+
+// restartPolicy is the restart configuration resolved for one agent
+type restartPolicy struct {
+	policy      string
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	prompt      string
+}
+
+// restartState is what the manager remembers about an agent for restarts.
+// It is guarded by Manager.mu.
+type restartState struct {
+	attempts int         // restart prompts sent since the agent last recovered
+	restarts int         // restart prompts sent in all
+	prompt   string      // latest prompt sent to the agent through AUTO
+	timer    *time.Timer // pending attempt, nil if none
+}
+
+// restartPolicyFor resolves the restart policy for an agent. Overrides
+// apply from least to most specific, like the watchdog thresholds.
+func (m *Manager) restartPolicyFor(a agent.Agent) restartPolicy {
+	cfg := m.cfg.Restart
+	p := restartPolicy{
+		policy:      cfg.Policy,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		maxBackoff:  cfg.MaxBackoff,
+		prompt:      cfg.Prompt,
+	}
+
+	for rank := 0; rank <= 3; rank++ {
+		for _, o := range cfg.Overrides {
+			r := 0
+			if o.Type != "" {
+				if o.Type != a.Type() {
+					continue
+				}
+				r |= 1
+			}
+			if o.Project != "" {
+				if o.Project != a.ProjectID() && o.Project != a.Directory() {
+					continue
+				}
+				r |= 2
+			}
+			if r != rank {
+				continue
+			}
+
+			if o.Policy != "" {
+				p.policy = o.Policy
+			}
+			if o.MaxAttempts != 0 {
+				p.maxAttempts = o.MaxAttempts
+			}
+			if o.Backoff != 0 {
+				p.backoff = o.Backoff
+			}
+			if o.MaxBackoff != 0 {
+				p.maxBackoff = o.MaxBackoff
+			}
+			if o.Prompt != "" {
+				p.prompt = o.Prompt
+			}
+		}
+	}
+
+	return p
+}
+
+// restarts reports whether the policy restarts an agent in this status
+func (p restartPolicy) restarts(status agent.Status) bool {
+	switch p.policy {
+	case config.RestartOnError:
+		return status == agent.StatusErrored
+	case config.RestartAlways:
+		return status == agent.StatusErrored || status == agent.StatusCompleted
+	}
+	return false
+}
+
+// recovered reports whether an agent in this status got through a turn the
+// policy accepts, which gives it a fresh set of attempts
+func (p restartPolicy) recovered(status agent.Status) bool {
+	return (status == agent.StatusIdle || status == agent.StatusCompleted) && !p.restarts(status)
+}
+
+// delay is the backoff before the given attempt, counting from 1
+func (p restartPolicy) delay(attempt int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempt && (p.maxBackoff <= 0 || d < p.maxBackoff); i++ {
+		d *= 2
+	}
+	if p.maxBackoff > 0 && d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d
+}
+
+// restartStateLocked returns the restart state of an agent, creating it
+func (m *Manager) restartStateLocked(id string) *restartState {
+	st, ok := m.restart[id]
+	if !ok {
+		st = &restartState{}
+		m.restart[id] = st
+	}
+	return st
+}
+
+// RecordInput remembers input sent to an agent without going through
+// SendInput, such as streamed input, so a restart can send it again
+func (m *Manager) RecordInput(id, input string) {
+	if input == "" {
+		return
+	}
+	m.mu.Lock()
+	m.restartStateLocked(id).prompt = input
+	m.mu.Unlock()
+}
+
+// Restarts returns how many times an agent has been sent a restart prompt
+func (m *Manager) Restarts(id string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if st, ok := m.restart[id]; ok && st.restarts > 0 {
+		return st.restarts
+	}
+	if rc, ok := m.agents[id].(agent.RestartCounter); ok {
+		return rc.Restarts()
+	}
+	return 0
+}
+
+// checkRestart schedules a restart for an agent that errored or, with the
+// always policy, completed
+func (m *Manager) checkRestart(event agent.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if event.Type == agent.EventAgentTerminated {
+		if st, ok := m.restart[event.AgentID]; ok {
+			if st.timer != nil {
+				st.timer.Stop()
+			}
+			delete(m.restart, event.AgentID)
+		}
+		return
+	}
+
+	a, ok := m.agents[event.AgentID]
+	if !ok {
+		return
+	}
+	st, restarted := m.restart[event.AgentID]
+	restarted = restarted && st.attempts > 0
+	if !restarted && event.Type != agent.EventAgentErrored && event.Type != agent.EventAgentCompleted {
+		return
+	}
+	if _, queued := a.(*queuedAgent); queued {
+		return // a failed spawn has no session to restart
+	}
+
+	p := m.restartPolicyFor(a)
+	if restarted && p.recovered(a.Status()) {
+		st.attempts = 0
+	}
+	if event.Type == agent.EventAgentErrored || event.Type == agent.EventAgentCompleted {
+		m.scheduleRestartLocked(a, p)
+	}
+}
+
+// scheduleRestartLocked starts the backoff timer for the agent's next
+// restart, unless one is pending or it is out of attempts
+func (m *Manager) scheduleRestartLocked(a agent.Agent, p restartPolicy) {
+	if !p.restarts(a.Status()) {
+		return
+	}
+	st := m.restartStateLocked(a.ID())
+	if st.timer != nil {
+		return
+	}
+	if p.maxAttempts > 0 && st.attempts >= p.maxAttempts {
+		return
+	}
+
+	id := a.ID()
+	st.timer = time.AfterFunc(p.delay(st.attempts+1), func() { m.restartAgent(id) })
+}
+
+// restartAgent sends the restart prompt once the backoff has passed, if the
+// agent still needs it
+func (m *Manager) restartAgent(id string) {
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	m.mu.Lock()
+	st, ok := m.restart[id]
+	a, exists := m.agents[id]
+	if !ok || !exists {
+		m.mu.Unlock()
+		return
+	}
+	st.timer = nil
+
+	p := m.restartPolicyFor(a)
+	status := a.Status()
+	if !p.restarts(status) {
+		// Recovered on its own, or the policy changed
+		m.mu.Unlock()
+		return
+	}
+
+	st.attempts++
+	st.restarts++
+	attempt := st.attempts
+	prompt := p.prompt
+	if prompt == "" {
+		prompt = st.prompt
+	}
+	if prompt == "" {
+		prompt = defaultRestartPrompt
+	}
+	m.mu.Unlock()
+
+	log.Printf("Restarting agent %s (attempt %d) after it %s", id, attempt, status)
+	err := a.SendInput(prompt)
+
+	if m.store != nil {
+		rec := &store.RestartRecord{
+			AgentID:   id,
+			Attempt:   attempt,
+			Reason:    status.String(),
+			Prompt:    prompt,
+			Timestamp: time.Now(),
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		m.store.SaveRestart(rec)
+	}
+
+	if err != nil {
+		log.Printf("Failed to restart agent %s: %v", id, err)
+		// No new status event may follow a failed send, so retry from here
+		m.mu.Lock()
+		if _, ok := m.restart[id]; ok {
+			m.scheduleRestartLocked(a, p)
+		}
+		m.mu.Unlock()
+	}
+
+	// Let the UI pick up the new attempt count
+	m.handleEvent(m.ctx, agent.Event{Type: agent.EventAgentUpdated, AgentID: id, Agent: a, Timestamp: time.Now()})
+}
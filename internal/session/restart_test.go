@@ -0,0 +1,273 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/store"
+)
+
+// inputAgent is a mock agent that records the input it is sent. Input
+// puts it back to running unless sending fails.
+type inputAgent struct {
+	*agent.MockAgent
+	mu       sync.Mutex
+	status   agent.Status
+	inputs   []string
+	sendFail bool
+}
+
+func newInputAgent(id, agentType string, status agent.Status) *inputAgent {
+	a := &inputAgent{MockAgent: agent.NewMockAgent(id, id), status: status}
+	a.MockType = agentType
+	return a
+}
+
+func (a *inputAgent) Status() agent.Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.status
+}
+
+func (a *inputAgent) setStatus(status agent.Status) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.status = status
+}
+
+func (a *inputAgent) SendInput(input string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inputs = append(a.inputs, input)
+	if a.sendFail {
+		return errors.New("send failed")
+	}
+	a.status = agent.StatusRunning
+	return nil
+}
+
+func (a *inputAgent) received() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.inputs...)
+}
+
+func newRestartManager(t *testing.T, restart config.RestartConfig) (*Manager, *store.Store) {
+	t.Helper()
+	st, err := store.New(filepath.Join(t.TempDir(), "auto.db"))
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return NewManager(&config.Config{Restart: restart}, st, agent.NewRegistry(), nil), st
+}
+
+// fail puts an agent in status and reports it the way a provider would
+func fail(m *Manager, a *inputAgent, status agent.Status) {
+	a.setStatus(status)
+	eventType := agent.EventAgentErrored
+	if status == agent.StatusCompleted {
+		eventType = agent.EventAgentCompleted
+	}
+	m.handleEvent(m.ctx, agent.Event{Type: eventType, AgentID: a.ID(), Agent: a, Timestamp: time.Now()})
+}
+
+func waitInputs(t *testing.T, a *inputAgent, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := a.received()
+		if len(got) >= n {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d inputs, got %q", n, got)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestRestartOnError(t *testing.T) {
+	m, st := newRestartManager(t, config.RestartConfig{
+		Policy:      config.RestartOnError,
+		MaxAttempts: 2,
+		Backoff:     5 * time.Millisecond,
+	})
+	a := newInputAgent("a", "opencode", agent.StatusRunning)
+	m.AddAgentForTesting(a)
+
+	if err := m.SendInput("a", "fix the flaky test"); err != nil {
+		t.Fatalf("SendInput() error = %v", err)
+	}
+
+	fail(m, a, agent.StatusErrored)
+	if got := waitInputs(t, a, 2); got[1] != "fix the flaky test" {
+		t.Errorf("restart sent %q, want the last prompt", got[1])
+	}
+
+	fail(m, a, agent.StatusErrored)
+	waitInputs(t, a, 3)
+
+	// Out of attempts
+	fail(m, a, agent.StatusErrored)
+	time.Sleep(50 * time.Millisecond)
+	if got := a.received(); len(got) != 3 {
+		t.Errorf("inputs = %q, want no restart past max_attempts", got)
+	}
+	if got := m.Restarts("a"); got != 2 {
+		t.Errorf("Restarts() = %d, want 2", got)
+	}
+
+	records, err := st.ListRestarts("a")
+	if err != nil {
+		t.Fatalf("ListRestarts() error = %v", err)
+	}
+	if len(records) != 2 || records[1].Attempt != 2 || records[1].Reason != "errored" || records[1].Prompt != "fix the flaky test" {
+		t.Errorf("restart records = %+v", records)
+	}
+}
+
+func TestRestartAttemptsResetOnRecovery(t *testing.T) {
+	m, st := newRestartManager(t, config.RestartConfig{
+		Policy:      config.RestartOnError,
+		MaxAttempts: 1,
+		Backoff:     5 * time.Millisecond,
+	})
+	a := newInputAgent("a", "opencode", agent.StatusRunning)
+	m.AddAgentForTesting(a)
+
+	fail(m, a, agent.StatusErrored)
+	waitInputs(t, a, 1)
+
+	// The restart worked: the agent finished its turn
+	a.setStatus(agent.StatusIdle)
+	m.handleEvent(m.ctx, agent.Event{Type: agent.EventAgentUpdated, AgentID: a.ID(), Agent: a, Timestamp: time.Now()})
+
+	fail(m, a, agent.StatusErrored)
+	waitInputs(t, a, 2)
+
+	// Errored again without recovering, so out of attempts
+	fail(m, a, agent.StatusErrored)
+	time.Sleep(50 * time.Millisecond)
+	if got := a.received(); len(got) != 2 {
+		t.Errorf("inputs = %q, want no restart past max_attempts", got)
+	}
+	if got := m.Restarts("a"); got != 2 {
+		t.Errorf("Restarts() = %d, want the restarts in all", got)
+	}
+	records, err := st.ListRestarts("a")
+	if err != nil {
+		t.Fatalf("ListRestarts() error = %v", err)
+	}
+	if len(records) != 2 || records[1].Attempt != 1 {
+		t.Errorf("restart records = %+v, want the second counted from 1", records)
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart config.RestartConfig
+		status  agent.Status
+		want    string // prompt sent, empty for none
+	}{
+		{"never", config.RestartConfig{Policy: config.RestartNever}, agent.StatusErrored, ""},
+		{"unset", config.RestartConfig{}, agent.StatusErrored, ""},
+		{"on-error ignores completed", config.RestartConfig{Policy: config.RestartOnError}, agent.StatusCompleted, ""},
+		{"always restarts completed", config.RestartConfig{Policy: config.RestartAlways}, agent.StatusCompleted, defaultRestartPrompt},
+		{"configured prompt", config.RestartConfig{Policy: config.RestartOnError, Prompt: "try again"}, agent.StatusErrored, "try again"},
+		{"type override", config.RestartConfig{
+			Policy: config.RestartNever,
+			Overrides: []config.RestartOverride{
+				{Type: "claude", Policy: config.RestartOnError},
+				{Type: "claude", Project: "/srv/api", Prompt: "resume"},
+			},
+		}, agent.StatusErrored, "resume"},
+		{"other type override", config.RestartConfig{
+			Policy:    config.RestartNever,
+			Overrides: []config.RestartOverride{{Type: "opencode", Policy: config.RestartOnError}},
+		}, agent.StatusErrored, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.restart.Backoff = time.Millisecond
+			m, _ := newRestartManager(t, tt.restart)
+			a := newInputAgent("a", "claude", agent.StatusRunning)
+			a.MockDirectory = "/srv/api"
+			m.AddAgentForTesting(a)
+
+			fail(m, a, tt.status)
+			if tt.want == "" {
+				time.Sleep(30 * time.Millisecond)
+				if got := a.received(); len(got) != 0 {
+					t.Errorf("inputs = %q, want no restart", got)
+				}
+				return
+			}
+			if got := waitInputs(t, a, 1); got[0] != tt.want {
+				t.Errorf("restart sent %q, want %q", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartFailedSendRetries(t *testing.T) {
+	m, st := newRestartManager(t, config.RestartConfig{
+		Policy:      config.RestartOnError,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	})
+	a := newInputAgent("a", "opencode", agent.StatusRunning)
+	a.sendFail = true
+	m.AddAgentForTesting(a)
+
+	// No further events arrive, so each failed send schedules the next attempt
+	fail(m, a, agent.StatusErrored)
+	waitInputs(t, a, 3)
+	time.Sleep(30 * time.Millisecond)
+	if got := a.received(); len(got) != 3 {
+		t.Errorf("inputs = %q, want 3 attempts", got)
+	}
+
+	records, _ := st.ListRestarts("a")
+	if len(records) != 3 || records[0].Error != "send failed" {
+		t.Errorf("restart records = %+v", records)
+	}
+}
+
+func TestRestartCancelledByTerminate(t *testing.T) {
+	m, _ := newRestartManager(t, config.RestartConfig{
+		Policy:  config.RestartOnError,
+		Backoff: 20 * time.Millisecond,
+	})
+	a := newInputAgent("a", "opencode", agent.StatusRunning)
+	m.AddAgentForTesting(a)
+
+	fail(m, a, agent.StatusErrored)
+	m.handleEvent(m.ctx, agent.Event{Type: agent.EventAgentTerminated, AgentID: "a", Timestamp: time.Now()})
+
+	time.Sleep(60 * time.Millisecond)
+	if got := a.received(); len(got) != 0 {
+		t.Errorf("inputs = %q, want the pending restart cancelled", got)
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	p := restartPolicy{backoff: time.Second, maxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 50: 5 * time.Second} {
+		if got := p.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	p.maxBackoff = 0
+	if got := p.delay(4); got != 8*time.Second {
+		t.Errorf("uncapped delay(4) = %v, want 8s", got)
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/eventbus"
+)
+
+// SendInputAsync sends input to a streaming agent and returns its stream.
+// Every stream event is also published to stream subscribers.
+func (m *Manager) SendInputAsync(ctx context.Context, id, input string) (<-chan agent.StreamEvent, error) {
+	a, ok := m.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("agent not found: %s", id)
+	}
+	sa, ok := a.(agent.StreamingAgent)
+	if !ok {
+		return nil, fmt.Errorf("streaming input %w for %s agents", agent.ErrUnsupported, a.Type())
+	}
+
+	events, err := sa.SendInputAsync(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	m.RecordInput(id, input)
+
+	out := make(chan agent.StreamEvent, 100)
+	go func() {
+		defer close(out)
+		for event := range events {
+			m.streams.Publish(event)
+			// A caller that gave up still lets the run finish and be published
+			if ctx.Err() != nil {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
+
+// SubscribeStreams calls fn for every stream event that passes the filter
+func (m *Manager) SubscribeStreams(opts eventbus.Options, fn func(agent.StreamEvent)) *eventbus.Subscription[agent.StreamEvent] {
+	return m.streams.Subscribe(opts, fn)
+}
+
+// streamTopic is the topic subscribers filter stream events by
+func (m *Manager) streamTopic(e agent.StreamEvent) eventbus.Topic {
+	t := eventbus.Topic{Type: e.Type}
+	if a, ok := m.Get(e.AgentID); ok {
+		t.AgentType = a.Type()
+		t.Project = a.ProjectID()
+	}
+	return t
+}
@@ -0,0 +1,216 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+)
+
+// watchKind identifies a condition the watchdog alerts on
+type watchKind int
+
+const (
+	watchLongRunning watchKind = iota
+	watchToolHung
+	watchNoProgress
+)
+
+// thresholds are the watchdog limits for one agent; zero disables a check
+type thresholds struct {
+	longRunning time.Duration
+	toolTimeout time.Duration
+	noProgress  time.Duration
+}
+
+// progressKey changes whenever an agent produces new output
+type progressKey struct {
+	lastActivity time.Time
+	tokensOut    int64
+	toolCalls    int
+}
+
+// watchState is what the watchdog remembers about an agent between checks
+type watchState struct {
+	runningSince time.Time
+	progress     progressKey
+	progressAt   time.Time
+
+	// Start of the episode each condition last alerted for, so every
+	// run, tool call or stall is reported once
+	fired map[watchKind]time.Time
+}
+
+// runWatchdog periodically checks agents for long runs, hung tools and stalls
+func (m *Manager) runWatchdog(ctx context.Context) {
+	interval := m.cfg.Alerts.WatchdogInterval
+	if interval <= 0 || m.alertMgr == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.checkWatchdog(ctx, now)
+		}
+	}
+}
+
+// checkWatchdog evaluates every agent once and sends any resulting alerts
+func (m *Manager) checkWatchdog(ctx context.Context, now time.Time) {
+	var alerts []*alert.Alert
+
+	m.mu.Lock()
+	for id, a := range m.agents {
+		st, ok := m.watch[id]
+		if !ok {
+			st = &watchState{fired: make(map[watchKind]time.Time)}
+			m.watch[id] = st
+		}
+		alerts = append(alerts, m.watchAgent(a, st, now)...)
+	}
+	for id := range m.watch {
+		if _, ok := m.agents[id]; !ok {
+			delete(m.watch, id)
+		}
+	}
+	m.mu.Unlock()
+
+	if m.alertMgr == nil {
+		return
+	}
+	for _, al := range alerts {
+		m.alertMgr.Send(ctx, al)
+	}
+}
+
+// watchAgent updates an agent's watch state and returns alerts for any
+// condition that crossed its threshold since the last check
+func (m *Manager) watchAgent(a agent.Agent, st *watchState, now time.Time) []*alert.Alert {
+	th := m.thresholdsFor(a)
+	status := a.Status()
+	running := status == agent.StatusRunning
+	metrics := a.Metrics()
+
+	var alerts []*alert.Alert
+	fire := func(kind watchKind, since time.Time, title, format string, args ...interface{}) {
+		if st.fired[kind].Equal(since) {
+			return
+		}
+		st.fired[kind] = since
+		alerts = append(alerts, &alert.Alert{
+			Level:   alert.LevelWarning,
+			Title:   title,
+			Message: fmt.Sprintf("Agent %s ", a.Name()) + fmt.Sprintf(format, args...),
+			AgentID: a.ID(),
+			Agent:   a,
+		})
+	}
+
+	// Running for too long in one stretch
+	if !running {
+		st.runningSince = time.Time{}
+	} else if st.runningSince.IsZero() {
+		st.runningSince = now
+	}
+	if running && th.longRunning > 0 && now.Sub(st.runningSince) >= th.longRunning {
+		fire(watchLongRunning, st.runningSince, "Long Running Agent",
+			"has been running for %s", shortDuration(now.Sub(st.runningSince)))
+	}
+
+	var progress agent.Progress
+	if pr, ok := a.(agent.ProgressReporter); ok {
+		progress = pr.Progress()
+	}
+
+	// A single tool call that never finishes
+	if !progress.ToolStarted.IsZero() && th.toolTimeout > 0 && now.Sub(progress.ToolStarted) >= th.toolTimeout {
+		tool := progress.Tool
+		if tool == "" {
+			tool = "a tool"
+		}
+		fire(watchToolHung, progress.ToolStarted, "Tool Hung",
+			"has been waiting on %s for %s", tool, shortDuration(now.Sub(progress.ToolStarted)))
+	}
+
+	// Running without new output, or a prompt nobody has answered
+	key := progressKey{a.LastActivity(), metrics.TokensOut, metrics.ToolCalls}
+	if key != st.progress || st.progressAt.IsZero() {
+		st.progress = key
+		st.progressAt = now
+	}
+	if th.noProgress > 0 {
+		waiting := !progress.AwaitingReply.IsZero() && (running || status == agent.StatusIdle || status == agent.StatusPending)
+		switch {
+		case waiting && now.Sub(progress.AwaitingReply) >= th.noProgress:
+			fire(watchNoProgress, progress.AwaitingReply, "No Progress",
+				"has not replied to a message sent %s ago", shortDuration(now.Sub(progress.AwaitingReply)))
+		case running && now.Sub(st.progressAt) >= th.noProgress:
+			fire(watchNoProgress, st.progressAt, "No Progress",
+				"has produced no output for %s", shortDuration(now.Sub(st.progressAt)))
+		}
+	}
+
+	return alerts
+}
+
+// thresholdsFor resolves the watchdog thresholds for an agent. Overrides
+// apply from least to most specific: global, by type, by project, then
+// overrides naming both. Projects match on project ID or directory.
+func (m *Manager) thresholdsFor(a agent.Agent) thresholds {
+	cfg := m.cfg.Alerts
+	th := thresholds{
+		longRunning: cfg.LongRunningThreshold,
+		toolTimeout: cfg.ToolTimeout,
+		noProgress:  cfg.NoProgressTimeout,
+	}
+
+	for rank := 0; rank <= 3; rank++ {
+		for _, o := range cfg.Thresholds {
+			r := 0
+			if o.Type != "" {
+				if o.Type != a.Type() {
+					continue
+				}
+				r |= 1
+			}
+			if o.Project != "" {
+				if o.Project != a.ProjectID() && o.Project != a.Directory() {
+					continue
+				}
+				r |= 2
+			}
+			if r != rank {
+				continue
+			}
+
+			if o.LongRunning != 0 {
+				th.longRunning = o.LongRunning
+			}
+			if o.ToolTimeout != 0 {
+				th.toolTimeout = o.ToolTimeout
+			}
+			if o.NoProgress != 0 {
+				th.noProgress = o.NoProgress
+			}
+		}
+	}
+
+	return th
+}
+
+// shortDuration formats a duration to the minute, or the second below one minute
+func shortDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
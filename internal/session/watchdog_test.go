@@ -0,0 +1,170 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/config"
+)
+
+// progressAgent is a mock agent that reports in-flight work
+type progressAgent struct {
+	*agent.MockAgent
+	progress agent.Progress
+}
+
+func (a *progressAgent) Progress() agent.Progress { return a.progress }
+
+func newWatchdogManager(alerts config.AlertsConfig) (*Manager, *alert.Manager) {
+	alertMgr := alert.NewManager(&config.AlertsConfig{}, nil)
+	m := NewManager(&config.Config{Alerts: alerts}, nil, agent.NewRegistry(), alertMgr)
+	return m, alertMgr
+}
+
+func alertTitles(alertMgr *alert.Manager) []string {
+	var titles []string
+	for _, a := range alertMgr.List(0, false) {
+		titles = append(titles, a.Title)
+	}
+	return titles
+}
+
+func TestWatchdogLongRunning(t *testing.T) {
+	m, alertMgr := newWatchdogManager(config.AlertsConfig{LongRunningThreshold: 30 * time.Minute})
+	ctx := context.Background()
+
+	a := agent.NewMockAgent("agent-1", "Agent 1")
+	a.MockStatus = agent.StatusRunning
+	m.agents[a.ID()] = a
+
+	start := time.Now()
+	m.checkWatchdog(ctx, start)
+	m.checkWatchdog(ctx, start.Add(29*time.Minute))
+	if n := len(alertMgr.List(0, false)); n != 0 {
+		t.Fatalf("alerts before threshold = %d, want 0", n)
+	}
+
+	m.checkWatchdog(ctx, start.Add(31*time.Minute))
+	m.checkWatchdog(ctx, start.Add(40*time.Minute))
+	titles := alertTitles(alertMgr)
+	if len(titles) != 1 || titles[0] != "Long Running Agent" {
+		t.Fatalf("alerts = %v, want one Long Running Agent", titles)
+	}
+
+	// A new run after going idle is reported again
+	a.MockStatus = agent.StatusIdle
+	m.checkWatchdog(ctx, start.Add(41*time.Minute))
+	a.MockStatus = agent.StatusRunning
+	m.checkWatchdog(ctx, start.Add(42*time.Minute))
+	m.checkWatchdog(ctx, start.Add(73*time.Minute))
+	if n := len(alertMgr.List(0, false)); n != 2 {
+		t.Errorf("alerts after second run = %d, want 2", n)
+	}
+}
+
+func TestWatchdogToolHung(t *testing.T) {
+	m, alertMgr := newWatchdogManager(config.AlertsConfig{ToolTimeout: 10 * time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	a := &progressAgent{MockAgent: agent.NewMockAgent("agent-1", "Agent 1")}
+	a.progress = agent.Progress{Tool: "bash", ToolStarted: now.Add(-5 * time.Minute)}
+	m.agents[a.ID()] = a
+
+	m.checkWatchdog(ctx, now)
+	if n := len(alertMgr.List(0, false)); n != 0 {
+		t.Fatalf("alerts before timeout = %d, want 0", n)
+	}
+
+	m.checkWatchdog(ctx, now.Add(6*time.Minute))
+	m.checkWatchdog(ctx, now.Add(7*time.Minute))
+	alerts := alertMgr.List(0, false)
+	if len(alerts) != 1 || alerts[0].Title != "Tool Hung" {
+		t.Fatalf("alerts = %v, want one Tool Hung", alertTitles(alertMgr))
+	}
+	if alerts[0].Message != "Agent Agent 1 has been waiting on bash for 11m" {
+		t.Errorf("message = %q", alerts[0].Message)
+	}
+
+	// The next tool call gets its own alert
+	a.progress = agent.Progress{Tool: "edit", ToolStarted: now.Add(8 * time.Minute)}
+	m.checkWatchdog(ctx, now.Add(19*time.Minute))
+	if n := len(alertMgr.List(0, false)); n != 2 {
+		t.Errorf("alerts after second tool = %d, want 2", n)
+	}
+}
+
+func TestWatchdogNoProgress(t *testing.T) {
+	m, alertMgr := newWatchdogManager(config.AlertsConfig{NoProgressTimeout: 5 * time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	a := &progressAgent{MockAgent: agent.NewMockAgent("agent-1", "Agent 1")}
+	a.MockStatus = agent.StatusRunning
+	m.agents[a.ID()] = a
+
+	// Output keeps arriving, so no alert
+	for i := 0; i < 3; i++ {
+		a.MockMetrics.TokensOut += 100
+		m.checkWatchdog(ctx, now.Add(time.Duration(i)*4*time.Minute))
+	}
+	if n := len(alertMgr.List(0, false)); n != 0 {
+		t.Fatalf("alerts with steady output = %d, want 0", n)
+	}
+
+	// Output stops
+	m.checkWatchdog(ctx, now.Add(14*time.Minute))
+	m.checkWatchdog(ctx, now.Add(16*time.Minute))
+	titles := alertTitles(alertMgr)
+	if len(titles) != 1 || titles[0] != "No Progress" {
+		t.Fatalf("alerts = %v, want one No Progress", titles)
+	}
+
+	// An unanswered prompt on an idle agent
+	a.MockStatus = agent.StatusIdle
+	a.MockMetrics.TokensOut += 100
+	a.progress = agent.Progress{AwaitingReply: now.Add(17 * time.Minute)}
+	m.checkWatchdog(ctx, now.Add(20*time.Minute))
+	m.checkWatchdog(ctx, now.Add(23*time.Minute))
+	m.checkWatchdog(ctx, now.Add(25*time.Minute))
+	alerts := alertMgr.List(0, false)
+	if len(alerts) != 2 || alerts[0].Message != "Agent Agent 1 has not replied to a message sent 6m ago" {
+		t.Errorf("alerts = %v, want a second No Progress for the prompt", alertTitles(alertMgr))
+	}
+}
+
+func TestWatchdogThresholdOverrides(t *testing.T) {
+	m, _ := newWatchdogManager(config.AlertsConfig{
+		LongRunningThreshold: 30 * time.Minute,
+		ToolTimeout:          10 * time.Minute,
+		NoProgressTimeout:    5 * time.Minute,
+		Thresholds: []config.ThresholdOverride{
+			{Type: "claude", Project: "big-repo", LongRunning: 4 * time.Hour},
+			{Project: "big-repo", LongRunning: 2 * time.Hour, ToolTimeout: -1},
+			{Type: "claude", LongRunning: time.Hour, NoProgress: 15 * time.Minute},
+		},
+	})
+
+	tests := []struct {
+		agentType string
+		project   string
+		want      thresholds
+	}{
+		{"opencode", "other", thresholds{30 * time.Minute, 10 * time.Minute, 5 * time.Minute}},
+		{"claude", "other", thresholds{time.Hour, 10 * time.Minute, 15 * time.Minute}},
+		{"opencode", "big-repo", thresholds{2 * time.Hour, -1, 5 * time.Minute}},
+		{"claude", "big-repo", thresholds{4 * time.Hour, -1, 15 * time.Minute}},
+	}
+
+	for _, tt := range tests {
+		a := agent.NewMockAgent("agent-1", "Agent 1")
+		a.MockType = tt.agentType
+		a.MockProjectID = tt.project
+		if got := m.thresholdsFor(a); got != tt.want {
+			t.Errorf("thresholdsFor(%s, %s) = %+v, want %+v", tt.agentType, tt.project, got, tt.want)
+		}
+	}
+}
@@ -2,10 +2,12 @@
 package store
 
 import (
+	"cmp"
 	"database/sql"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -58,6 +60,38 @@ type MetricRecord struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// RestartRecord represents an attempt to restart an agent
+type RestartRecord struct {
+	ID        int64     `json:"id"`
+	AgentID   string    `json:"agent_id"`
+	Attempt   int       `json:"attempt"`
+	Reason    string    `json:"reason"` // status that triggered the restart
+	Prompt    string    `json:"prompt"`
+	Error     string    `json:"error,omitempty"` // why the prompt could not be sent
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending = "pending" // waiting for its next attempt
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed" // out of attempts
+)
+
+// DeliveryRecord is an alert queued for, or delivered to, a remote channel
+type DeliveryRecord struct {
+	ID          int64     `json:"id"`
+	AlertID     string    `json:"alert_id"`
+	Channel     string    `json:"channel"`
+	Payload     string    `json:"payload"` // the alert as sent, as JSON
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // New creates a new store
 func New(dbPath string) (*Store, error) {
 	// Ensure directory exists
@@ -146,6 +180,32 @@ func (s *Store) migrate() error {
 			FOREIGN KEY (session_id) REFERENCES sessions(id)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_output_chunks_session_id ON output_chunks(session_id)`,
+
+		`CREATE TABLE IF NOT EXISTS restarts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			agent_id TEXT NOT NULL,
+			attempt INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			prompt TEXT,
+			error TEXT,
+			timestamp DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_restarts_agent_id ON restarts(agent_id)`,
+
+		`CREATE TABLE IF NOT EXISTS deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			alert_id TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT,
+			next_attempt DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deliveries_due ON deliveries(channel, status, next_attempt)`,
+		`CREATE INDEX IF NOT EXISTS idx_deliveries_status ON deliveries(status)`,
 	}
 
 	for _, m := range migrations {
@@ -423,6 +483,149 @@ func (s *Store) GetOutput(sessionID string) (string, error) {
 	return builder.String(), nil
 }
 
+// SaveRestart records a restart attempt
+func (s *Store) SaveRestart(rec *RestartRecord) error {
+	res, err := s.db.Exec(`
+		INSERT INTO restarts (agent_id, attempt, reason, prompt, error, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, rec.AgentID, rec.Attempt, rec.Reason, rec.Prompt, rec.Error, rec.Timestamp)
+	if err != nil {
+		return err
+	}
+	rec.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListRestarts lists the restart attempts of an agent, oldest first
+func (s *Store) ListRestarts(agentID string) ([]*RestartRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT id, agent_id, attempt, reason, prompt, error, timestamp
+		FROM restarts
+		WHERE agent_id = ?
+		ORDER BY id ASC
+	`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*RestartRecord
+	for rows.Next() {
+		rec := &RestartRecord{}
+		var prompt, errMsg sql.NullString
+		if err := rows.Scan(&rec.ID, &rec.AgentID, &rec.Attempt, &rec.Reason, &prompt, &errMsg, &rec.Timestamp); err != nil {
+			return nil, err
+		}
+		rec.Prompt = prompt.String
+		rec.Error = errMsg.String
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// SaveDelivery queues a delivery
+func (s *Store) SaveDelivery(rec *DeliveryRecord) error {
+	res, err := s.db.Exec(`
+		INSERT INTO deliveries (alert_id, channel, payload, status, attempts, last_error, next_attempt, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.AlertID, rec.Channel, rec.Payload, rec.Status, rec.Attempts, rec.LastError,
+		rec.NextAttempt.UTC(), rec.CreatedAt.UTC(), rec.UpdatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	rec.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateDelivery records the outcome of an attempt at a delivery
+func (s *Store) UpdateDelivery(rec *DeliveryRecord) error {
+	_, err := s.db.Exec(`
+		UPDATE deliveries
+		SET status = ?, attempts = ?, last_error = ?, next_attempt = ?, updated_at = ?
+		WHERE id = ?
+	`, rec.Status, rec.Attempts, rec.LastError, rec.NextAttempt.UTC(), rec.UpdatedAt.UTC(), rec.ID)
+	return err
+}
+
+// ClaimDeliveries claims the pending deliveries to a channel whose next
+// attempt is due by now, oldest first, by moving their next attempt to
+// until. Processes sharing the store each get a delivery only once; one
+// that has not recorded an outcome by until leaves it to be claimed again.
+func (s *Store) ClaimDeliveries(channel string, now, until time.Time, limit int) ([]*DeliveryRecord, error) {
+	records, err := s.scanDeliveries(`
+		UPDATE deliveries SET next_attempt = ?
+		WHERE id IN (
+			SELECT id FROM deliveries
+			WHERE channel = ? AND status = ? AND next_attempt <= ?
+			ORDER BY next_attempt ASC, id ASC
+			LIMIT ?
+		)
+		RETURNING `+deliveryColumns, until.UTC(), channel, DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING has no order
+	slices.SortFunc(records, func(a, b *DeliveryRecord) int { return cmp.Compare(a.ID, b.ID) })
+	return records, nil
+}
+
+// NextDelivery returns when the next pending delivery to a channel is due,
+// and false when none is pending
+func (s *Store) NextDelivery(channel string) (time.Time, bool, error) {
+	records, err := s.queryDeliveries(`
+		WHERE channel = ? AND status = ?
+		ORDER BY next_attempt ASC
+		LIMIT 1
+	`, channel, DeliveryPending)
+	if err != nil || len(records) == 0 {
+		return time.Time{}, false, err
+	}
+	return records[0].NextAttempt, true, nil
+}
+
+// ListDeliveries lists deliveries with a status, or all of them, most
+// recently updated first
+func (s *Store) ListDeliveries(status string, limit int) ([]*DeliveryRecord, error) {
+	query := `WHERE (? = '' OR status = ?) ORDER BY updated_at DESC, id DESC`
+	args := []interface{}{status, status}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return s.queryDeliveries(query, args...)
+}
+
+// deliveryColumns are the columns scanDeliveries reads
+const deliveryColumns = `id, alert_id, channel, payload, status, attempts, last_error, next_attempt, created_at, updated_at`
+
+func (s *Store) queryDeliveries(where string, args ...interface{}) ([]*DeliveryRecord, error) {
+	return s.scanDeliveries(`SELECT `+deliveryColumns+` FROM deliveries `+where, args...)
+}
+
+// scanDeliveries runs a query returning deliveryColumns
+func (s *Store) scanDeliveries(query string, args ...interface{}) ([]*DeliveryRecord, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*DeliveryRecord
+	for rows.Next() {
+		rec := &DeliveryRecord{}
+		var lastError sql.NullString
+		if err := rows.Scan(&rec.ID, &rec.AlertID, &rec.Channel, &rec.Payload, &rec.Status, &rec.Attempts,
+			&lastError, &rec.NextAttempt, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rec.LastError = lastError.String
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
 // GetStats gets aggregate statistics
 func (s *Store) GetStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -496,6 +699,18 @@ func (s *Store) Cleanup(maxAgeDays int) error {
 
 	// Delete old metrics
 	_, err = s.db.Exec(`DELETE FROM metrics WHERE timestamp < ?`, cutoff)
+	if err != nil {
+		return err
+	}
+
+	// Delete old restart attempts
+	_, err = s.db.Exec(`DELETE FROM restarts WHERE timestamp < ?`, cutoff)
+	if err != nil {
+		return err
+	}
+
+	// Delete old deliveries that are done with
+	_, err = s.db.Exec(`DELETE FROM deliveries WHERE updated_at < ? AND status != ?`, cutoff.UTC(), DeliveryPending)
 	return err
 }
 
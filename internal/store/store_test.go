@@ -118,6 +118,119 @@ func TestAlertOperations(t *testing.T) {
 	}
 }
 
+func TestRestartOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	for i, errMsg := range []string{"opencode run failed", ""} {
+		rec := &RestartRecord{
+			AgentID:   "agent-1",
+			Attempt:   i + 1,
+			Reason:    "errored",
+			Prompt:    "continue",
+			Error:     errMsg,
+			Timestamp: time.Now(),
+		}
+		if err := store.SaveRestart(rec); err != nil {
+			t.Fatalf("Failed to save restart: %v", err)
+		}
+		if rec.ID == 0 {
+			t.Error("SaveRestart should set the record ID")
+		}
+	}
+	store.SaveRestart(&RestartRecord{AgentID: "agent-2", Attempt: 1, Reason: "completed", Timestamp: time.Now()})
+
+	restarts, err := store.ListRestarts("agent-1")
+	if err != nil {
+		t.Fatalf("Failed to list restarts: %v", err)
+	}
+	if len(restarts) != 2 {
+		t.Fatalf("Should have 2 restarts for agent-1, got %d", len(restarts))
+	}
+	if restarts[0].Attempt != 1 || restarts[0].Error != "opencode run failed" || restarts[1].Attempt != 2 || restarts[1].Prompt != "continue" {
+		t.Errorf("Unexpected restarts: %+v, %+v", restarts[0], restarts[1])
+	}
+}
+
+func TestDeliveryOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	store, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	for i, channel := range []string{"slack", "slack", "discord"} {
+		rec := &DeliveryRecord{
+			AlertID:     "alert-1",
+			Channel:     channel,
+			Payload:     `{"title":"Agent Error"}`,
+			Status:      DeliveryPending,
+			NextAttempt: now.Add(time.Duration(i) * time.Minute),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := store.SaveDelivery(rec); err != nil {
+			t.Fatalf("Failed to save delivery: %v", err)
+		}
+		if rec.ID == 0 {
+			t.Error("SaveDelivery should set the record ID")
+		}
+	}
+
+	next, ok, err := store.NextDelivery("slack")
+	if err != nil || !ok || !next.Equal(now) {
+		t.Errorf("NextDelivery() = %v, %v, %v; want %v", next, ok, err, now)
+	}
+	lease := now.Add(30 * time.Second)
+	due, err := store.ClaimDeliveries("slack", now, lease, 10)
+	if err != nil {
+		t.Fatalf("Failed to claim due deliveries: %v", err)
+	}
+	if len(due) != 1 || due[0].Channel != "slack" || due[0].Payload != `{"title":"Agent Error"}` || !due[0].NextAttempt.Equal(lease) {
+		t.Fatalf("Unexpected due deliveries: %+v", due)
+	}
+
+	// A claimed delivery is not claimed again until its lease runs out
+	if again, _ := store.ClaimDeliveries("slack", now, lease, 10); len(again) != 0 {
+		t.Errorf("Claimed delivery was claimed again: %+v", again)
+	}
+	if again, _ := store.ClaimDeliveries("slack", lease, lease.Add(time.Minute), 1); len(again) != 1 || again[0].ID != due[0].ID {
+		t.Errorf("Delivery with an expired lease = %+v, want it claimed again", again)
+	}
+
+	due[0].Status = DeliveryFailed
+	due[0].Attempts = 3
+	due[0].LastError = "slack webhook returned status 500"
+	due[0].UpdatedAt = now.Add(time.Second)
+	if err := store.UpdateDelivery(due[0]); err != nil {
+		t.Fatalf("Failed to update delivery: %v", err)
+	}
+	if due, _ := store.ClaimDeliveries("slack", now.Add(time.Hour), now.Add(2*time.Hour), 10); len(due) != 1 {
+		t.Errorf("Failed deliveries should not be due, got %d due", len(due))
+	}
+
+	failed, err := store.ListDeliveries(DeliveryFailed, 0)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Attempts != 3 || failed[0].LastError != "slack webhook returned status 500" {
+		t.Errorf("Unexpected failed deliveries: %+v", failed)
+	}
+	if all, _ := store.ListDeliveries("", 2); len(all) != 2 || all[0].Status != DeliveryFailed {
+		t.Errorf("ListDeliveries should list the most recently updated first, got %+v", all)
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -7,9 +7,13 @@ import (
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
 	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/batch"
 	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
 	"github.com/CastAIPhil/AUTO/internal/session"
+	"github.com/CastAIPhil/AUTO/internal/store"
 	"github.com/CastAIPhil/AUTO/internal/tui/components"
+	"github.com/CastAIPhil/AUTO/internal/workflow"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -33,14 +37,27 @@ type App struct {
 	manager  *session.Manager
 	alertMgr *alert.Manager
 
-	agentList   *components.AgentList
-	viewport    *components.SessionViewport
-	stats       *components.StatsPanel
-	alerts      *components.AlertsPanel
-	input       *components.InputBar
-	command     *components.CommandPalette
-	help        *components.HelpScreen
-	spawnDialog *components.SpawnDialog
+	agentList    *components.AgentList
+	viewport     *components.SessionViewport
+	stats        *components.StatsPanel
+	alerts       *components.AlertsPanel
+	input        *components.InputBar
+	command      *components.CommandPalette
+	help         *components.HelpScreen
+	spawnDialog  *components.SpawnDialog
+	batchView    *components.BatchView
+	workflowView *components.WorkflowView
+	worktreeView *components.WorktreeView
+	filesView    *components.FilesView
+	deliveries   *components.DeliveriesView
+
+	// fileSource lists the files agents changed; the daemon's in attached mode
+	fileSource func(ctx context.Context) ([]conflict.File, error)
+	// deliverySource lists alert deliveries by status; the daemon's in attached mode
+	deliverySource func(ctx context.Context, status string, limit int) ([]alert.Delivery, error)
+	// snoozer sets and lists alert snoozes; the daemon's in attached mode
+	snoozer Snoozer
+	snoozes []alert.Snooze
 
 	activePane   Pane
 	showStats    bool
@@ -75,6 +92,13 @@ func NewApp(cfg *config.Config, manager *session.Manager, alertMgr *alert.Manage
 		theme:    theme,
 		manager:  manager,
 		alertMgr: alertMgr,
+		fileSource: func(context.Context) ([]conflict.File, error) {
+			return manager.Files(), nil
+		},
+		deliverySource: func(_ context.Context, status string, limit int) ([]alert.Delivery, error) {
+			return alertMgr.Deliveries(status, limit)
+		},
+		snoozer: localSnoozer{alertMgr},
 
 		activePane: PaneAgentList,
 		showStats:  cfg.UI.ShowMetrics,
@@ -89,6 +113,7 @@ func (a *App) Init() tea.Cmd {
 	return tea.Batch(
 		a.tickCmd(),
 		a.waitForEvents(),
+		a.snooze(components.SnoozeMsg{}),
 	)
 }
 
@@ -137,6 +162,36 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, cmd
 		}
 
+		if a.batchView.IsVisible() {
+			var cmd tea.Cmd
+			a.batchView, cmd = a.batchView.Update(msg)
+			return a, cmd
+		}
+
+		if a.workflowView.IsVisible() {
+			var cmd tea.Cmd
+			a.workflowView, cmd = a.workflowView.Update(msg)
+			return a, cmd
+		}
+
+		if a.worktreeView.IsVisible() {
+			var cmd tea.Cmd
+			a.worktreeView, cmd = a.worktreeView.Update(msg)
+			return a, cmd
+		}
+
+		if a.filesView.IsVisible() {
+			var cmd tea.Cmd
+			a.filesView, cmd = a.filesView.Update(msg)
+			return a, cmd
+		}
+
+		if a.deliveries.IsVisible() {
+			var cmd tea.Cmd
+			a.deliveries, cmd = a.deliveries.Update(msg)
+			return a, cmd
+		}
+
 		if a.spawnVisible && a.spawnDialog != nil {
 			var cmd tea.Cmd
 			a.spawnDialog, cmd = a.spawnDialog.Update(msg)
@@ -218,6 +273,18 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tickMsg:
+		if a.batchView != nil {
+			a.batchView.Refresh()
+		}
+		if a.workflowView != nil {
+			a.workflowView.Refresh()
+		}
+		if a.filesView != nil && a.filesView.IsVisible() {
+			cmds = append(cmds, a.loadFiles())
+		}
+		if a.deliveries != nil && a.deliveries.IsVisible() {
+			cmds = append(cmds, a.loadDeliveries())
+		}
 		a.statsDirty = true
 		if a.stats != nil {
 			a.stats.MarkDirty()
@@ -241,8 +308,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.agentList, cmd = a.agentList.Update(msg)
 			cmds = append(cmds, cmd)
 		}
-		if a.viewport != nil && msg.Agent != nil {
-			if a.viewport.Agent() != nil && a.viewport.Agent().ID() == msg.AgentID {
+		if a.viewport != nil && a.viewport.Agent() != nil && a.viewport.Agent().ID() == msg.AgentID {
+			// Output events carry stream events of runs started elsewhere,
+			// such as in a replayed recording
+			if stream, ok := msg.Data.(agent.StreamEvent); ok && msg.Type == agent.EventAgentOutput {
+				a.viewport, _ = a.viewport.Update(components.StreamEventMsg{Event: stream, AgentID: msg.AgentID})
+			} else if msg.Agent != nil {
 				a.viewport, _ = a.viewport.Update(components.AgentSelectedMsg{Agent: msg.Agent})
 			}
 		}
@@ -328,10 +399,74 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a, nil
 
+	case components.RunBatchMsg:
+		return a, a.batchView.Show()
+
+	case components.BatchSubmitMsg:
+		manifest, err := batch.Load(msg.Path)
+		if err != nil {
+			a.batchView.SetError(err)
+			return a, nil
+		}
+		a.batchView.SetBatch(batch.Submit(a.ctx, a.manager, manifest), batch.ResultPath(msg.Path))
+		return a, nil
+
+	case components.RunWorkflowMsg:
+		return a, a.workflowView.Show()
+
+	case components.WorkflowSubmitMsg:
+		def, err := workflow.Load(msg.Path)
+		if err != nil {
+			a.workflowView.SetError(err)
+			return a, nil
+		}
+		a.workflowView.SetRun(workflow.Start(a.ctx, a.manager, def))
+		return a, nil
+
+	case components.ShowWorktreeMsg:
+		a.worktreeView.Show(a.agentList.Selected())
+		return a, nil
+
+	case components.ShowFilesMsg:
+		a.filesView.Show()
+		return a, a.loadFiles()
+
+	case components.FilesLoadedMsg:
+		if msg.Err != nil {
+			a.filesView.SetError(msg.Err)
+		} else {
+			a.filesView.SetFiles(msg.Files)
+		}
+		return a, nil
+
+	case components.ShowDeliveriesMsg:
+		a.deliveries.Show()
+		return a, a.loadDeliveries()
+
+	case components.DeliveriesLoadedMsg:
+		if msg.Err != nil {
+			a.deliveries.SetError(msg.Err)
+		} else {
+			a.deliveries.SetDeliveries(msg.Deliveries)
+		}
+		return a, nil
+
 	case *alert.Alert:
 		if a.alerts != nil {
 			a.alerts, _ = a.alerts.Update(msg)
 		}
+
+	case components.SnoozeMsg:
+		return a, a.snooze(msg)
+
+	case components.SnoozesLoadedMsg:
+		if msg.Err == nil {
+			a.snoozes = msg.Snoozes
+		}
+		if a.alerts != nil {
+			a.alerts, _ = a.alerts.Update(msg)
+		}
+		return a, nil
 	}
 
 	if a.agentList != nil && a.activePane == PaneAgentList {
@@ -416,6 +551,7 @@ func (a *App) updateSizes() {
 		if a.alerts == nil {
 			a.alerts = components.NewAlertsPanel(a.theme, a.alertMgr, rightPanelWidth, alertsHeight)
 			a.alerts.Update(components.AlertRefreshMsg{})
+			a.alerts.Update(components.SnoozesLoadedMsg{Snoozes: a.snoozes})
 		} else {
 			a.alerts.SetSize(rightPanelWidth, alertsHeight)
 		}
@@ -437,6 +573,31 @@ func (a *App) updateSizes() {
 	}
 	a.help.SetSize(a.width*2/3, a.height*2/3)
 
+	if a.batchView == nil {
+		a.batchView = components.NewBatchView(a.theme)
+	}
+	a.batchView.SetSize(a.width*2/3, a.height*2/3)
+
+	if a.workflowView == nil {
+		a.workflowView = components.NewWorkflowView(a.theme)
+	}
+	a.workflowView.SetSize(a.width*3/4, a.height*3/4)
+
+	if a.worktreeView == nil {
+		a.worktreeView = components.NewWorktreeView(a.theme)
+	}
+	a.worktreeView.SetSize(a.width*3/4, a.height*3/4)
+
+	if a.filesView == nil {
+		a.filesView = components.NewFilesView(a.theme)
+	}
+	a.filesView.SetSize(a.width*3/4, a.height*3/4)
+
+	if a.deliveries == nil {
+		a.deliveries = components.NewDeliveriesView(a.theme)
+	}
+	a.deliveries.SetSize(a.width*3/4, a.height*3/4)
+
 	if a.spawnDialog == nil {
 		a.spawnDialog = components.NewSpawnDialog(a.theme, a.width*2/3, a.height*2/3)
 	} else {
@@ -501,6 +662,26 @@ func (a *App) View() string {
 		return a.renderCentered(a.spawnDialog.View())
 	}
 
+	if a.batchView.IsVisible() {
+		return a.renderCentered(a.batchView.View())
+	}
+
+	if a.workflowView.IsVisible() {
+		return a.renderCentered(a.workflowView.View())
+	}
+
+	if a.worktreeView.IsVisible() {
+		return a.renderCentered(a.worktreeView.View())
+	}
+
+	if a.filesView.IsVisible() {
+		return a.renderCentered(a.filesView.View())
+	}
+
+	if a.deliveries.IsVisible() {
+		return a.renderCentered(a.deliveries.View())
+	}
+
 	header := a.renderHeader()
 	body := a.renderBody()
 	footer := a.renderFooter()
@@ -687,6 +868,87 @@ func (a *App) SetContext(ctx context.Context) {
 	a.ctx = ctx
 }
 
+// SetFileSource sets where the file ownership view gets its files from
+func (a *App) SetFileSource(fn func(ctx context.Context) ([]conflict.File, error)) {
+	a.fileSource = fn
+}
+
+// loadFiles fetches the changed files for the file ownership view
+func (a *App) loadFiles() tea.Cmd {
+	source, ctx := a.fileSource, a.ctx
+	return func() tea.Msg {
+		files, err := source(ctx)
+		return components.FilesLoadedMsg{Files: files, Err: err}
+	}
+}
+
+// SetDeliverySource sets where the failed deliveries view gets its
+// deliveries from
+func (a *App) SetDeliverySource(fn func(ctx context.Context, status string, limit int) ([]alert.Delivery, error)) {
+	a.deliverySource = fn
+}
+
+// loadDeliveries fetches the failed deliveries, then those being retried
+func (a *App) loadDeliveries() tea.Cmd {
+	source, ctx := a.deliverySource, a.ctx
+	return func() tea.Msg {
+		failed, err := source(ctx, store.DeliveryFailed, 100)
+		if err != nil {
+			return components.DeliveriesLoadedMsg{Err: err}
+		}
+		pending, err := source(ctx, store.DeliveryPending, 100)
+		return components.DeliveriesLoadedMsg{Deliveries: append(failed, pending...), Err: err}
+	}
+}
+
+// Snoozer sets and lists alert snoozes
+type Snoozer interface {
+	Snooze(ctx context.Context, s alert.Snooze) ([]alert.Snooze, error)
+	Snoozes(ctx context.Context) ([]alert.Snooze, error)
+}
+
+// localSnoozer snoozes alerts in this process
+type localSnoozer struct {
+	m *alert.Manager
+}
+
+func (l localSnoozer) Snooze(ctx context.Context, s alert.Snooze) ([]alert.Snooze, error) {
+	l.m.Snooze(s)
+	return l.m.Snoozes(), nil
+}
+
+func (l localSnoozer) Snoozes(ctx context.Context) ([]alert.Snooze, error) {
+	return l.m.Snoozes(), nil
+}
+
+// SetSnoozer sets where alert snoozes are kept
+func (a *App) SetSnoozer(s Snoozer) {
+	a.snoozer = s
+}
+
+// snooze snoozes for alerts.snooze_duration, or ends, the snooze of a
+// message; an empty one only loads the snoozes in effect
+func (a *App) snooze(msg components.SnoozeMsg) tea.Cmd {
+	snoozer, ctx := a.snoozer, a.ctx
+	s := msg.Snooze
+	if !msg.End {
+		s.Until = time.Now().Add(a.cfg.Alerts.SnoozeDuration)
+	}
+	return func() tea.Msg {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		var snoozes []alert.Snooze
+		var err error
+		if s.AgentID == "" && s.Project == "" {
+			snoozes, err = snoozer.Snoozes(ctx)
+		} else {
+			snoozes, err = snoozer.Snooze(ctx, s)
+		}
+		return components.SnoozesLoadedMsg{Snoozes: snoozes, Err: err}
+	}
+}
+
 // EventChannel returns the event channel for pushing events
 func (a *App) EventChannel() chan<- agent.Event {
 	return a.eventChan
@@ -696,7 +958,7 @@ func (a *App) EventChannel() chan<- agent.Event {
 func (a *App) startStreaming(streamingAgent agent.StreamingAgent, input string) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithCancel(a.ctx)
-		eventChan, err := streamingAgent.SendInputAsync(ctx, input)
+		eventChan, err := a.manager.SendInputAsync(ctx, streamingAgent.ID(), input)
 		if err != nil {
 			cancel()
 			// Return error as a stream event
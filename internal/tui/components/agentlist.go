@@ -17,12 +17,16 @@ import (
 type AgentItem struct {
 	Agent      agent.Agent
 	ChildCount int
+	Restarts   int
 }
 
 func (i AgentItem) Title() string {
 	name := i.Agent.Name()
 	if i.ChildCount > 0 {
-		return fmt.Sprintf("%s [%d]", name, i.ChildCount)
+		name = fmt.Sprintf("%s [%d]", name, i.ChildCount)
+	}
+	if i.Restarts > 0 {
+		name = fmt.Sprintf("%s ↻%d", name, i.Restarts)
 	}
 	return name
 }
@@ -281,7 +285,7 @@ func (a *AgentList) updateItems() {
 		if a.viewMode == ViewModePrimary {
 			childCount = a.manager.ChildCount(ag.ID())
 		}
-		items[i] = AgentItem{Agent: ag, ChildCount: childCount}
+		items[i] = AgentItem{Agent: ag, ChildCount: childCount, Restarts: a.manager.Restarts(ag.ID())}
 	}
 
 	a.list.SetItems(items)
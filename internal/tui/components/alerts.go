@@ -2,6 +2,7 @@ package components
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,6 +20,8 @@ type AlertsPanel struct {
 	height   int
 	cursor   int
 	offset   int
+	snoozes  []alert.Snooze
+	err      error
 }
 
 // NewAlertsPanel creates a new alerts panel
@@ -59,6 +62,25 @@ func (a *AlertsPanel) Update(msg tea.Msg) (*AlertsPanel, tea.Cmd) {
 		case "R":
 			a.alertMgr.MarkAllRead()
 			a.refresh()
+		case "z", "Z":
+			if s, ok := a.snoozeTarget(msg.String() == "Z"); ok {
+				return a, func() tea.Msg { return SnoozeMsg{Snooze: s} }
+			}
+		case "u":
+			if a.cursor < len(a.alerts) {
+				for _, s := range a.snoozes {
+					if s.AgentID == a.alerts[a.cursor].AgentID || (s.Project != "" && s.Project == projectOf(a.alerts[a.cursor])) {
+						s.Until = time.Time{}
+						return a, func() tea.Msg { return SnoozeMsg{Snooze: s, End: true} }
+					}
+				}
+			}
+		}
+
+	case SnoozesLoadedMsg:
+		a.err = msg.Err
+		if msg.Err == nil {
+			a.snoozes = msg.Snoozes
 		}
 
 	case AlertRefreshMsg:
@@ -74,6 +96,44 @@ func (a *AlertsPanel) Update(msg tea.Msg) (*AlertsPanel, tea.Cmd) {
 // AlertRefreshMsg triggers a refresh
 type AlertRefreshMsg struct{}
 
+// SnoozeMsg asks to snooze, or with End to stop snoozing, the alerts of
+// an agent or project
+type SnoozeMsg struct {
+	Snooze alert.Snooze
+	End    bool
+}
+
+// SnoozesLoadedMsg carries the snoozes in effect
+type SnoozesLoadedMsg struct {
+	Snoozes []alert.Snooze
+	Err     error
+}
+
+// snoozeTarget returns a snooze of the selected alert's agent, or project
+func (a *AlertsPanel) snoozeTarget(project bool) (alert.Snooze, bool) {
+	if a.cursor >= len(a.alerts) {
+		return alert.Snooze{}, false
+	}
+	al := a.alerts[a.cursor]
+	if project {
+		p := projectOf(al)
+		return alert.Snooze{Project: p, Name: p}, p != ""
+	}
+	name := al.AgentID
+	if al.Agent != nil {
+		name = al.Agent.Name()
+	}
+	return alert.Snooze{AgentID: al.AgentID, Name: name}, al.AgentID != ""
+}
+
+// projectOf returns the project of an alert's agent, if known
+func projectOf(al *alert.Alert) string {
+	if al.Agent == nil {
+		return ""
+	}
+	return al.Agent.ProjectID()
+}
+
 // refresh refreshes the alerts list
 func (a *AlertsPanel) refresh() {
 	a.alerts = a.alertMgr.List(50, false)
@@ -109,7 +169,11 @@ func (a *AlertsPanel) View() string {
 		title = fmt.Sprintf("Alerts (%d unread)", unreadCount)
 	}
 	b.WriteString(a.theme.Title.Render(title))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+	if line := a.snoozeLine(); line != "" {
+		b.WriteString(a.theme.Base.Faint(true).Render(line))
+	}
+	b.WriteString("\n")
 
 	if len(a.alerts) == 0 {
 		b.WriteString(a.theme.Base.Faint(true).Render("  No alerts"))
@@ -150,6 +214,17 @@ func (a *AlertsPanel) renderAlert(al *alert.Alert, selected bool) string {
 	}
 
 	text := al.Title
+	if al.Suppressed != "" {
+		text += " [suppressed: " + al.Suppressed + "]"
+	}
+	if len(al.Skipped) > 0 {
+		skipped := make([]string, 0, len(al.Skipped))
+		for ch, why := range al.Skipped {
+			skipped = append(skipped, ch+": "+why)
+		}
+		sort.Strings(skipped)
+		text += " [not sent to " + strings.Join(skipped, ", ") + "]"
+	}
 	if len(text) > a.width-10 {
 		text = text[:a.width-13] + "..."
 	}
@@ -162,13 +237,45 @@ func (a *AlertsPanel) renderAlert(al *alert.Alert, selected bool) string {
 		return a.theme.SelectedItemStyle.Render(line)
 	}
 
-	if !al.Read {
+	if !al.Read && al.Suppressed == "" {
 		return a.theme.Base.Bold(true).Render(line)
 	}
 
 	return a.theme.Base.Faint(true).Render(line)
 }
 
+// snoozeLine lists what is snoozed and for how long, or the last error
+func (a *AlertsPanel) snoozeLine() string {
+	if a.err != nil {
+		return "  Snooze failed: " + a.err.Error()
+	}
+	var parts []string
+	for _, s := range a.snoozes {
+		if left := time.Until(s.Until); left > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", s.Name, formatDurationLeft(left)))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	line := "  Snoozed: " + strings.Join(parts, ", ")
+	if len(line) > a.width-4 && a.width > 7 {
+		line = line[:a.width-7] + "..."
+	}
+	return line
+}
+
+// formatDurationLeft formats the time left of a snooze
+func formatDurationLeft(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
 // SetSize sets the component size
 func (a *AlertsPanel) SetSize(width, height int) {
 	a.width = width
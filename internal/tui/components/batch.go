@@ -0,0 +1,237 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/batch"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// BatchView asks for a batch manifest and shows the outcome of its tasks
+type BatchView struct {
+	theme      *Theme
+	input      textinput.Model
+	batch      *batch.Batch
+	resultPath string
+	written    bool
+	err        error
+	visible    bool
+	width      int
+	height     int
+}
+
+// NewBatchView creates a new batch view
+func NewBatchView(theme *Theme) *BatchView {
+	ti := textinput.New()
+	ti.Placeholder = "Path to manifest (YAML or JSON)"
+	ti.CharLimit = 500
+
+	return &BatchView{
+		theme: theme,
+		input: ti,
+	}
+}
+
+// Update handles messages
+func (v *BatchView) Update(msg tea.Msg) (*BatchView, tea.Cmd) {
+	if !v.visible {
+		return v, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	if keyMsg.String() == "esc" {
+		v.Hide()
+		return v, nil
+	}
+
+	if v.batch == nil {
+		if keyMsg.String() == "enter" {
+			path := strings.TrimSpace(v.input.Value())
+			if path == "" {
+				return v, nil
+			}
+			return v, func() tea.Msg { return BatchSubmitMsg{Path: path} }
+		}
+		var cmd tea.Cmd
+		v.input, cmd = v.input.Update(msg)
+		return v, cmd
+	}
+
+	switch keyMsg.String() {
+	case "x":
+		if !v.batch.Done() {
+			v.batch.Cancel()
+			v.Refresh()
+		}
+	case "n":
+		if v.batch.Done() {
+			v.batch = nil
+			v.err = nil
+			v.input.SetValue("")
+			return v, v.input.Focus()
+		}
+	}
+	return v, nil
+}
+
+// Refresh updates the batch and writes its result once every task has finished
+func (v *BatchView) Refresh() {
+	if v.batch == nil {
+		return
+	}
+	v.batch.Update()
+	if v.batch.Done() && !v.written {
+		v.written = true
+		v.err = v.batch.Result().WriteFile(v.resultPath)
+	}
+}
+
+// SetBatch shows a submitted batch
+func (v *BatchView) SetBatch(b *batch.Batch, resultPath string) {
+	v.batch = b
+	v.resultPath = resultPath
+	v.written = false
+	v.err = nil
+	v.input.Blur()
+}
+
+// SetError shows why a manifest could not be submitted
+func (v *BatchView) SetError(err error) {
+	v.err = err
+}
+
+// View renders the batch view
+func (v *BatchView) View() string {
+	if !v.visible {
+		return ""
+	}
+
+	var b strings.Builder
+	faint := v.theme.Base.Faint(true)
+
+	if v.batch == nil {
+		b.WriteString(v.theme.Title.Render("Run Batch"))
+		b.WriteString("\n\n")
+		b.WriteString(v.theme.InputStyle.Render(v.input.View()))
+		b.WriteString("\n\n")
+		if v.err != nil {
+			b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render(v.err.Error()))
+			b.WriteString("\n\n")
+		}
+		b.WriteString(faint.Render("enter: submit  esc: close"))
+		return v.theme.CommandStyle.Width(v.width).Render(b.String())
+	}
+
+	result := v.batch.Result()
+	b.WriteString(v.theme.Title.Render("Batch: " + result.Name))
+	b.WriteString("\n\n")
+
+	maxRows := v.height - 8
+	if maxRows < 1 {
+		maxRows = 1
+	}
+	for i, t := range result.Tasks {
+		if i == maxRows {
+			b.WriteString(faint.Render(fmt.Sprintf("  ... %d more", len(result.Tasks)-i)))
+			b.WriteString("\n")
+			break
+		}
+		status := outcomeStatus(t.Outcome)
+		line := fmt.Sprintf("%s %-24s %-10s %s", status.Icon(), truncate(t.Name, 24), t.Outcome, t.AgentID)
+		if t.Error != "" {
+			line += "  " + t.Error
+		}
+		b.WriteString(v.theme.StatusStyle(status).Render(truncate(line, v.width-4)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("%d completed, %d errored, %d cancelled of %d",
+		result.Summary[batch.OutcomeCompleted], result.Summary[batch.OutcomeErrored],
+		result.Summary[batch.OutcomeCancelled], len(result.Tasks)))
+	b.WriteString("\n")
+
+	switch {
+	case v.err != nil:
+		b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render("Failed to write result: " + v.err.Error()))
+		b.WriteString("\n")
+	case v.written:
+		b.WriteString(faint.Render("Result written to " + v.resultPath))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if v.batch.Done() {
+		b.WriteString(faint.Render("n: new batch  esc: close"))
+	} else {
+		b.WriteString(faint.Render("x: cancel batch  esc: close (keeps running)"))
+	}
+	return v.theme.CommandStyle.Width(v.width).Render(b.String())
+}
+
+// outcomeStatus maps a task outcome to the agent status it is styled as
+func outcomeStatus(o batch.Outcome) agent.Status {
+	switch o {
+	case batch.OutcomeRunning:
+		return agent.StatusRunning
+	case batch.OutcomeCompleted:
+		return agent.StatusCompleted
+	case batch.OutcomeErrored:
+		return agent.StatusErrored
+	case batch.OutcomeCancelled:
+		return agent.StatusCancelled
+	default:
+		return agent.StatusPending
+	}
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n < 4 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// Show shows the batch view
+func (v *BatchView) Show() tea.Cmd {
+	v.visible = true
+	if v.batch == nil {
+		return v.input.Focus()
+	}
+	return nil
+}
+
+// Hide hides the batch view; a running batch keeps being tracked
+func (v *BatchView) Hide() {
+	v.visible = false
+	v.input.Blur()
+}
+
+// IsVisible returns whether the batch view is visible
+func (v *BatchView) IsVisible() bool {
+	return v.visible
+}
+
+// SetSize sets the component size
+func (v *BatchView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+	v.input.Width = width - 8
+}
+
+// RunBatchMsg opens the batch view
+type RunBatchMsg struct{}
+
+// BatchSubmitMsg asks for the manifest at Path to be submitted
+type BatchSubmitMsg struct {
+	Path string
+}
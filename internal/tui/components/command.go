@@ -265,6 +265,31 @@ func DefaultCommands() []Command {
 			Keys:        "n",
 			Action:      func() tea.Msg { return SpawnSessionMsg{} },
 		},
+		{
+			Name:        "Run Batch",
+			Description: "Spawn agents from a batch manifest",
+			Action:      func() tea.Msg { return RunBatchMsg{} },
+		},
+		{
+			Name:        "Run Workflow",
+			Description: "Run a workflow of dependent agent steps",
+			Action:      func() tea.Msg { return RunWorkflowMsg{} },
+		},
+		{
+			Name:        "Worktree",
+			Description: "Review, merge or discard the selected agent's worktree",
+			Action:      func() tea.Msg { return ShowWorktreeMsg{} },
+		},
+		{
+			Name:        "File Ownership",
+			Description: "Show which agents changed which files and their conflicts",
+			Action:      func() tea.Msg { return ShowFilesMsg{} },
+		},
+		{
+			Name:        "Failed Deliveries",
+			Description: "Show alerts that could not be sent to webhooks or email",
+			Action:      func() tea.Msg { return ShowDeliveriesMsg{} },
+		},
 	}
 }
 
@@ -1,12 +1,24 @@
 package components
 
 import (
+	"context"
+	"errors"
 	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/batch"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+	"github.com/CastAIPhil/AUTO/internal/workflow"
+	"github.com/CastAIPhil/AUTO/internal/worktree"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -384,6 +396,33 @@ func TestSpawnDialogConfirmState(t *testing.T) {
 	}
 }
 
+func TestSpawnDialogWorktreeToggle(t *testing.T) {
+	theme := DefaultDarkTheme()
+	d := NewSpawnDialog(theme, 80, 40)
+	d.selectedDir = "/test"
+	d.state = SpawnStateConfirm
+
+	if d.Result().Config.Worktree {
+		t.Error("worktree should be off by default")
+	}
+
+	d, _ = d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+	if !d.Result().Config.Worktree {
+		t.Error("'w' should turn the worktree on")
+	}
+	if d.IsComplete() {
+		t.Error("toggling the worktree should not submit")
+	}
+	if !strings.Contains(d.View(), "Worktree: on") {
+		t.Error("view should show the worktree is on")
+	}
+
+	d.Reset()
+	if d.Result().Config.Worktree {
+		t.Error("Reset() should turn the worktree off")
+	}
+}
+
 // =============================================================================
 // HelpScreen Tests
 // =============================================================================
@@ -849,6 +888,10 @@ func TestAgentItem(t *testing.T) {
 	if !strings.Contains(titleWithChildren, "[3]") {
 		t.Errorf("Title with children should contain [3], got %q", titleWithChildren)
 	}
+	restarted := AgentItem{Agent: mockAg, ChildCount: 3, Restarts: 2}
+	if got := restarted.Title(); got != "test-agent [3] ↻2" {
+		t.Errorf("Title with restarts = %q, want test-agent [3] ↻2", got)
+	}
 }
 
 // =============================================================================
@@ -903,3 +946,394 @@ func TestStatusIndicator(t *testing.T) {
 		})
 	}
 }
+
+// =============================================================================
+// BatchView Tests
+// =============================================================================
+
+// doneSpawner spawns agents that have already completed
+type doneSpawner struct {
+	mu     sync.Mutex
+	agents map[string]agent.Agent
+}
+
+func (s *doneSpawner) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := &mockAgent{id: config.Name + "-id", name: config.Name, status: agent.StatusCompleted}
+	s.agents[a.id] = a
+	return a, nil
+}
+
+func (s *doneSpawner) Resolve(id string) (agent.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[id]
+	return a, ok
+}
+
+func (s *doneSpawner) Terminate(id string) error { return nil }
+
+func TestBatchViewSubmit(t *testing.T) {
+	v := NewBatchView(DefaultDarkTheme())
+	v.SetSize(80, 24)
+	v.Show()
+
+	for _, r := range "tasks.yaml" {
+		v, _ = v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	_, cmd := v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter should submit the manifest")
+	}
+	if msg, ok := cmd().(BatchSubmitMsg); !ok || msg.Path != "tasks.yaml" {
+		t.Errorf("enter produced %v, want BatchSubmitMsg for tasks.yaml", cmd())
+	}
+
+	v.SetError(errors.New("invalid manifest tasks.yaml: no tasks"))
+	if !strings.Contains(v.View(), "no tasks") {
+		t.Error("View() should show the submit error")
+	}
+}
+
+func TestBatchViewProgress(t *testing.T) {
+	v := NewBatchView(DefaultDarkTheme())
+	v.SetSize(80, 24)
+	v.Show()
+
+	s := &doneSpawner{agents: make(map[string]agent.Agent)}
+	m := &batch.Manifest{Name: "fanout", Tasks: []batch.Task{{Name: "api", Directory: "/srv/api"}, {Name: "web", Directory: "/srv/web"}}}
+	resultPath := filepath.Join(t.TempDir(), "fanout.result.json")
+	v.SetBatch(batch.Submit(context.Background(), s, m), resultPath)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		v.Refresh()
+		if _, err := os.Stat(resultPath); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	view := v.View()
+	for _, want := range []string{"Batch: fanout", "api", "web", "2 completed, 0 errored, 0 cancelled of 2", "Result written to"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q:\n%s", want, view)
+		}
+	}
+
+	v, cmd := v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if cmd == nil || !strings.Contains(v.View(), "Run Batch") {
+		t.Error("n should start a new batch once done")
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if v.IsVisible() {
+		t.Error("esc should close the batch view")
+	}
+}
+
+// =============================================================================
+// WorkflowView Tests
+// =============================================================================
+
+func TestWorkflowViewSubmit(t *testing.T) {
+	v := NewWorkflowView(DefaultDarkTheme())
+	v.SetSize(80, 24)
+	v.Show()
+
+	for _, r := range "feature.yaml" {
+		v, _ = v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	_, cmd := v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter should submit the workflow")
+	}
+	if msg, ok := cmd().(WorkflowSubmitMsg); !ok || msg.Path != "feature.yaml" {
+		t.Errorf("enter produced %v, want WorkflowSubmitMsg for feature.yaml", cmd())
+	}
+
+	v.SetError(errors.New("invalid workflow feature.yaml: dependency cycle between steps a, b"))
+	if !strings.Contains(v.View(), "dependency cycle") {
+		t.Error("View() should show the submit error")
+	}
+}
+
+func TestWorkflowViewGraph(t *testing.T) {
+	v := NewWorkflowView(DefaultDarkTheme())
+	v.SetSize(100, 30)
+	v.Show()
+
+	def := &workflow.Definition{
+		Name:      "feature",
+		Directory: "/srv/app",
+		Steps: []workflow.Step{
+			{ID: "plan"},
+			{ID: "build", DependsOn: []string{"plan"}},
+		},
+	}
+	if err := def.Validate("/"); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	run := workflow.Start(context.Background(), &doneSpawner{agents: make(map[string]agent.Agent)}, def)
+	v.SetRun(run)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !run.Done() && time.Now().Before(deadline) {
+		v.Refresh()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	view := v.View()
+	for _, want := range []string{"Workflow: feature", "completed", "plan", "build", "→"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q:\n%s", want, view)
+		}
+	}
+
+	v, _ = v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if !strings.Contains(v.View(), "after: plan") {
+		t.Error("j should select the next step and show its dependencies")
+	}
+
+	v, cmd := v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if cmd == nil || !strings.Contains(v.View(), "Run Workflow") {
+		t.Error("n should start a new workflow once done")
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if v.IsVisible() {
+		t.Error("esc should close the workflow view")
+	}
+}
+
+// =============================================================================
+// WorktreeView Tests
+// =============================================================================
+
+// newWorktreeAgent spawns a mock agent in a new worktree of a temp repository
+func newWorktreeAgent(t *testing.T) (*agent.MockAgent, string) {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "--quiet"}, {"commit", "--quiet", "--allow-empty", "--message", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	_, dir, err := worktree.Create(repo, t.TempDir(), "fix")
+	if err != nil {
+		t.Fatalf("worktree.Create() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fix.txt"), []byte("fixed\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	a := agent.NewMockAgent("ses_fix", "fix")
+	a.MockDirectory = dir
+	a.MockStatus = agent.StatusRunning
+	return a, repo
+}
+
+func TestWorktreeViewNotWorktree(t *testing.T) {
+	v := NewWorktreeView(DefaultDarkTheme())
+	v.SetSize(100, 40)
+
+	a := agent.NewMockAgent("ses_plain", "plain")
+	a.MockDirectory = t.TempDir()
+	v.Show(a)
+	if !v.IsVisible() || !strings.Contains(v.View(), "does not run in its own worktree") {
+		t.Errorf("view = %q", v.View())
+	}
+
+	v.Show(nil)
+	if !strings.Contains(v.View(), "no agent selected") {
+		t.Errorf("view = %q", v.View())
+	}
+}
+
+func TestWorktreeViewMerge(t *testing.T) {
+	a, repo := newWorktreeAgent(t)
+	v := NewWorktreeView(DefaultDarkTheme())
+	v.SetSize(100, 40)
+	v.Show(a)
+
+	view := v.View()
+	for _, want := range []string{"auto/fix-", "fix.txt", "+fixed"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	if !strings.Contains(v.View(), "still running") {
+		t.Error("merge should be refused while the agent runs")
+	}
+
+	a.Pause()
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	if !strings.Contains(v.View(), "is paused") {
+		t.Error("merge should be refused while the agent is paused")
+	}
+
+	a.Resume()
+	a.MockStatus = agent.StatusCompleted
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	if !strings.Contains(v.View(), "Merged auto/fix-") {
+		t.Errorf("view after merge = %q", v.View())
+	}
+	if _, err := os.Stat(filepath.Join(repo, "fix.txt")); err != nil {
+		t.Errorf("merged file missing from the repository: %v", err)
+	}
+}
+
+func TestWorktreeViewDiscard(t *testing.T) {
+	a, repo := newWorktreeAgent(t)
+	a.MockStatus = agent.StatusIdle
+	v := NewWorktreeView(DefaultDarkTheme())
+	v.SetSize(100, 40)
+	v.Show(a)
+
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	if !strings.Contains(v.View(), "(y/n)") {
+		t.Fatal("discard should ask for confirmation")
+	}
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if _, err := os.Stat(a.MockDirectory); err != nil {
+		t.Fatal("declining should keep the worktree")
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if _, err := os.Stat(a.MockDirectory); !os.IsNotExist(err) {
+		t.Error("confirming should remove the worktree")
+	}
+	if _, err := os.Stat(filepath.Join(repo, "fix.txt")); !os.IsNotExist(err) {
+		t.Error("discarded changes should not reach the repository")
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if v.IsVisible() {
+		t.Error("esc should close the worktree view")
+	}
+}
+
+// =============================================================================
+// FilesView Tests
+// =============================================================================
+
+func TestFilesView(t *testing.T) {
+	v := NewFilesView(DefaultDarkTheme())
+	v.SetSize(100, 40)
+	v.Show()
+	if !v.IsVisible() || !strings.Contains(v.View(), "Loading") {
+		t.Errorf("view = %q", v.View())
+	}
+
+	now := time.Now()
+	v.SetFiles([]conflict.File{
+		{
+			Path:     "/srv/app/main.go",
+			Conflict: true,
+			Changes: []conflict.Change{
+				{AgentID: "b", AgentName: "beta", Time: now, Source: conflict.SourceWatch},
+				{AgentID: "a", AgentName: "alpha", Time: now.Add(-time.Minute), Source: conflict.SourceAgent},
+			},
+		},
+		{
+			Path:    "/srv/app/util.go",
+			Changes: []conflict.Change{{AgentID: "a", AgentName: "alpha", Time: now, Source: conflict.SourceAgent}},
+		},
+	})
+
+	view := v.View()
+	for _, want := range []string{"2 files, 1 conflicts", "⚠ /srv/app/main.go", "alpha", "beta", "/srv/app/util.go"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	if view := v.View(); strings.Contains(view, "util.go") || !strings.Contains(view, "conflicts only") {
+		t.Errorf("conflicts only view = %q", view)
+	}
+
+	v.SetError(errors.New("daemon unreachable"))
+	if !strings.Contains(v.View(), "daemon unreachable") {
+		t.Error("view should show the error")
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if v.IsVisible() {
+		t.Error("esc should close the files view")
+	}
+}
+
+func TestDeliveriesView(t *testing.T) {
+	v := NewDeliveriesView(DefaultDarkTheme())
+	v.SetSize(100, 40)
+	v.Show()
+	if !v.IsVisible() || !strings.Contains(v.View(), "Loading") {
+		t.Errorf("view = %q", v.View())
+	}
+
+	v.SetDeliveries(nil)
+	if !strings.Contains(v.View(), "All alerts were delivered") {
+		t.Errorf("empty view = %q", v.View())
+	}
+
+	now := time.Now()
+	v.SetDeliveries([]alert.Delivery{
+		{Channel: "slack", Title: "Agent Error", Status: "failed", Attempts: 8, LastError: "status 503", Updated: now},
+		{Channel: "discord", Title: "Agent Completed", Status: "pending", Attempts: 2, LastError: "timeout", NextAttempt: now.Add(5 * time.Minute)},
+	})
+	view := v.View()
+	for _, want := range []string{"1 failed, 1 retrying", "✗ slack", "Agent Error", "gave up after 8 attempts", "status 503", "↻ discord", "next in 4m"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	v.SetError(errors.New("daemon unreachable"))
+	if !strings.Contains(v.View(), "daemon unreachable") {
+		t.Error("view should show the error")
+	}
+	v.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if v.IsVisible() {
+		t.Error("esc should close the deliveries view")
+	}
+}
+
+func TestAlertsPanelSnooze(t *testing.T) {
+	m := alert.NewManager(&config.AlertsConfig{}, nil)
+	a := agent.NewMockAgent("ses_1", "fixer")
+	m.Snooze(alert.Snooze{AgentID: a.ID(), Name: a.Name(), Until: time.Now().Add(time.Hour)})
+	m.Send(context.Background(), &alert.Alert{Level: alert.LevelError, Title: "Agent Error", AgentID: a.ID(), Agent: a})
+
+	p := NewAlertsPanel(DefaultDarkTheme(), m, 80, 20)
+	p.Update(AlertRefreshMsg{})
+	p.Update(SnoozesLoadedMsg{Snoozes: m.Snoozes()})
+	view := p.View()
+	for _, want := range []string{"[suppressed: snoozed]", "Snoozed: fixer"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Z'}})
+	if msg, ok := cmd().(SnoozeMsg); !ok || msg.Snooze.Project != "test-project" || msg.End {
+		t.Errorf("Z = %#v, want a project snooze", cmd())
+	}
+	_, cmd = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	if msg, ok := cmd().(SnoozeMsg); !ok || msg.Snooze.AgentID != a.ID() || !msg.End {
+		t.Errorf("u = %#v, want the agent's snooze ended", cmd())
+	}
+}
@@ -0,0 +1,200 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/CastAIPhil/AUTO/internal/store"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DeliveriesView shows alerts that could not be sent to webhooks or email:
+// those out of attempts and those waiting to be retried
+type DeliveriesView struct {
+	theme      *Theme
+	deliveries []alert.Delivery
+	loaded     bool
+	offset     int
+	err        error
+	visible    bool
+	width      int
+	height     int
+}
+
+// NewDeliveriesView creates a new failed deliveries view
+func NewDeliveriesView(theme *Theme) *DeliveriesView {
+	return &DeliveriesView{theme: theme}
+}
+
+// Update handles messages
+func (v *DeliveriesView) Update(msg tea.Msg) (*DeliveriesView, tea.Cmd) {
+	if !v.visible {
+		return v, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q":
+		v.Hide()
+	case "j", "down":
+		v.scroll(1)
+	case "k", "up":
+		v.scroll(-1)
+	case "pgdown", " ":
+		v.scroll(v.rows())
+	case "pgup":
+		v.scroll(-v.rows())
+	}
+	return v, nil
+}
+
+// Show shows the view; the deliveries are set once loaded
+func (v *DeliveriesView) Show() {
+	v.visible = true
+	v.loaded = false
+	v.offset = 0
+	v.err = nil
+}
+
+// SetDeliveries sets the failed and retrying deliveries
+func (v *DeliveriesView) SetDeliveries(deliveries []alert.Delivery) {
+	v.deliveries = deliveries
+	v.loaded = true
+	v.err = nil
+	v.scroll(0)
+}
+
+// SetError shows an error loading the deliveries
+func (v *DeliveriesView) SetError(err error) {
+	v.err = err
+}
+
+// lines renders two lines per delivery: the alert, then its last error
+func (v *DeliveriesView) lines() []string {
+	faint := v.theme.Base.Faint(true)
+	errored := v.theme.StatusStyle(agent.StatusErrored)
+
+	var lines []string
+	for _, d := range v.deliveries {
+		head := fmt.Sprintf("%-8s %s", d.Channel, truncate(d.Title, v.width-16))
+		var detail string
+		if d.Status == store.DeliveryFailed {
+			lines = append(lines, errored.Render("✗ "+head))
+			detail = fmt.Sprintf("gave up after %d attempts, %s", d.Attempts, formatRelativeTime(d.Updated))
+		} else {
+			lines = append(lines, "↻ "+head)
+			detail = fmt.Sprintf("%d failed attempts, next in %s", d.Attempts, formatDurationLeft(time.Until(d.NextAttempt)))
+		}
+		if d.LastError != "" {
+			detail += ": " + d.LastError
+		}
+		lines = append(lines, faint.Render("    "+truncate(detail, v.width-8)))
+	}
+	return lines
+}
+
+// scroll moves the list by n lines
+func (v *DeliveriesView) scroll(n int) {
+	v.offset += n
+	if max := len(v.lines()) - v.rows(); v.offset > max {
+		v.offset = max
+	}
+	if v.offset < 0 {
+		v.offset = 0
+	}
+}
+
+// rows is how many lines fit in the view
+func (v *DeliveriesView) rows() int {
+	rows := v.height - 8
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// View renders the failed deliveries view
+func (v *DeliveriesView) View() string {
+	if !v.visible {
+		return ""
+	}
+
+	var b strings.Builder
+	faint := v.theme.Base.Faint(true)
+
+	b.WriteString(v.theme.Title.Render("Failed Deliveries"))
+	b.WriteString("\n")
+
+	failed := 0
+	for _, d := range v.deliveries {
+		if d.Status == store.DeliveryFailed {
+			failed++
+		}
+	}
+	b.WriteString(faint.Render(fmt.Sprintf("%d failed, %d retrying", failed, len(v.deliveries)-failed)))
+	b.WriteString("\n\n")
+
+	lines := v.lines()
+	switch {
+	case !v.loaded && v.err == nil:
+		b.WriteString(faint.Render("Loading..."))
+		b.WriteString("\n")
+	case v.loaded && len(lines) == 0:
+		b.WriteString(faint.Render("All alerts were delivered"))
+		b.WriteString("\n")
+	default:
+		end := v.offset + v.rows()
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for _, line := range lines[v.offset:end] {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		if len(lines) > end-v.offset {
+			b.WriteString(faint.Render(fmt.Sprintf("  lines %d-%d of %d", v.offset+1, end, len(lines))))
+			b.WriteString("\n")
+		}
+	}
+
+	if v.err != nil {
+		b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render(v.err.Error()))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(faint.Render("j/k: scroll  esc: close"))
+	return v.theme.CommandStyle.Width(v.width).Render(b.String())
+}
+
+// Hide hides the failed deliveries view
+func (v *DeliveriesView) Hide() {
+	v.visible = false
+}
+
+// IsVisible returns whether the failed deliveries view is visible
+func (v *DeliveriesView) IsVisible() bool {
+	return v.visible
+}
+
+// SetSize sets the component size
+func (v *DeliveriesView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+}
+
+// ShowDeliveriesMsg opens the failed deliveries view
+type ShowDeliveriesMsg struct{}
+
+// DeliveriesLoadedMsg carries the deliveries for the failed deliveries view
+type DeliveriesLoadedMsg struct {
+	Deliveries []alert.Delivery
+	Err        error
+}
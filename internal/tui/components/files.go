@@ -0,0 +1,217 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/conflict"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// FilesView shows which agents changed which files and highlights files
+// changed by several agents
+type FilesView struct {
+	theme         *Theme
+	files         []conflict.File
+	loaded        bool
+	conflictsOnly bool
+	offset        int
+	err           error
+	visible       bool
+	width         int
+	height        int
+}
+
+// NewFilesView creates a new file ownership view
+func NewFilesView(theme *Theme) *FilesView {
+	return &FilesView{theme: theme}
+}
+
+// Update handles messages
+func (v *FilesView) Update(msg tea.Msg) (*FilesView, tea.Cmd) {
+	if !v.visible {
+		return v, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q":
+		v.Hide()
+	case "j", "down":
+		v.scroll(1)
+	case "k", "up":
+		v.scroll(-1)
+	case "pgdown", " ":
+		v.scroll(v.rows())
+	case "pgup":
+		v.scroll(-v.rows())
+	case "c":
+		v.conflictsOnly = !v.conflictsOnly
+		v.offset = 0
+	}
+	return v, nil
+}
+
+// Show shows the view; the files are set once loaded
+func (v *FilesView) Show() {
+	v.visible = true
+	v.loaded = false
+	v.offset = 0
+	v.err = nil
+}
+
+// SetFiles sets the changed files
+func (v *FilesView) SetFiles(files []conflict.File) {
+	v.files = files
+	v.loaded = true
+	v.err = nil
+	v.scroll(0)
+}
+
+// SetError shows an error loading the files
+func (v *FilesView) SetError(err error) {
+	v.err = err
+}
+
+// shown returns the files listed under the current filter
+func (v *FilesView) shown() []conflict.File {
+	if !v.conflictsOnly {
+		return v.files
+	}
+	var files []conflict.File
+	for _, f := range v.files {
+		if f.Conflict {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// lines renders one line per file followed by one per change
+func (v *FilesView) lines() []string {
+	faint := v.theme.Base.Faint(true)
+	errored := v.theme.StatusStyle(agent.StatusErrored)
+
+	var lines []string
+	for _, f := range v.shown() {
+		path := truncate(f.Path, v.width-8)
+		if f.Conflict {
+			lines = append(lines, errored.Render("⚠ "+path))
+		} else {
+			lines = append(lines, "  "+path)
+		}
+		for _, c := range f.Changes {
+			line := fmt.Sprintf("    %-24s %-10s %s", truncate(c.AgentName, 24), formatRelativeTime(c.Time), c.Source)
+			lines = append(lines, faint.Render(line))
+		}
+	}
+	return lines
+}
+
+// scroll moves the list by n lines
+func (v *FilesView) scroll(n int) {
+	v.offset += n
+	if max := len(v.lines()) - v.rows(); v.offset > max {
+		v.offset = max
+	}
+	if v.offset < 0 {
+		v.offset = 0
+	}
+}
+
+// rows is how many lines fit in the view
+func (v *FilesView) rows() int {
+	rows := v.height - 8
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// View renders the file ownership view
+func (v *FilesView) View() string {
+	if !v.visible {
+		return ""
+	}
+
+	var b strings.Builder
+	faint := v.theme.Base.Faint(true)
+
+	b.WriteString(v.theme.Title.Render("File Ownership"))
+	b.WriteString("\n")
+
+	conflicts := 0
+	for _, f := range v.files {
+		if f.Conflict {
+			conflicts++
+		}
+	}
+	summary := fmt.Sprintf("%d files, %d conflicts", len(v.files), conflicts)
+	if v.conflictsOnly {
+		summary += " (conflicts only)"
+	}
+	b.WriteString(faint.Render(summary))
+	b.WriteString("\n\n")
+
+	lines := v.lines()
+	switch {
+	case !v.loaded && v.err == nil:
+		b.WriteString(faint.Render("Loading..."))
+		b.WriteString("\n")
+	case v.loaded && len(lines) == 0:
+		b.WriteString(faint.Render("No files changed by agents"))
+		b.WriteString("\n")
+	default:
+		end := v.offset + v.rows()
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for _, line := range lines[v.offset:end] {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		if len(lines) > end-v.offset {
+			b.WriteString(faint.Render(fmt.Sprintf("  lines %d-%d of %d", v.offset+1, end, len(lines))))
+			b.WriteString("\n")
+		}
+	}
+
+	if v.err != nil {
+		b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render(v.err.Error()))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(faint.Render("j/k: scroll  c: conflicts only  esc: close"))
+	return v.theme.CommandStyle.Width(v.width).Render(b.String())
+}
+
+// Hide hides the file ownership view
+func (v *FilesView) Hide() {
+	v.visible = false
+}
+
+// IsVisible returns whether the file ownership view is visible
+func (v *FilesView) IsVisible() bool {
+	return v.visible
+}
+
+// SetSize sets the component size
+func (v *FilesView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+}
+
+// ShowFilesMsg opens the file ownership view
+type ShowFilesMsg struct{}
+
+// FilesLoadedMsg carries the files for the file ownership view
+type FilesLoadedMsg struct {
+	Files []conflict.File
+	Err   error
+}
@@ -81,6 +81,8 @@ func (h *HelpScreen) View() string {
 				{"space", "Pause/resume agent"},
 				{"r", "Refresh"},
 				{"R", "Mark all alerts read"},
+				{"z / Z", "Snooze alert's agent / project"},
+				{"u", "End snooze of alert"},
 			},
 		},
 		{
@@ -26,6 +26,7 @@ type SpawnDialog struct {
 	cancelled    bool
 	submitted    bool
 	selectedDir  string
+	worktree     bool
 }
 
 type SpawnResult struct {
@@ -116,6 +117,9 @@ func (d *SpawnDialog) Update(msg tea.Msg) (*SpawnDialog, tea.Cmd) {
 			case "n", "N":
 				d.cancelled = true
 				return d, nil
+			case "w", "W":
+				d.worktree = !d.worktree
+				return d, nil
 			}
 		}
 	}
@@ -165,10 +169,15 @@ func (d *SpawnDialog) renderNameAndConfirm() string {
 	}
 	content += d.nameInput.View() + "\n\n"
 
-	content += labelStyle.Render("Provider: ") + d.providerType + "\n\n"
+	content += labelStyle.Render("Provider: ") + d.providerType + "\n"
+	worktree := "off"
+	if d.worktree {
+		worktree = "on (new git worktree and branch)"
+	}
+	content += labelStyle.Render("Worktree: ") + worktree + "\n\n"
 
 	if d.state == SpawnStateConfirm {
-		content += activeLabel.Render("Spawn session? (y/n)") + "\n"
+		content += activeLabel.Render("Spawn session? (y/n, w: toggle worktree)") + "\n"
 	}
 
 	helpStyle := lipgloss.NewStyle().
@@ -194,6 +203,7 @@ func (d *SpawnDialog) Result() SpawnResult {
 			Type:      d.providerType,
 			Name:      name,
 			Directory: d.selectedDir,
+			Worktree:  d.worktree,
 		},
 		Cancelled: false,
 	}
@@ -219,6 +229,7 @@ func (d *SpawnDialog) Reset() {
 	d.cancelled = false
 	d.submitted = false
 	d.selectedDir = ""
+	d.worktree = false
 	d.nameInput.SetValue("")
 	d.dirPicker.Reset("")
 }
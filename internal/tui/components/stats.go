@@ -2,6 +2,7 @@ package components
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
@@ -102,6 +103,26 @@ func (s *StatsPanel) renderContent() string {
 	b.WriteString(fmt.Sprintf("  Tool Calls: %d\n", stats.TotalToolCalls))
 	b.WriteString(fmt.Sprintf("  Errors:     %d\n", stats.TotalErrors))
 
+	if len(stats.CostByModel) > 0 {
+		b.WriteString("\n")
+		b.WriteString(s.theme.Subtitle.Render("Cost by Model"))
+		b.WriteString("\n")
+		models := make([]string, 0, len(stats.CostByModel))
+		for model := range stats.CostByModel {
+			models = append(models, model)
+		}
+		sort.Slice(models, func(i, j int) bool {
+			return stats.CostByModel[models[i]] > stats.CostByModel[models[j]]
+		})
+		for _, model := range models {
+			name := model
+			if len(name) > 20 {
+				name = name[:20] + "..."
+			}
+			b.WriteString(fmt.Sprintf("  %s: $%.2f\n", name, stats.CostByModel[model]))
+		}
+	}
+
 	if len(stats.ByType) > 1 {
 		b.WriteString("\n")
 		b.WriteString(s.theme.Subtitle.Render("By Type"))
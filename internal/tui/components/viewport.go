@@ -238,6 +238,9 @@ func (s *SessionViewport) renderHeader() string {
 	}
 
 	header := fmt.Sprintf("%s %s - %s", status, name, task)
+	if model := s.agent.Metrics().Model; model != "" {
+		header += s.theme.Base.Faint(true).Render(" [" + model + "]")
+	}
 
 	// Add streaming indicator
 	if s.isStreaming {
@@ -267,6 +270,9 @@ func (s *SessionViewport) renderFooter() string {
 		metrics.ToolCalls,
 		scrollInfo,
 	)
+	if metrics.ContextUtilization > 0 {
+		info = fmt.Sprintf("Context: %d%% | %s", int(metrics.ContextUtilization*100), info)
+	}
 
 	if s.autoScroll {
 		info += " [auto-scroll]"
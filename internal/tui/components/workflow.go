@@ -0,0 +1,274 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/workflow"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// workflowNodeWidth is the width of a step box in the graph
+const workflowNodeWidth = 18
+
+// WorkflowView asks for a workflow definition and shows its graph with the
+// status of each step
+type WorkflowView struct {
+	theme   *Theme
+	input   textinput.Model
+	run     *workflow.Run
+	cursor  int
+	err     error
+	visible bool
+	width   int
+	height  int
+}
+
+// NewWorkflowView creates a new workflow view
+func NewWorkflowView(theme *Theme) *WorkflowView {
+	ti := textinput.New()
+	ti.Placeholder = "Path to workflow (YAML or JSON)"
+	ti.CharLimit = 500
+
+	return &WorkflowView{
+		theme: theme,
+		input: ti,
+	}
+}
+
+// Update handles messages
+func (v *WorkflowView) Update(msg tea.Msg) (*WorkflowView, tea.Cmd) {
+	if !v.visible {
+		return v, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	if keyMsg.String() == "esc" {
+		v.Hide()
+		return v, nil
+	}
+
+	if v.run == nil {
+		if keyMsg.String() == "enter" {
+			path := strings.TrimSpace(v.input.Value())
+			if path == "" {
+				return v, nil
+			}
+			return v, func() tea.Msg { return WorkflowSubmitMsg{Path: path} }
+		}
+		var cmd tea.Cmd
+		v.input, cmd = v.input.Update(msg)
+		return v, cmd
+	}
+
+	switch keyMsg.String() {
+	case "j", "down":
+		if v.cursor < len(v.run.Steps())-1 {
+			v.cursor++
+		}
+	case "k", "up":
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case "x":
+		if !v.run.Done() {
+			v.run.Cancel()
+		}
+	case "n":
+		if v.run.Done() {
+			v.run = nil
+			v.err = nil
+			v.input.SetValue("")
+			return v, v.input.Focus()
+		}
+	}
+	return v, nil
+}
+
+// Refresh updates the status of the workflow's steps
+func (v *WorkflowView) Refresh() {
+	if v.run != nil {
+		v.run.Update()
+	}
+}
+
+// SetRun shows a started workflow
+func (v *WorkflowView) SetRun(run *workflow.Run) {
+	v.run = run
+	v.cursor = 0
+	v.err = nil
+	v.input.Blur()
+}
+
+// SetError shows why a workflow could not be started
+func (v *WorkflowView) SetError(err error) {
+	v.err = err
+}
+
+// View renders the workflow view
+func (v *WorkflowView) View() string {
+	if !v.visible {
+		return ""
+	}
+
+	var b strings.Builder
+	faint := v.theme.Base.Faint(true)
+
+	if v.run == nil {
+		b.WriteString(v.theme.Title.Render("Run Workflow"))
+		b.WriteString("\n\n")
+		b.WriteString(v.theme.InputStyle.Render(v.input.View()))
+		b.WriteString("\n\n")
+		if v.err != nil {
+			b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render(v.err.Error()))
+			b.WriteString("\n\n")
+		}
+		b.WriteString(faint.Render("enter: start  esc: close"))
+		return v.theme.CommandStyle.Width(v.width).Render(b.String())
+	}
+
+	steps := v.run.Steps()
+	if v.cursor >= len(steps) {
+		v.cursor = len(steps) - 1
+	}
+
+	state := "running"
+	if v.run.Done() {
+		state = "halted"
+		if v.run.Succeeded() {
+			state = "completed"
+		}
+	}
+	b.WriteString(v.theme.Title.Render("Workflow: " + v.run.Name()))
+	b.WriteString(faint.Render("  " + state))
+	b.WriteString("\n\n")
+	b.WriteString(v.renderGraph(steps))
+	b.WriteString("\n\n")
+	b.WriteString(v.renderDetails(steps[v.cursor]))
+	b.WriteString("\n")
+
+	if v.run.Done() {
+		b.WriteString(faint.Render("j/k: select step  n: new workflow  esc: close"))
+	} else {
+		b.WriteString(faint.Render("j/k: select step  x: cancel workflow  esc: close (keeps running)"))
+	}
+	return v.theme.CommandStyle.Width(v.width).Render(b.String())
+}
+
+// renderGraph draws one column per level, each step as a box coloured by status
+func (v *WorkflowView) renderGraph(steps []workflow.StepState) string {
+	var levels [][]string
+	for i, st := range steps {
+		for len(levels) <= st.Level {
+			levels = append(levels, nil)
+		}
+		levels[st.Level] = append(levels[st.Level], v.renderNode(st, i == v.cursor))
+	}
+
+	arrow := v.theme.Base.Faint(true).Render(" → ")
+	var columns []string
+	for i, nodes := range levels {
+		if i > 0 {
+			columns = append(columns, arrow)
+		}
+		columns = append(columns, lipgloss.JoinVertical(lipgloss.Left, nodes...))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Center, columns...)
+}
+
+func (v *WorkflowView) renderNode(st workflow.StepState, selected bool) string {
+	status := st.Status.AgentStatus()
+	color := v.theme.StatusStyle(status).GetForeground()
+
+	label := fmt.Sprintf("%s %s", status.Icon(), truncate(st.ID, workflowNodeWidth-6))
+	detail := string(st.Status)
+	if st.Attempts > 1 {
+		detail += fmt.Sprintf(" #%d", st.Attempts)
+	}
+
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color).
+		Foreground(color).
+		Padding(0, 1).
+		Width(workflowNodeWidth)
+	if selected {
+		style = style.Border(lipgloss.ThickBorder()).Bold(true)
+	}
+	return style.Render(label + "\n" + detail)
+}
+
+// renderDetails describes the selected step and the tail of its output
+func (v *WorkflowView) renderDetails(st workflow.StepState) string {
+	var b strings.Builder
+	faint := v.theme.Base.Faint(true)
+
+	b.WriteString(v.theme.StatusStyle(st.Status.AgentStatus()).Render(st.Name))
+	b.WriteString(faint.Render(fmt.Sprintf("  %s, attempt %d", st.Status, st.Attempts)))
+	b.WriteString("\n")
+	if len(st.DependsOn) > 0 {
+		b.WriteString(faint.Render("after: " + strings.Join(st.DependsOn, ", ")))
+		b.WriteString("\n")
+	}
+	if st.AgentID != "" {
+		b.WriteString(faint.Render("agent: " + st.AgentID))
+		b.WriteString("\n")
+	}
+	if st.Error != "" {
+		b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render(truncate(st.Error, v.width-4)))
+		b.WriteString("\n")
+	}
+	if st.Output != "" {
+		lines := strings.Split(st.Output, "\n")
+		if len(lines) > 5 {
+			lines = lines[len(lines)-5:]
+		}
+		for _, line := range lines {
+			b.WriteString(truncate(line, v.width-4))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Show shows the workflow view
+func (v *WorkflowView) Show() tea.Cmd {
+	v.visible = true
+	if v.run == nil {
+		return v.input.Focus()
+	}
+	return nil
+}
+
+// Hide hides the workflow view; a running workflow keeps being tracked
+func (v *WorkflowView) Hide() {
+	v.visible = false
+	v.input.Blur()
+}
+
+// IsVisible returns whether the workflow view is visible
+func (v *WorkflowView) IsVisible() bool {
+	return v.visible
+}
+
+// SetSize sets the component size
+func (v *WorkflowView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+	v.input.Width = width - 8
+}
+
+// RunWorkflowMsg opens the workflow view
+type RunWorkflowMsg struct{}
+
+// WorkflowSubmitMsg asks for the workflow at Path to be started
+type WorkflowSubmitMsg struct {
+	Path string
+}
@@ -0,0 +1,292 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/worktree"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// WorktreeView shows the changes an agent made in its own git worktree and
+// merges or discards them
+type WorktreeView struct {
+	theme    *Theme
+	agent    agent.Agent
+	worktree *worktree.Worktree
+	stat     string
+	diff     []string
+	offset   int
+	confirm  bool // discard asked for, waiting for y
+	notice   string
+	err      error
+	visible  bool
+	width    int
+	height   int
+}
+
+// NewWorktreeView creates a new worktree view
+func NewWorktreeView(theme *Theme) *WorktreeView {
+	return &WorktreeView{theme: theme}
+}
+
+// Update handles messages
+func (v *WorktreeView) Update(msg tea.Msg) (*WorktreeView, tea.Cmd) {
+	if !v.visible {
+		return v, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	if v.confirm {
+		v.confirm = false
+		if keyMsg.String() == "y" || keyMsg.String() == "Y" {
+			v.discard()
+		}
+		return v, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q":
+		v.Hide()
+	case "j", "down":
+		v.scroll(1)
+	case "k", "up":
+		v.scroll(-1)
+	case "pgdown", " ":
+		v.scroll(v.diffRows())
+	case "pgup":
+		v.scroll(-v.diffRows())
+	case "r":
+		v.load()
+	case "m":
+		v.merge()
+	case "d":
+		if v.ready() {
+			v.confirm = true
+		}
+	}
+	return v, nil
+}
+
+// Show shows the worktree of an agent
+func (v *WorktreeView) Show(a agent.Agent) {
+	v.visible = true
+	v.agent = a
+	v.worktree = nil
+	v.notice = ""
+	v.err = nil
+	v.confirm = false
+	if a == nil {
+		v.err = errors.New("no agent selected")
+		return
+	}
+
+	w, err := worktree.Open(a.Directory())
+	if errors.Is(err, worktree.ErrNotWorktree) {
+		err = fmt.Errorf("%s does not run in its own worktree; spawn it with the worktree option", a.Name())
+	}
+	if err != nil {
+		v.err = err
+		return
+	}
+	v.worktree = w
+	v.load()
+}
+
+// load reads the diff of the worktree against its base
+func (v *WorktreeView) load() {
+	if v.worktree == nil {
+		return
+	}
+	v.offset = 0
+	v.stat, v.err = v.worktree.DiffStat()
+	if v.err != nil {
+		return
+	}
+	diff, err := v.worktree.Diff()
+	if err != nil {
+		v.err = err
+		return
+	}
+	v.diff = strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	if diff == "" {
+		v.diff = nil
+	}
+}
+
+// ready reports whether the agent has stopped working in its worktree, and
+// explains why not otherwise
+func (v *WorktreeView) ready() bool {
+	if v.worktree == nil {
+		return false
+	}
+	if status := v.agent.Status(); status == agent.StatusPending || status == agent.StatusRunning {
+		v.err = fmt.Errorf("%s is still running; stop it first", v.agent.Name())
+		return false
+	}
+	if agent.IsPaused(v.agent) {
+		v.err = fmt.Errorf("%s is paused; stop it first", v.agent.Name())
+		return false
+	}
+	v.err = nil
+	return true
+}
+
+// merge commits the agent's changes and merges its branch
+func (v *WorktreeView) merge() {
+	if !v.ready() {
+		return
+	}
+	message := fmt.Sprintf("Changes by agent %s (%s)", v.agent.Name(), v.agent.ID())
+	if err := v.worktree.Merge(message); err != nil {
+		v.err = err
+		return
+	}
+	v.notice = fmt.Sprintf("Merged %s into %s", v.worktree.Branch, v.worktree.Repo)
+	v.worktree = nil
+}
+
+// discard removes the worktree and its branch
+func (v *WorktreeView) discard() {
+	if !v.ready() {
+		return
+	}
+	if err := v.worktree.Remove(); err != nil {
+		v.err = err
+		return
+	}
+	v.notice = "Discarded " + v.worktree.Branch
+	v.worktree = nil
+}
+
+// scroll moves the diff by n lines
+func (v *WorktreeView) scroll(n int) {
+	v.offset += n
+	if max := len(v.diff) - v.diffRows(); v.offset > max {
+		v.offset = max
+	}
+	if v.offset < 0 {
+		v.offset = 0
+	}
+}
+
+// diffRows is how many diff lines fit in the view
+func (v *WorktreeView) diffRows() int {
+	rows := v.height - 10 - strings.Count(v.stat, "\n")
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// View renders the worktree view
+func (v *WorktreeView) View() string {
+	if !v.visible {
+		return ""
+	}
+
+	var b strings.Builder
+	faint := v.theme.Base.Faint(true)
+
+	title := "Worktree"
+	if v.agent != nil {
+		title += ": " + v.agent.Name()
+	}
+	b.WriteString(v.theme.Title.Render(title))
+	b.WriteString("\n\n")
+
+	if v.worktree != nil {
+		b.WriteString(fmt.Sprintf("Branch: %s  (base %s)\n", v.worktree.Branch, shortSHA(v.worktree.Base)))
+		b.WriteString(faint.Render("Path:   " + v.worktree.Path))
+		b.WriteString("\n\n")
+
+		if len(v.diff) == 0 {
+			b.WriteString(faint.Render("No changes"))
+			b.WriteString("\n")
+		} else {
+			b.WriteString(v.stat)
+			b.WriteString("\n\n")
+			end := v.offset + v.diffRows()
+			if end > len(v.diff) {
+				end = len(v.diff)
+			}
+			for _, line := range v.diff[v.offset:end] {
+				b.WriteString(v.renderDiffLine(truncate(line, v.width-4)))
+				b.WriteString("\n")
+			}
+			if len(v.diff) > end-v.offset {
+				b.WriteString(faint.Render(fmt.Sprintf("  lines %d-%d of %d", v.offset+1, end, len(v.diff))))
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	if v.notice != "" {
+		b.WriteString(v.theme.StatusStyle(agent.StatusCompleted).Render(v.notice))
+		b.WriteString("\n")
+	}
+	if v.err != nil {
+		b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render(v.err.Error()))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case v.confirm:
+		b.WriteString(v.theme.StatusStyle(agent.StatusErrored).Render("Discard every change and remove the worktree? (y/n)"))
+	case v.worktree != nil:
+		b.WriteString(faint.Render("j/k: scroll  m: merge  d: discard  r: reload  esc: close"))
+	default:
+		b.WriteString(faint.Render("esc: close"))
+	}
+	return v.theme.CommandStyle.Width(v.width).Render(b.String())
+}
+
+// renderDiffLine colours added and removed lines
+func (v *WorktreeView) renderDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return v.theme.Base.Bold(true).Render(line)
+	case strings.HasPrefix(line, "+"):
+		return v.theme.StatusStyle(agent.StatusCompleted).Render(line)
+	case strings.HasPrefix(line, "-"):
+		return v.theme.StatusStyle(agent.StatusErrored).Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return v.theme.Base.Faint(true).Render(line)
+	}
+	return line
+}
+
+// shortSHA abbreviates a commit hash
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// Hide hides the worktree view
+func (v *WorktreeView) Hide() {
+	v.visible = false
+	v.confirm = false
+}
+
+// IsVisible returns whether the worktree view is visible
+func (v *WorktreeView) IsVisible() bool {
+	return v.visible
+}
+
+// SetSize sets the component size
+func (v *WorktreeView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+}
+
+// ShowWorktreeMsg opens the worktree view for the selected agent
+type ShowWorktreeMsg struct{}
@@ -0,0 +1,350 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// StepStatus is the state of a step in a run
+type StepStatus string
+
+const (
+	StepWaiting   StepStatus = "waiting"
+	StepStarting  StepStatus = "starting"
+	StepRunning   StepStatus = "running"
+	StepCompleted StepStatus = "completed"
+	StepErrored   StepStatus = "errored"
+	StepSkipped   StepStatus = "skipped"
+	StepCancelled StepStatus = "cancelled"
+)
+
+// Finished reports whether the step will not change any more
+func (s StepStatus) Finished() bool {
+	switch s {
+	case StepCompleted, StepErrored, StepSkipped, StepCancelled:
+		return true
+	}
+	return false
+}
+
+// AgentStatus returns the agent status a step status is shown as
+func (s StepStatus) AgentStatus() agent.Status {
+	switch s {
+	case StepRunning:
+		return agent.StatusRunning
+	case StepCompleted:
+		return agent.StatusCompleted
+	case StepErrored:
+		return agent.StatusErrored
+	case StepSkipped, StepCancelled:
+		return agent.StatusCancelled
+	default:
+		return agent.StatusPending
+	}
+}
+
+// Spawner starts and finds agents; session.Manager implements it
+type Spawner interface {
+	Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error)
+	Resolve(id string) (agent.Agent, bool)
+	Terminate(id string) error
+}
+
+// StepState is the progress of a step
+type StepState struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	DependsOn  []string   `json:"depends_on,omitempty"`
+	Level      int        `json:"level"`
+	Status     StepStatus `json:"status"`
+	AgentID    string     `json:"agent_id,omitempty"`
+	Attempts   int        `json:"attempts"`
+	Output     string     `json:"output,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at,omitempty"`
+	FinishedAt time.Time  `json:"finished_at,omitempty"`
+
+	step *Step
+}
+
+// Run executes a workflow. Steps are spawned once every step they depend on
+// has completed; an errored step is retried up to its Retries and otherwise
+// halts the workflow, skipping the steps that have not started.
+type Run struct {
+	def     *Definition
+	spawner Spawner
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu         sync.Mutex
+	steps      []*StepState
+	byID       map[string]*StepState
+	halted     bool
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// Start begins running a validated workflow
+func Start(ctx context.Context, spawner Spawner, def *Definition) *Run {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Run{
+		def:       def,
+		spawner:   spawner,
+		ctx:       ctx,
+		cancel:    cancel,
+		byID:      make(map[string]*StepState, len(def.Steps)),
+		startedAt: time.Now(),
+	}
+	for i := range def.Steps {
+		s := &def.Steps[i]
+		st := &StepState{
+			ID:        s.ID,
+			Name:      s.Name,
+			DependsOn: s.DependsOn,
+			Level:     s.level,
+			Status:    StepWaiting,
+			step:      s,
+		}
+		r.steps = append(r.steps, st)
+		r.byID[s.ID] = st
+	}
+
+	r.mu.Lock()
+	r.scheduleLocked()
+	r.mu.Unlock()
+	return r
+}
+
+// Name returns the workflow name
+func (r *Run) Name() string {
+	return r.def.Name
+}
+
+// scheduleLocked spawns the waiting steps whose dependencies have completed
+func (r *Run) scheduleLocked() {
+	if r.halted {
+		return
+	}
+	for _, st := range r.steps {
+		if st.Status != StepWaiting || !r.readyLocked(st) {
+			continue
+		}
+
+		prompt, err := st.step.render(r.outputLocked)
+		if err != nil {
+			st.Attempts = st.step.Retries + 1 // a template error won't go away on retry
+			r.failLocked(st, fmt.Sprintf("prompt: %v", err))
+			continue
+		}
+
+		st.Status = StepStarting
+		st.Attempts++
+		st.AgentID = ""
+		if st.StartedAt.IsZero() {
+			st.StartedAt = time.Now()
+		}
+		go r.spawn(st, st.step.SpawnConfig(prompt))
+	}
+}
+
+func (r *Run) readyLocked(st *StepState) bool {
+	for _, dep := range st.DependsOn {
+		if r.byID[dep].Status != StepCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// outputLocked is the template function giving a completed step's output
+func (r *Run) outputLocked(id string) (string, error) {
+	st, ok := r.byID[id]
+	if !ok {
+		return "", fmt.Errorf("unknown step %q", id)
+	}
+	if st.Status != StepCompleted {
+		return "", fmt.Errorf("step %q has not completed", id)
+	}
+	return st.Output, nil
+}
+
+func (r *Run) spawn(st *StepState, config agent.SpawnConfig) {
+	a, err := r.spawner.Spawn(r.ctx, config)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if st.Status != StepStarting {
+		// Cancelled while spawning; stop the agent if it started anyway
+		if err == nil {
+			st.AgentID = a.ID()
+			go r.spawner.Terminate(a.ID())
+		}
+		return
+	}
+	if err != nil {
+		r.failLocked(st, err.Error())
+		r.scheduleLocked()
+		return
+	}
+	st.AgentID = a.ID()
+	st.Status = StepRunning
+	r.checkLocked(st, a)
+	r.scheduleLocked()
+}
+
+// Update refreshes the running steps and starts the steps that became ready
+func (r *Run) Update() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, st := range r.steps {
+		if st.Status != StepRunning {
+			continue
+		}
+		a, ok := r.spawner.Resolve(st.AgentID)
+		if !ok {
+			r.endLocked(st, StepCancelled, "agent went away")
+			r.haltLocked()
+			continue
+		}
+		st.AgentID = a.ID()
+		r.checkLocked(st, a)
+	}
+	r.scheduleLocked()
+}
+
+// checkLocked moves a running step on once its agent has finished. Idle
+// counts as completed, since opencode sessions go idle once their prompt
+// has run, unless the agent is only paused.
+func (r *Run) checkLocked(st *StepState, a agent.Agent) {
+	status := a.Status()
+	if status == agent.StatusIdle && agent.IsPaused(a) {
+		return
+	}
+	switch status {
+	case agent.StatusIdle, agent.StatusCompleted:
+		st.Output = strings.TrimSpace(readOutput(a))
+		r.endLocked(st, StepCompleted, "")
+	case agent.StatusErrored, agent.StatusContextLimit:
+		msg := a.Status().String()
+		if err := a.LastError(); err != nil {
+			msg = err.Error()
+		}
+		r.failLocked(st, msg)
+	case agent.StatusCancelled:
+		r.endLocked(st, StepCancelled, "")
+		r.haltLocked()
+	}
+}
+
+// failLocked retries an errored step, or halts the workflow when it is out of retries
+func (r *Run) failLocked(st *StepState, msg string) {
+	st.Error = msg
+	if st.Attempts <= st.step.Retries && !r.halted {
+		st.Status = StepWaiting
+		return
+	}
+	r.endLocked(st, StepErrored, msg)
+	r.haltLocked()
+}
+
+func (r *Run) endLocked(st *StepState, status StepStatus, msg string) {
+	st.Status = status
+	st.Error = msg
+	st.FinishedAt = time.Now()
+	r.checkDoneLocked()
+}
+
+// haltLocked stops scheduling; steps already running are left to finish
+func (r *Run) haltLocked() {
+	r.halted = true
+	for _, st := range r.steps {
+		if st.Status == StepWaiting {
+			st.Status = StepSkipped
+		}
+	}
+	r.checkDoneLocked()
+}
+
+func (r *Run) checkDoneLocked() {
+	if !r.finishedAt.IsZero() {
+		return
+	}
+	for _, st := range r.steps {
+		if !st.Status.Finished() {
+			return
+		}
+	}
+	r.finishedAt = time.Now()
+	r.cancel()
+}
+
+// Cancel stops the workflow, terminating the agents of unfinished steps
+func (r *Run) Cancel() {
+	r.mu.Lock()
+	r.halted = true
+	var ids []string
+	for _, st := range r.steps {
+		if st.Status.Finished() {
+			continue
+		}
+		if st.Status == StepRunning {
+			ids = append(ids, st.AgentID)
+		}
+		r.endLocked(st, StepCancelled, "")
+	}
+	r.mu.Unlock()
+
+	for _, id := range ids {
+		r.spawner.Terminate(id)
+	}
+}
+
+// Done reports whether every step has finished
+func (r *Run) Done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.finishedAt.IsZero()
+}
+
+// Succeeded reports whether every step completed
+func (r *Run) Succeeded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, st := range r.steps {
+		if st.Status != StepCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// Steps returns a snapshot of the steps in definition order
+func (r *Run) Steps() []StepState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	steps := make([]StepState, len(r.steps))
+	for i, st := range r.steps {
+		steps[i] = *st
+		steps[i].step = nil
+	}
+	return steps
+}
+
+// readOutput returns an agent's output as a string
+func readOutput(a agent.Agent) string {
+	out := a.Output()
+	if out == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(out)
+	return string(data)
+}
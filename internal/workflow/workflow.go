@@ -0,0 +1,215 @@
+// Package workflow runs agents as steps of a dependency graph, feeding the
+// output of earlier steps into the prompts of later ones
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultType is the agent type used when neither a step nor the workflow names one
+const DefaultType = "opencode"
+
+// Definition is a workflow: steps and the steps each depends on
+type Definition struct {
+	Name      string `yaml:"name" json:"name"`
+	Directory string `yaml:"directory" json:"directory"`
+	Type      string `yaml:"type" json:"type"`
+	Steps     []Step `yaml:"steps" json:"steps"`
+}
+
+// Step is one agent in a workflow. Its prompt is a text/template in which
+// {{ output "id" }} is the final output of a completed step.
+type Step struct {
+	ID        string            `yaml:"id" json:"id"`
+	Name      string            `yaml:"name" json:"name,omitempty"`
+	Type      string            `yaml:"type" json:"type,omitempty"`
+	Directory string            `yaml:"directory" json:"directory,omitempty"`
+	Prompt    string            `yaml:"prompt" json:"prompt"`
+	Env       map[string]string `yaml:"env" json:"env,omitempty"`
+	DependsOn []string          `yaml:"depends_on" json:"depends_on,omitempty"`
+	Retries   int               `yaml:"retries" json:"retries,omitempty"` // times an errored step is respawned before the workflow halts
+
+	level int // depth in the graph: 0 for steps without dependencies
+}
+
+// Load reads a YAML or JSON workflow definition
+func Load(path string) (*Definition, error) {
+	path = expandHome(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var d Definition
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&d)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&d)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %w", path, err)
+	}
+
+	if d.Name == "" {
+		d.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := d.Validate(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// Validate applies the workflow defaults to each step, resolving relative
+// directories against base, and checks that the steps form a DAG
+func (d *Definition) Validate(base string) error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+
+	index := make(map[string]int, len(d.Steps))
+	for i := range d.Steps {
+		s := &d.Steps[i]
+		if s.ID == "" {
+			return fmt.Errorf("step %d: id is required", i+1)
+		}
+		if _, ok := index[s.ID]; ok {
+			return fmt.Errorf("duplicate step %q", s.ID)
+		}
+		index[s.ID] = i
+
+		if s.Name == "" {
+			s.Name = s.ID
+		}
+		if s.Type == "" {
+			s.Type = d.Type
+		}
+		if s.Type == "" {
+			s.Type = DefaultType
+		}
+		if s.Directory == "" {
+			s.Directory = d.Directory
+		}
+		if s.Directory == "" {
+			return fmt.Errorf("step %q: directory is required", s.ID)
+		}
+		s.Directory = expandHome(s.Directory)
+		if !filepath.IsAbs(s.Directory) {
+			s.Directory = filepath.Join(base, s.Directory)
+		}
+		s.Directory = filepath.Clean(s.Directory)
+		if s.Retries < 0 {
+			return fmt.Errorf("step %q: retries must not be negative", s.ID)
+		}
+		if _, err := parsePrompt(s, nil); err != nil {
+			return fmt.Errorf("step %q: %w", s.ID, err)
+		}
+	}
+
+	for _, s := range d.Steps {
+		for _, dep := range s.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("step %q depends on unknown step %q", s.ID, dep)
+			}
+			if dep == s.ID {
+				return fmt.Errorf("step %q depends on itself", s.ID)
+			}
+		}
+	}
+
+	// Assign levels in topological order; steps left over are in a cycle
+	placed := 0
+	done := make([]bool, len(d.Steps))
+	for placed < len(d.Steps) {
+		progress := false
+		for i := range d.Steps {
+			s := &d.Steps[i]
+			if done[i] {
+				continue
+			}
+			level, ready := 0, true
+			for _, dep := range s.DependsOn {
+				j := index[dep]
+				if !done[j] {
+					ready = false
+					break
+				}
+				if d.Steps[j].level+1 > level {
+					level = d.Steps[j].level + 1
+				}
+			}
+			if ready {
+				s.level = level
+				done[i] = true
+				placed++
+				progress = true
+			}
+		}
+		if !progress {
+			var cycle []string
+			for i, s := range d.Steps {
+				if !done[i] {
+					cycle = append(cycle, s.ID)
+				}
+			}
+			return fmt.Errorf("dependency cycle between steps %s", strings.Join(cycle, ", "))
+		}
+	}
+	return nil
+}
+
+// parsePrompt parses a step's prompt template. With a nil output function
+// it only checks the syntax.
+func parsePrompt(s *Step, output func(id string) (string, error)) (*template.Template, error) {
+	if output == nil {
+		output = func(string) (string, error) { return "", nil }
+	}
+	return template.New(s.ID).Funcs(template.FuncMap{"output": output}).Option("missingkey=error").Parse(s.Prompt)
+}
+
+// render fills in a step's prompt from the outputs of earlier steps
+func (s *Step) render(output func(id string) (string, error)) (string, error) {
+	tmpl, err := parsePrompt(s, output)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, nil); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// SpawnConfig returns the spawn request for the step with a rendered prompt
+func (s *Step) SpawnConfig(prompt string) agent.SpawnConfig {
+	return agent.SpawnConfig{
+		Type:      s.Type,
+		Name:      s.Name,
+		Directory: s.Directory,
+		Prompt:    prompt,
+		Env:       s.Env,
+	}
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
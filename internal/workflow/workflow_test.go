@@ -0,0 +1,317 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+)
+
+// fakeSpawner spawns running mock agents whose output names the step
+type fakeSpawner struct {
+	mu         sync.Mutex
+	agents     map[string]*agent.MockAgent
+	prompts    map[string][]string // step name -> prompts it was spawned with
+	failSpawn  map[string]bool
+	terminated []string
+}
+
+func newFakeSpawner() *fakeSpawner {
+	return &fakeSpawner{
+		agents:    make(map[string]*agent.MockAgent),
+		prompts:   make(map[string][]string),
+		failSpawn: make(map[string]bool),
+	}
+}
+
+func (s *fakeSpawner) Spawn(ctx context.Context, config agent.SpawnConfig) (agent.Agent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompts[config.Name] = append(s.prompts[config.Name], config.Prompt)
+	if s.failSpawn[config.Name] {
+		return nil, errors.New("spawn failed")
+	}
+	id := config.Name + "-" + string(rune('0'+len(s.prompts[config.Name])))
+	a := agent.NewMockAgent(id, config.Name)
+	a.MockOutput = []byte("output of " + config.Name + "\n")
+	s.agents[id] = a
+	return a, nil
+}
+
+func (s *fakeSpawner) Resolve(id string) (agent.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[id]
+	return a, ok
+}
+
+func (s *fakeSpawner) Terminate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminated = append(s.terminated, id)
+	return nil
+}
+
+func (s *fakeSpawner) setStatus(id string, status agent.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents[id].MockStatus = status
+}
+
+func (s *fakeSpawner) pause(id string, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if paused {
+		s.agents[id].Pause()
+	} else {
+		s.agents[id].Resume()
+	}
+}
+
+func (s *fakeSpawner) promptsOf(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts[name]...)
+}
+
+// waitFor updates the run until cond holds
+func waitFor(t *testing.T, r *Run, what string, cond func(map[string]StepState) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r.Update()
+		steps := make(map[string]StepState)
+		for _, st := range r.Steps() {
+			steps[st.ID] = st
+		}
+		if cond(steps) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s: %+v", what, steps)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func statusIs(id string, status StepStatus) func(map[string]StepState) bool {
+	return func(steps map[string]StepState) bool { return steps[id].Status == status }
+}
+
+func pipeline(retries int) *Definition {
+	d := &Definition{
+		Name:      "feature",
+		Directory: "/srv/app",
+		Type:      "mock",
+		Steps: []Step{
+			{ID: "plan", Prompt: "Plan the feature"},
+			{ID: "api", Prompt: "Implement the API: {{ output \"plan\" }}", DependsOn: []string{"plan"}, Retries: retries},
+			{ID: "web", Prompt: "Implement the UI: {{ output \"plan\" }}", DependsOn: []string{"plan"}},
+			{ID: "review", Prompt: "Review {{ output \"api\" }} and {{ output \"web\" }}", DependsOn: []string{"api", "web"}},
+		},
+	}
+	if err := d.Validate("/"); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feature.yaml")
+	content := `
+directory: app
+type: process
+steps:
+  - id: plan
+    prompt: Plan it
+  - id: build
+    name: Build
+    directory: /srv/other
+    prompt: "Build {{ output \"plan\" }}"
+    depends_on: [plan]
+    retries: 2
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if d.Name != "feature" {
+		t.Errorf("Name = %q, want the file name", d.Name)
+	}
+	plan, build := d.Steps[0], d.Steps[1]
+	if plan.Name != "plan" || plan.Type != "process" || plan.Directory != filepath.Join(filepath.Dir(path), "app") || plan.level != 0 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if build.Name != "Build" || build.Directory != "/srv/other" || build.Retries != 2 || build.level != 1 {
+		t.Errorf("build = %+v", build)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []Step
+		want  string
+	}{
+		{"no steps", nil, "no steps"},
+		{"missing id", []Step{{Prompt: "x"}}, "step 1: id is required"},
+		{"duplicate", []Step{{ID: "a"}, {ID: "a"}}, `duplicate step "a"`},
+		{"unknown dependency", []Step{{ID: "a", DependsOn: []string{"b"}}}, `depends on unknown step "b"`},
+		{"self dependency", []Step{{ID: "a", DependsOn: []string{"a"}}}, "depends on itself"},
+		{"cycle", []Step{{ID: "a"}, {ID: "b", DependsOn: []string{"c"}}, {ID: "c", DependsOn: []string{"b"}}}, "dependency cycle between steps b, c"},
+		{"bad template", []Step{{ID: "a", Prompt: "{{ output "}}, "step \"a\""},
+		{"negative retries", []Step{{ID: "a", Retries: -1}}, "retries must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Definition{Directory: "/srv", Steps: tt.steps}
+			err := d.Validate("/")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	s := newFakeSpawner()
+	r := Start(context.Background(), s, pipeline(0))
+
+	waitFor(t, r, "plan to run", statusIs("plan", StepRunning))
+	if len(s.promptsOf("api")) != 0 {
+		t.Fatal("api should wait for plan")
+	}
+
+	s.setStatus("plan-1", agent.StatusCompleted)
+	waitFor(t, r, "api and web to run", func(steps map[string]StepState) bool {
+		return steps["api"].Status == StepRunning && steps["web"].Status == StepRunning
+	})
+	if got := s.promptsOf("api"); len(got) != 1 || got[0] != "Implement the API: output of plan" {
+		t.Errorf("api prompt = %q, want the plan output filled in", got)
+	}
+
+	// Idle counts as done, like an opencode session that answered its prompt
+	s.setStatus("api-1", agent.StatusIdle)
+	waitFor(t, r, "api to complete", statusIs("api", StepCompleted))
+	if len(s.promptsOf("review")) != 0 {
+		t.Fatal("review should wait for every dependency")
+	}
+
+	s.setStatus("web-1", agent.StatusCompleted)
+	waitFor(t, r, "review to run", statusIs("review", StepRunning))
+	if got := s.promptsOf("review"); got[0] != "Review output of api and output of web" {
+		t.Errorf("review prompt = %q", got[0])
+	}
+
+	s.setStatus("review-1", agent.StatusCompleted)
+	waitFor(t, r, "review to complete", statusIs("review", StepCompleted))
+	if !r.Done() || !r.Succeeded() {
+		t.Error("run should be done and succeeded")
+	}
+	if steps := r.Steps(); steps[3].Level != 2 || steps[3].Output != "output of review" {
+		t.Errorf("review = %+v", steps[3])
+	}
+}
+
+func TestRunPausedStepKeepsRunning(t *testing.T) {
+	s := newFakeSpawner()
+	r := Start(context.Background(), s, pipeline(0))
+	waitFor(t, r, "plan to run", statusIs("plan", StepRunning))
+
+	// A paused agent is idle without having finished
+	s.pause("plan-1", true)
+	for i := 0; i < 5; i++ {
+		r.Update()
+	}
+	if st := r.Steps()[0]; st.Status != StepRunning || st.Output != "" {
+		t.Fatalf("paused plan = %+v, want it still running", st)
+	}
+	if len(s.promptsOf("api")) != 0 {
+		t.Fatal("api started on the output of a paused step")
+	}
+
+	s.pause("plan-1", false)
+	s.setStatus("plan-1", agent.StatusIdle)
+	waitFor(t, r, "plan to complete", statusIs("plan", StepCompleted))
+}
+
+func TestRunRetriesThenHalts(t *testing.T) {
+	s := newFakeSpawner()
+	r := Start(context.Background(), s, pipeline(1))
+
+	waitFor(t, r, "plan to run", statusIs("plan", StepRunning))
+	s.setStatus("plan-1", agent.StatusCompleted)
+	waitFor(t, r, "api to run", statusIs("api", StepRunning))
+
+	s.setStatus("api-1", agent.StatusErrored)
+	waitFor(t, r, "api to be retried", func(steps map[string]StepState) bool {
+		return steps["api"].Status == StepRunning && steps["api"].Attempts == 2
+	})
+	if got := s.promptsOf("api"); len(got) != 2 || got[1] != got[0] {
+		t.Errorf("api prompts = %q, want the same prompt respawned", got)
+	}
+
+	s.setStatus("api-2", agent.StatusErrored)
+	waitFor(t, r, "api to error", statusIs("api", StepErrored))
+	steps := r.Steps()
+	if steps[3].Status != StepSkipped {
+		t.Errorf("review status = %s, want skipped after the halt", steps[3].Status)
+	}
+	if steps[2].Status != StepRunning {
+		t.Errorf("web status = %s, want it left running", steps[2].Status)
+	}
+	if r.Done() {
+		t.Error("run should wait for running steps after halting")
+	}
+
+	s.setStatus("web-1", agent.StatusCompleted)
+	waitFor(t, r, "web to complete", statusIs("web", StepCompleted))
+	if !r.Done() || r.Succeeded() {
+		t.Error("halted run should be done without succeeding")
+	}
+}
+
+func TestRunSpawnFailureHalts(t *testing.T) {
+	s := newFakeSpawner()
+	s.failSpawn["plan"] = true
+	r := Start(context.Background(), s, pipeline(0))
+
+	waitFor(t, r, "plan to error", statusIs("plan", StepErrored))
+	for _, st := range r.Steps()[1:] {
+		if st.Status != StepSkipped {
+			t.Errorf("%s status = %s, want skipped", st.ID, st.Status)
+		}
+	}
+	if !r.Done() || r.Steps()[0].Error != "spawn failed" {
+		t.Errorf("run done = %v, plan = %+v", r.Done(), r.Steps()[0])
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	s := newFakeSpawner()
+	r := Start(context.Background(), s, pipeline(0))
+	waitFor(t, r, "plan to run", statusIs("plan", StepRunning))
+
+	r.Cancel()
+	if !r.Done() {
+		t.Fatal("cancelled run should be done")
+	}
+	for _, st := range r.Steps() {
+		if st.Status != StepCancelled {
+			t.Errorf("%s status = %s, want cancelled", st.ID, st.Status)
+		}
+	}
+	if len(s.terminated) != 1 || s.terminated[0] != "plan-1" {
+		t.Errorf("terminated = %v, want the running agent", s.terminated)
+	}
+}
@@ -0,0 +1,270 @@
+// Package worktree isolates spawned agents in their own git worktree and
+// branch, and merges or discards their work afterwards
+package worktree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BranchPrefix starts the name of every branch created for an agent
+const BranchPrefix = "auto/"
+
+// baseKey is the branch config key holding the commit a branch was created from
+const baseKey = "auto-base"
+
+// ErrNotWorktree is returned by Open for directories AUTO did not create
+var ErrNotWorktree = errors.New("not an AUTO worktree")
+
+// Worktree is a git worktree created for an agent. Everything is kept in
+// git itself, so any process can reopen it from the agent's directory.
+type Worktree struct {
+	Path   string // top of the worktree
+	Branch string // branch checked out in it
+	Base   string // commit the branch was created from
+	Repo   string // top of the main working tree, where merges land
+}
+
+// Create adds a worktree for the repository containing dir on a new branch
+// named after name, under root. It returns the worktree and the directory
+// in it that corresponds to dir.
+func Create(dir, root, name string) (*Worktree, string, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", fmt.Errorf("%s is not in a git repository: %w", dir, err)
+	}
+	rel, err := relativeTo(top, dir)
+	if err != nil {
+		return nil, "", err
+	}
+	base, err := git(top, "rev-parse", "--verify", "HEAD^{commit}")
+	if err != nil {
+		return nil, "", fmt.Errorf("repository %s has no commits: %w", top, err)
+	}
+
+	slug := slugify(name) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	w := &Worktree{
+		Path:   filepath.Join(expandHome(root), filepath.Base(top), slug),
+		Branch: BranchPrefix + slug,
+		Base:   base,
+		Repo:   top,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(w.Path), 0755); err != nil {
+		return nil, "", err
+	}
+	if _, err := git(top, "worktree", "add", "-b", w.Branch, w.Path, base); err != nil {
+		return nil, "", fmt.Errorf("failed to create worktree: %w", err)
+	}
+	if _, err := git(top, "config", "branch."+w.Branch+"."+baseKey, base); err != nil {
+		w.Remove()
+		return nil, "", err
+	}
+	return w, filepath.Join(w.Path, rel), nil
+}
+
+// Open returns the worktree containing dir, or ErrNotWorktree
+func Open(dir string) (*Worktree, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotWorktree
+	}
+	branch, err := git(top, "symbolic-ref", "--short", "HEAD")
+	if err != nil || !strings.HasPrefix(branch, BranchPrefix) {
+		return nil, ErrNotWorktree
+	}
+	base, err := git(top, "config", "--get", "branch."+branch+"."+baseKey)
+	if err != nil {
+		return nil, ErrNotWorktree
+	}
+
+	// The main working tree is always listed first
+	list, err := git(top, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	repo := strings.TrimPrefix(strings.SplitN(list, "\n", 2)[0], "worktree ")
+	if repo == top {
+		return nil, ErrNotWorktree
+	}
+
+	return &Worktree{Path: top, Branch: branch, Base: base, Repo: repo}, nil
+}
+
+// Diff returns the changes in the worktree against its base commit,
+// including uncommitted and untracked files. It only reads the worktree,
+// whose agent may still be working in it.
+func (w *Worktree) Diff() (string, error) {
+	diff, err := gitRaw(w.Path, "diff", w.Base)
+	if err != nil {
+		return "", err
+	}
+	untracked, err := gitRaw(w.Path, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return "", err
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if path == "" {
+			continue
+		}
+		added, err := newFileDiff(w.Path, path)
+		if err != nil {
+			return "", err
+		}
+		diff += added
+	}
+	return diff, nil
+}
+
+// DiffStat summarises Diff
+func (w *Worktree) DiffStat() (string, error) {
+	diff, err := w.Diff()
+	if err != nil || diff == "" {
+		return "", err
+	}
+	stat, err := gitInput(w.Path, diff, "apply", "--stat")
+	return strings.TrimSpace(stat), err
+}
+
+// newFileDiff returns the diff adding an untracked file
+func newFileDiff(dir, path string) (string, error) {
+	cmd := exec.Command("git", "diff", "--no-index", "--", os.DevNull, path)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// --no-index exits with 1 when the files differ, as they do here
+	var exit *exec.ExitError
+	if err := cmd.Run(); err != nil && !(errors.As(err, &exit) && exit.ExitCode() == 1) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git diff: %s", msg)
+		}
+		return "", fmt.Errorf("git diff: %w", err)
+	}
+	return stdout.String(), nil
+}
+
+// Merge commits any uncommitted changes in the worktree with message,
+// merges its branch into the branch checked out in the main working tree
+// and removes the worktree. If the merge fails, it is aborted and the
+// worktree is kept.
+func (w *Worktree) Merge(message string) error {
+	if _, err := git(w.Path, "add", "--all"); err != nil {
+		return err
+	}
+	if status, err := git(w.Path, "status", "--porcelain"); err != nil {
+		return err
+	} else if status != "" {
+		if _, err := git(w.Path, "commit", "--quiet", "--message", message); err != nil {
+			return fmt.Errorf("failed to commit changes: %w", err)
+		}
+	}
+
+	ahead, err := git(w.Repo, "rev-list", "--count", w.Base+".."+w.Branch)
+	if err != nil {
+		return err
+	}
+	if ahead != "0" {
+		if _, err := git(w.Repo, "merge", "--no-ff", "--no-edit", w.Branch); err != nil {
+			git(w.Repo, "merge", "--abort")
+			return fmt.Errorf("failed to merge %s: %w", w.Branch, err)
+		}
+	}
+	return w.Remove()
+}
+
+// Remove discards the worktree and deletes its branch
+func (w *Worktree) Remove() error {
+	if _, err := git(w.Repo, "worktree", "remove", "--force", w.Path); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+	if _, err := git(w.Repo, "branch", "-D", w.Branch); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	out, err := gitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+func gitRaw(dir string, args ...string) (string, error) {
+	return gitInput(dir, "", args...)
+}
+
+// gitInput runs a git command in dir with input on stdin
+func gitInput(dir, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// relativeTo returns dir relative to the repository top, resolving symlinks
+// the way git does
+func relativeTo(top, dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ".", nil
+	}
+	return rel, nil
+}
+
+// slugify turns a name into something safe for a branch and directory name
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimSuffix(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "agent"
+	}
+	return slug
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
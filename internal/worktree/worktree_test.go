@@ -0,0 +1,208 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a git repository with one commit and returns its path
+func newRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(dir, "shop")
+	writeFile(t, filepath.Join(repo, "api", "main.go"), "package main\n")
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "--all"},
+		{"commit", "--quiet", "--message", "initial"},
+	} {
+		if _, err := git(repo, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	return repo
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateAndOpen(t *testing.T) {
+	repo := newRepo(t)
+	root := filepath.Join(filepath.Dir(repo), "worktrees")
+
+	w, dir, err := Create(filepath.Join(repo, "api"), root, "Fix Login!")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(w.Branch, "auto/fix-login-") || w.Repo != repo {
+		t.Errorf("worktree = %+v", w)
+	}
+	if !strings.HasPrefix(w.Path, filepath.Join(root, "shop")) || dir != filepath.Join(w.Path, "api") {
+		t.Errorf("path = %s, dir = %s", w.Path, dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		t.Errorf("worktree is not checked out: %v", err)
+	}
+
+	opened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if *opened != *w {
+		t.Errorf("Open() = %+v, want %+v", opened, w)
+	}
+
+	if _, err := Open(repo); !errors.Is(err, ErrNotWorktree) {
+		t.Errorf("Open(main repo) error = %v, want ErrNotWorktree", err)
+	}
+	if _, err := Open(t.TempDir()); !errors.Is(err, ErrNotWorktree) {
+		t.Errorf("Open(non-repo) error = %v, want ErrNotWorktree", err)
+	}
+}
+
+func TestCreateOutsideRepo(t *testing.T) {
+	newRepo(t)
+	if _, _, err := Create(t.TempDir(), t.TempDir(), "agent"); err == nil {
+		t.Error("Create() outside a repository should fail")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	repo := newRepo(t)
+	w, dir, err := Create(repo, t.TempDir(), "agent")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "api", "main.go"), "package main\n\nfunc main() {}\n")
+	writeFile(t, filepath.Join(dir, "README.md"), "# Shop\n")
+
+	diff, err := w.Diff()
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	for _, want := range []string{"+func main() {}", "+# Shop", "api/main.go", "README.md"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("Diff() missing %q:\n%s", want, diff)
+		}
+	}
+
+	stat, err := w.DiffStat()
+	if err != nil || !strings.Contains(stat, "2 files changed") || !strings.Contains(stat, "README.md") {
+		t.Errorf("DiffStat() = %q, %v", stat, err)
+	}
+
+	// Viewing the changes leaves the index alone
+	if staged, _ := git(dir, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("Diff() staged %q", staged)
+	}
+	if status, _ := git(dir, "status", "--porcelain"); !strings.Contains(status, "?? README.md") {
+		t.Errorf("status = %q, want README.md still untracked", status)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	repo := newRepo(t)
+	w, dir, err := Create(repo, t.TempDir(), "agent")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "README.md"), "# Shop\n")
+
+	if err := w.Merge("Add README"); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo, "README.md"))
+	if err != nil || string(data) != "# Shop\n" {
+		t.Errorf("README.md in repo = %q, %v", data, err)
+	}
+	if log, _ := git(repo, "log", "--format=%s"); !strings.Contains(log, "Add README") {
+		t.Errorf("log = %q, want the agent's commit", log)
+	}
+	assertRemoved(t, w)
+}
+
+func TestMergeConflictKeepsWorktree(t *testing.T) {
+	repo := newRepo(t)
+	w, dir, err := Create(repo, t.TempDir(), "agent")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "api", "main.go"), "package api\n")
+
+	writeFile(t, filepath.Join(repo, "api", "main.go"), "package server\n")
+	git(repo, "commit", "--quiet", "--all", "--message", "rename package")
+
+	if err := w.Merge("Rename package"); err == nil {
+		t.Fatal("Merge() should fail on a conflict")
+	}
+	if status, _ := git(repo, "status", "--porcelain"); status != "" {
+		t.Errorf("repo should be clean after the merge is aborted, got %q", status)
+	}
+	if _, err := Open(dir); err != nil {
+		t.Errorf("worktree should be kept after a failed merge: %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	repo := newRepo(t)
+	w, dir, err := Create(repo, t.TempDir(), "agent")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "scratch.txt"), "throwaway\n")
+
+	if err := w.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	assertRemoved(t, w)
+	if _, err := os.Stat(filepath.Join(repo, "scratch.txt")); !os.IsNotExist(err) {
+		t.Error("discarded changes should not reach the repo")
+	}
+}
+
+func assertRemoved(t *testing.T, w *Worktree) {
+	t.Helper()
+	if _, err := os.Stat(w.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree directory still exists: %v", err)
+	}
+	if branches, _ := git(w.Repo, "branch", "--list", w.Branch); branches != "" {
+		t.Errorf("branch %s still exists", w.Branch)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Fix Login!":                  "fix-login",
+		"  --api--  ":                 "api",
+		"":                            "agent",
+		"émoji 🚀 only":                "moji-only",
+		strings.Repeat("abcdefgh", 8): strings.Repeat("abcdefgh", 5),
+	}
+	for in, want := range tests {
+		if got := slugify(in); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
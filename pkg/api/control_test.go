@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/config"
+	"github.com/CastAIPhil/AUTO/internal/session"
+)
+
+// streamingMockAgent is a StreamingAgent whose runs emit a fixed set of events
+type streamingMockAgent struct {
+	*agent.MockAgent
+	mu        sync.Mutex
+	executing bool
+	cancelled bool
+}
+
+func (a *streamingMockAgent) SendInputAsync(ctx context.Context, input string) (<-chan agent.StreamEvent, error) {
+	events := make(chan agent.StreamEvent, 3)
+	events <- agent.StreamEvent{Type: "text", AgentID: a.ID(), Text: "echo: " + input}
+	events <- agent.StreamEvent{Type: "tool", AgentID: a.ID(), ToolName: "bash", State: "completed"}
+	events <- agent.StreamEvent{Type: "done", AgentID: a.ID()}
+	close(events)
+	return events, nil
+}
+
+func (a *streamingMockAgent) IsExecuting() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.executing
+}
+
+func (a *streamingMockAgent) CancelExecution() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cancelled = true
+	a.executing = false
+}
+
+// unsupportedAgent rejects pause and resume like file-backed agents do
+type unsupportedAgent struct {
+	*agent.MockAgent
+}
+
+func (a *unsupportedAgent) Pause() error {
+	return fmt.Errorf("pause %w for test sessions", agent.ErrUnsupported)
+}
+
+func do(server *Server, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) Response {
+	t.Helper()
+
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	return resp
+}
+
+func TestSpawnAgent(t *testing.T) {
+	registry := agent.NewRegistry()
+	provider := agent.NewMockProvider()
+	registry.Register(provider)
+	manager := session.NewManager(&config.Config{}, nil, registry, nil)
+	server := NewServer(manager, ":0")
+
+	w := do(server, http.MethodPost, "/api/agents", `{"type":"mock","name":"worker","directory":"/tmp/w","prompt":"go"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	resp := decodeResponse(t, w)
+	data := resp.Data.(map[string]interface{})
+	if data["id"] != "worker-id" || data["directory"] != "/tmp/w" {
+		t.Errorf("spawned agent = %v", data)
+	}
+	if _, ok := manager.Get("worker-id"); !ok {
+		t.Error("spawned agent should be tracked by the manager")
+	}
+
+	w = do(server, http.MethodPost, "/api/agents", `{not json`)
+	if w.Code != http.StatusBadRequest || decodeResponse(t, w).Code != CodeBadRequest {
+		t.Errorf("invalid body: status = %d", w.Code)
+	}
+
+	w = do(server, http.MethodPost, "/api/agents", `{"type":"other","name":"x"}`)
+	if w.Code != http.StatusBadRequest || decodeResponse(t, w).Code != CodeBadRequest {
+		t.Errorf("unknown type: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSendInputPlain(t *testing.T) {
+	server, manager := setupTestServer()
+
+	w := do(server, http.MethodPost, "/api/agents/agent-1/input", `{"input":"hello"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	a, _ := manager.Get("agent-1")
+	if mock := a.(*agent.MockAgent); mock.LastInput != "hello" {
+		t.Errorf("LastInput = %q, want hello", mock.LastInput)
+	}
+
+	w = do(server, http.MethodPost, "/api/agents/agent-1/input", `{"input":""}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty input: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = do(server, http.MethodPost, "/api/agents/missing/input", `{"input":"x"}`)
+	if w.Code != http.StatusNotFound || decodeResponse(t, w).Code != CodeNotFound {
+		t.Errorf("missing agent: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestSendInputStreamsNDJSON(t *testing.T) {
+	server, manager := setupTestServer()
+	manager.AddAgentForTesting(&streamingMockAgent{MockAgent: agent.NewMockAgent("stream-1", "Streamer")})
+
+	w := do(server, http.MethodPost, "/api/agents/stream-1/input", `{"input":"build it"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	var events []agent.StreamEvent
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var event agent.StreamEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if events[0].Text != "echo: build it" || events[1].ToolName != "bash" || events[2].Type != "done" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestCancelAgent(t *testing.T) {
+	server, manager := setupTestServer()
+	streamer := &streamingMockAgent{MockAgent: agent.NewMockAgent("stream-1", "Streamer")}
+	manager.AddAgentForTesting(streamer)
+
+	w := do(server, http.MethodPost, "/api/agents/stream-1/cancel", "")
+	if w.Code != http.StatusConflict || decodeResponse(t, w).Code != CodeConflict {
+		t.Errorf("idle cancel: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	streamer.executing = true
+	w = do(server, http.MethodPost, "/api/agents/stream-1/cancel", "")
+	if w.Code != http.StatusOK || !streamer.cancelled {
+		t.Errorf("cancel: status = %d, cancelled = %v", w.Code, streamer.cancelled)
+	}
+
+	w = do(server, http.MethodPost, "/api/agents/agent-1/cancel", "")
+	if w.Code != http.StatusNotImplemented || decodeResponse(t, w).Code != CodeUnsupported {
+		t.Errorf("non-streaming cancel: status = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestPauseResumeAgent(t *testing.T) {
+	server, manager := setupTestServer()
+	manager.AddAgentForTesting(&unsupportedAgent{MockAgent: agent.NewMockAgent("fixed-1", "Fixed")})
+
+	w := do(server, http.MethodPost, "/api/agents/agent-1/pause", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("pause: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	a, _ := manager.Get("agent-1")
+	if a.Status() != agent.StatusIdle {
+		t.Errorf("status after pause = %v, want idle", a.Status())
+	}
+
+	w = do(server, http.MethodPost, "/api/agents/agent-1/resume", "")
+	if w.Code != http.StatusOK || a.Status() != agent.StatusRunning {
+		t.Errorf("resume: status = %d, agent = %v", w.Code, a.Status())
+	}
+
+	w = do(server, http.MethodPost, "/api/agents/fixed-1/pause", "")
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("unsupported pause: status = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+	if resp := decodeResponse(t, w); resp.Code != CodeUnsupported || resp.Success {
+		t.Errorf("unsupported pause response = %+v", resp)
+	}
+}
+
+func TestRoutingErrors(t *testing.T) {
+	server, _ := setupTestServer()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{http.MethodDelete, "/api/agents", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{http.MethodGet, "/api/agents/agent-1/pause", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{http.MethodPost, "/api/agents/agent-1/explode", http.StatusNotFound, CodeNotFound},
+		{http.MethodGet, "/api/unknown", http.StatusNotFound, CodeNotFound},
+		{http.MethodPost, "/api/agents/missing/terminate", http.StatusNotFound, CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := do(server, tt.method, tt.path, "")
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if resp := decodeResponse(t, w); resp.Code != tt.code {
+				t.Errorf("code = %q, want %q", resp.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// clientBufferSize is how many messages a client may lag behind before it is evicted
+	clientBufferSize = 256
+	// writeWait bounds a single write to a client
+	writeWait = 10 * time.Second
+	// pingInterval is how often idle streams are kept alive
+	pingInterval = 30 * time.Second
+)
+
+// StreamMessage is a single message on the /api/events stream
+type StreamMessage struct {
+	Kind      string        `json:"kind"` // "agent_event" or "alert"
+	Timestamp time.Time     `json:"timestamp"`
+	Event     *EventMessage `json:"event,omitempty"`
+	Alert     *AlertMessage `json:"alert,omitempty"`
+}
+
+// EventMessage represents an agent.Event on the stream
+type EventMessage struct {
+	Type    string         `json:"type"`
+	AgentID string         `json:"agent_id"`
+	Agent   *AgentResponse `json:"agent,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// AlertMessage represents an alert.Alert on the stream
+type AlertMessage struct {
+	ID      string `json:"id"`
+	Level   string `json:"level"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	AgentID string `json:"agent_id,omitempty"`
+}
+
+// streamClient is a subscriber to the event stream (WebSocket or SSE)
+type streamClient struct {
+	send chan []byte
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// PublishEvent fans an agent event out to all stream clients without blocking
+func (s *Server) PublishEvent(event agent.Event) {
+	msg := &EventMessage{
+		Type:    event.Type.String(),
+		AgentID: event.AgentID,
+	}
+	if event.Agent != nil {
+		resp := toAgentResponse(event.Agent)
+		msg.Agent = &resp
+	}
+	if event.Error != nil {
+		msg.Error = event.Error.Error()
+	}
+
+	ts := event.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	s.broadcast(StreamMessage{Kind: "agent_event", Timestamp: ts, Event: msg})
+}
+
+// PublishAlert fans an alert out to all stream clients without blocking
+func (s *Server) PublishAlert(a *alert.Alert) {
+	s.broadcast(StreamMessage{
+		Kind:      "alert",
+		Timestamp: a.Timestamp,
+		Alert: &AlertMessage{
+			ID:      a.ID,
+			Level:   string(a.Level),
+			Title:   a.Title,
+			Message: a.Message,
+			AgentID: a.AgentID,
+		},
+	})
+}
+
+// ClientCount returns the number of connected stream clients
+func (s *Server) ClientCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.clients)
+}
+
+func (s *Server) broadcast(msg StreamMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		select {
+		case c.send <- data:
+		default:
+			// The client is not keeping up; drop it rather than block the publisher
+			delete(s.clients, c)
+			close(c.send)
+			log.Printf("API: evicted slow event stream client")
+		}
+	}
+}
+
+func (s *Server) addClient() *streamClient {
+	c := &streamClient{send: make(chan []byte, clientBufferSize)}
+	s.mu.Lock()
+	s.clients[c] = true
+	s.mu.Unlock()
+	return c
+}
+
+func (s *Server) removeClient(c *streamClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c.send)
+	}
+}
+
+// handleEvents streams events over WebSocket, or SSE for plain HTTP clients
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+	s.serveSSE(w, r)
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade already replied with an error
+	}
+	defer conn.Close()
+
+	c := s.addClient()
+	defer s.removeClient(c)
+
+	// Drain incoming frames so close and pong messages are processed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "too slow"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "streaming not supported")
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	c := s.addClient()
+	defer s.removeClient(c)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				return
+			}
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
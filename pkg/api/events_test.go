@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
+	"github.com/gorilla/websocket"
+)
+
+func waitForClients(t *testing.T, server *Server, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.ClientCount() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("ClientCount() = %d, want %d", server.ClientCount(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	server, manager := setupTestServer()
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/events"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	waitForClients(t, server, 1)
+
+	a, _ := manager.Get("agent-1")
+	server.PublishEvent(agent.Event{Type: agent.EventAgentCompleted, AgentID: "agent-1", Agent: a})
+	server.PublishAlert(&alert.Alert{ID: "alert-1", Level: alert.LevelSuccess, Title: "Done", AgentID: "agent-1"})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg StreamMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if msg.Kind != "agent_event" || msg.Event == nil || msg.Event.Type != "completed" {
+		t.Fatalf("first message = %+v, want completed agent event", msg)
+	}
+	if msg.Event.Agent == nil || msg.Event.Agent.Name != "Test Agent 1" {
+		t.Errorf("event agent = %+v, want Test Agent 1", msg.Event.Agent)
+	}
+
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if msg.Kind != "alert" || msg.Alert == nil || msg.Alert.ID != "alert-1" {
+		t.Errorf("second message = %+v, want alert-1", msg)
+	}
+
+	conn.Close()
+	waitForClients(t, server, 0)
+}
+
+func TestEventsSSE(t *testing.T) {
+	server, _ := setupTestServer()
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	waitForClients(t, server, 1)
+	server.PublishEvent(agent.Event{Type: agent.EventAgentErrored, AgentID: "agent-2"})
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var msg StreamMessage
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg); err != nil {
+			t.Fatalf("invalid SSE payload %q: %v", line, err)
+		}
+		if msg.Event == nil || msg.Event.Type != "errored" || msg.Event.AgentID != "agent-2" {
+			t.Errorf("SSE message = %+v, want errored event for agent-2", msg)
+		}
+		return
+	}
+}
+
+func TestEventsSlowClientEvicted(t *testing.T) {
+	server, _ := setupTestServer()
+
+	// A client that never reads
+	c := server.addClient()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < clientBufferSize+10; i++ {
+			server.PublishEvent(agent.Event{Type: agent.EventAgentUpdated, AgentID: "agent-1"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publishing blocked on a slow client")
+	}
+
+	if server.ClientCount() != 0 {
+		t.Errorf("ClientCount() = %d, want slow client evicted", server.ClientCount())
+	}
+	if len(c.send) != clientBufferSize {
+		t.Errorf("buffered %d messages, want %d", len(c.send), clientBufferSize)
+	}
+
+	// Removing an already evicted client is a no-op
+	server.removeClient(c)
+}
+
+func TestEventsMethodNotAllowed(t *testing.T) {
+	server, _ := setupTestServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events", nil)
+	w := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
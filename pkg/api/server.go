@@ -3,27 +3,31 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
 	"github.com/CastAIPhil/AUTO/internal/session"
+	"github.com/CastAIPhil/AUTO/internal/store"
 )
 
 // Server provides the HTTP API
 type Server struct {
 	manager    *session.Manager
+	alertMgr   *alert.Manager
 	addr       string
+	token      string
 	httpServer *http.Server
+	ctx        context.Context
 	mu         sync.RWMutex
-	clients    map[*wsClient]bool
-}
-
-// wsClient represents a WebSocket client
-type wsClient struct {
-	send chan []byte
+	clients    map[*streamClient]bool
 }
 
 // NewServer creates a new API server
@@ -31,18 +35,37 @@ func NewServer(manager *session.Manager, addr string) *Server {
 	s := &Server{
 		manager: manager,
 		addr:    addr,
-		clients: make(map[*wsClient]bool),
+		ctx:     context.Background(),
+		clients: make(map[*streamClient]bool),
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/agents", s.handleAgents)
-	mux.HandleFunc("/api/agents/", s.handleAgent)
-	mux.HandleFunc("/api/stats", s.handleStats)
-	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.HandleFunc("GET /api/health", s.handleHealth)
+	mux.HandleFunc("GET /api/agents", s.handleAgents)
+	mux.HandleFunc("POST /api/agents", s.handleSpawn)
+	mux.HandleFunc("GET /api/agents/{id}", s.handleAgent)
+	mux.HandleFunc("POST /api/agents/{id}/terminate", s.handleTerminate)
+	mux.HandleFunc("POST /api/agents/{id}/input", s.handleInput)
+	mux.HandleFunc("POST /api/agents/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("POST /api/agents/{id}/pause", s.handlePause)
+	mux.HandleFunc("POST /api/agents/{id}/resume", s.handleResume)
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	mux.HandleFunc("GET /api/deliveries", s.handleDeliveries)
+
+	// Known paths with the wrong method get a structured 405, anything else a 404
+	for _, path := range []string{
+		"/api/health", "/api/agents", "/api/agents/{id}", "/api/stats", "/api/events", "/api/deliveries",
+		"/api/agents/{id}/terminate", "/api/agents/{id}/input", "/api/agents/{id}/cancel",
+		"/api/agents/{id}/pause", "/api/agents/{id}/resume",
+	} {
+		mux.HandleFunc(path, s.handleMethodNotAllowed)
+	}
+	mux.HandleFunc("/", s.handleNotFound)
 
 	s.httpServer = &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      s.authorize(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -51,6 +74,38 @@ func NewServer(manager *session.Manager, addr string) *Server {
 	return s
 }
 
+// SetContext sets the context that spawned agents are bound to
+func (s *Server) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// SetToken sets the bearer token that every request but health checks must
+// carry; empty accepts them without one
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// authorize rejects requests without the bearer token. Reads need it too,
+// since agent output and the event stream carry everything agents do.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && r.URL.Path != "/api/health" {
+			got := []byte(r.Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(got, []byte("Bearer "+s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="auto"`)
+				s.writeError(w, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetAlerts sets the alert manager whose deliveries the API lists
+func (s *Server) SetAlerts(m *alert.Manager) {
+	s.alertMgr = m
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
@@ -61,6 +116,11 @@ func (s *Server) Stop(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
 // Addr returns the server address
 func (s *Server) Addr() string {
 	return s.addr
@@ -71,6 +131,23 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"` // Machine-readable error code
+}
+
+// Error codes returned in Response.Code
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnsupported      = "unsupported"
+	CodeConflict         = "conflict"
+	CodeInternal         = "internal_error"
+)
+
+// InputRequest is the body of POST /api/agents/{id}/input
+type InputRequest struct {
+	Input string `json:"input"`
 }
 
 // AgentResponse represents an agent in API responses
@@ -88,19 +165,47 @@ type AgentResponse struct {
 	TokensOut    int64     `json:"tokens_out"`
 }
 
+// toAgentResponse converts an agent into its API representation
+func toAgentResponse(a agent.Agent) AgentResponse {
+	metrics := a.Metrics()
+	return AgentResponse{
+		ID:           a.ID(),
+		Name:         a.Name(),
+		Type:         a.Type(),
+		Status:       a.Status().String(),
+		Directory:    a.Directory(),
+		ProjectID:    a.ProjectID(),
+		CurrentTask:  a.CurrentTask(),
+		StartTime:    a.StartTime(),
+		LastActivity: a.LastActivity(),
+		TokensIn:     metrics.TokensIn,
+		TokensOut:    metrics.TokensOut,
+	}
+}
+
 func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
 
-func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+func (s *Server) writeError(w http.ResponseWriter, status int, code, message string) {
 	s.writeJSON(w, status, Response{
 		Success: false,
 		Error:   message,
+		Code:    code,
 	})
 }
 
+// writeControlError maps an agent control error onto a status and code
+func (s *Server) writeControlError(w http.ResponseWriter, action string, err error) {
+	if errors.Is(err, agent.ErrUnsupported) {
+		s.writeError(w, http.StatusNotImplemented, CodeUnsupported, err.Error())
+		return
+	}
+	s.writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("failed to %s: %v", action, err))
+}
+
 func (s *Server) writeSuccess(w http.ResponseWriter, data interface{}) {
 	s.writeJSON(w, http.StatusOK, Response{
 		Success: true,
@@ -108,99 +213,184 @@ func (s *Server) writeSuccess(w http.ResponseWriter, data interface{}) {
 	})
 }
 
+func (s *Server) handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	s.writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
+
+func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	s.writeError(w, http.StatusNotFound, CodeNotFound, "not found")
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
 	s.writeSuccess(w, map[string]string{"status": "healthy"})
 }
 
 func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-
 	agents := s.manager.List()
 	response := make([]AgentResponse, 0, len(agents))
 
 	for _, a := range agents {
-		metrics := a.Metrics()
-		response = append(response, AgentResponse{
-			ID:           a.ID(),
-			Name:         a.Name(),
-			Type:         a.Type(),
-			Status:       a.Status().String(),
-			Directory:    a.Directory(),
-			ProjectID:    a.ProjectID(),
-			CurrentTask:  a.CurrentTask(),
-			StartTime:    a.StartTime(),
-			LastActivity: a.LastActivity(),
-			TokensIn:     metrics.TokensIn,
-			TokensOut:    metrics.TokensOut,
-		})
+		response = append(response, toAgentResponse(a))
 	}
 
 	s.writeSuccess(w, response)
 }
 
+// lookupAgent resolves the {id} path value, writing a 404 if it is unknown
+func (s *Server) lookupAgent(w http.ResponseWriter, r *http.Request) (agent.Agent, bool) {
+	id := r.PathValue("id")
+	a, found := s.manager.Get(id)
+	if !found {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "agent not found")
+		return nil, false
+	}
+	return a, true
+}
+
 func (s *Server) handleAgent(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from path: /api/agents/{id}
-	id := r.URL.Path[len("/api/agents/"):]
-	if id == "" {
-		s.writeError(w, http.StatusBadRequest, "agent ID required")
+	a, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	s.writeSuccess(w, toAgentResponse(a))
+}
+
+func (s *Server) handleSpawn(w http.ResponseWriter, r *http.Request) {
+	var cfg agent.SpawnConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid spawn config: %v", err))
+		return
+	}
+
+	a, err := s.manager.Spawn(s.ctx, cfg)
+	if errors.Is(err, session.ErrUnknownAgentType) {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("failed to spawn: %v", err))
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, Response{
+		Success: true,
+		Data:    toAgentResponse(a),
+	})
+}
+
+func (s *Server) handleTerminate(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	if err := s.manager.Terminate(a.ID()); err != nil {
+		s.writeControlError(w, "terminate", err)
+		return
+	}
+	s.writeSuccess(w, map[string]string{"status": "terminated"})
+}
+
+// handleInput sends input to an agent. Streaming agents have their
+// execution events written back as NDJSON until the run finishes.
+func (s *Server) handleInput(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+
+	var req InputRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid input request: %v", err))
+		return
+	}
+	if req.Input == "" {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "input required")
 		return
 	}
 
-	// Handle terminate action
-	if len(id) > 10 && id[len(id)-10:] == "/terminate" {
-		id = id[:len(id)-10]
-		if r.Method != http.MethodPost {
-			s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	sa, streaming := a.(agent.StreamingAgent)
+	if !streaming {
+		if err := s.manager.SendInput(a.ID(), req.Input); err != nil {
+			s.writeControlError(w, "send input", err)
 			return
 		}
-		if err := s.manager.Terminate(id); err != nil {
-			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to terminate: %v", err))
+		s.writeSuccess(w, map[string]string{"status": "sent"})
+		return
+	}
+
+	if sa.IsExecuting() {
+		s.writeError(w, http.StatusConflict, CodeConflict, "agent is already executing")
+		return
+	}
+
+	events, err := s.manager.SendInputAsync(r.Context(), a.ID(), req.Input)
+	if err != nil {
+		s.writeControlError(w, "send input", err)
+		return
+	}
+
+	// The stream lasts as long as the agent's run, beyond the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for event := range events {
+		if err := enc.Encode(event); err != nil {
+			sa.CancelExecution()
 			return
 		}
-		s.writeSuccess(w, map[string]string{"status": "terminated"})
-		return
+		rc.Flush()
 	}
+}
 
-	if r.Method != http.MethodGet {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAgent(w, r)
+	if !ok {
 		return
 	}
 
-	agent, found := s.manager.Get(id)
-	if !found {
-		s.writeError(w, http.StatusNotFound, "agent not found")
+	sa, streaming := a.(agent.StreamingAgent)
+	if !streaming {
+		s.writeError(w, http.StatusNotImplemented, CodeUnsupported, fmt.Sprintf("cancel not supported for %s agents", a.Type()))
+		return
+	}
+	if !sa.IsExecuting() {
+		s.writeError(w, http.StatusConflict, CodeConflict, "agent is not executing")
 		return
 	}
 
-	metrics := agent.Metrics()
-	s.writeSuccess(w, AgentResponse{
-		ID:           agent.ID(),
-		Name:         agent.Name(),
-		Type:         agent.Type(),
-		Status:       agent.Status().String(),
-		Directory:    agent.Directory(),
-		ProjectID:    agent.ProjectID(),
-		CurrentTask:  agent.CurrentTask(),
-		StartTime:    agent.StartTime(),
-		LastActivity: agent.LastActivity(),
-		TokensIn:     metrics.TokensIn,
-		TokensOut:    metrics.TokensOut,
-	})
+	sa.CancelExecution()
+	s.writeSuccess(w, map[string]string{"status": "cancelled"})
 }
 
-func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	if err := s.manager.Pause(a.ID()); err != nil {
+		s.writeControlError(w, "pause", err)
 		return
 	}
+	s.writeSuccess(w, map[string]string{"status": "paused"})
+}
 
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	if err := s.manager.Resume(a.ID()); err != nil {
+		s.writeControlError(w, "resume", err)
+		return
+	}
+	s.writeSuccess(w, map[string]string{"status": "resumed"})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	stats := s.manager.Stats()
 
 	// Convert status map to string keys for JSON
@@ -218,3 +408,34 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		"total_errors":    stats.TotalErrors,
 	})
 }
+
+// handleDeliveries lists alert deliveries to remote channels, most
+// recently updated first. ?status=failed lists the failed ones.
+func (s *Server) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.DeliveryPending, store.DeliverySent, store.DeliveryFailed:
+	default:
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("unknown status %q", status))
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+		limit = n
+	}
+
+	deliveries := []alert.Delivery{}
+	if s.alertMgr != nil {
+		var err error
+		if deliveries, err = s.alertMgr.Deliveries(status, limit); err != nil {
+			s.writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("failed to list deliveries: %v", err))
+			return
+		}
+	}
+	s.writeSuccess(w, deliveries)
+}
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/CastAIPhil/AUTO/internal/agent"
+	"github.com/CastAIPhil/AUTO/internal/alert"
 	"github.com/CastAIPhil/AUTO/internal/config"
 	"github.com/CastAIPhil/AUTO/internal/session"
 )
@@ -49,7 +52,7 @@ func TestHandleHealth(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
 	w := httptest.NewRecorder()
 
-	server.handleHealth(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
@@ -68,7 +71,7 @@ func TestHandleHealthMethodNotAllowed(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/health", nil)
 	w := httptest.NewRecorder()
 
-	server.handleHealth(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusMethodNotAllowed {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
@@ -81,7 +84,7 @@ func TestHandleAgents(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/agents", nil)
 	w := httptest.NewRecorder()
 
-	server.handleAgents(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
@@ -108,7 +111,7 @@ func TestHandleAgent(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/agents/agent-1", nil)
 	w := httptest.NewRecorder()
 
-	server.handleAgent(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
@@ -127,7 +130,7 @@ func TestHandleAgentNotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/agents/non-existent", nil)
 	w := httptest.NewRecorder()
 
-	server.handleAgent(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusNotFound {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
@@ -140,7 +143,7 @@ func TestHandleStats(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
 	w := httptest.NewRecorder()
 
-	server.handleStats(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
@@ -167,9 +170,100 @@ func TestHandleAgentTerminate(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/agents/agent-1/terminate", nil)
 	w := httptest.NewRecorder()
 
-	server.handleAgent(w, req)
+	server.Handler().ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
 	}
 }
+
+func TestHandleDeliveries(t *testing.T) {
+	server, _ := setupTestServer()
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer discord.Close()
+	alertMgr := alert.NewManager(&config.AlertsConfig{
+		DiscordEnabled:    true,
+		DiscordWebhookURL: discord.URL,
+		Delivery:          config.DeliveryConfig{RetryConfig: config.RetryConfig{MaxAttempts: 1}},
+	}, nil)
+	defer alertMgr.Close()
+	server.SetAlerts(alertMgr)
+
+	alertMgr.Send(context.Background(), &alert.Alert{Level: alert.LevelError, Title: "Agent Error"})
+	var deliveries []alert.Delivery
+	for deadline := time.Now().Add(5 * time.Second); len(deliveries) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		req := httptest.NewRequest(http.MethodGet, "/api/deliveries?status=failed", nil)
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp struct {
+			Data []alert.Delivery `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		deliveries = resp.Data
+	}
+	if len(deliveries) != 1 || deliveries[0].Channel != "discord" || deliveries[0].Status != "failed" || deliveries[0].Title != "Agent Error" {
+		t.Errorf("deliveries = %+v, want the failed discord delivery", deliveries)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/deliveries?status=lost", nil)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	server, _ := setupTestServer()
+	server.SetToken("s3cret")
+
+	for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/agents/agent-1/terminate", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusUnauthorized || resp.Code != CodeUnauthorized {
+			t.Errorf("Authorization %q: status = %d (%s), want unauthorized", auth, w.Code, resp.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/agents/agent-1/terminate", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	if w.Code == http.StatusUnauthorized {
+		t.Error("request with the token was rejected")
+	}
+
+	// Reading needs the token too, except for health checks
+	for _, path := range []string{"/api/agents", "/api/agents/agent-1", "/api/events"} {
+		w = httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s status = %d, want %d", path, w.Code, http.StatusUnauthorized)
+		}
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/agents", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET with the token status = %d, want %d", w.Code, http.StatusOK)
+	}
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("health status = %d, want %d", w.Code, http.StatusOK)
+	}
+}